@@ -57,6 +57,19 @@ const (
 	TerminateSuspendDebtNamespaceAnnoStatus = "TerminateSuspend"
 )
 
+// The annotations of the resources which record their state before the namespace is suspended,
+// the state is restored and the annotations are removed when the namespace is resumed.
+const (
+	PreSuspendReplicasAnnoKey       = "debt.sealos/pre-suspend-replicas"
+	PreSuspendDevboxStateAnnoKey    = "debt.sealos/pre-suspend-state"
+	PreSuspendCronJobSuspendAnnoKey = "debt.sealos/pre-suspend-suspend"
+	PreSuspendIngressBackendAnnoKey = "debt.sealos/pre-suspend-backends"
+)
+
+// PaymentRequiredServiceLabelKey labels the payment required service created by the controller
+// in a suspended namespace, the services of the same name without the label belong to the user.
+const PaymentRequiredServiceLabelKey = "debt.sealos/payment-required"
+
 // DebtSpec defines the desired state of Debt
 type DebtSpec struct {
 	UserName string `json:"userName,omitempty"`
@@ -92,10 +92,19 @@ type BillingReconciler struct {
 	AccountV2       database.AccountV2
 	Properties      *resources.PropertyTypeLS
 	concurrentLimit int64
+
+	// Budget checks the budgets of the owners after their billings, nil to disable it
+	Budget *BudgetChecker
 }
 
 func (r *BillingReconciler) ExecuteBillingTask() error {
 	r.Logger.Info("start billing reconcile", "time", time.Now().Format(time.RFC3339))
+	// the extension controllers may create properties at any time
+	if properties, err := r.DBClient.GetPropertyTypeLS(); err != nil {
+		r.Logger.Error(err, "failed to reload the property types, billing with the loaded ones")
+	} else {
+		r.Properties = properties
+	}
 	ownerListMap, err := r.getRecentUsedOwners()
 	if err != nil {
 		return fmt.Errorf("failed to get the owner list of the recently used resource: %w", err)
@@ -149,6 +158,12 @@ func (r *BillingReconciler) reconcileOwnerList(ownerListMap map[string][]string,
 			if err := r.DBClient.UpdateBillingStatus(orderIDs, resources.Unsettled); err != nil {
 				r.Logger.Error(err, "update billing unsettled status failed", "orderIDs", orderIDs)
 			}
+			continue
+		}
+		if r.Budget != nil {
+			if err := r.Budget.Check(context.Background(), owner); err != nil {
+				r.Logger.Error(err, "check budget failed", "owner", owner)
+			}
 		}
 	}
 	if len(failedList) > 0 {
@@ -239,6 +254,9 @@ func (r *BillingReconciler) Init() error {
 	if err := r.DBClient.CreateBillingIfNotExist(); err != nil {
 		return fmt.Errorf("create billing collection failed: %w", err)
 	}
+	if err := r.DBClient.CreatePricingRulesIndex(); err != nil {
+		return fmt.Errorf("create pricing rules index failed: %w", err)
+	}
 	r.concurrentLimit = env.GetInt64EnvWithDefault("BILLING_CONCURRENT_LIMIT", 100)
 	return nil
 }
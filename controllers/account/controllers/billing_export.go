@@ -0,0 +1,470 @@
+/*
+Copyright 2024 sealos.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/parquet-go/parquet-go"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/labring/sealos/controllers/pkg/database"
+	v1 "github.com/labring/sealos/controllers/pkg/notification/api/v1"
+	"github.com/labring/sealos/controllers/pkg/resources"
+	pkgtypes "github.com/labring/sealos/controllers/pkg/types"
+	"github.com/labring/sealos/controllers/pkg/utils/env"
+
+	objectstoragev1 "github/labring/sealos/controllers/objectstorage/api/v1"
+)
+
+const (
+	EnvBillingExportInterval  = "BILLING_EXPORT_INTERVAL"
+	EnvBillingExportURLExpiry = "BILLING_EXPORT_URL_EXPIRY"
+	// OSExternalEndpointEnv is the endpoint of the object storage for the users, the download
+	// links of the exports are signed for it.
+	OSExternalEndpointEnv = "OSExternalEndpoint"
+
+	defaultBillingExportInterval = 10 * time.Minute
+	// the presigned urls of the object storage expire in at most 7 days
+	defaultBillingExportURLExpiry = 7 * 24 * time.Hour
+	billingExportBatchSize        = 20
+	maxBillingExportBackoff       = 6 * time.Hour
+	// billingExportPartSize is the part size of the multipart uploads of the exports, it is the
+	// memory buffered for an upload.
+	billingExportPartSize = 16 << 20
+	// billingExportRowGroupSize is the number of the rows of a row group of the parquet exports.
+	billingExportRowGroupSize = 100000
+
+	// billingExportBucketName is the ObjectStorageBucket of the exports in the namespace of the user,
+	// the object storage controller reconciles it into the private bucket <user>-billing-export.
+	billingExportBucketName   = "billing-export"
+	billingExportBucketPolicy = "private"
+	billingExportNoticePrefix = "billing-export-"
+	billingExportFromEn       = "Billing-System"
+	billingExportFromZh       = "账单系统"
+)
+
+// BillingLineItem is a line of the billing export, it is the usage and the amount of a property
+// of an app in a billing. The amounts are in the same unit as the balances, 1 = 1/1,000,000 ¥.
+type BillingLineItem struct {
+	Time      time.Time `parquet:"time,timestamp(millisecond)"`
+	OrderID   string    `parquet:"order_id"`
+	Namespace string    `parquet:"namespace"`
+	AppType   string    `parquet:"app_type"`
+	AppName   string    `parquet:"app_name"`
+	NodePool  string    `parquet:"node_pool"`
+	Property  string    `parquet:"property"`
+	Used      int64     `parquet:"used"`
+	Unit      string    `parquet:"unit"`
+	Amount    int64     `parquet:"amount"`
+}
+
+var billingLineItemHeader = []string{"time", "order_id", "namespace", "app_type", "app_name", "node_pool", "property", "used", "unit", "amount"}
+
+//+kubebuilder:rbac:groups=objectstorage.sealos.io,resources=objectstoragebuckets,verbs=get;list;watch;create;update;patch
+
+// BillingExportTaskRunner creates the billing exports of the due schedules and writes the pending
+// billing exports to the object storage of their users, the users are notified with the download link.
+type BillingExportTaskRunner struct {
+	client.Client
+	AccountV2 database.AccountV2
+	DBClient  database.Account
+	Logger    logr.Logger
+	Interval  time.Duration
+	URLExpiry time.Duration
+
+	OSNamespace      string
+	OSAdminSecret    string
+	InternalEndpoint string
+	ExternalEndpoint string
+
+	osClient      *minio.Client
+	presignClient *minio.Client
+}
+
+func (r *BillingExportTaskRunner) Start(ctx context.Context) error {
+	r.OSNamespace = os.Getenv(OSNamespace)
+	r.OSAdminSecret = os.Getenv(OSAdminSecret)
+	r.InternalEndpoint = os.Getenv(OSInternalEndpointEnv)
+	r.ExternalEndpoint = os.Getenv(OSExternalEndpointEnv)
+	if r.InternalEndpoint == "" || r.OSNamespace == "" || r.OSAdminSecret == "" {
+		r.Logger.Info("the endpoint or namespace or admin secret env of object storage is nil, skip billing export")
+		return nil
+	}
+	if r.Interval <= 0 {
+		r.Interval = env.GetDurationEnvWithDefault(EnvBillingExportInterval, defaultBillingExportInterval)
+	}
+	if r.URLExpiry <= 0 {
+		r.URLExpiry = env.GetDurationEnvWithDefault(EnvBillingExportURLExpiry, defaultBillingExportURLExpiry)
+	}
+	ticker := time.NewTicker(r.Interval)
+	defer func() {
+		ticker.Stop()
+		r.Logger.Info("stop billing export")
+	}()
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Export(ctx); err != nil {
+				r.Logger.Error(err, "fail to export billings")
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Export creates the billing exports of the due schedules and processes the pending billing exports.
+func (r *BillingExportTaskRunner) Export(ctx context.Context) error {
+	created, err := r.AccountV2.CreateDueBillingExports(time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to create due billing exports: %w", err)
+	}
+	if created > 0 {
+		r.Logger.Info("created scheduled billing exports", "count", created)
+	}
+	exports, err := r.AccountV2.GetPendingBillingExports(time.Now(), billingExportBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to get pending billing exports: %w", err)
+	}
+	for i := range exports {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		export := &exports[i]
+		owner, err := r.export(ctx, export)
+		if err != nil {
+			r.Logger.Error(err, "fail to export billings", "id", export.ID, "userUID", export.UserUID, "attempts", export.Attempts+1)
+			retryBillingExport(export, err, time.Now(), r.Interval)
+		} else {
+			export.Status, export.Error, export.NextAttemptAt = pkgtypes.BillingExportStatusDone, "", nil
+		}
+		if err := r.AccountV2.UpdateBillingExport(export); err != nil {
+			return fmt.Errorf("failed to update billing export %s: %w", export.ID, err)
+		}
+		if owner == "" || export.Status == pkgtypes.BillingExportStatusPending {
+			continue
+		}
+		if err := r.sendNotice(ctx, owner, export); err != nil {
+			r.Logger.Error(err, "fail to send billing export notice", "id", export.ID, "owner", owner)
+		}
+	}
+	return nil
+}
+
+// retryBillingExport records the failed attempt of the export, the export is retried with an
+// exponential backoff from interval, and fails after MaxBillingExportAttempts attempts.
+func retryBillingExport(export *pkgtypes.BillingExport, err error, now time.Time, interval time.Duration) {
+	export.Attempts++
+	export.Error = err.Error()
+	if export.Attempts >= pkgtypes.MaxBillingExportAttempts {
+		export.Status, export.NextAttemptAt = pkgtypes.BillingExportStatusFailed, nil
+		return
+	}
+	backoff := interval << (export.Attempts - 1)
+	if backoff > maxBillingExportBackoff {
+		backoff = maxBillingExportBackoff
+	}
+	nextAttemptAt := now.UTC().Add(backoff)
+	export.NextAttemptAt = &nextAttemptAt
+}
+
+// export writes the billing export to the object storage of the user and returns the owner to notify.
+// The billings are streamed from the database into a multipart upload of the object.
+func (r *BillingExportTaskRunner) export(ctx context.Context, export *pkgtypes.BillingExport) (string, error) {
+	user, err := r.AccountV2.GetUserCr(&pkgtypes.UserQueryOpts{UID: export.UserUID})
+	if err != nil {
+		return "", fmt.Errorf("failed to get user cr: %w", err)
+	}
+	properties, err := r.DBClient.GetPropertyTypeLS()
+	if err != nil {
+		return user.CrName, fmt.Errorf("failed to get property types: %w", err)
+	}
+	if err = r.initOSClient(ctx); err != nil {
+		return user.CrName, err
+	}
+	bucket, err := r.ensureBillingExportBucket(ctx, user.CrName)
+	if err != nil {
+		return user.CrName, err
+	}
+	object := billingExportObject(export)
+	contentType := "text/csv"
+	if export.Format == pkgtypes.BillingExportFormatParquet {
+		contentType = "application/vnd.apache.parquet"
+	}
+	reader, writer := io.Pipe()
+	var rows int64
+	written := make(chan error, 1)
+	go func() {
+		err := r.writeBillingExport(writer, user.CrName, export, properties, &rows)
+		_ = writer.CloseWithError(err)
+		written <- err
+	}()
+	_, err = r.osClient.PutObject(ctx, bucket, object, reader, -1, minio.PutObjectOptions{ContentType: contentType, PartSize: billingExportPartSize})
+	// stop the writer if the upload failed before reading all of it
+	_ = reader.CloseWithError(err)
+	if writeErr := <-written; writeErr != nil {
+		return user.CrName, fmt.Errorf("failed to write %s: %w", export.Format, writeErr)
+	}
+	if err != nil {
+		return user.CrName, fmt.Errorf("failed to put object %s/%s: %w", bucket, object, err)
+	}
+	params := url.Values{}
+	params.Set("response-content-disposition", fmt.Sprintf("attachment; filename=%q", path.Base(object)))
+	link, err := r.presignClient.PresignedGetObject(ctx, bucket, object, r.URLExpiry, params)
+	if err != nil {
+		return user.CrName, fmt.Errorf("failed to presign object %s/%s: %w", bucket, object, err)
+	}
+	expireAt := time.Now().UTC().Add(r.URLExpiry)
+	export.Bucket, export.Object, export.URL, export.URLExpireAt, export.Rows = bucket, object, link.String(), &expireAt, rows
+	return user.CrName, nil
+}
+
+// writeBillingExport writes the line items of the billings of the export read from the database
+// to w, and counts them in rows.
+func (r *BillingExportTaskRunner) writeBillingExport(w io.Writer, owner string, export *pkgtypes.BillingExport, properties *resources.PropertyTypeLS, rows *int64) error {
+	writer, err := newBillingExportWriter(w, export.Format)
+	if err != nil {
+		return err
+	}
+	if err = r.DBClient.WalkConsumptionBillings(owner, export.Workspace, export.StartTime, export.EndTime, func(billing *resources.Billing) error {
+		items := billingLineItems(billing, properties)
+		*rows += int64(len(items))
+		return writer.Write(items)
+	}); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// ensureBillingExportBucket ensures the private ObjectStorageBucket of the exports of the user and
+// returns the name of its bucket, the export is retried until the object storage controller reconciles it.
+func (r *BillingExportTaskRunner) ensureBillingExportBucket(ctx context.Context, owner string) (string, error) {
+	bucket := &objectstoragev1.ObjectStorageBucket{ObjectMeta: metav1.ObjectMeta{Name: billingExportBucketName, Namespace: GetUserNamespace(owner)}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, bucket, func() error {
+		bucket.Spec.Policy = billingExportBucketPolicy
+		return nil
+	}); err != nil {
+		return "", fmt.Errorf("failed to ensure object storage bucket %s/%s: %w", bucket.Namespace, bucket.Name, err)
+	}
+	if bucket.Status.Name == "" {
+		return "", fmt.Errorf("object storage bucket %s/%s is not ready", bucket.Namespace, bucket.Name)
+	}
+	return bucket.Status.Name, nil
+}
+
+func (r *BillingExportTaskRunner) initOSClient(ctx context.Context) error {
+	if r.osClient != nil {
+		return nil
+	}
+	secret := &corev1.Secret{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: r.OSAdminSecret, Namespace: r.OSNamespace}, secret); err != nil {
+		return fmt.Errorf("failed to get secret %s/%s: %w", r.OSNamespace, r.OSAdminSecret, err)
+	}
+	accessKey, secretKey := string(secret.Data[OSAccessKey]), string(secret.Data[OSSecretKey])
+	osClient, err := objectstoragev1.NewOSClient(r.InternalEndpoint, accessKey, secretKey)
+	if err != nil {
+		return fmt.Errorf("failed to new object storage client: %w", err)
+	}
+	presignClient := osClient
+	if r.ExternalEndpoint != "" {
+		// the region is set to sign the links without requesting the bucket location from the external endpoint
+		presignClient, err = minio.New(r.ExternalEndpoint, &minio.Options{
+			Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+			Secure: true,
+			Region: "us-east-1",
+		})
+		if err != nil {
+			return fmt.Errorf("failed to new external object storage client: %w", err)
+		}
+	}
+	r.osClient, r.presignClient = osClient, presignClient
+	return nil
+}
+
+func (r *BillingExportTaskRunner) sendNotice(ctx context.Context, owner string, export *pkgtypes.BillingExport) error {
+	scopeEn, scopeZh := "all workspaces", "所有工作空间"
+	if export.Workspace != "" {
+		scopeEn, scopeZh = "workspace "+export.Workspace, "工作空间 "+export.Workspace
+	}
+	start, end := export.StartTime.Format(time.DateOnly), export.EndTime.Format(time.DateOnly)
+	title, titleZh := "Billing Export Ready", "账单导出完成"
+	messageEn := fmt.Sprintf("The billing export of %s from %s to %s is ready, download it before %s: %s",
+		scopeEn, start, end, export.URLExpireAt.Format(time.DateTime), export.URL)
+	messageZh := fmt.Sprintf("%s %s 至 %s 的账单已导出，请在 %s 前下载：%s", scopeZh, start, end, export.URLExpireAt.Format(time.DateTime), export.URL)
+	if export.Status == pkgtypes.BillingExportStatusFailed {
+		title, titleZh = "Billing Export Failed", "账单导出失败"
+		messageEn = fmt.Sprintf("The billing export of %s from %s to %s failed, please try again later.", scopeEn, start, end)
+		messageZh = fmt.Sprintf("%s %s 至 %s 的账单导出失败，请稍后重试。", scopeZh, start, end)
+	}
+	ntf := &v1.Notification{ObjectMeta: metav1.ObjectMeta{Name: billingExportNoticePrefix + export.ID.String(), Namespace: GetUserNamespace(owner)}}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, ntf, func() error {
+		ntf.Spec = v1.NotificationSpec{
+			Title:      title,
+			Message:    messageEn,
+			From:       billingExportFromEn,
+			Importance: v1.Low,
+			Timestamp:  time.Now().UTC().Unix(),
+			I18n: map[string]v1.I18n{
+				languageZh: {
+					Title:   titleZh,
+					From:    billingExportFromZh,
+					Message: messageZh,
+				},
+			},
+		}
+		if ntf.Labels == nil {
+			ntf.Labels = make(map[string]string)
+		}
+		ntf.Labels[readStatusLabel] = falseStatus
+		return nil
+	})
+	return err
+}
+
+// billingExportObject returns the object name of the export, the exports of a workspace are put
+// in the directory of the workspace.
+func billingExportObject(export *pkgtypes.BillingExport) string {
+	dir := "all"
+	if export.Workspace != "" {
+		dir = export.Workspace
+	}
+	return fmt.Sprintf("%s/%s_%s_%s.%s", dir, export.StartTime.UTC().Format("20060102"), export.EndTime.UTC().Format("20060102"),
+		export.ID.String()[:8], export.Format)
+}
+
+// billingLineItems flattens the billing into a line item per property of every app cost, the
+// billing without app costs is a single line item of its amount.
+func billingLineItems(billing *resources.Billing, properties *resources.PropertyTypeLS) []BillingLineItem {
+	base := BillingLineItem{
+		Time:      billing.Time.UTC(),
+		OrderID:   billing.OrderID,
+		Namespace: billing.Namespace,
+		AppType:   resources.AppTypeReverse[billing.AppType],
+		AppName:   billing.AppName,
+	}
+	if len(billing.AppCosts) == 0 {
+		base.Amount = billing.Amount
+		return []BillingLineItem{base}
+	}
+	var items []BillingLineItem
+	for _, cost := range billing.AppCosts {
+		item := base
+		item.AppType, item.NodePool = resources.AppTypeReverse[cost.Type], cost.NodePool
+		if cost.Name != "" {
+			item.AppName = cost.Name
+		}
+		enums := make([]int, 0, len(cost.Used))
+		for enum := range cost.Used {
+			enums = append(enums, int(enum))
+		}
+		for enum := range cost.UsedAmount {
+			if _, ok := cost.Used[enum]; !ok {
+				enums = append(enums, int(enum))
+			}
+		}
+		if len(enums) == 0 {
+			item.Amount = cost.Amount
+			items = append(items, item)
+			continue
+		}
+		sort.Ints(enums)
+		for _, enum := range enums {
+			propertyItem := item
+			propertyItem.Property = strconv.Itoa(enum)
+			if property, ok := properties.EnumMap[uint8(enum)]; ok {
+				propertyItem.Property, propertyItem.Unit = property.Name, property.UnitString
+			}
+			propertyItem.Used, propertyItem.Amount = cost.Used[uint8(enum)], cost.UsedAmount[uint8(enum)]
+			items = append(items, propertyItem)
+		}
+	}
+	return items
+}
+
+// billingExportWriter writes the line items of a billing export in its format, the file is
+// complete when it is closed.
+type billingExportWriter interface {
+	Write(items []BillingLineItem) error
+	Close() error
+}
+
+func newBillingExportWriter(w io.Writer, format pkgtypes.BillingExportFormat) (billingExportWriter, error) {
+	switch format {
+	case pkgtypes.BillingExportFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(billingLineItemHeader); err != nil {
+			return nil, err
+		}
+		return &csvExportWriter{writer: writer}, nil
+	case pkgtypes.BillingExportFormatParquet:
+		// the row groups are flushed to w as they are full instead of buffering the whole file
+		return &parquetExportWriter{writer: parquet.NewGenericWriter[BillingLineItem](w, parquet.MaxRowsPerRowGroup(billingExportRowGroupSize))}, nil
+	default:
+		return nil, format.Validate()
+	}
+}
+
+type csvExportWriter struct {
+	writer *csv.Writer
+}
+
+func (c *csvExportWriter) Write(items []BillingLineItem) error {
+	for _, item := range items {
+		if err := c.writer.Write([]string{
+			item.Time.Format(time.RFC3339), item.OrderID, item.Namespace, item.AppType, item.AppName, item.NodePool,
+			item.Property, strconv.FormatInt(item.Used, 10), item.Unit, strconv.FormatInt(item.Amount, 10),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *csvExportWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+type parquetExportWriter struct {
+	writer *parquet.GenericWriter[BillingLineItem]
+}
+
+func (p *parquetExportWriter) Write(items []BillingLineItem) error {
+	_, err := p.writer.Write(items)
+	return err
+}
+
+func (p *parquetExportWriter) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2024 sealos.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/labring/sealos/controllers/pkg/database"
+	"github.com/labring/sealos/controllers/pkg/resources"
+	pkgtypes "github.com/labring/sealos/controllers/pkg/types"
+
+	objectstoragev1 "github/labring/sealos/controllers/objectstorage/api/v1"
+)
+
+func TestBillingExport(t *testing.T) {
+	now := time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC)
+	cpu, memory := resources.DefaultPropertyTypeLS.StringMap["cpu"], resources.DefaultPropertyTypeLS.StringMap["memory"]
+	billings := []resources.Billing{
+		{
+			Time: now, OrderID: "order-1", Namespace: "ns-user", AppType: resources.AppType[resources.APP], AppName: "web",
+			AppCosts: []resources.AppCost{{
+				Type:       resources.AppType[resources.APP],
+				Name:       "web",
+				Used:       resources.EnumUsedMap{memory.Enum: 2048, cpu.Enum: 1000},
+				UsedAmount: resources.EnumUsedMap{memory.Enum: 200, cpu.Enum: 6700},
+				Amount:     6900,
+			}},
+			Amount: 6900,
+		},
+		{Time: now.Add(time.Hour), OrderID: "order-2", Namespace: "ns-user", AppType: resources.AppType[resources.LLMToken], AppName: "chat", Amount: 100},
+	}
+	var items []BillingLineItem
+	for i := range billings {
+		items = append(items, billingLineItems(&billings[i], resources.DefaultPropertyTypeLS)...)
+	}
+	want := []BillingLineItem{
+		{Time: now, OrderID: "order-1", Namespace: "ns-user", AppType: resources.APP, AppName: "web", Property: cpu.Name, Used: 1000, Unit: cpu.UnitString, Amount: 6700},
+		{Time: now, OrderID: "order-1", Namespace: "ns-user", AppType: resources.APP, AppName: "web", Property: memory.Name, Used: 2048, Unit: memory.UnitString, Amount: 200},
+		{Time: now.Add(time.Hour), OrderID: "order-2", Namespace: "ns-user", AppType: resources.LLMToken, AppName: "chat", Amount: 100},
+	}
+	if cpu.Enum > memory.Enum {
+		want[0], want[1] = want[1], want[0]
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Fatalf("billingLineItems() = %+v, want %+v", items, want)
+	}
+
+	r := &BillingExportTaskRunner{DBClient: &fakeBillings{billings: billings}}
+	export := &pkgtypes.BillingExport{Format: pkgtypes.BillingExportFormatCSV, StartTime: now, EndTime: now.AddDate(0, 1, 0)}
+	buf := &bytes.Buffer{}
+	var rows int64
+	if err := r.writeBillingExport(buf, "user", export, resources.DefaultPropertyTypeLS, &rows); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows != int64(len(items)) || len(records) != len(items)+1 || !reflect.DeepEqual(records[0], billingLineItemHeader) {
+		t.Fatalf("rows = %d, csv records = %v", rows, records)
+	}
+	if got := records[3]; got[0] != now.Add(time.Hour).Format(time.RFC3339) || got[4] != "chat" || got[9] != "100" {
+		t.Errorf("csv record = %v", got)
+	}
+
+	buf.Reset()
+	rows = 0
+	export.Format = pkgtypes.BillingExportFormatParquet
+	if err := r.writeBillingExport(buf, "user", export, resources.DefaultPropertyTypeLS, &rows); err != nil {
+		t.Fatal(err)
+	}
+	parquetRows, err := parquet.Read[BillingLineItem](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows != int64(len(items)) || !reflect.DeepEqual(parquetRows, items) {
+		t.Errorf("rows = %d, parquet rows = %+v, want %+v", rows, parquetRows, items)
+	}
+
+	// the error of the database stops the export
+	r.DBClient = &fakeBillings{billings: billings, err: errors.New("cursor closed")}
+	if err := r.writeBillingExport(io.Discard, "user", export, resources.DefaultPropertyTypeLS, &rows); err == nil {
+		t.Errorf("writeBillingExport() error = nil, want the error of the database")
+	}
+}
+
+// fakeBillings walks the billings, and fails after them with err.
+type fakeBillings struct {
+	database.Account
+	billings []resources.Billing
+	err      error
+}
+
+func (f *fakeBillings) WalkConsumptionBillings(_, _ string, _, _ time.Time, fn func(billing *resources.Billing) error) error {
+	for i := range f.billings {
+		if err := fn(&f.billings[i]); err != nil {
+			return err
+		}
+	}
+	return f.err
+}
+
+func TestRetryBillingExport(t *testing.T) {
+	now := time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC)
+	export := &pkgtypes.BillingExport{Status: pkgtypes.BillingExportStatusPending}
+	for attempt, backoff := range []time.Duration{10 * time.Minute, 20 * time.Minute, 40 * time.Minute, 80 * time.Minute} {
+		retryBillingExport(export, errors.New("bucket is not ready"), now, 10*time.Minute)
+		if export.Status != pkgtypes.BillingExportStatusPending || export.Attempts != attempt+1 {
+			t.Fatalf("attempt %d: status = %s, attempts = %d", attempt+1, export.Status, export.Attempts)
+		}
+		if export.NextAttemptAt == nil || !export.NextAttemptAt.Equal(now.Add(backoff)) {
+			t.Errorf("attempt %d: next attempt at %v, want %v", attempt+1, export.NextAttemptAt, now.Add(backoff))
+		}
+	}
+	retryBillingExport(export, errors.New("bucket is not ready"), now, 10*time.Minute)
+	if export.Status != pkgtypes.BillingExportStatusFailed || export.NextAttemptAt != nil || export.Error != "bucket is not ready" {
+		t.Errorf("status = %s, next attempt at %v, error %q, want failed", export.Status, export.NextAttemptAt, export.Error)
+	}
+
+	// the backoff is capped
+	export = &pkgtypes.BillingExport{Status: pkgtypes.BillingExportStatusPending, Attempts: 3}
+	retryBillingExport(export, errors.New("timeout"), now, time.Hour)
+	if !export.NextAttemptAt.Equal(now.Add(maxBillingExportBackoff)) {
+		t.Errorf("next attempt at %v, want %v", export.NextAttemptAt, now.Add(maxBillingExportBackoff))
+	}
+}
+
+func TestEnsureBillingExportBucket(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := objectstoragev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	clt := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&objectstoragev1.ObjectStorageBucket{}).Build()
+	r := &BillingExportTaskRunner{Client: clt}
+	ctx := context.Background()
+
+	// the bucket is not ready until the object storage controller reconciles it
+	if _, err := r.ensureBillingExportBucket(ctx, "user"); err == nil {
+		t.Fatal("ensureBillingExportBucket() error = nil, want not ready")
+	}
+	bucket := &objectstoragev1.ObjectStorageBucket{}
+	if err := clt.Get(ctx, client.ObjectKey{Name: billingExportBucketName, Namespace: "ns-user"}, bucket); err != nil {
+		t.Fatal(err)
+	}
+	if bucket.Spec.Policy != billingExportBucketPolicy {
+		t.Errorf("bucket policy = %q, want %q", bucket.Spec.Policy, billingExportBucketPolicy)
+	}
+	bucket.Status.Name = "user-billing-export"
+	if err := clt.Status().Update(ctx, bucket); err != nil {
+		t.Fatal(err)
+	}
+	name, err := r.ensureBillingExportBucket(ctx, "user")
+	if err != nil || name != "user-billing-export" {
+		t.Errorf("ensureBillingExportBucket() = %q, %v, want user-billing-export", name, err)
+	}
+}
@@ -0,0 +1,334 @@
+/*
+Copyright 2024 sealos.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+
+	client2 "github.com/alibabacloud-go/dysmsapi-20170525/v3/client"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	accountv1 "github.com/labring/sealos/controllers/account/api/v1"
+	"github.com/labring/sealos/controllers/account/controllers/utils"
+	"github.com/labring/sealos/controllers/pkg/database"
+	v1 "github.com/labring/sealos/controllers/pkg/notification/api/v1"
+	pkgtypes "github.com/labring/sealos/controllers/pkg/types"
+	"github.com/labring/sealos/controllers/pkg/utils/env"
+)
+
+const (
+	// BudgetSMSCodeEnv is the sms template code of the budget alert, its params are
+	// user_id, threshold and amount.
+	BudgetSMSCodeEnv = "BUDGET_SMS_CODE"
+	// BudgetCheckIntervalEnv is the interval to check the budgets which are not evaluated in the
+	// current month, e.g. the changed budgets and the suspended budgets of the previous month.
+	BudgetCheckIntervalEnv = "BUDGET_CHECK_INTERVAL"
+
+	defaultBudgetCheckInterval = 10 * time.Minute
+
+	budgetNoticePrefix = "budget-alert-"
+	budgetFromEn       = "Budget-System"
+	budgetFromZh       = "预算系统"
+)
+
+// BudgetChecker alerts the users whose spending of the month crosses the thresholds of
+// their budgets, and suspends the namespaces of the budgets with the hard limit.
+// The budgets are checked after the billings of their owners, and periodically when they
+// are changed or a new month starts, as the suspended owners have no billings to resume them.
+type BudgetChecker struct {
+	client.Client
+	AccountV2 database.AccountV2
+	DBClient  database.Account
+	logr.Logger
+	Interval time.Duration
+
+	smsConfig  *SmsConfig
+	smtpConfig *utils.SMTPConfig
+	now        func() time.Time
+}
+
+// budgetActions is the result of evaluating a budget with the spending of the month.
+type budgetActions struct {
+	// alert is the threshold to alert, 0 if there is nothing to alert
+	alert   int
+	suspend bool
+	resume  bool
+}
+
+func (c *BudgetChecker) Init() {
+	c.Logger = ctrl.Log.WithName("controller").WithName("Budget")
+	c.now = time.Now
+	if smtpConfig, err := newSMTPConfigFromEnv(); err != nil {
+		c.Logger.Info("email of budget alert is disabled", "reason", err.Error())
+	} else {
+		c.smtpConfig = smtpConfig
+	}
+	if code := os.Getenv(BudgetSMSCodeEnv); code != "" {
+		if err := env.CheckEnvSetting([]string{SMSAccessKeyIDEnv, SMSAccessKeySecretEnv, SMSEndpointEnv, SMSSignNameEnv}); err != nil {
+			c.Logger.Error(err, "sms of budget alert is disabled")
+			return
+		}
+		smsClient, err := utils.CreateSMSClient(os.Getenv(SMSAccessKeyIDEnv), os.Getenv(SMSAccessKeySecretEnv), os.Getenv(SMSEndpointEnv))
+		if err != nil {
+			c.Logger.Error(err, "sms of budget alert is disabled")
+			return
+		}
+		c.smsConfig = &SmsConfig{Client: smsClient, SmsSignName: os.Getenv(SMSSignNameEnv), SmsCode: map[int]string{0: code}}
+	}
+}
+
+func (c *BudgetChecker) Start(ctx context.Context) error {
+	if c.Interval <= 0 {
+		c.Interval = env.GetDurationEnvWithDefault(BudgetCheckIntervalEnv, defaultBudgetCheckInterval)
+	}
+	ticker := time.NewTicker(c.Interval)
+	defer func() {
+		ticker.Stop()
+		c.Logger.Info("stop budget check")
+	}()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.CheckUnchecked(ctx); err != nil {
+				c.Logger.Error(err, "fail to check budgets")
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// CheckUnchecked checks the owners of the budgets which are not evaluated in the current month.
+func (c *BudgetChecker) CheckUnchecked(ctx context.Context) error {
+	budgets, err := c.AccountV2.GetUncheckedBudgets(pkgtypes.BudgetPeriod(c.now()))
+	if err != nil {
+		return err
+	}
+	checked := make(map[uuid.UUID]bool)
+	for i := range budgets {
+		userUID := budgets[i].UserUID
+		if checked[userUID] {
+			continue
+		}
+		checked[userUID] = true
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		userCr, err := c.AccountV2.GetUserCr(&pkgtypes.UserQueryOpts{UID: userUID})
+		if err != nil {
+			c.Logger.Error(err, "get user cr of budget failed", "userUID", userUID)
+			continue
+		}
+		if err = c.Check(ctx, userCr.CrName); err != nil {
+			c.Logger.Error(err, "check budget failed", "owner", userCr.CrName)
+		}
+	}
+	return nil
+}
+
+// Check evaluates the budgets of the owner with the consumption of the current month.
+func (c *BudgetChecker) Check(ctx context.Context, owner string) error {
+	budgets, err := c.AccountV2.GetBudgets(&pkgtypes.UserQueryOpts{Owner: owner})
+	if err != nil {
+		return fmt.Errorf("get budgets failed: %w", err)
+	}
+	if len(budgets) == 0 {
+		return nil
+	}
+	now := c.now().UTC()
+	period := pkgtypes.BudgetPeriod(now)
+	consumption, err := c.DBClient.GetOwnerConsumption(owner, period, now)
+	if err != nil {
+		return fmt.Errorf("get consumption failed: %w", err)
+	}
+	var ownNamespaces []string
+	for i := range budgets {
+		budget := &budgets[i]
+		spent, namespaces := consumption[budget.Workspace], []string{budget.Workspace}
+		if budget.Workspace == "" {
+			spent = 0
+			for _, amount := range consumption {
+				spent += amount
+			}
+			if ownNamespaces == nil {
+				if ownNamespaces, err = getOwnNsList(c.Client, owner); err != nil {
+					return err
+				}
+			}
+			namespaces = ownNamespaces
+		}
+		changed := budget.Period.IsZero() || !budget.Period.Equal(period)
+		actions := evaluateBudget(budget, spent, period)
+		if actions.resume {
+			if inDebt, err := c.inDebt(owner); err != nil {
+				return err
+			} else if inDebt {
+				// the namespaces are kept suspended for the debt, the debt controller resumes them
+				c.Logger.Info("skip resuming the namespaces of the budget in debt", "owner", owner, "workspace", budget.Workspace)
+			} else if err = c.updateNamespaceStatus(ctx, accountv1.ResumeDebtNamespaceAnnoStatus, namespaces); err != nil {
+				return fmt.Errorf("resume namespaces failed: %w", err)
+			}
+			budget.Suspended, changed = false, true
+		}
+		if actions.alert > 0 {
+			if err = c.sendAlert(ctx, owner, budget, spent, actions.alert, namespaces); err != nil {
+				c.Logger.Error(err, "send budget alert failed", "owner", owner, "workspace", budget.Workspace, "threshold", actions.alert)
+			}
+			budget.AlertedThreshold, changed = actions.alert, true
+		}
+		if actions.suspend {
+			c.Logger.Info("suspend the namespaces of the budget with hard limit", "owner", owner, "workspace", budget.Workspace, "spent", spent, "budget", budget.MonthlyAmount)
+			if err = c.updateNamespaceStatus(ctx, accountv1.SuspendDebtNamespaceAnnoStatus, namespaces); err != nil {
+				return fmt.Errorf("suspend namespaces failed: %w", err)
+			}
+			budget.Suspended, changed = true, true
+		}
+		budget.Period = period
+		if changed {
+			if err = c.AccountV2.UpdateBudgetStatus(budget); err != nil {
+				return fmt.Errorf("update budget status failed: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// evaluateBudget returns what to do with the spending of period, the alert status of the
+// budget is reset at the start of a new period.
+func evaluateBudget(budget *pkgtypes.Budget, spent int64, period time.Time) budgetActions {
+	alerted := budget.AlertedThreshold
+	if !budget.Period.Equal(period) {
+		alerted = 0
+	}
+	var actions budgetActions
+	if crossed := budget.CrossedThreshold(spent); crossed > alerted {
+		actions.alert = crossed
+	}
+	exceeded := budget.ExceedsHardLimit(spent)
+	actions.suspend = exceeded && !budget.Suspended
+	actions.resume = !exceeded && budget.Suspended
+	return actions
+}
+
+func (c *BudgetChecker) inDebt(owner string) (bool, error) {
+	account, err := c.AccountV2.GetAccount(&pkgtypes.UserQueryOpts{Owner: owner})
+	if err != nil {
+		return false, fmt.Errorf("get account failed: %w", err)
+	}
+	return account.Balance-account.DeductionBalance < 0, nil
+}
+
+func (c *BudgetChecker) updateNamespaceStatus(ctx context.Context, status string, namespaces []string) error {
+	for i := range namespaces {
+		ns := &corev1.Namespace{}
+		if err := c.Get(ctx, types.NamespacedName{Name: namespaces[i]}, ns); err != nil {
+			return err
+		}
+		if ns.Annotations == nil {
+			ns.Annotations = make(map[string]string)
+		}
+		ns.Annotations[accountv1.DebtNamespaceAnnoStatusKey] = status
+		if err := c.Update(ctx, ns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *BudgetChecker) sendAlert(ctx context.Context, owner string, budget *pkgtypes.Budget, spent int64, threshold int, namespaces []string) error {
+	amount, limit := formatAmount(spent), formatAmount(budget.MonthlyAmount)
+	scopeEn, scopeZh := "your account", "您的账户"
+	if budget.Workspace != "" {
+		scopeEn, scopeZh = "workspace "+budget.Workspace, "工作空间 "+budget.Workspace
+	}
+	messageEn := fmt.Sprintf("The spending of %s this month has reached %d%% of the budget: %s / %s.", scopeEn, threshold, amount, limit)
+	messageZh := fmt.Sprintf("%s本月的消费已达到预算的 %d%%：%s / %s。", scopeZh, threshold, amount, limit)
+	if budget.ExceedsHardLimit(spent) {
+		messageEn += " The resources are suspended by the hard limit until the budget is raised or the next month."
+		messageZh += " 资源已因预算硬限制被暂停，提高预算或下月后恢复。"
+	}
+	spec := v1.NotificationSpec{
+		Title:        "Budget Alert",
+		Message:      messageEn,
+		From:         budgetFromEn,
+		Importance:   v1.High,
+		DesktopPopup: true,
+		Timestamp:    c.now().UTC().Unix(),
+		I18n: map[string]v1.I18n{
+			languageZh: {
+				Title:   "预算告警",
+				From:    budgetFromZh,
+				Message: messageZh,
+			},
+		},
+	}
+	for i := range namespaces {
+		ntf := &v1.Notification{ObjectMeta: metav1.ObjectMeta{Name: budgetNoticePrefix + strconv.Itoa(threshold), Namespace: namespaces[i]}}
+		if _, err := controllerutil.CreateOrUpdate(ctx, c.Client, ntf, func() error {
+			ntf.Spec = *spec.DeepCopy()
+			if ntf.Labels == nil {
+				ntf.Labels = make(map[string]string)
+			}
+			ntf.Labels[readStatusLabel] = falseStatus
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	if c.smsConfig == nil && c.smtpConfig == nil {
+		return nil
+	}
+	phone, email, err := getUserContact(c.AccountV2, owner)
+	if err != nil {
+		return err
+	}
+	if c.smsConfig != nil && phone != "" {
+		if err = utils.SendSms(c.smsConfig.Client, &client2.SendSmsRequest{
+			PhoneNumbers:  tea.String(phone),
+			SignName:      tea.String(c.smsConfig.SmsSignName),
+			TemplateCode:  tea.String(c.smsConfig.SmsCode[0]),
+			TemplateParam: tea.String(fmt.Sprintf(`{"user_id":%q,"threshold":"%d","amount":%q}`, owner, threshold, amount)),
+		}); err != nil {
+			return fmt.Errorf("failed to send sms notice: %w", err)
+		}
+	}
+	if c.smtpConfig != nil && email != "" {
+		if err = c.smtpConfig.SendEmail(messageZh+"<br>"+messageEn, email); err != nil {
+			return fmt.Errorf("failed to send email notice: %w", err)
+		}
+	}
+	return nil
+}
+
+// formatAmount formats an amount in the unit of 1/1000000 ¥.
+func formatAmount(amount int64) string {
+	return fmt.Sprintf("¥%.2f", float64(amount)/1_000_000)
+}
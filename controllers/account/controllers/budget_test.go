@@ -0,0 +1,103 @@
+/*
+Copyright 2024 sealos.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+
+	"github.com/labring/sealos/controllers/pkg/database"
+	pkgtypes "github.com/labring/sealos/controllers/pkg/types"
+)
+
+func TestEvaluateBudget(t *testing.T) {
+	period := pkgtypes.BudgetPeriod(time.Date(2024, 3, 15, 8, 0, 0, 0, time.UTC))
+	lastPeriod := period.AddDate(0, -1, 0)
+	tests := []struct {
+		name   string
+		budget pkgtypes.Budget
+		spent  int64
+		want   budgetActions
+	}{
+		{"under thresholds", pkgtypes.Budget{MonthlyAmount: 100}, 49, budgetActions{}},
+		{"cross 50", pkgtypes.Budget{MonthlyAmount: 100}, 50, budgetActions{alert: 50}},
+		{"cross 50 and 80 at once", pkgtypes.Budget{MonthlyAmount: 100}, 85, budgetActions{alert: 80}},
+		{"already alerted", pkgtypes.Budget{MonthlyAmount: 100, Period: period, AlertedThreshold: 80}, 90, budgetActions{}},
+		{"alerted last month", pkgtypes.Budget{MonthlyAmount: 100, Period: lastPeriod, AlertedThreshold: 80}, 60, budgetActions{alert: 50}},
+		{"custom thresholds", pkgtypes.Budget{MonthlyAmount: 100, Thresholds: []int{90, 30}}, 40, budgetActions{alert: 30}},
+		{"soft limit exceeded", pkgtypes.Budget{MonthlyAmount: 100, Period: period, AlertedThreshold: 80}, 120, budgetActions{alert: 100}},
+		{"hard limit exceeded", pkgtypes.Budget{MonthlyAmount: 100, HardLimit: true, Period: period, AlertedThreshold: 100}, 100, budgetActions{suspend: true}},
+		{"hard limit kept", pkgtypes.Budget{MonthlyAmount: 100, HardLimit: true, Period: period, AlertedThreshold: 100, Suspended: true}, 130, budgetActions{}},
+		{"budget raised", pkgtypes.Budget{MonthlyAmount: 200, HardLimit: true, Period: period, Suspended: true}, 130, budgetActions{alert: 50, resume: true}},
+		{"new month", pkgtypes.Budget{MonthlyAmount: 100, HardLimit: true, Period: lastPeriod, AlertedThreshold: 100, Suspended: true}, 0, budgetActions{resume: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := evaluateBudget(&tt.budget, tt.spent, period); got != tt.want {
+				t.Errorf("evaluateBudget() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeBudgets returns the unchecked budgets and records the owners whose budgets are checked.
+type fakeBudgets struct {
+	database.AccountV2
+	unchecked []pkgtypes.Budget
+	period    time.Time
+	owners    []string
+}
+
+func (f *fakeBudgets) GetUncheckedBudgets(period time.Time) ([]pkgtypes.Budget, error) {
+	f.period = period
+	return f.unchecked, nil
+}
+
+func (f *fakeBudgets) GetUserCr(ops *pkgtypes.UserQueryOpts) (*pkgtypes.RegionUserCr, error) {
+	return &pkgtypes.RegionUserCr{CrName: "owner-" + ops.UID.String()[:1], UserUID: ops.UID}, nil
+}
+
+func (f *fakeBudgets) GetBudgets(ops *pkgtypes.UserQueryOpts) ([]pkgtypes.Budget, error) {
+	f.owners = append(f.owners, ops.Owner)
+	return nil, nil
+}
+
+func TestCheckUncheckedBudgets(t *testing.T) {
+	now := time.Date(2024, 4, 1, 0, 5, 0, 0, time.UTC)
+	user1, user2 := uuid.MustParse("10000000-0000-0000-0000-000000000000"), uuid.MustParse("20000000-0000-0000-0000-000000000000")
+	budgets := &fakeBudgets{unchecked: []pkgtypes.Budget{
+		// a budget suspended last month and a changed budget of the same user
+		{UserUID: user1, Suspended: true, Period: now.AddDate(0, -1, 0)},
+		{UserUID: user1, Workspace: "ns-1"},
+		{UserUID: user2},
+	}}
+	checker := &BudgetChecker{AccountV2: budgets, Logger: logr.Discard(), now: func() time.Time { return now }}
+	if err := checker.CheckUnchecked(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !budgets.period.Equal(pkgtypes.BudgetPeriod(now)) {
+		t.Errorf("period = %s, want %s", budgets.period, pkgtypes.BudgetPeriod(now))
+	}
+	if want := []string{"owner-1", "owner-2"}; !reflect.DeepEqual(budgets.owners, want) {
+		t.Errorf("checked owners = %v, want %v", budgets.owners, want)
+	}
+}
@@ -50,6 +50,7 @@ import (
 	"github.com/labring/sealos/controllers/account/controllers/utils"
 
 	"github.com/go-logr/logr"
+	"github.com/google/uuid"
 
 	accountv1 "github.com/labring/sealos/controllers/account/api/v1"
 	v1 "github.com/labring/sealos/controllers/pkg/notification/api/v1"
@@ -263,141 +264,54 @@ var ErrAccountNotExist = errors.New("account not exist")
 /*
 NormalPeriod -> WarningPeriod -> ApproachingDeletionPeriod -> ImmediateDeletePeriod -> FinalDeletePeriod
 正常期：账户余额大于等于0
+欠费后按用户所属的欠费策略(DebtPolicy)依次进入各阶段，未分配策略的用户使用默认策略:
 预警期：账户余额小于0时,且超时超过WarningPeriodSeconds (default is 0 day)
-临近删除期：账户余额小于0，且上次更新时间超过ApproachingDeletionPeriodSeconds (default is 4 days)
-即刻删除期：账户余额小于0，且上次更新时间超过ImmediateDeletePeriodSeconds (default is 3 days)
+临近删除期：账户余额小于0，且上次更新时间超过ApproachingDeletionPeriodSeconds (default is 4 days)，或欠费超过充值金额的一半
+即刻删除期：账户余额小于0，且上次更新时间超过ImmediateDeletePeriodSeconds (default is 3 days)，或欠费超过充值金额
 最终删除期：账户余额小于0，且上次更新时间超过FinalDeletePeriodSeconds (default is 7 days)
 
 欠费后到完全删除的总周期=WarningPeriodSeconds+ApproachingDeletionPeriodSeconds+ImmediateDeletePeriodSeconds+FinalDeletePeriodSeconds
+余额大于等于0时恢复正常期，撤销已发送的消息通知，并恢复被暂停的用户资源
 */
 func (r *DebtReconciler) reconcileDebtStatus(ctx context.Context, debt *accountv1.Debt, account *pkgtypes.Account, userNamespaceList []string, smsEnable bool) error {
 	oweamount := account.Balance - account.DeductionBalance
 	//更新间隔秒钟数
 	updateIntervalSeconds := time.Now().UTC().Unix() - debt.Status.LastUpdateTimestamp
 	lastStatus := debt.Status
-	//userNamespace := GetUserNamespace(account.Name)
 	update := false
 
-	// 判断上次状态到当前的状态
 	switch lastStatus.AccountDebtStatus {
-	case accountv1.NormalPeriod:
-		/*
-			余额大于等于0:
-			  正常期 -> 正常期: 无操作返回
-			余额小于0:
-			  正常期 -> 警告期: 更新status 状态warning事件及更新事件，并发送警告消息通知
-		*/
-		if oweamount >= 0 {
-			return nil
-		}
-		update = SetDebtStatus(debt, accountv1.NormalPeriod, accountv1.WarningPeriod)
-		if err := r.sendWarningNotice(ctx, debt.Spec.UserName, oweamount, userNamespaceList, smsEnable); err != nil {
-			r.Logger.Error(err, "send warning notice error")
-		}
-	case accountv1.WarningPeriod:
-		/*
-			余额大于等于0：
-			  警告期 -> 正常期：更新status 状态normal事件及更新时间，撤销warning消息通知
-			余额小于0：
-			上次更新时间小于临近删除时间, 且欠费小于总金额的一半：
-			  警告期 -> 警告期：无操作返回
-			else：
-			  警告期 -> 临近删除期： 更新status 状态approachingDeletion事件及更新时间，发送临近删除消息通知
-		*/
-		if oweamount >= 0 {
-			update = SetDebtStatus(debt, accountv1.WarningPeriod, accountv1.NormalPeriod)
-			if err := r.readNotice(ctx, userNamespaceList, WarningNotice); err != nil {
-				r.Logger.Error(err, "readNotice WarningNotice error")
-			}
-			break
-		}
-		//上次更新时间小于临近删除时间
-		if updateIntervalSeconds < DebtConfig[accountv1.ApproachingDeletionPeriod] && (account.Balance/2)+oweamount > 0 {
-			return nil
-		}
-		update = SetDebtStatus(debt, accountv1.WarningPeriod, accountv1.ApproachingDeletionPeriod)
-		if err := r.sendApproachingDeletionNotice(ctx, debt.Spec.UserName, oweamount, userNamespaceList, smsEnable); err != nil {
-			r.Logger.Error(err, "sendApproachingDeletionNotice error")
-		}
-
-	case accountv1.ApproachingDeletionPeriod:
-		/*
-			余额大于0：
-			  临近删除期 -> 正常期：更新status 状态normal事件及更新时间，撤销临近删除消息通知
-			余额大于0：
-			上次更新时间小于最终删除时间，且欠费不大于总金额：
-			  临近删除期 -> 临近删除期：无操作返回
-			else：
-			  临近删除期 -> 即刻删除期： 执行暂停用户资源，更新status 状态imminentDeletionPeriod事件及更新时间，发送最终删除消息通知
-		*/
-		if oweamount >= 0 {
-			update = SetDebtStatus(debt, accountv1.ApproachingDeletionPeriod, accountv1.NormalPeriod)
-			if err := r.readNotice(ctx, userNamespaceList, ApproachingDeletionNotice, WarningNotice); err != nil {
-				r.Logger.Error(err, "readNotice ApproachingDeletionNotice error")
-			}
-			break
-		}
-		if updateIntervalSeconds < DebtConfig[accountv1.ImminentDeletionPeriod] && account.Balance+oweamount > 0 {
-			return nil
-		}
-		update = SetDebtStatus(debt, accountv1.ApproachingDeletionPeriod, accountv1.ImminentDeletionPeriod)
-		if err := r.sendImminentDeletionNotice(ctx, debt.Spec.UserName, oweamount, userNamespaceList, smsEnable); err != nil {
-			r.Logger.Error(err, "sendImminentDeletionNotice error")
-		}
-		if err := r.SuspendUserResource(ctx, userNamespaceList); err != nil {
+	case accountv1.NormalPeriod, accountv1.WarningPeriod, accountv1.ApproachingDeletionPeriod, accountv1.ImminentDeletionPeriod, accountv1.FinalDeletionPeriod:
+		policy, err := r.getDebtPolicy(account.UserUID)
+		if err != nil {
 			return err
 		}
-	case accountv1.ImminentDeletionPeriod:
-		/*
-			余额大于0：
-			  即刻删除期 -> 正常期：恢复用户资源，更新status 状态normal事件及更新时间，撤销最终删除消息通知
-			上次更新时间小于最终删除时间：
-			  即刻删除期 -> 即刻删除期：无操作返回
-			else：
-			  即刻删除期 -> 最终删除期： 删除用户全部资源，更新status 状态finalDeletionPeriod事件及更新时间。发生最终删除消息通知
-		*/
+		current := policy.StageIndex(string(lastStatus.AccountDebtStatus))
 		if oweamount >= 0 {
-			update = SetDebtStatus(debt, accountv1.ImminentDeletionPeriod, accountv1.NormalPeriod)
-			// 恢复用户资源
-			if err := r.ResumeUserResource(ctx, userNamespaceList); err != nil {
-				return err
+			if lastStatus.AccountDebtStatus == accountv1.NormalPeriod {
+				return nil
 			}
-			if err := r.readNotice(ctx, userNamespaceList, ImminentDeletionNotice, ApproachingDeletionNotice, WarningNotice); err != nil {
-				r.Logger.Error(err, "readNotice ImminentDeletionNotice error")
+			update = SetDebtStatus(debt, lastStatus.AccountDebtStatus, accountv1.NormalPeriod)
+			if err := r.recoverDebtStage(ctx, policy, current, lastStatus.AccountDebtStatus, userNamespaceList); err != nil {
+				return err
 			}
 			break
 		}
-		//上次更新时间小于最终删除时间, 且欠费不大于总金额的两倍
-		if updateIntervalSeconds < DebtConfig[accountv1.FinalDeletionPeriod] {
+		// the status is not a stage of the policy of the user, e.g. the user is assigned to another policy, it stays until the debt is paid
+		if current < 0 && lastStatus.AccountDebtStatus != accountv1.NormalPeriod {
 			return nil
 		}
-		// TODO 暂时只暂停资源，后续会添加真正删除全部资源逻辑, 或直接删除namespace
-		update = SetDebtStatus(debt, accountv1.ImminentDeletionPeriod, accountv1.FinalDeletionPeriod)
-		if err := r.sendFinalDeletionNotice(ctx, debt.Spec.UserName, oweamount, userNamespaceList, smsEnable); err != nil {
-			r.Error(err, "sendFinalDeletionNotice error")
-		}
-		if err := r.SuspendUserResource(ctx, userNamespaceList); err != nil {
-			return err
-		}
-	case accountv1.FinalDeletionPeriod:
-		/*
-			余额大于0：
-			  最终删除期 -> 正常期：更新status 状态normal事件及更新时间
-		*/
-		if oweamount >= 0 {
-			if err := r.readNotice(ctx, userNamespaceList, FinalDeletionNotice, ImminentDeletionNotice, ApproachingDeletionNotice, WarningNotice); err != nil {
-				r.Logger.Error(err, "readNotice FinalDeletionNotice error")
-			}
-			//TODO 用户从欠费到正常，是否需要发送消息通知
-			update = SetDebtStatus(debt, accountv1.FinalDeletionPeriod, accountv1.NormalPeriod)
-
-			// TODO 暂时非真正完全删除，仍可恢复用户资源，后续会添加真正删除全部资源逻辑，不在执行恢复逻辑
-			if err := r.ResumeUserResource(ctx, userNamespaceList); err != nil {
-				return err
+		next := policy.NextStage(current, account.Balance, -oweamount, updateIntervalSeconds)
+		if next == current {
+			// the resources stay released in the deletion stage
+			if policy.Stages[current].HasAction(pkgtypes.DebtActionDelete) {
+				return r.updateNamespaceStatus(ctx, accountv1.TerminateSuspendDebtNamespaceAnnoStatus, userNamespaceList)
 			}
-			break
+			return nil
 		}
-		if err := r.SuspendUserResource(ctx, userNamespaceList); err != nil {
+		stage := policy.Stages[next]
+		update = SetDebtStatus(debt, lastStatus.AccountDebtStatus, accountv1.DebtStatusType(stage.Status))
+		if err := r.runDebtStage(ctx, debt.Spec.UserName, oweamount, stage, userNamespaceList, smsEnable); err != nil {
 			return err
 		}
 	//兼容老版本
@@ -414,6 +328,97 @@ func (r *DebtReconciler) reconcileDebtStatus(ctx context.Context, debt *accountv
 	return nil
 }
 
+// getDebtPolicy returns the debt policy of the user, the policy built from DebtConfig if none is configured.
+func (r *DebtReconciler) getDebtPolicy(userUID uuid.UUID) (*pkgtypes.DebtPolicy, error) {
+	policy, err := r.AccountV2.GetDebtPolicy(userUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get debt policy: %w", err)
+	}
+	if policy == nil {
+		policy = defaultDebtPolicy()
+	}
+	return policy, nil
+}
+
+// defaultDebtPolicy is the lifecycle of DebtConfig, it releases the resources in the final deletion stage.
+func defaultDebtPolicy() *pkgtypes.DebtPolicy {
+	return &pkgtypes.DebtPolicy{
+		Name: pkgtypes.DefaultDebtPolicyName,
+		Stages: []pkgtypes.DebtStage{
+			{
+				Status:   string(accountv1.WarningPeriod),
+				Actions:  []pkgtypes.DebtAction{pkgtypes.DebtActionNotify},
+				Channels: []pkgtypes.DebtChannel{pkgtypes.DebtChannelNotification, pkgtypes.DebtChannelSMS, pkgtypes.DebtChannelVMS, pkgtypes.DebtChannelEmail},
+			},
+			{
+				Status:      string(accountv1.ApproachingDeletionPeriod),
+				WaitSeconds: DebtConfig[accountv1.ApproachingDeletionPeriod],
+				DebtPercent: 50,
+				Actions:     []pkgtypes.DebtAction{pkgtypes.DebtActionNotify},
+				Channels:    []pkgtypes.DebtChannel{pkgtypes.DebtChannelNotification},
+			},
+			{
+				Status:      string(accountv1.ImminentDeletionPeriod),
+				WaitSeconds: DebtConfig[accountv1.ImminentDeletionPeriod],
+				DebtPercent: 100,
+				Actions:     []pkgtypes.DebtAction{pkgtypes.DebtActionNotify, pkgtypes.DebtActionSuspend},
+				Channels:    []pkgtypes.DebtChannel{pkgtypes.DebtChannelNotification, pkgtypes.DebtChannelSMS, pkgtypes.DebtChannelEmail},
+			},
+			{
+				Status:      string(accountv1.FinalDeletionPeriod),
+				WaitSeconds: DebtConfig[accountv1.FinalDeletionPeriod],
+				Actions:     []pkgtypes.DebtAction{pkgtypes.DebtActionNotify, pkgtypes.DebtActionDelete},
+				Channels:    []pkgtypes.DebtChannel{pkgtypes.DebtChannelNotification},
+			},
+		},
+	}
+}
+
+// runDebtStage runs the actions of the stage entered by the user.
+func (r *DebtReconciler) runDebtStage(ctx context.Context, user string, oweAmount int64, stage pkgtypes.DebtStage, namespaces []string, smsEnable bool) error {
+	if stage.HasAction(pkgtypes.DebtActionNotify) {
+		if err := r.sendNotice(ctx, user, oweAmount, stage, namespaces, smsEnable); err != nil {
+			r.Logger.Error(err, "send debt notice error", "status", stage.Status)
+		}
+	}
+	// TODO 暂时只暂停资源，后续会添加真正删除全部资源逻辑, 或直接删除namespace
+	if stage.HasAction(pkgtypes.DebtActionDelete) {
+		return r.updateNamespaceStatus(ctx, accountv1.TerminateSuspendDebtNamespaceAnnoStatus, namespaces)
+	}
+	if stage.HasAction(pkgtypes.DebtActionSuspend) {
+		return r.SuspendUserResource(ctx, namespaces)
+	}
+	return nil
+}
+
+// recoverDebtStage reads the notices of the stages up to the status and resumes the resources
+// if they are suspended, current is the index of the status in the policy.
+func (r *DebtReconciler) recoverDebtStage(ctx context.Context, policy *pkgtypes.DebtPolicy, current int, status accountv1.DebtStatusType, namespaces []string) error {
+	// the resources may be suspended by the stage of another policy
+	suspended := current < 0
+	for i := 0; i <= current; i++ {
+		if policy.Stages[i].HasAction(pkgtypes.DebtActionSuspend) || policy.Stages[i].HasAction(pkgtypes.DebtActionDelete) {
+			suspended = true
+		}
+	}
+	if suspended {
+		if err := r.ResumeUserResource(ctx, namespaces); err != nil {
+			return err
+		}
+	}
+	var notices []int
+	for _, s := range pkgtypes.DebtStatusOrder {
+		notices = append(notices, debtStatusNotice[s])
+		if s == string(status) {
+			break
+		}
+	}
+	if err := r.readNotice(ctx, namespaces, notices...); err != nil {
+		r.Logger.Error(err, "readNotice error", "status", status)
+	}
+	return nil
+}
+
 func (r *DebtReconciler) syncDebt(ctx context.Context, owner, userID string, debt *accountv1.Debt) error {
 	debt.Name = GetDebtName(owner)
 	debt.Namespace = r.accountSystemNamespace
@@ -477,6 +482,14 @@ const (
 	FinalDeletionNotice
 )
 
+// debtStatusNotice is the notice type of the debt status.
+var debtStatusNotice = map[string]int{
+	string(accountv1.WarningPeriod):             WarningNotice,
+	string(accountv1.ApproachingDeletionPeriod): ApproachingDeletionNotice,
+	string(accountv1.ImminentDeletionPeriod):    ImminentDeletionNotice,
+	string(accountv1.FinalDeletionPeriod):       FinalDeletionNotice,
+}
+
 const (
 	fromEn = "Debt-System"
 	fromZh = "欠费系统"
@@ -515,32 +528,16 @@ var (
 	UTCPlus8    = time.FixedZone("UTC+8", 8*3600)
 )
 
-func (r *DebtReconciler) sendSMSNotice(user string, oweAmount int64, noticeType int) error {
+func (r *DebtReconciler) sendSMSNotice(user string, oweAmount int64, noticeType int, stage pkgtypes.DebtStage) error {
 	if r.SmsConfig == nil && r.VmsConfig == nil && r.smtpConfig == nil {
 		return nil
 	}
-	_user, err := r.AccountV2.GetUser(&pkgtypes.UserQueryOpts{Owner: user})
+	phone, email, err := getUserContact(r.AccountV2, user)
 	if err != nil {
-		return fmt.Errorf("failed to get user: %w", err)
-	}
-	// skip abnormal user
-	if _user.Status != pkgtypes.UserStatusNormal {
-		return nil
-	}
-	outh, err := r.AccountV2.GetUserOauthProvider(&pkgtypes.UserQueryOpts{UID: _user.UID, ID: _user.ID})
-	if err != nil {
-		return fmt.Errorf("failed to get user oauth provider: %w", err)
-	}
-	phone, email := "", ""
-	for i := range outh {
-		if outh[i].ProviderType == pkgtypes.OauthProviderTypePhone {
-			phone = outh[i].ProviderID
-		} else if outh[i].ProviderType == pkgtypes.OauthProviderTypeEmail {
-			email = outh[i].ProviderID
-		}
+		return err
 	}
 	if phone != "" {
-		if r.SmsConfig != nil && r.SmsConfig.SmsCode[noticeType] != "" {
+		if r.SmsConfig != nil && stage.HasChannel(pkgtypes.DebtChannelSMS) && r.SmsConfig.SmsCode[noticeType] != "" {
 			oweamount := strconv.FormatInt(int64(math.Abs(math.Ceil(float64(oweAmount)/1_000_000))), 10)
 			err = utils.SendSms(r.SmsConfig.Client, &client2.SendSmsRequest{
 				PhoneNumbers: tea.String(phone),
@@ -553,14 +550,14 @@ func (r *DebtReconciler) sendSMSNotice(user string, oweAmount int64, noticeType
 				return fmt.Errorf("failed to send sms notice: %w", err)
 			}
 		}
-		if r.VmsConfig != nil && noticeType == WarningNotice && r.VmsConfig.TemplateCode[noticeType] != "" {
+		if r.VmsConfig != nil && stage.HasChannel(pkgtypes.DebtChannelVMS) && r.VmsConfig.TemplateCode[noticeType] != "" {
 			err = utils.SendVms(phone, r.VmsConfig.TemplateCode[noticeType], r.VmsConfig.NumberPoll, GetSendVmsTimeInUTCPlus8(time.Now()), forbidTimes)
 			if err != nil {
 				return fmt.Errorf("failed to send vms notice: %w", err)
 			}
 		}
 	}
-	if r.smtpConfig != nil && email != "" {
+	if r.smtpConfig != nil && stage.HasChannel(pkgtypes.DebtChannelEmail) && email != "" {
 		if err = r.smtpConfig.SendEmail(EmailTemplateZH[noticeType]+"\n"+EmailTemplateEN[noticeType], email); err != nil {
 			return fmt.Errorf("failed to send email notice: %w", err)
 		}
@@ -568,6 +565,30 @@ func (r *DebtReconciler) sendSMSNotice(user string, oweAmount int64, noticeType
 	return nil
 }
 
+// getUserContact returns the phone and email of the user, they are empty for an abnormal user.
+func getUserContact(accountV2 database.AccountV2, user string) (phone, email string, err error) {
+	_user, err := accountV2.GetUser(&pkgtypes.UserQueryOpts{Owner: user})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get user: %w", err)
+	}
+	// skip abnormal user
+	if _user.Status != pkgtypes.UserStatusNormal {
+		return "", "", nil
+	}
+	outh, err := accountV2.GetUserOauthProvider(&pkgtypes.UserQueryOpts{UID: _user.UID, ID: _user.ID})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get user oauth provider: %w", err)
+	}
+	for i := range outh {
+		if outh[i].ProviderType == pkgtypes.OauthProviderTypePhone {
+			phone = outh[i].ProviderID
+		} else if outh[i].ProviderType == pkgtypes.OauthProviderTypeEmail {
+			email = outh[i].ProviderID
+		}
+	}
+	return phone, email, nil
+}
+
 // GetSendVmsTimeInUTCPlus8 send vms time in UTC+8 10:00-20:00
 func GetSendVmsTimeInUTCPlus8(t time.Time) time.Time {
 	nowInUTCPlus8 := t.In(UTCPlus8)
@@ -607,7 +628,9 @@ func (r *DebtReconciler) readNotice(ctx context.Context, namespaces []string, no
 	return nil
 }
 
-func (r *DebtReconciler) sendNotice(ctx context.Context, user string, oweAmount int64, noticeType int, namespaces []string, smsEnable bool) error {
+// sendNotice sends the notice of the stage to its channels.
+func (r *DebtReconciler) sendNotice(ctx context.Context, user string, oweAmount int64, stage pkgtypes.DebtStage, namespaces []string, smsEnable bool) error {
+	noticeType := debtStatusNotice[stage.Status]
 	now := time.Now().UTC().Unix()
 	ntfTmp := &v1.Notification{
 		ObjectMeta: metav1.ObjectMeta{
@@ -630,6 +653,9 @@ func (r *DebtReconciler) sendNotice(ctx context.Context, user string, oweAmount
 		},
 	}
 	for i := range namespaces {
+		if !stage.HasChannel(pkgtypes.DebtChannelNotification) {
+			break
+		}
 		ntf := ntfTmp.DeepCopy()
 		ntfSpec := ntfTmpSpc.DeepCopy()
 		ntf.Namespace = namespaces[i]
@@ -644,28 +670,12 @@ func (r *DebtReconciler) sendNotice(ctx context.Context, user string, oweAmount
 			return err
 		}
 	}
-	if smsEnable && (noticeType == WarningNotice || noticeType == ImminentDeletionNotice) {
-		return r.sendSMSNotice(user, oweAmount, noticeType)
+	if smsEnable {
+		return r.sendSMSNotice(user, oweAmount, noticeType, stage)
 	}
 	return nil
 }
 
-func (r *DebtReconciler) sendWarningNotice(ctx context.Context, user string, oweAmount int64, namespaces []string, smsEnable bool) error {
-	return r.sendNotice(ctx, user, oweAmount, WarningNotice, namespaces, smsEnable)
-}
-
-func (r *DebtReconciler) sendApproachingDeletionNotice(ctx context.Context, user string, oweAmount int64, namespaces []string, smsEnable bool) error {
-	return r.sendNotice(ctx, user, oweAmount, ApproachingDeletionNotice, namespaces, smsEnable)
-}
-
-func (r *DebtReconciler) sendImminentDeletionNotice(ctx context.Context, user string, oweAmount int64, namespaces []string, smsEnable bool) error {
-	return r.sendNotice(ctx, user, oweAmount, ImminentDeletionNotice, namespaces, smsEnable)
-}
-
-func (r *DebtReconciler) sendFinalDeletionNotice(ctx context.Context, user string, oweAmount int64, namespaces []string, smsEnable bool) error {
-	return r.sendNotice(ctx, user, oweAmount, FinalDeletionNotice, namespaces, smsEnable)
-}
-
 func (r *DebtReconciler) SuspendUserResource(ctx context.Context, namespaces []string) error {
 	return r.updateNamespaceStatus(ctx, accountv1.SuspendDebtNamespaceAnnoStatus, namespaces)
 }
@@ -747,21 +757,29 @@ func (r *DebtReconciler) setupVmsConfig() error {
 }
 
 func (r *DebtReconciler) setupSMTPConfig() error {
+	smtpConfig, err := newSMTPConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	r.smtpConfig = smtpConfig
+	return nil
+}
+
+func newSMTPConfigFromEnv() (*utils.SMTPConfig, error) {
 	if err := env.CheckEnvSetting([]string{SMTPHostEnv, SMTPPortEnv, SMTPFromEnv, SMTPPasswordEnv, SMTPTitleEnv}); err != nil {
-		return fmt.Errorf("check env setting error: %w", err)
+		return nil, fmt.Errorf("check env setting error: %w", err)
 	}
 	serverPort, err := strconv.Atoi(os.Getenv(SMTPPortEnv))
 	if err != nil {
-		return fmt.Errorf("invalid smtp port: %w", err)
+		return nil, fmt.Errorf("invalid smtp port: %w", err)
 	}
-	r.smtpConfig = &utils.SMTPConfig{
+	return &utils.SMTPConfig{
 		ServerHost: os.Getenv(SMTPHostEnv),
 		ServerPort: serverPort,
 		FromEmail:  os.Getenv(SMTPFromEnv),
 		Passwd:     os.Getenv(SMTPPasswordEnv),
 		EmailTitle: os.Getenv(SMTPTitleEnv),
-	}
-	return nil
+	}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -17,6 +17,9 @@ package controllers
 import (
 	"testing"
 	"time"
+
+	accountv1 "github.com/labring/sealos/controllers/account/api/v1"
+	pkgtypes "github.com/labring/sealos/controllers/pkg/types"
 )
 
 func Test_splitSmsCodeMap(t *testing.T) {
@@ -51,3 +54,51 @@ func TestGetTimeInUTCPlus8(t *testing.T) {
 		t.Logf("time: %v, timeInUTCPlus8: %v", _t, GetSendVmsTimeInUTCPlus8(_t))
 	}
 }
+
+func TestDefaultDebtPolicy(t *testing.T) {
+	// DebtConfig shares its map with accountv1.DefaultDebtConfig, the test sets a copy
+	debtConfig := DebtConfig
+	t.Cleanup(func() {
+		DebtConfig = debtConfig
+	})
+	DebtConfig = make(map[accountv1.DebtStatusType]int64, len(debtConfig))
+	for status, period := range debtConfig {
+		DebtConfig[status] = period
+	}
+	DebtConfig[accountv1.ApproachingDeletionPeriod] = 4 * accountv1.DaySecond
+	DebtConfig[accountv1.ImminentDeletionPeriod] = 3 * accountv1.DaySecond
+	DebtConfig[accountv1.FinalDeletionPeriod] = 7 * accountv1.DaySecond
+	policy := defaultDebtPolicy()
+	if err := policy.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		status                 accountv1.DebtStatusType
+		balance, debt, elapsed int64
+		want                   accountv1.DebtStatusType
+	}{
+		{accountv1.NormalPeriod, 100, 1, 0, accountv1.WarningPeriod},
+		{accountv1.WarningPeriod, 100, 1, accountv1.DaySecond, accountv1.WarningPeriod},
+		{accountv1.WarningPeriod, 100, 1, 4 * accountv1.DaySecond, accountv1.ApproachingDeletionPeriod},
+		{accountv1.WarningPeriod, 100, 50, 0, accountv1.ApproachingDeletionPeriod},
+		{accountv1.ApproachingDeletionPeriod, 100, 99, accountv1.DaySecond, accountv1.ApproachingDeletionPeriod},
+		{accountv1.ApproachingDeletionPeriod, 100, 100, 0, accountv1.ImminentDeletionPeriod},
+		{accountv1.ImminentDeletionPeriod, 100, 1000, 6 * accountv1.DaySecond, accountv1.ImminentDeletionPeriod},
+		{accountv1.ImminentDeletionPeriod, 100, 1, 7 * accountv1.DaySecond, accountv1.FinalDeletionPeriod},
+	}
+	for _, tt := range tests {
+		current := policy.StageIndex(string(tt.status))
+		next := policy.NextStage(current, tt.balance, tt.debt, tt.elapsed)
+		got := tt.status
+		if next != current {
+			got = accountv1.DebtStatusType(policy.Stages[next].Status)
+		}
+		if got != tt.want {
+			t.Errorf("next status of %s (balance %d, debt %d, elapsed %d) = %s, want %s", tt.status, tt.balance, tt.debt, tt.elapsed, got, tt.want)
+		}
+	}
+	// the resources are suspended from the imminent deletion stage and released in the final deletion stage
+	if !policy.Stages[2].HasAction(pkgtypes.DebtActionSuspend) || !policy.Stages[3].HasAction(pkgtypes.DebtActionDelete) {
+		t.Errorf("actions of default debt policy = %+v", policy.Stages)
+	}
+}
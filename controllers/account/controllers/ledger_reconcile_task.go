@@ -0,0 +1,98 @@
+/*
+Copyright 2024 sealos.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+
+	"github.com/labring/sealos/controllers/pkg/database"
+	"github.com/labring/sealos/controllers/pkg/utils/env"
+)
+
+const (
+	EnvLedgerReconcileInterval  = "LEDGER_RECONCILE_INTERVAL"
+	EnvLedgerReconcileBatchSize = "LEDGER_RECONCILE_BATCH_SIZE"
+
+	defaultLedgerReconcileInterval  = 24 * time.Hour
+	defaultLedgerReconcileBatchSize = 1000
+)
+
+// LedgerReconcileTaskRunner reconciles the stored balances of the accounts with their ledger
+// daily, the accounts which do not match are saved as discrepancies for the admins to review.
+type LedgerReconcileTaskRunner struct {
+	AccountV2 database.AccountV2
+	Logger    logr.Logger
+	Interval  time.Duration
+	BatchSize int
+}
+
+func (r *LedgerReconcileTaskRunner) Start(ctx context.Context) error {
+	if r.Interval <= 0 {
+		r.Interval = env.GetDurationEnvWithDefault(EnvLedgerReconcileInterval, defaultLedgerReconcileInterval)
+	}
+	if r.BatchSize <= 0 {
+		r.BatchSize = env.GetIntEnvWithDefault(EnvLedgerReconcileBatchSize, defaultLedgerReconcileBatchSize)
+	}
+	ticker := time.NewTicker(r.Interval)
+	defer func() {
+		ticker.Stop()
+		r.Logger.Info("stop ledger reconciliation")
+	}()
+	for {
+		select {
+		case <-ticker.C:
+			r.Logger.Info("start ledger reconciliation", "time", time.Now().Format(time.RFC3339))
+			scanned, discrepancies, err := r.Reconcile(ctx)
+			if err != nil {
+				r.Logger.Error(err, "fail to reconcile ledger", "scanned", scanned, "discrepancies", discrepancies)
+				continue
+			}
+			r.Logger.Info("end ledger reconciliation", "scanned", scanned, "discrepancies", discrepancies)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Reconcile checks all accounts in batches and returns the number of the scanned accounts and the discrepancies.
+func (r *LedgerReconcileTaskRunner) Reconcile(ctx context.Context) (scanned, discrepancies int, err error) {
+	after, checkedAt := uuid.Nil, time.Now().UTC()
+	for {
+		if ctx.Err() != nil {
+			return scanned, discrepancies, ctx.Err()
+		}
+		last, n, found, err := r.AccountV2.ReconcileLedger(after, r.BatchSize, checkedAt)
+		if err != nil {
+			return scanned, discrepancies, fmt.Errorf("failed to reconcile ledger after %s: %w", after, err)
+		}
+		scanned += n
+		discrepancies += len(found)
+		for _, d := range found {
+			r.Logger.Info("ledger discrepancy", "userUID", d.UserUID, "balance", d.Balance, "ledgerBalance", d.LedgerBalance,
+				"deductionBalance", d.DeductionBalance, "ledgerDeductionBalance", d.LedgerDeductionBalance)
+		}
+		if n < r.BatchSize {
+			return scanned, discrepancies, nil
+		}
+		after = last
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2024 sealos.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+
+	"github.com/labring/sealos/controllers/pkg/database"
+	pkgtypes "github.com/labring/sealos/controllers/pkg/types"
+)
+
+// fakeLedger reconciles accounts 1..n in batches, the even accounts are discrepancies.
+type fakeLedger struct {
+	database.AccountV2
+	n       int
+	batches []uuid.UUID
+}
+
+func (f *fakeLedger) ReconcileLedger(after uuid.UUID, batchSize int, checkedAt time.Time) (uuid.UUID, int, []pkgtypes.LedgerDiscrepancy, error) {
+	f.batches = append(f.batches, after)
+	start := int(after[15])
+	var discrepancies []pkgtypes.LedgerDiscrepancy
+	last := after
+	scanned := 0
+	for i := start + 1; i <= f.n && scanned < batchSize; i++ {
+		last = uuid.UUID{15: byte(i)}
+		scanned++
+		if i%2 == 0 {
+			discrepancies = append(discrepancies, pkgtypes.LedgerDiscrepancy{UserUID: last, CheckedAt: checkedAt})
+		}
+	}
+	return last, scanned, discrepancies, nil
+}
+
+func TestLedgerReconcile(t *testing.T) {
+	ledger := &fakeLedger{n: 7}
+	runner := &LedgerReconcileTaskRunner{AccountV2: ledger, Logger: logr.Discard(), BatchSize: 3}
+	scanned, discrepancies, err := runner.Reconcile(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scanned != 7 || discrepancies != 3 {
+		t.Errorf("Reconcile() = %d, %d, want 7, 3", scanned, discrepancies)
+	}
+	// the batches start after the last account of the previous batch
+	want := []uuid.UUID{uuid.Nil, {15: 3}, {15: 6}}
+	if len(ledger.batches) != len(want) {
+		t.Fatalf("batches = %v, want %v", ledger.batches, want)
+	}
+	for i := range want {
+		if ledger.batches[i] != want[i] {
+			t.Errorf("batch %d after = %s, want %s", i, ledger.batches[i], want[i])
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -60,6 +61,9 @@ type NamespaceReconciler struct {
 	OSNamespace      string
 	OSAdminSecret    string
 	InternalEndpoint string
+	// PaymentRequiredHost is the host of the payment required page, the ingresses of the suspended
+	// namespaces are routed to it, they are not changed if it is empty.
+	PaymentRequiredHost string
 }
 
 const (
@@ -71,12 +75,17 @@ const (
 	OSInternalEndpointEnv = "OSInternalEndpoint"
 	OSNamespace           = "OSNamespace"
 	OSAdminSecret         = "OSAdminSecret"
+	PaymentRequiredHost   = "PaymentRequiredHost"
 )
 
 //+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=namespaces/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=core,resources=namespaces/finalizers,verbs=update
 //+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=devbox.sealos.io,resources=devboxes,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=apps.kubeblocks.io,resources=clusters,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=apps.kubeblocks.io,resources=clusters/status,verbs=get;update;patch
@@ -140,12 +149,17 @@ func (r *NamespaceReconciler) SuspendUserResource(ctx context.Context, namespace
 	// limit0 resource quota
 	// suspend pod: deploy pod && clone unmanaged pod
 	// suspend cronjob
+	// the replicas of the workloads, the devbox states, the cronjob suspend flags and the ingress backends
+	// are recorded in the annotations before they are suspended, and restored on resume
 	pipelines := []func(context.Context, string) error{
 		r.suspendKBCluster,
 		r.suspendOrphanPod,
 		r.limitResourceQuotaCreate,
+		r.scaleDownWorkloads,
+		r.stopDevboxes,
 		r.deleteControlledPod,
 		r.suspendCronJob,
+		r.suspendIngresses,
 		r.suspendObjectStorage,
 	}
 	for _, fn := range pipelines {
@@ -159,9 +173,14 @@ func (r *NamespaceReconciler) SuspendUserResource(ctx context.Context, namespace
 func (r *NamespaceReconciler) ResumeUserResource(ctx context.Context, namespace string) error {
 	// delete limit0 resource quota
 	// resume pod
+	// restore the recorded state of the workloads, devboxes, cronjobs and ingresses
 	pipelines := []func(context.Context, string) error{
 		r.limitResourceQuotaDelete,
 		r.resumePod,
+		r.restoreWorkloads,
+		r.startDevboxes,
+		r.resumeCronJob,
+		r.resumeIngresses,
 		r.resumeObjectStorage,
 	}
 	for _, fn := range pipelines {
@@ -455,6 +474,7 @@ func (r *NamespaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	r.OSAdminSecret = os.Getenv(OSAdminSecret)
 	r.InternalEndpoint = os.Getenv(OSInternalEndpointEnv)
 	r.OSNamespace = os.Getenv(OSNamespace)
+	r.PaymentRequiredHost = os.Getenv(PaymentRequiredHost)
 
 	if r.OSAdminSecret == "" || r.InternalEndpoint == "" || r.OSNamespace == "" {
 		r.Log.V(1).Info("failed to get the endpoint or namespace or admin secret env of object storage")
@@ -490,19 +510,51 @@ func (AnnotationChangedPredicate) Create(e event.CreateEvent) bool {
 	return ok
 }
 
+// suspendCronJob records the suspend flags of the cronjobs and suspends them.
 func (r *NamespaceReconciler) suspendCronJob(ctx context.Context, namespace string) error {
 	cronJobList := batchv1.CronJobList{}
 	if err := r.Client.List(ctx, &cronJobList, client.InNamespace(namespace)); err != nil {
 		return err
 	}
-	for _, cronJob := range cronJobList.Items {
-		if cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend {
+	for i := range cronJobList.Items {
+		cronJob := &cronJobList.Items[i]
+		if _, ok := cronJob.Annotations[v1.PreSuspendCronJobSuspendAnnoKey]; ok {
 			continue
 		}
+		if cronJob.Annotations == nil {
+			cronJob.Annotations = make(map[string]string)
+		}
+		cronJob.Annotations[v1.PreSuspendCronJobSuspendAnnoKey] = strconv.FormatBool(ptr.Deref(cronJob.Spec.Suspend, false))
 		cronJob.Spec.Suspend = ptr.To(true)
-		if err := r.Client.Update(ctx, &cronJob); err != nil {
+		if err := r.Client.Update(ctx, cronJob); err != nil {
 			return fmt.Errorf("failed to suspend cronjob %s: %w", cronJob.Name, err)
 		}
 	}
 	return nil
 }
+
+// resumeCronJob restores the suspend flags of the cronjobs recorded by suspendCronJob.
+func (r *NamespaceReconciler) resumeCronJob(ctx context.Context, namespace string) error {
+	cronJobList := batchv1.CronJobList{}
+	if err := r.Client.List(ctx, &cronJobList, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+	for i := range cronJobList.Items {
+		cronJob := &cronJobList.Items[i]
+		suspend, ok := cronJob.Annotations[v1.PreSuspendCronJobSuspendAnnoKey]
+		if !ok {
+			continue
+		}
+		delete(cronJob.Annotations, v1.PreSuspendCronJobSuspendAnnoKey)
+		if previous, err := strconv.ParseBool(suspend); err != nil {
+			r.Log.Error(err, "invalid pre-suspend flag of cronjob, resume it", "cronjob", cronJob.Name, "namespace", namespace)
+			cronJob.Spec.Suspend = ptr.To(false)
+		} else {
+			cronJob.Spec.Suspend = ptr.To(previous)
+		}
+		if err := r.Client.Update(ctx, cronJob); err != nil {
+			return fmt.Errorf("failed to resume cronjob %s: %w", cronJob.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,357 @@
+/*
+Copyright 2024 sealos.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	v1 "github.com/labring/sealos/controllers/account/api/v1"
+)
+
+const (
+	// PaymentRequiredServiceName is the ExternalName service of the payment required page, the
+	// ingresses of a suspended namespace are routed to it.
+	PaymentRequiredServiceName = "debt-payment-required"
+	paymentRequiredServicePort = 80
+
+	devboxStateStopped = "Stopped"
+)
+
+// errForeignPaymentRequiredService is returned when a service of the payment required service
+// name exists without the label of the controller, the service is not adopted.
+var errForeignPaymentRequiredService = errors.New("service " + PaymentRequiredServiceName + " exists and is not created by the account controller")
+
+// DevboxListGVK is the devbox list, the devboxes are handled as unstructured objects since the
+// devbox controller may not be installed.
+var DevboxListGVK = schema.GroupVersionKind{Group: "devbox.sealos.io", Version: "v1alpha1", Kind: "DevboxList"}
+
+// scaleDownWorkloads records the replicas of the deployments and statefulsets and scales them to
+// zero. The workloads controlled by other resources, e.g. the statefulsets of the kubeblocks
+// clusters, are left to their controllers.
+func (r *NamespaceReconciler) scaleDownWorkloads(ctx context.Context, namespace string) error {
+	deployments := appsv1.DeploymentList{}
+	if err := r.Client.List(ctx, &deployments, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+	for i := range deployments.Items {
+		deploy := &deployments.Items[i]
+		if !recordReplicas(&deploy.ObjectMeta, deploy.Spec.Replicas) {
+			continue
+		}
+		deploy.Spec.Replicas = ptr.To[int32](0)
+		if err := r.Client.Update(ctx, deploy); err != nil {
+			return fmt.Errorf("failed to scale down deployment %s: %w", deploy.Name, err)
+		}
+	}
+	statefulSets := appsv1.StatefulSetList{}
+	if err := r.Client.List(ctx, &statefulSets, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+	for i := range statefulSets.Items {
+		sts := &statefulSets.Items[i]
+		if !recordReplicas(&sts.ObjectMeta, sts.Spec.Replicas) {
+			continue
+		}
+		sts.Spec.Replicas = ptr.To[int32](0)
+		if err := r.Client.Update(ctx, sts); err != nil {
+			return fmt.Errorf("failed to scale down statefulset %s: %w", sts.Name, err)
+		}
+	}
+	return nil
+}
+
+// restoreWorkloads restores the replicas recorded by scaleDownWorkloads.
+func (r *NamespaceReconciler) restoreWorkloads(ctx context.Context, namespace string) error {
+	deployments := appsv1.DeploymentList{}
+	if err := r.Client.List(ctx, &deployments, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+	for i := range deployments.Items {
+		deploy := &deployments.Items[i]
+		replicas, ok := r.restoreReplicas(&deploy.ObjectMeta)
+		if !ok {
+			continue
+		}
+		deploy.Spec.Replicas = replicas
+		if err := r.Client.Update(ctx, deploy); err != nil {
+			return fmt.Errorf("failed to restore deployment %s: %w", deploy.Name, err)
+		}
+	}
+	statefulSets := appsv1.StatefulSetList{}
+	if err := r.Client.List(ctx, &statefulSets, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+	for i := range statefulSets.Items {
+		sts := &statefulSets.Items[i]
+		replicas, ok := r.restoreReplicas(&sts.ObjectMeta)
+		if !ok {
+			continue
+		}
+		sts.Spec.Replicas = replicas
+		if err := r.Client.Update(ctx, sts); err != nil {
+			return fmt.Errorf("failed to restore statefulset %s: %w", sts.Name, err)
+		}
+	}
+	return nil
+}
+
+// recordReplicas records the replicas in the annotation of the workload, it returns false if the
+// workload is controlled by another resource or is already recorded.
+func recordReplicas(obj *metav1.ObjectMeta, replicas *int32) bool {
+	if metav1.GetControllerOfNoCopy(obj) != nil {
+		return false
+	}
+	if _, ok := obj.Annotations[v1.PreSuspendReplicasAnnoKey]; ok {
+		return false
+	}
+	if obj.Annotations == nil {
+		obj.Annotations = make(map[string]string)
+	}
+	// the default replicas is 1
+	obj.Annotations[v1.PreSuspendReplicasAnnoKey] = strconv.Itoa(int(ptr.Deref(replicas, 1)))
+	return true
+}
+
+// restoreReplicas removes the annotation of the recorded replicas and returns them, it returns
+// false if the workload has no record.
+func (r *NamespaceReconciler) restoreReplicas(obj *metav1.ObjectMeta) (*int32, bool) {
+	value, ok := obj.Annotations[v1.PreSuspendReplicasAnnoKey]
+	if !ok {
+		return nil, false
+	}
+	delete(obj.Annotations, v1.PreSuspendReplicasAnnoKey)
+	replicas, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || replicas < 0 {
+		r.Log.Error(fmt.Errorf("invalid pre-suspend replicas %q", value), "restore with 1 replica", "name", obj.Name, "namespace", obj.Namespace)
+		replicas = 1
+	}
+	return ptr.To(int32(replicas)), true
+}
+
+// stopDevboxes records the states of the devboxes and stops them.
+func (r *NamespaceReconciler) stopDevboxes(ctx context.Context, namespace string) error {
+	devboxes, err := r.listDevboxes(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	for i := range devboxes {
+		devbox := &devboxes[i]
+		annotations := devbox.GetAnnotations()
+		if _, ok := annotations[v1.PreSuspendDevboxStateAnnoKey]; ok {
+			continue
+		}
+		state, _, err := unstructured.NestedString(devbox.Object, "spec", "state")
+		if err != nil {
+			return fmt.Errorf("failed to get state of devbox %s: %w", devbox.GetName(), err)
+		}
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[v1.PreSuspendDevboxStateAnnoKey] = state
+		devbox.SetAnnotations(annotations)
+		if err := unstructured.SetNestedField(devbox.Object, devboxStateStopped, "spec", "state"); err != nil {
+			return fmt.Errorf("failed to set state of devbox %s: %w", devbox.GetName(), err)
+		}
+		if err := r.Client.Update(ctx, devbox); err != nil {
+			return fmt.Errorf("failed to stop devbox %s: %w", devbox.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// startDevboxes restores the states of the devboxes recorded by stopDevboxes.
+func (r *NamespaceReconciler) startDevboxes(ctx context.Context, namespace string) error {
+	devboxes, err := r.listDevboxes(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	for i := range devboxes {
+		devbox := &devboxes[i]
+		annotations := devbox.GetAnnotations()
+		state, ok := annotations[v1.PreSuspendDevboxStateAnnoKey]
+		if !ok {
+			continue
+		}
+		delete(annotations, v1.PreSuspendDevboxStateAnnoKey)
+		devbox.SetAnnotations(annotations)
+		if state != "" {
+			if err := unstructured.SetNestedField(devbox.Object, state, "spec", "state"); err != nil {
+				return fmt.Errorf("failed to set state of devbox %s: %w", devbox.GetName(), err)
+			}
+		}
+		if err := r.Client.Update(ctx, devbox); err != nil {
+			return fmt.Errorf("failed to restore devbox %s: %w", devbox.GetName(), err)
+		}
+	}
+	return nil
+}
+
+func (r *NamespaceReconciler) listDevboxes(ctx context.Context, namespace string) ([]unstructured.Unstructured, error) {
+	list := unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(DevboxListGVK)
+	if err := r.Client.List(ctx, &list, client.InNamespace(namespace)); err != nil {
+		// the devbox is not installed
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list devboxes: %w", err)
+	}
+	return list.Items, nil
+}
+
+// suspendIngresses records the backends of the ingresses and routes them to the payment required page.
+func (r *NamespaceReconciler) suspendIngresses(ctx context.Context, namespace string) error {
+	if r.PaymentRequiredHost == "" {
+		return nil
+	}
+	ingresses := networkingv1.IngressList{}
+	if err := r.Client.List(ctx, &ingresses, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+	if len(ingresses.Items) == 0 {
+		return nil
+	}
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: PaymentRequiredServiceName, Namespace: namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, svc, func() error {
+		if svc.ResourceVersion != "" && !isPaymentRequiredService(svc) {
+			return errForeignPaymentRequiredService
+		}
+		if svc.Labels == nil {
+			svc.Labels = make(map[string]string)
+		}
+		svc.Labels[v1.PaymentRequiredServiceLabelKey] = "true"
+		svc.Spec.Type = corev1.ServiceTypeExternalName
+		svc.Spec.ExternalName = r.PaymentRequiredHost
+		svc.Spec.Ports = []corev1.ServicePort{{Name: "http", Port: paymentRequiredServicePort}}
+		return nil
+	}); errors.Is(err, errForeignPaymentRequiredService) {
+		// the ingresses are left to the user, the workloads behind them are already suspended
+		r.Log.Error(err, "skip suspending ingresses", "namespace", namespace)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to create payment required service: %w", err)
+	}
+	for i := range ingresses.Items {
+		ingress := &ingresses.Items[i]
+		if _, ok := ingress.Annotations[v1.PreSuspendIngressBackendAnnoKey]; ok {
+			continue
+		}
+		backends := ingressBackends(ingress)
+		recorded := make([]networkingv1.IngressBackend, len(backends))
+		for j := range backends {
+			recorded[j] = *backends[j]
+			*backends[j] = paymentRequiredBackend()
+		}
+		data, err := json.Marshal(recorded)
+		if err != nil {
+			return fmt.Errorf("failed to marshal backends of ingress %s: %w", ingress.Name, err)
+		}
+		if ingress.Annotations == nil {
+			ingress.Annotations = make(map[string]string)
+		}
+		ingress.Annotations[v1.PreSuspendIngressBackendAnnoKey] = string(data)
+		if err := r.Client.Update(ctx, ingress); err != nil {
+			return fmt.Errorf("failed to suspend ingress %s: %w", ingress.Name, err)
+		}
+	}
+	return nil
+}
+
+// resumeIngresses restores the backends of the ingresses recorded by suspendIngresses. The
+// backends are matched by their positions, the backends changed during the suspension are kept.
+func (r *NamespaceReconciler) resumeIngresses(ctx context.Context, namespace string) error {
+	ingresses := networkingv1.IngressList{}
+	if err := r.Client.List(ctx, &ingresses, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+	for i := range ingresses.Items {
+		ingress := &ingresses.Items[i]
+		data, ok := ingress.Annotations[v1.PreSuspendIngressBackendAnnoKey]
+		if !ok {
+			continue
+		}
+		delete(ingress.Annotations, v1.PreSuspendIngressBackendAnnoKey)
+		var recorded []networkingv1.IngressBackend
+		if err := json.Unmarshal([]byte(data), &recorded); err != nil {
+			r.Log.Error(err, "invalid pre-suspend backends of ingress", "ingress", ingress.Name, "namespace", namespace)
+		}
+		for j, backend := range ingressBackends(ingress) {
+			if j < len(recorded) && isPaymentRequiredBackend(backend) {
+				*backend = recorded[j]
+			}
+		}
+		if err := r.Client.Update(ctx, ingress); err != nil {
+			return fmt.Errorf("failed to resume ingress %s: %w", ingress.Name, err)
+		}
+	}
+	svc := &corev1.Service{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: PaymentRequiredServiceName, Namespace: namespace}, svc); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !isPaymentRequiredService(svc) {
+		return nil
+	}
+	return client.IgnoreNotFound(r.Client.Delete(ctx, svc, client.Preconditions{UID: &svc.UID}))
+}
+
+// isPaymentRequiredService returns whether the service is created by suspendIngresses.
+func isPaymentRequiredService(svc *corev1.Service) bool {
+	return svc.Labels[v1.PaymentRequiredServiceLabelKey] == "true"
+}
+
+// ingressBackends returns the default backend and the backends of the paths of the ingress in order.
+func ingressBackends(ingress *networkingv1.Ingress) []*networkingv1.IngressBackend {
+	var backends []*networkingv1.IngressBackend
+	if ingress.Spec.DefaultBackend != nil {
+		backends = append(backends, ingress.Spec.DefaultBackend)
+	}
+	for i := range ingress.Spec.Rules {
+		if ingress.Spec.Rules[i].HTTP == nil {
+			continue
+		}
+		for j := range ingress.Spec.Rules[i].HTTP.Paths {
+			backends = append(backends, &ingress.Spec.Rules[i].HTTP.Paths[j].Backend)
+		}
+	}
+	return backends
+}
+
+func paymentRequiredBackend() networkingv1.IngressBackend {
+	return networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+		Name: PaymentRequiredServiceName,
+		Port: networkingv1.ServiceBackendPort{Number: paymentRequiredServicePort},
+	}}
+}
+
+func isPaymentRequiredBackend(backend *networkingv1.IngressBackend) bool {
+	return backend.Service != nil && backend.Service.Name == PaymentRequiredServiceName
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2024 sealos.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	kbv1alpha1 "github.com/apecloud/kubeblocks/apis/apps/v1alpha1"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSuspendAndResumeUserResource(t *testing.T) {
+	const ns = "ns-user"
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := kbv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	devboxGV := DevboxListGVK.GroupVersion()
+	scheme.AddKnownTypeWithName(devboxGV.WithKind("Devbox"), &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(DevboxListGVK, &unstructured.UnstructuredList{})
+
+	meta := func(name string) metav1.ObjectMeta { return metav1.ObjectMeta{Name: name, Namespace: ns} }
+	owned := meta("owned")
+	owned.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps.kubeblocks.io/v1alpha1", Kind: "Cluster", Name: "db", UID: "uid", Controller: ptr.To(true)}}
+	devbox := &unstructured.Unstructured{}
+	devbox.SetGroupVersionKind(devboxGV.WithKind("Devbox"))
+	devbox.SetName("devbox")
+	devbox.SetNamespace(ns)
+	_ = unstructured.SetNestedField(devbox.Object, "Running", "spec", "state")
+	appBackend := networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: "app", Port: networkingv1.ServiceBackendPort{Number: 8080}}}
+	clt := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&appsv1.Deployment{ObjectMeta: meta("app"), Spec: appsv1.DeploymentSpec{Replicas: ptr.To[int32](3)}},
+		&appsv1.StatefulSet{ObjectMeta: meta("sts"), Spec: appsv1.StatefulSetSpec{Replicas: ptr.To[int32](2)}},
+		&appsv1.StatefulSet{ObjectMeta: owned, Spec: appsv1.StatefulSetSpec{Replicas: ptr.To[int32](1)}},
+		&batchv1.CronJob{ObjectMeta: meta("active"), Spec: batchv1.CronJobSpec{Suspend: ptr.To(false)}},
+		&batchv1.CronJob{ObjectMeta: meta("paused"), Spec: batchv1.CronJobSpec{Suspend: ptr.To(true)}},
+		&networkingv1.Ingress{ObjectMeta: meta("app"), Spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{
+			Host: "app.example.com",
+			IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+				Paths: []networkingv1.HTTPIngressPath{{Path: "/", Backend: appBackend}},
+			}},
+		}}}},
+		devbox,
+	).Build()
+	r := &NamespaceReconciler{Client: clt, Log: logr.Discard(), PaymentRequiredHost: "payment-required.example.com"}
+	ctx := context.Background()
+
+	check := func(suspended bool) {
+		t.Helper()
+		deploy, sts, ownedSts := &appsv1.Deployment{}, &appsv1.StatefulSet{}, &appsv1.StatefulSet{}
+		for name, obj := range map[string]client.Object{"app": deploy, "sts": sts, "owned": ownedSts} {
+			if err := clt.Get(ctx, client.ObjectKey{Name: name, Namespace: ns}, obj); err != nil {
+				t.Fatal(err)
+			}
+		}
+		wantDeploy, wantSts := int32(3), int32(2)
+		if suspended {
+			wantDeploy, wantSts = 0, 0
+		}
+		if *deploy.Spec.Replicas != wantDeploy || *sts.Spec.Replicas != wantSts {
+			t.Errorf("replicas = %d, %d, want %d, %d", *deploy.Spec.Replicas, *sts.Spec.Replicas, wantDeploy, wantSts)
+		}
+		// the statefulset of the kubeblocks cluster is left to kubeblocks
+		if *ownedSts.Spec.Replicas != 1 {
+			t.Errorf("replicas of owned statefulset = %d, want 1", *ownedSts.Spec.Replicas)
+		}
+
+		active, paused := &batchv1.CronJob{}, &batchv1.CronJob{}
+		_ = clt.Get(ctx, client.ObjectKey{Name: "active", Namespace: ns}, active)
+		_ = clt.Get(ctx, client.ObjectKey{Name: "paused", Namespace: ns}, paused)
+		if *active.Spec.Suspend != suspended || !*paused.Spec.Suspend {
+			t.Errorf("cronjob suspend = %v, %v, want %v, true", *active.Spec.Suspend, *paused.Spec.Suspend, suspended)
+		}
+
+		box := &unstructured.Unstructured{}
+		box.SetGroupVersionKind(devboxGV.WithKind("Devbox"))
+		_ = clt.Get(ctx, client.ObjectKey{Name: "devbox", Namespace: ns}, box)
+		state, _, _ := unstructured.NestedString(box.Object, "spec", "state")
+		if wantState := map[bool]string{true: "Stopped", false: "Running"}[suspended]; state != wantState {
+			t.Errorf("devbox state = %s, want %s", state, wantState)
+		}
+
+		ingress := &networkingv1.Ingress{}
+		_ = clt.Get(ctx, client.ObjectKey{Name: "app", Namespace: ns}, ingress)
+		backend := ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service
+		if wantBackend := map[bool]string{true: PaymentRequiredServiceName, false: "app"}[suspended]; backend.Name != wantBackend {
+			t.Errorf("ingress backend = %s, want %s", backend.Name, wantBackend)
+		}
+		err := clt.Get(ctx, client.ObjectKey{Name: PaymentRequiredServiceName, Namespace: ns}, &corev1.Service{})
+		if suspended != (err == nil) {
+			t.Errorf("get payment required service error = %v, suspended %v", err, suspended)
+		}
+		if !suspended {
+			for _, obj := range []client.Object{deploy, sts, active, paused, box, ingress} {
+				if len(obj.GetAnnotations()) != 0 {
+					t.Errorf("annotations of %s = %v, want none", obj.GetName(), obj.GetAnnotations())
+				}
+			}
+		}
+	}
+
+	if err := r.SuspendUserResource(ctx, ns); err != nil {
+		t.Fatal(err)
+	}
+	check(true)
+	// suspending again keeps the recorded state
+	if err := r.SuspendUserResource(ctx, ns); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ResumeUserResource(ctx, ns); err != nil {
+		t.Fatal(err)
+	}
+	check(false)
+}
+
+func TestSuspendIngressesWithForeignService(t *testing.T) {
+	const ns = "ns-user"
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	backend := networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: "app", Port: networkingv1.ServiceBackendPort{Number: 8080}}}
+	clt := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: PaymentRequiredServiceName, Namespace: ns}, Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP}},
+		&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: ns}, Spec: networkingv1.IngressSpec{DefaultBackend: &backend}},
+	).Build()
+	r := &NamespaceReconciler{Client: clt, Log: logr.Discard(), PaymentRequiredHost: "payment-required.example.com"}
+	ctx := context.Background()
+
+	if err := r.suspendIngresses(ctx, ns); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.resumeIngresses(ctx, ns); err != nil {
+		t.Fatal(err)
+	}
+	// the service of the user is neither adopted nor deleted
+	svc := &corev1.Service{}
+	if err := clt.Get(ctx, client.ObjectKey{Name: PaymentRequiredServiceName, Namespace: ns}, svc); err != nil {
+		t.Fatal(err)
+	}
+	if svc.Spec.Type != corev1.ServiceTypeClusterIP || isPaymentRequiredService(svc) {
+		t.Errorf("service = %s %v, want the unchanged service of the user", svc.Spec.Type, svc.Labels)
+	}
+	ingress := &networkingv1.Ingress{}
+	if err := clt.Get(ctx, client.ObjectKey{Name: "app", Namespace: ns}, ingress); err != nil {
+		t.Fatal(err)
+	}
+	if ingress.Spec.DefaultBackend.Service.Name != "app" {
+		t.Errorf("ingress backend = %s, want app", ingress.Spec.DefaultBackend.Service.Name)
+	}
+}
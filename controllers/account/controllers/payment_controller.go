@@ -53,6 +53,7 @@ type PaymentReconciler struct {
 	createDuration    time.Duration
 	accountConfig     pkgtypes.AccountConfig
 	userLock          map[uuid.UUID]*sync.Mutex
+	userLockMu        sync.Mutex
 	domain            string
 }
 
@@ -65,9 +66,15 @@ var (
 const (
 	EnvPaymentReconcileDuration = "PAYMENT_RECONCILE_DURATION"
 	EnvPaymentCreateDuration    = "PAYMENT_CREATE_DURATION"
+	// EnvPaymentWebhookAddr is the listen address of the payment callbacks, the callbacks
+	// are disabled if it is empty.
+	EnvPaymentWebhookAddr = "PAYMENT_WEBHOOK_ADDR"
 
 	defaultReconcileDuration = 10 * time.Second
 	defaultCreateDuration    = 5 * time.Second
+	// defaultWebhookReconcileDuration is the polling duration when the payments are confirmed
+	// by the callbacks, the polling is only a safety net for the lost callbacks.
+	defaultWebhookReconcileDuration = 5 * time.Minute
 )
 
 //+kubebuilder:rbac:groups=account.sealos.io,resources=payments,verbs=get;list;watch;create;update;patch;delete
@@ -83,6 +90,10 @@ func (r *PaymentReconciler) SetupWithManager(mgr ctrl.Manager) (err error) {
 	r.reconcileDuration = defaultReconcileDuration
 	r.createDuration = defaultCreateDuration
 	r.userLock = make(map[uuid.UUID]*sync.Mutex)
+	webhookAddr := os.Getenv(EnvPaymentWebhookAddr)
+	if webhookAddr != "" {
+		r.reconcileDuration = defaultWebhookReconcileDuration
+	}
 	if duration := os.Getenv(EnvPaymentReconcileDuration); duration != "" {
 		reconcileDuration, err := time.ParseDuration(duration)
 		if err == nil {
@@ -107,6 +118,11 @@ func (r *PaymentReconciler) SetupWithManager(mgr ctrl.Manager) (err error) {
 	if err := mgr.Add(r); err != nil {
 		return fmt.Errorf("add payment controller failed: %w", err)
 	}
+	if webhookAddr != "" {
+		if err := mgr.Add(&PaymentWebhookServer{Payment: r, Addr: webhookAddr, Logger: r.Logger.WithName("webhook")}); err != nil {
+			return fmt.Errorf("add payment webhook server failed: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -195,55 +211,19 @@ func (r *PaymentReconciler) reconcilePayment(payment *accountv1.Payment) error {
 		return nil
 	}
 	// get payment handler
-	payHandler, err := pay.NewPayHandler(payment.Spec.PaymentMethod)
+	payHandler, config, err := pay.NewChargeHandler(payment.Spec.PaymentMethod)
 	if err != nil {
 		return fmt.Errorf("get payment Interface failed: %w", err)
 	}
 	// TODO The GetPaymentDetails may cause issues when using Stripe
-	status, orderAmount, err := payHandler.GetPaymentDetails(payment.Status.TradeNO)
+	status, chargedAmount, err := payHandler.GetPaymentDetails(payment.Status.TradeNO)
 	if err != nil {
 		return fmt.Errorf("get payment details failed: %w", err)
 	}
 	switch status {
 	case pay.PaymentSuccess:
-		user, err := r.Account.AccountV2.GetUser(&pkgtypes.UserQueryOpts{ID: payment.Spec.UserID})
-		if err != nil {
-			return fmt.Errorf("get user failed: %w", err)
-		}
-		if r.userLock[user.UID] == nil {
-			r.userLock[user.UID] = &sync.Mutex{}
-		}
-		r.userLock[user.UID].Lock()
-		defer r.userLock[user.UID].Unlock()
-		userDiscount, err := r.Account.AccountV2.GetUserRechargeDiscount(&pkgtypes.UserQueryOpts{ID: payment.Spec.UserID})
-		if err != nil {
-			return fmt.Errorf("get user discount failed: %w", err)
-		}
-		//1¥ = 100WechatPayAmount; 1 WechatPayAmount = 10000 SealosAmount
-		payAmount := orderAmount * 10000
-		isFirstRecharge, gift := getFirstRechargeDiscount(payAmount, userDiscount)
-		paymentRaw := pkgtypes.PaymentRaw{
-			UserUID:         user.UID,
-			Amount:          payAmount,
-			Gift:            gift,
-			CreatedAt:       payment.CreationTimestamp.Time,
-			RegionUserOwner: getUsername(payment.Namespace),
-			Method:          payment.Spec.PaymentMethod,
-			TradeNO:         payment.Status.TradeNO,
-			CodeURL:         payment.Status.CodeURL,
-		}
-		if isFirstRecharge {
-			paymentRaw.ActivityType = pkgtypes.ActivityTypeFirstRecharge
-		}
-
-		if err = r.Account.AccountV2.Payment(&pkgtypes.Payment{
-			PaymentRaw: paymentRaw,
-		}); err != nil {
-			return fmt.Errorf("payment failed: %w", err)
-		}
-		payment.Status.Status = pay.PaymentSuccess
-		if err := r.Status().Update(context.Background(), payment); err != nil {
-			return fmt.Errorf("update payment failed: %w", err)
+		if err := r.settlePayment(payment, config, chargedAmount); err != nil {
+			return err
 		}
 	//case pay.PaymentFailed, pay.PaymentExpired:
 	default:
@@ -254,6 +234,68 @@ func (r *PaymentReconciler) reconcilePayment(payment *accountv1.Payment) error {
 	return nil
 }
 
+// settlePayment adds the chargedAmount of the payment provider in CNY to the balance of the user
+// and marks the payment successful, it is settled once by the trade number of the payment. The
+// charged amount and currency are saved with the payment to refund it in them.
+func (r *PaymentReconciler) settlePayment(payment *accountv1.Payment, config pay.ProviderConfig, chargedAmount int64) error {
+	if payment.Status.Status == pay.PaymentSuccess {
+		return nil
+	}
+	user, err := r.Account.AccountV2.GetUser(&pkgtypes.UserQueryOpts{ID: payment.Spec.UserID})
+	if err != nil {
+		return fmt.Errorf("get user failed: %w", err)
+	}
+	unlock := r.lockUser(user.UID)
+	defer unlock()
+	userDiscount, err := r.Account.AccountV2.GetUserRechargeDiscount(&pkgtypes.UserQueryOpts{ID: payment.Spec.UserID})
+	if err != nil {
+		return fmt.Errorf("get user discount failed: %w", err)
+	}
+	//1¥ = 100WechatPayAmount; 1 WechatPayAmount = 10000 SealosAmount
+	payAmount := config.FromProviderAmount(chargedAmount) * 10000
+	isFirstRecharge, gift := getFirstRechargeDiscount(payAmount, userDiscount)
+	paymentRaw := pkgtypes.PaymentRaw{
+		UserUID:         user.UID,
+		Amount:          payAmount,
+		Gift:            gift,
+		CreatedAt:       payment.CreationTimestamp.Time,
+		RegionUserOwner: getUsername(payment.Namespace),
+		Method:          payment.Spec.PaymentMethod,
+		TradeNO:         payment.Status.TradeNO,
+		CodeURL:         payment.Status.CodeURL,
+		ChargedAmount:   chargedAmount,
+		ChargedCurrency: config.ChargedCurrency(payment.Spec.PaymentMethod),
+	}
+	if isFirstRecharge {
+		paymentRaw.ActivityType = pkgtypes.ActivityTypeFirstRecharge
+	}
+
+	if err = r.Account.AccountV2.Payment(&pkgtypes.Payment{
+		PaymentRaw: paymentRaw,
+	}); err != nil {
+		return fmt.Errorf("payment failed: %w", err)
+	}
+	payment.Status.Status = pay.PaymentSuccess
+	if err := r.Status().Update(context.Background(), payment); err != nil {
+		return fmt.Errorf("update payment failed: %w", err)
+	}
+	return nil
+}
+
+// lockUser serializes the settlement of the payments of the user between the polling and
+// the callbacks of the payment providers.
+func (r *PaymentReconciler) lockUser(uid uuid.UUID) (unlock func()) {
+	r.userLockMu.Lock()
+	lock, ok := r.userLock[uid]
+	if !ok {
+		lock = &sync.Mutex{}
+		r.userLock[uid] = lock
+	}
+	r.userLockMu.Unlock()
+	lock.Lock()
+	return lock.Unlock
+}
+
 func (r *PaymentReconciler) expiredOvertimePayment(payment *accountv1.Payment) error {
 	if payment.CreationTimestamp.Time.Add(10 * time.Minute).After(time.Now()) {
 		return nil
@@ -0,0 +1,158 @@
+/*
+Copyright 2024 sealos.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	accountv1 "github.com/labring/sealos/controllers/account/api/v1"
+	"github.com/labring/sealos/controllers/pkg/pay"
+)
+
+const (
+	StripeWebhookPath = "/payment/stripe/webhook"
+	WechatNotifyPath  = "/payment/wechat/notify"
+	// AlipayNotifyPath is the path of the notify_url of alipay, see pay.AlipayNotifyURL.
+	AlipayNotifyPath = "/payment/alipay/notify"
+)
+
+// PaymentWebhookServer serves the signed callbacks of the payment providers and settles the
+// paid payments without waiting for the polling of PaymentReconciler.
+type PaymentWebhookServer struct {
+	Payment *PaymentReconciler
+	Addr    string
+	Logger  logr.Logger
+}
+
+var (
+	_ manager.LeaderElectionRunnable = &PaymentWebhookServer{}
+	_ manager.Runnable               = &PaymentWebhookServer{}
+)
+
+// NeedLeaderElection returns false, the callbacks may reach any replica and the settlement
+// is idempotent by the trade number.
+func (s *PaymentWebhookServer) NeedLeaderElection() bool {
+	return false
+}
+
+func (s *PaymentWebhookServer) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc(StripeWebhookPath, s.handle(pay.ParseStripeNotification, ""))
+	mux.HandleFunc(WechatNotifyPath, s.handle(pay.ParseWechatNotification, ""))
+	mux.HandleFunc(AlipayNotifyPath, s.handle(pay.ParseAlipayNotification, pay.AlipayNotifyAck))
+	server := &http.Server{
+		Addr:              s.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			s.Logger.Error(err, "shutdown payment webhook server failed")
+		}
+	}()
+	s.Logger.Info("start payment webhook server", "addr", s.Addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("payment webhook server failed: %w", err)
+	}
+	return nil
+}
+
+// notifyResponse is the response body of a failed callback, wechat pay retries the
+// notify unless it is answered with 2xx.
+type notifyResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// handle returns the handler of the notifications parsed by parse, the handled notifications
+// are answered with the body ack, or with no content if ack is empty.
+func (s *PaymentWebhookServer) handle(parse func(r *http.Request) (*pay.Notification, error), ack string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeNotifyResponse(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		notification, err := parse(r)
+		if err != nil {
+			s.Logger.Error(err, "invalid payment notification", "path", r.URL.Path)
+			writeNotifyResponse(w, http.StatusBadRequest, "invalid notification")
+			return
+		}
+		if notification == nil || notification.Status != pay.PaymentSuccess {
+			writeNotifyAck(w, ack)
+			return
+		}
+		if err = s.settle(r.Context(), notification); err != nil {
+			s.Logger.Error(err, "settle payment failed", "tradeNO", notification.TradeNO)
+			writeNotifyResponse(w, http.StatusInternalServerError, "settle payment failed")
+			return
+		}
+		writeNotifyAck(w, ack)
+	}
+}
+
+// settle settles the payment of the trade of the notification, the notification of a trade
+// which is unknown or already settled is ignored.
+func (s *PaymentWebhookServer) settle(ctx context.Context, notification *pay.Notification) error {
+	paymentList := &accountv1.PaymentList{}
+	if err := s.Payment.List(ctx, paymentList); err != nil {
+		return fmt.Errorf("list payments failed: %w", err)
+	}
+	for i := range paymentList.Items {
+		payment := &paymentList.Items[i]
+		if payment.Status.TradeNO != notification.TradeNO {
+			continue
+		}
+		config, err := pay.GetProviderConfig(payment.Spec.PaymentMethod)
+		if err != nil {
+			return err
+		}
+		if err = s.Payment.settlePayment(payment, config, notification.Amount); err != nil {
+			return err
+		}
+		s.Logger.Info("payment settled by notification", "payment", payment.Name, "namespace", payment.Namespace, "tradeNO", notification.TradeNO)
+		return nil
+	}
+	s.Logger.Info("payment of notification not found", "tradeNO", notification.TradeNO)
+	return nil
+}
+
+func writeNotifyAck(w http.ResponseWriter, ack string) {
+	if ack == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = w.Write([]byte(ack))
+}
+
+func writeNotifyResponse(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(notifyResponse{Code: "FAIL", Message: message})
+}
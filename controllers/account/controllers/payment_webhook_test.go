@@ -0,0 +1,88 @@
+/*
+Copyright 2024 sealos.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	accountv1 "github.com/labring/sealos/controllers/account/api/v1"
+	"github.com/labring/sealos/controllers/pkg/pay"
+)
+
+func TestPaymentWebhookServerHandle(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := accountv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	// the settled payment is skipped without touching the database
+	settled := &accountv1.Payment{
+		ObjectMeta: metav1.ObjectMeta{Name: "settled", Namespace: "ns-test"},
+		Spec:       accountv1.PaymentSpec{PaymentMethod: pay.MethodStripe},
+		Status:     accountv1.PaymentStatus{TradeNO: "trade-settled", Status: pay.PaymentSuccess},
+	}
+	s := &PaymentWebhookServer{
+		Payment: &PaymentReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(settled).Build()},
+		Logger:  logr.Discard(),
+	}
+	tests := []struct {
+		name         string
+		method       string
+		notification *pay.Notification
+		err          error
+		want         int
+	}{
+		{"invalid signature", http.MethodPost, nil, fmt.Errorf("invalid signature"), http.StatusBadRequest},
+		{"wrong method", http.MethodGet, nil, nil, http.StatusMethodNotAllowed},
+		{"ignored event", http.MethodPost, nil, nil, http.StatusNoContent},
+		{"not paid", http.MethodPost, &pay.Notification{TradeNO: "trade-settled", Status: pay.PaymentNotPaid}, nil, http.StatusNoContent},
+		{"unknown trade", http.MethodPost, &pay.Notification{TradeNO: "trade-unknown", Status: pay.PaymentSuccess, Amount: 100}, nil, http.StatusNoContent},
+		{"already settled", http.MethodPost, &pay.Notification{TradeNO: "trade-settled", Status: pay.PaymentSuccess, Amount: 100}, nil, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := s.handle(func(*http.Request) (*pay.Notification, error) {
+				return tt.notification, tt.err
+			}, "")
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(tt.method, StripeWebhookPath, nil))
+			if rec.Code != tt.want {
+				t.Errorf("handle() status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaymentWebhookServerAck(t *testing.T) {
+	s := &PaymentWebhookServer{Logger: logr.Discard()}
+	// alipay retries the notify unless it is answered with the ack
+	handler := s.handle(func(*http.Request) (*pay.Notification, error) {
+		return &pay.Notification{TradeNO: "trade", Status: pay.PaymentNotPaid}, nil
+	}, pay.AlipayNotifyAck)
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, AlipayNotifyPath, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != pay.AlipayNotifyAck {
+		t.Errorf("handle() = %d %q, want 200 %q", rec.Code, rec.Body.String(), pay.AlipayNotifyAck)
+	}
+}
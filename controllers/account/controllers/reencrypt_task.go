@@ -0,0 +1,113 @@
+/*
+Copyright 2024 sealos.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+
+	"github.com/labring/sealos/controllers/pkg/crypto"
+	"github.com/labring/sealos/controllers/pkg/database"
+	"github.com/labring/sealos/controllers/pkg/database/cockroach"
+	"github.com/labring/sealos/controllers/pkg/utils/env"
+)
+
+const (
+	EnvReencryptBatchSize     = "REENCRYPT_BATCH_SIZE"
+	EnvReencryptBatchInterval = "REENCRYPT_BATCH_INTERVAL"
+
+	defaultReencryptBatchSize     = 500
+	defaultReencryptBatchInterval = time.Second
+)
+
+// ReencryptTaskRunner re-encrypts the encrypted balances of the accounts and the encrypted unit
+// prices of the property types with the current key after the key ring is rotated. It runs once
+// on the leader when the key ring has more than one key, the rows are migrated online in batches,
+// and the readers decrypt both the old and the new ciphertexts in the meantime.
+type ReencryptTaskRunner struct {
+	AccountV2     database.AccountV2
+	DBClient      database.Account
+	Logger        logr.Logger
+	BatchSize     int
+	BatchInterval time.Duration
+}
+
+func (r *ReencryptTaskRunner) Start(ctx context.Context) error {
+	ring, err := crypto.DefaultKeyRing()
+	if err != nil {
+		return err
+	}
+	if len(ring.Versions()) <= 1 {
+		return nil
+	}
+	if r.BatchSize <= 0 {
+		r.BatchSize = env.GetIntEnvWithDefault(EnvReencryptBatchSize, defaultReencryptBatchSize)
+	}
+	if r.BatchInterval <= 0 {
+		r.BatchInterval = env.GetDurationEnvWithDefault(EnvReencryptBatchInterval, defaultReencryptBatchInterval)
+	}
+	r.Logger.Info("start re-encryption", "versions", ring.Versions(), "current version", ring.CurrentVersion())
+	migrated, err := r.reencryptAccounts(ctx)
+	if err != nil {
+		// the rows migrated are consistent, the rest are migrated on the next start
+		r.Logger.Error(err, "failed to re-encrypt accounts", "migrated", migrated)
+		return nil
+	}
+	properties, err := r.DBClient.ReencryptPropertyTypes(r.BatchSize)
+	if err != nil {
+		r.Logger.Error(err, "failed to re-encrypt property types", "migrated", properties)
+		return nil
+	}
+	// the env BASE_BALANCE is not migrated, it is decrypted on every start by the key ring
+	if baseBalance := os.Getenv(cockroach.EnvBaseBalance); ring.NeedsReencrypt(baseBalance) {
+		newBaseBalance, _, err := ring.Reencrypt(baseBalance)
+		if err != nil {
+			r.Logger.Error(err, "failed to re-encrypt env", "env", cockroach.EnvBaseBalance)
+			return nil
+		}
+		r.Logger.Info("end re-encryption, set the env to the re-encrypted value before the old key versions are removed",
+			"accounts", migrated, "property types", properties, "env", cockroach.EnvBaseBalance, "value", newBaseBalance)
+		return nil
+	}
+	r.Logger.Info("end re-encryption, the old key versions can be removed", "accounts", migrated, "property types", properties)
+	return nil
+}
+
+func (r *ReencryptTaskRunner) reencryptAccounts(ctx context.Context) (int, error) {
+	after, total := uuid.Nil, 0
+	for {
+		last, scanned, migrated, err := r.AccountV2.ReencryptAccounts(after, r.BatchSize)
+		if err != nil {
+			return total, fmt.Errorf("failed to re-encrypt accounts after %s: %w", after, err)
+		}
+		total += migrated
+		if scanned < r.BatchSize {
+			return total, nil
+		}
+		after = last
+		select {
+		case <-ctx.Done():
+			return total, ctx.Err()
+		case <-time.After(r.BatchInterval):
+		}
+	}
+}
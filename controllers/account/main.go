@@ -27,6 +27,7 @@ import (
 	"github.com/labring/sealos/controllers/pkg/database/cockroach"
 	"github.com/labring/sealos/controllers/pkg/database/mongo"
 	notificationv1 "github.com/labring/sealos/controllers/pkg/notification/api/v1"
+	"github.com/labring/sealos/controllers/pkg/pay"
 	"github.com/labring/sealos/controllers/pkg/resources"
 	"github.com/labring/sealos/controllers/pkg/types"
 	rate "github.com/labring/sealos/controllers/pkg/utils/rate"
@@ -47,6 +48,8 @@ import (
 
 	accountv1 "github.com/labring/sealos/controllers/account/api/v1"
 	"github.com/labring/sealos/controllers/account/controllers"
+
+	objectstoragev1 "github/labring/sealos/controllers/objectstorage/api/v1"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -62,6 +65,7 @@ func init() {
 	utilruntime.Must(userv1.AddToScheme(scheme))
 	utilruntime.Must(notificationv1.AddToScheme(scheme))
 	utilruntime.Must(kbv1alpha1.SchemeBuilder.AddToScheme(scheme))
+	utilruntime.Must(objectstoragev1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -141,6 +145,7 @@ func main() {
 			setupLog.Error(err, "unable to disconnect from mongo")
 		}
 	}()
+	pay.SetExchangeRateFunc(dbClient.GetExchangeRate)
 	var cvmDBClient database.Interface
 	cvmURI := os.Getenv(database.CVMMongoURI)
 	if cvmURI != "" {
@@ -223,6 +228,16 @@ func main() {
 		Client:     mgr.GetClient(),
 		Scheme:     mgr.GetScheme(),
 		AccountV2:  v2Account,
+		Budget: &controllers.BudgetChecker{
+			Client:    mgr.GetClient(),
+			AccountV2: v2Account,
+			DBClient:  dbClient,
+		},
+	}
+	billingReconciler.Budget.Init()
+	if err := mgr.Add(billingReconciler.Budget); err != nil {
+		setupLog.Error(err, "unable to add budget checker")
+		os.Exit(1)
 	}
 	if err = billingReconciler.Init(); err != nil {
 		setupLog.Error(err, "unable to init billing reconciler")
@@ -269,6 +284,33 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := mgr.Add(&controllers.ReencryptTaskRunner{
+		AccountV2: v2Account,
+		DBClient:  dbClient,
+		Logger:    ctrl.Log.WithName("ReencryptTaskRunner"),
+	}); err != nil {
+		setupLog.Error(err, "unable to add re-encrypt task runner")
+		os.Exit(1)
+	}
+
+	if err := mgr.Add(&controllers.LedgerReconcileTaskRunner{
+		AccountV2: v2Account,
+		Logger:    ctrl.Log.WithName("LedgerReconcileTaskRunner"),
+	}); err != nil {
+		setupLog.Error(err, "unable to add ledger reconcile task runner")
+		os.Exit(1)
+	}
+
+	if err := mgr.Add(&controllers.BillingExportTaskRunner{
+		Client:    mgr.GetClient(),
+		AccountV2: v2Account,
+		DBClient:  dbClient,
+		Logger:    ctrl.Log.WithName("BillingExportTaskRunner"),
+	}); err != nil {
+		setupLog.Error(err, "unable to add billing export task runner")
+		os.Exit(1)
+	}
+
 	if cvmDBClient != nil {
 		cvmTaskRunner := &controllers.CVMTaskRunner{
 			DBClient:          cvmDBClient,
@@ -34,9 +34,13 @@ const defaultEncryptionKey = "Bg1c3Dd5e9e0F84bdF0A5887cF43aB63"
 
 var encryptionKey = defaultEncryptionKey
 
-// Encrypt encrypts the given plaintext using AES-GCM.
+// Encrypt encrypts the given plaintext using AES-GCM with the current key of the key ring.
 func Encrypt(plaintext []byte) (string, error) {
-	return EncryptWithKey(plaintext, []byte(encryptionKey))
+	ring, err := DefaultKeyRing()
+	if err != nil {
+		return "", err
+	}
+	return ring.Encrypt(plaintext)
 }
 
 // EncryptWithKey encrypts the given plaintext using AES-GCM.
@@ -79,9 +83,13 @@ func DecryptInt64(in string) (int64, error) {
 	return strconv.ParseInt(string(out), 10, 64)
 }
 
-// Decrypt decrypts the given ciphertext using AES-GCM.
-func Decrypt(ciphertextBase64 string) ([]byte, error) {
-	return DecryptWithKey(ciphertextBase64, []byte(encryptionKey))
+// Decrypt decrypts the given ciphertext using AES-GCM with the key of its version in the key ring.
+func Decrypt(ciphertext string) ([]byte, error) {
+	ring, err := DefaultKeyRing()
+	if err != nil {
+		return nil, err
+	}
+	return ring.Decrypt(ciphertext)
 }
 
 // DecryptWithKey decrypts the given ciphertext using AES-GCM.
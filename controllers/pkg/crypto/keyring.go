@@ -0,0 +1,195 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// EnvKeyRing is the active encryption keys, a comma separated list of <version>=<key>, e.g.
+// "0=<old key>,1=<new key>". The newest version encrypts, and every listed version decrypts.
+//
+// Version 0 is the version of the unversioned ciphertexts encrypted before the key ring, keep
+// it in the list until the re-encryption is done. If the env is empty, the key ring only has
+// the built-in key of version 0.
+const EnvKeyRing = "CRYPTO_KEYS"
+
+// versionPrefix starts the version of a versioned ciphertext, "v<version>:<base64>". The
+// prefix is not in the base64 alphabet, so the unversioned ciphertexts are not ambiguous.
+const versionPrefix = "v"
+
+const versionSeparator = ":"
+
+// KeyRing is the active encryption keys by version.
+type KeyRing struct {
+	keys    map[int][]byte
+	current int
+}
+
+// NewKeyRing returns a key ring with the keys, the newest version is the current key.
+func NewKeyRing(keys map[int][]byte) (*KeyRing, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("empty key ring")
+	}
+	ring := &KeyRing{keys: make(map[int][]byte, len(keys)), current: -1}
+	for version, key := range keys {
+		if version < 0 {
+			return nil, fmt.Errorf("invalid key version %d", version)
+		}
+		switch len(key) {
+		case 16, 24, 32:
+		default:
+			return nil, fmt.Errorf("invalid length %d of key version %d, must be 16, 24 or 32", len(key), version)
+		}
+		ring.keys[version] = key
+		if version > ring.current {
+			ring.current = version
+		}
+	}
+	return ring, nil
+}
+
+// ParseKeyRing parses the key ring of the EnvKeyRing format.
+func ParseKeyRing(config string) (*KeyRing, error) {
+	keys := make(map[int][]byte)
+	for _, entry := range strings.Split(config, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		versionStr, key, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid key ring entry, must be <version>=<key>")
+		}
+		version, err := strconv.Atoi(strings.TrimSpace(versionStr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid key version %q: %w", versionStr, err)
+		}
+		if _, ok := keys[version]; ok {
+			return nil, fmt.Errorf("duplicate key version %d", version)
+		}
+		keys[version] = []byte(strings.TrimSpace(key))
+	}
+	return NewKeyRing(keys)
+}
+
+// CurrentVersion returns the version of the key which encrypts.
+func (k *KeyRing) CurrentVersion() int {
+	return k.current
+}
+
+// Versions returns the active versions in ascending order.
+func (k *KeyRing) Versions() []int {
+	versions := make([]int, 0, len(k.keys))
+	for version := range k.keys {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+// Encrypt encrypts the plaintext with the current key, the ciphertext is unversioned if the
+// current version is 0 to be compatible with the readers before the key ring.
+func (k *KeyRing) Encrypt(plaintext []byte) (string, error) {
+	ciphertext, err := EncryptWithKey(plaintext, k.keys[k.current])
+	if err != nil {
+		return "", err
+	}
+	if k.current == 0 {
+		return ciphertext, nil
+	}
+	return versionPrefix + strconv.Itoa(k.current) + versionSeparator + ciphertext, nil
+}
+
+// Decrypt decrypts the ciphertext with the key of its version.
+func (k *KeyRing) Decrypt(ciphertext string) ([]byte, error) {
+	version, data, err := parseVersion(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := k.keys[version]
+	if !ok {
+		return nil, fmt.Errorf("key version %d is not active", version)
+	}
+	return DecryptWithKey(data, key)
+}
+
+// NeedsReencrypt returns whether the ciphertext is not encrypted with the current key.
+func (k *KeyRing) NeedsReencrypt(ciphertext string) bool {
+	version, _, err := parseVersion(ciphertext)
+	return err == nil && version != k.current
+}
+
+// Reencrypt re-encrypts the ciphertext with the current key, it returns false if the
+// ciphertext is already encrypted with the current key.
+func (k *KeyRing) Reencrypt(ciphertext string) (string, bool, error) {
+	if !k.NeedsReencrypt(ciphertext) {
+		return ciphertext, false, nil
+	}
+	plaintext, err := k.Decrypt(ciphertext)
+	if err != nil {
+		return "", false, err
+	}
+	newCiphertext, err := k.Encrypt(plaintext)
+	if err != nil {
+		return "", false, err
+	}
+	return newCiphertext, true, nil
+}
+
+// parseVersion returns the key version and the base64 data of the ciphertext.
+func parseVersion(ciphertext string) (int, string, error) {
+	if !strings.HasPrefix(ciphertext, versionPrefix) {
+		return 0, ciphertext, nil
+	}
+	versionStr, data, found := strings.Cut(strings.TrimPrefix(ciphertext, versionPrefix), versionSeparator)
+	if !found {
+		// "v" is also a base64 character, the ciphertext is unversioned without the separator
+		return 0, ciphertext, nil
+	}
+	version, err := strconv.Atoi(versionStr)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid key version of ciphertext: %w", err)
+	}
+	return version, data, nil
+}
+
+var (
+	defaultKeyRing     *KeyRing
+	defaultKeyRingErr  error
+	defaultKeyRingOnce sync.Once
+)
+
+// DefaultKeyRing returns the key ring loaded from EnvKeyRing.
+func DefaultKeyRing() (*KeyRing, error) {
+	defaultKeyRingOnce.Do(func() {
+		if config := os.Getenv(EnvKeyRing); config != "" {
+			defaultKeyRing, defaultKeyRingErr = ParseKeyRing(config)
+		} else {
+			defaultKeyRing, defaultKeyRingErr = NewKeyRing(map[int][]byte{0: []byte(encryptionKey)})
+		}
+		if defaultKeyRingErr != nil {
+			defaultKeyRingErr = fmt.Errorf("failed to load key ring from env %s: %w", EnvKeyRing, defaultKeyRingErr)
+		}
+	})
+	return defaultKeyRing, defaultKeyRingErr
+}
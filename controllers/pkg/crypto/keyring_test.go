@@ -0,0 +1,85 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	oldKey = "Bg1c3Dd5e9e0F84bdF0A5887cF43aB63"
+	newKey = "0123456789abcdef0123456789abcdef"
+)
+
+func TestKeyRingRotation(t *testing.T) {
+	legacyRing, err := ParseKeyRing("0=" + oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := legacyRing.Encrypt([]byte("100"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasPrefix(legacy, versionPrefix+"0") {
+		t.Errorf("ciphertext of version 0 = %s, want unversioned", legacy)
+	}
+	// the unversioned ciphertexts before the key ring are version 0
+	if plaintext, err := DecryptWithKey(legacy, []byte(oldKey)); err != nil || string(plaintext) != "100" {
+		t.Errorf("DecryptWithKey() of version 0 = %s, %v", plaintext, err)
+	}
+
+	ring, err := ParseKeyRing("0=" + oldKey + ", 1=" + newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ring.CurrentVersion() != 1 {
+		t.Errorf("CurrentVersion() = %d, want 1", ring.CurrentVersion())
+	}
+	rotated, changed, err := ring.Reencrypt(legacy)
+	if err != nil || !changed || !strings.HasPrefix(rotated, "v1:") {
+		t.Fatalf("Reencrypt() = %s, %v, %v, want v1 ciphertext", rotated, changed, err)
+	}
+	if _, changed, _ = ring.Reencrypt(rotated); changed {
+		t.Errorf("Reencrypt() of current version is changed")
+	}
+	for _, ciphertext := range []string{legacy, rotated} {
+		if plaintext, err := ring.Decrypt(ciphertext); err != nil || string(plaintext) != "100" {
+			t.Errorf("Decrypt(%s) = %s, %v, want 100", ciphertext, plaintext, err)
+		}
+	}
+
+	// the old version is removed after the re-encryption
+	newRing, err := ParseKeyRing("1=" + newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := newRing.Decrypt(rotated); err != nil || string(plaintext) != "100" {
+		t.Errorf("Decrypt() of rotated = %s, %v, want 100", plaintext, err)
+	}
+	if _, err = newRing.Decrypt(legacy); err == nil || !strings.Contains(err.Error(), "not active") {
+		t.Errorf("Decrypt() of removed version error = %v, want not active", err)
+	}
+}
+
+func TestParseKeyRing(t *testing.T) {
+	for _, config := range []string{"", "1", "a=" + newKey, "1=short", "1=" + newKey + ",1=" + oldKey, "-1=" + newKey} {
+		if _, err := ParseKeyRing(config); err == nil {
+			t.Errorf("ParseKeyRing(%q) is accepted", config)
+		}
+	}
+}
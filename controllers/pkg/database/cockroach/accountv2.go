@@ -247,7 +247,7 @@ func (c *Cockroach) ProcessPendingTaskRewards() error {
 				fmt.Printf("usertask %v reward is 0, skip\n", userTask)
 				return nil
 			}
-			if err = c.updateBalanceRaw(tx, &types.UserQueryOpts{UID: userTask.UserUID}, task.Reward, false, true, true); err != nil {
+			if err = c.updateBalanceRaw(tx, &types.UserQueryOpts{UID: userTask.UserUID}, task.Reward, false, true, true, types.LedgerAccountGift, userTask.ID.String()); err != nil {
 				return fmt.Errorf("failed to update balance: %w", err)
 			}
 			msg := fmt.Sprintf("task %s reward", task.Title)
@@ -385,6 +385,309 @@ func (c *Cockroach) GetWorkspace(namespaces ...string) ([]types.Workspace, error
 	return workspaces, nil
 }
 
+// GetBudgets returns the budgets of the user and its workspaces in the local region.
+func (c *Cockroach) GetBudgets(ops *types.UserQueryOpts) ([]types.Budget, error) {
+	userUID, err := c.getUserUID(ops)
+	if err != nil {
+		return nil, err
+	}
+	var budgets []types.Budget
+	if err := c.DB.Where(&types.Budget{UserUID: userUID, RegionUID: c.LocalRegion.UID}).Find(&budgets).Error; err != nil {
+		return nil, fmt.Errorf("failed to get budgets: %v", err)
+	}
+	return budgets, nil
+}
+
+// SetBudget creates or updates the budget of the user, or of the workspace if it is set.
+// The alert status is reset, so the thresholds are checked again with the new amount.
+func (c *Cockroach) SetBudget(ops *types.UserQueryOpts, budget *types.Budget) error {
+	userUID, err := c.getUserUID(ops)
+	if err != nil {
+		return err
+	}
+	if budget.MonthlyAmount <= 0 {
+		return fmt.Errorf("monthly amount of budget must be greater than 0")
+	}
+	for _, t := range budget.Thresholds {
+		if t <= 0 {
+			return fmt.Errorf("invalid budget threshold %d", t)
+		}
+	}
+	budget.UserUID, budget.RegionUID = userUID, c.LocalRegion.UID
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		var existing types.Budget
+		err := tx.Where(&types.Budget{UserUID: userUID, RegionUID: budget.RegionUID}).
+			Where(`"workspace" = ?`, budget.Workspace).First(&existing).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to get budget: %v", err)
+		}
+		if err == nil {
+			budget.ID, budget.CreatedAt = existing.ID, existing.CreatedAt
+			// keep the suspension, it is resumed by the budget checker when the spending is under the new limit
+			budget.Suspended = existing.Suspended
+		}
+		// the zero period makes the budget checker evaluate the budget again without waiting for a billing
+		budget.Period, budget.AlertedThreshold = time.Time{}, 0
+		budget.UpdatedAt = time.Now().UTC()
+		if err := tx.Save(budget).Error; err != nil {
+			return fmt.Errorf("failed to save budget: %v", err)
+		}
+		return nil
+	})
+}
+
+// GetUncheckedBudgets returns the budgets in the local region which are not evaluated in period yet,
+// they are the new or changed budgets and the budgets of the previous periods.
+func (c *Cockroach) GetUncheckedBudgets(period time.Time) ([]types.Budget, error) {
+	var budgets []types.Budget
+	if err := c.DB.Where(&types.Budget{RegionUID: c.LocalRegion.UID}).
+		Where(`("period" IS NULL OR "period" < ?)`, period).Find(&budgets).Error; err != nil {
+		return nil, fmt.Errorf("failed to get unchecked budgets: %v", err)
+	}
+	return budgets, nil
+}
+
+// UpdateBudgetStatus saves the alert and suspension status of the budget.
+func (c *Cockroach) UpdateBudgetStatus(budget *types.Budget) error {
+	return c.DB.Model(&types.Budget{}).Where(&types.Budget{ID: budget.ID}).Updates(map[string]interface{}{
+		"period":           budget.Period,
+		"alertedThreshold": budget.AlertedThreshold,
+		"suspended":        budget.Suspended,
+		"updatedAt":        time.Now().UTC(),
+	}).Error
+}
+
+// GetDebtPolicy returns the debt policy assigned to the user, or the default policy if the user
+// is not assigned. It returns nil if neither exists.
+func (c *Cockroach) GetDebtPolicy(userUID uuid.UUID) (*types.DebtPolicy, error) {
+	name := types.DefaultDebtPolicyName
+	var assignment types.UserDebtPolicy
+	err := c.DB.Where(&types.UserDebtPolicy{UserUID: userUID}).First(&assignment).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get debt policy of user %s: %v", userUID, err)
+	}
+	if err == nil {
+		name = assignment.Policy
+	}
+	var policy types.DebtPolicy
+	if err := c.DB.Where(&types.DebtPolicy{Name: name}).First(&policy).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get debt policy %s: %v", name, err)
+	}
+	return &policy, nil
+}
+
+// GetDebtPolicies returns all debt policies.
+func (c *Cockroach) GetDebtPolicies() ([]types.DebtPolicy, error) {
+	var policies []types.DebtPolicy
+	if err := c.DB.Order(`"name"`).Find(&policies).Error; err != nil {
+		return nil, fmt.Errorf("failed to get debt policies: %v", err)
+	}
+	return policies, nil
+}
+
+// SaveDebtPolicy creates or updates the debt policy.
+func (c *Cockroach) SaveDebtPolicy(policy *types.DebtPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		var existing types.DebtPolicy
+		err := tx.Where(&types.DebtPolicy{Name: policy.Name}).First(&existing).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to get debt policy: %v", err)
+		}
+		now := time.Now().UTC()
+		policy.CreatedAt, policy.UpdatedAt = now, now
+		if err == nil {
+			policy.CreatedAt = existing.CreatedAt
+		}
+		if err := tx.Save(policy).Error; err != nil {
+			return fmt.Errorf("failed to save debt policy: %v", err)
+		}
+		return nil
+	})
+}
+
+// SetUserDebtPolicy assigns the user to the debt policy, an empty policy assigns the user back to the default policy.
+func (c *Cockroach) SetUserDebtPolicy(userUID uuid.UUID, policy string) error {
+	if policy == "" {
+		return c.DB.Where(&types.UserDebtPolicy{UserUID: userUID}).Delete(&types.UserDebtPolicy{}).Error
+	}
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(&types.DebtPolicy{Name: policy}).First(&types.DebtPolicy{}).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("debt policy %s not found", policy)
+			}
+			return fmt.Errorf("failed to get debt policy %s: %v", policy, err)
+		}
+		if err := tx.Save(&types.UserDebtPolicy{UserUID: userUID, Policy: policy, UpdatedAt: time.Now().UTC()}).Error; err != nil {
+			return fmt.Errorf("failed to assign debt policy: %v", err)
+		}
+		return nil
+	})
+}
+
+// CreateBillingExport requests a billing export of the user in the local region, it returns
+// types.ErrBillingExportPending if another billing export of the user is pending.
+func (c *Cockroach) CreateBillingExport(export *types.BillingExport) error {
+	if err := export.Validate(); err != nil {
+		return err
+	}
+	return c.Localdb.Transaction(func(tx *gorm.DB) error {
+		var pending types.BillingExport
+		if err := tx.Where(&types.BillingExport{UserUID: export.UserUID, Status: types.BillingExportStatusPending}).
+			Limit(1).Find(&pending).Error; err != nil {
+			return fmt.Errorf("failed to get pending billing export: %v", err)
+		}
+		if pending.ID != uuid.Nil {
+			return fmt.Errorf("%w: %s", types.ErrBillingExportPending, pending.ID)
+		}
+		now := time.Now().UTC()
+		export.ID, export.Status, export.CreatedAt, export.UpdatedAt = uuid.New(), types.BillingExportStatusPending, now, now
+		if err := tx.Create(export).Error; err != nil {
+			return fmt.Errorf("failed to create billing export: %v", err)
+		}
+		return nil
+	})
+}
+
+// GetBillingExports returns the latest billing exports of the user.
+func (c *Cockroach) GetBillingExports(userUID uuid.UUID, limit int) ([]types.BillingExport, error) {
+	var exports []types.BillingExport
+	if err := c.Localdb.Where(&types.BillingExport{UserUID: userUID}).Order(`"createdAt" DESC`).Limit(limit).Find(&exports).Error; err != nil {
+		return nil, fmt.Errorf("failed to get billing exports: %v", err)
+	}
+	return exports, nil
+}
+
+// GetPendingBillingExports returns the oldest pending billing exports which are due to be attempted at now.
+func (c *Cockroach) GetPendingBillingExports(now time.Time, limit int) ([]types.BillingExport, error) {
+	var exports []types.BillingExport
+	if err := c.Localdb.Where(&types.BillingExport{Status: types.BillingExportStatusPending}).
+		Where(`"nextAttemptAt" IS NULL OR "nextAttemptAt" <= ?`, now.UTC()).Order(`"createdAt"`).Limit(limit).Find(&exports).Error; err != nil {
+		return nil, fmt.Errorf("failed to get pending billing exports: %v", err)
+	}
+	return exports, nil
+}
+
+// UpdateBillingExport saves the result of the billing export. The failed exports of the schedules
+// are not exported again by their schedules, the users are notified to export them.
+func (c *Cockroach) UpdateBillingExport(export *types.BillingExport) error {
+	export.UpdatedAt = time.Now().UTC()
+	if err := c.Localdb.Model(&types.BillingExport{}).Where(&types.BillingExport{ID: export.ID}).Updates(map[string]interface{}{
+		"status":        export.Status,
+		"bucket":        export.Bucket,
+		"object":        export.Object,
+		"url":           export.URL,
+		"urlExpireAt":   export.URLExpireAt,
+		"rows":          export.Rows,
+		"error":         export.Error,
+		"attempts":      export.Attempts,
+		"nextAttemptAt": export.NextAttemptAt,
+		"updatedAt":     export.UpdatedAt,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update billing export: %v", err)
+	}
+	return nil
+}
+
+// GetBillingExportSchedules returns the billing export schedules of the user.
+func (c *Cockroach) GetBillingExportSchedules(userUID uuid.UUID) ([]types.BillingExportSchedule, error) {
+	var schedules []types.BillingExportSchedule
+	if err := c.Localdb.Where(&types.BillingExportSchedule{UserUID: userUID}).Find(&schedules).Error; err != nil {
+		return nil, fmt.Errorf("failed to get billing export schedules: %v", err)
+	}
+	return schedules, nil
+}
+
+// SetBillingExportSchedule creates or updates the monthly billing export of the user, or of the workspace if it is set.
+func (c *Cockroach) SetBillingExportSchedule(schedule *types.BillingExportSchedule) error {
+	if err := schedule.Format.Validate(); err != nil {
+		return err
+	}
+	return c.Localdb.Transaction(func(tx *gorm.DB) error {
+		var existing types.BillingExportSchedule
+		err := tx.Where(&types.BillingExportSchedule{UserUID: schedule.UserUID}).
+			Where(`"workspace" = ?`, schedule.Workspace).First(&existing).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to get billing export schedule: %v", err)
+		}
+		now := time.Now().UTC()
+		schedule.ID, schedule.CreatedAt, schedule.LastPeriod = uuid.New(), now, nil
+		if err == nil {
+			schedule.ID, schedule.CreatedAt, schedule.LastPeriod = existing.ID, existing.CreatedAt, existing.LastPeriod
+		}
+		schedule.UpdatedAt = now
+		if err := tx.Save(schedule).Error; err != nil {
+			return fmt.Errorf("failed to save billing export schedule: %v", err)
+		}
+		return nil
+	})
+}
+
+// DeleteBillingExportSchedule stops the monthly billing export of the user or the workspace.
+func (c *Cockroach) DeleteBillingExportSchedule(userUID uuid.UUID, workspace string) error {
+	return c.Localdb.Where(&types.BillingExportSchedule{UserUID: userUID}).Where(`"workspace" = ?`, workspace).
+		Delete(&types.BillingExportSchedule{}).Error
+}
+
+// CreateDueBillingExports creates the billing exports of the previous month for the schedules which
+// have not exported it, and returns the number of the created exports.
+func (c *Cockroach) CreateDueBillingExports(now time.Time) (int, error) {
+	created := 0
+	err := c.Localdb.Transaction(func(tx *gorm.DB) error {
+		var schedules []types.BillingExportSchedule
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Find(&schedules).Error; err != nil {
+			return fmt.Errorf("failed to get billing export schedules: %v", err)
+		}
+		for i := range schedules {
+			period, due := schedules[i].DuePeriod(now)
+			if !due {
+				continue
+			}
+			export := &types.BillingExport{
+				ID:         uuid.New(),
+				UserUID:    schedules[i].UserUID,
+				Workspace:  schedules[i].Workspace,
+				Format:     schedules[i].Format,
+				StartTime:  period,
+				EndTime:    period.AddDate(0, 1, 0),
+				ScheduleID: &schedules[i].ID,
+				Status:     types.BillingExportStatusPending,
+				CreatedAt:  now.UTC(),
+				UpdatedAt:  now.UTC(),
+			}
+			if err := tx.Create(export).Error; err != nil {
+				return fmt.Errorf("failed to create billing export: %v", err)
+			}
+			if err := tx.Model(&types.BillingExportSchedule{}).Where(&types.BillingExportSchedule{ID: schedules[i].ID}).
+				Updates(map[string]interface{}{"lastPeriod": period, "updatedAt": now.UTC()}).Error; err != nil {
+				return fmt.Errorf("failed to update billing export schedule: %v", err)
+			}
+			created++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return created, nil
+}
+
+func (c *Cockroach) getUserUID(ops *types.UserQueryOpts) (uuid.UUID, error) {
+	if ops.UID != uuid.Nil {
+		return ops.UID, nil
+	}
+	userCr, err := c.GetUserCr(ops)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get user cr: %v", err)
+	}
+	return userCr.UserUID, nil
+}
+
 func checkOps(ops *types.UserQueryOpts) error {
 	if ops.Owner == "" && ops.UID == uuid.Nil && ops.ID == "" {
 		return fmt.Errorf("empty query opts")
@@ -525,11 +828,14 @@ func (c *Cockroach) GetUserOauthProvider(ops *types.UserQueryOpts) ([]types.Oaut
 	return provider, nil
 }
 
-func (c *Cockroach) updateBalance(tx *gorm.DB, ops *types.UserQueryOpts, amount int64, isDeduction, add bool) error {
-	return c.updateBalanceRaw(tx, ops, amount, isDeduction, add, false)
+// updateBalance changes the balance or the deduction balance of the user by amount and writes the
+// balanced ledger entries against the counterpart in the same transaction, reference is the id of
+// the record which causes the change.
+func (c *Cockroach) updateBalance(tx *gorm.DB, ops *types.UserQueryOpts, amount int64, isDeduction, add bool, counterpart types.LedgerAccount, reference string) error {
+	return c.updateBalanceRaw(tx, ops, amount, isDeduction, add, false, counterpart, reference)
 }
 
-func (c *Cockroach) updateBalanceRaw(tx *gorm.DB, ops *types.UserQueryOpts, amount int64, isDeduction, add bool, isActive bool) error {
+func (c *Cockroach) updateBalanceRaw(tx *gorm.DB, ops *types.UserQueryOpts, amount int64, isDeduction, add bool, isActive bool, counterpart types.LedgerAccount, reference string) error {
 	if ops.UID == uuid.Nil {
 		user, err := c.GetUserCr(ops)
 		if err != nil {
@@ -537,17 +843,22 @@ func (c *Cockroach) updateBalanceRaw(tx *gorm.DB, ops *types.UserQueryOpts, amou
 		}
 		ops.UID = user.UserUID
 	}
-	return c.updateWithAccount(ops.UID, isDeduction, add, isActive, amount, tx)
+	return c.updateWithAccount(ops.UID, isDeduction, add, isActive, amount, tx, counterpart, reference)
 }
 
-func (c *Cockroach) updateWithAccount(userUID uuid.UUID, isDeduction, add, isActive bool, amount int64, db *gorm.DB) error {
+func (c *Cockroach) updateWithAccount(userUID uuid.UUID, isDeduction, add, isActive bool, amount int64, db *gorm.DB, counterpart types.LedgerAccount, reference string) error {
+	if err := c.openLedger(db, userUID); err != nil {
+		return err
+	}
 	exprs := map[string]interface{}{}
 	control := "-"
 	if add {
 		control = "+"
 	}
+	account := types.LedgerAccountBalance
 	if isDeduction {
 		exprs["deduction_balance"] = gorm.Expr("deduction_balance "+control+" ?", amount)
+		account = types.LedgerAccountDeductionBalance
 	} else {
 		exprs["balance"] = gorm.Expr("balance "+control+" ?", amount)
 	}
@@ -555,7 +866,43 @@ func (c *Cockroach) updateWithAccount(userUID uuid.UUID, isDeduction, add, isAct
 		exprs[`"activityBonus"`] = gorm.Expr(`"activityBonus" + ?`, amount)
 	}
 	result := db.Model(&types.Account{}).Where(`"userUid" = ?`, userUID).Updates(exprs)
-	return HandleUpdateResult(result, types.Account{}.TableName())
+	if err := HandleUpdateResult(result, types.Account{}.TableName()); err != nil {
+		return err
+	}
+	if !add {
+		amount = -amount
+	}
+	if err := db.Create(types.NewLedgerEntries(userUID, account, counterpart, amount, reference)).Error; err != nil {
+		return fmt.Errorf("failed to create ledger entries: %w", err)
+	}
+	return nil
+}
+
+// openLedger writes the opening entries of the balances of the account before its first ledger
+// entries, the account is locked so that the opening entries are written once.
+func (c *Cockroach) openLedger(db *gorm.DB, userUID uuid.UUID) error {
+	var account types.Account
+	if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Select(`"userUid"`, "balance", "deduction_balance").
+		Where(`"userUid" = ?`, userUID).Limit(1).Find(&account).Error; err != nil {
+		return fmt.Errorf("failed to get account: %w", err)
+	}
+	if account.UserUID == uuid.Nil {
+		// the update reports the missing account
+		return nil
+	}
+	var opened []uuid.UUID
+	if err := db.Model(&types.LedgerEntry{}).Where(`"userUid" = ?`, userUID).Limit(1).Pluck("id", &opened).Error; err != nil {
+		return fmt.Errorf("failed to get ledger entries: %w", err)
+	}
+	if len(opened) > 0 {
+		return nil
+	}
+	entries := types.NewLedgerEntries(userUID, types.LedgerAccountBalance, types.LedgerAccountOpening, account.Balance, "")
+	entries = append(entries, types.NewLedgerEntries(userUID, types.LedgerAccountDeductionBalance, types.LedgerAccountOpening, account.DeductionBalance, "")...)
+	if err := db.Create(entries).Error; err != nil {
+		return fmt.Errorf("failed to create opening ledger entries: %w", err)
+	}
+	return nil
 }
 
 func HandleUpdateResult(result *gorm.DB, entityName string) error {
@@ -570,36 +917,36 @@ func HandleUpdateResult(result *gorm.DB, entityName string) error {
 
 func (c *Cockroach) AddBalance(ops *types.UserQueryOpts, amount int64) error {
 	return c.DB.Transaction(func(tx *gorm.DB) error {
-		return c.updateBalance(tx, ops, amount, false, true)
+		return c.updateBalance(tx, ops, amount, false, true, types.LedgerAccountAdjustment, "")
 	})
 }
 
 func (c *Cockroach) AddRewardBalance(ops *types.UserQueryOpts, amount int64, db *gorm.DB) error {
 	return db.Transaction(func(tx *gorm.DB) error {
-		return c.updateBalance(tx, ops, amount, false, true)
+		return c.updateBalance(tx, ops, amount, false, true, types.LedgerAccountGift, "")
 	})
 }
 
 func (c *Cockroach) ReduceBalance(ops *types.UserQueryOpts, amount int64) error {
 	return c.DB.Transaction(func(tx *gorm.DB) error {
-		return c.updateBalance(tx, ops, amount, false, false)
+		return c.updateBalance(tx, ops, amount, false, false, types.LedgerAccountAdjustment, "")
 	})
 }
 
 func (c *Cockroach) ReduceDeductionBalance(ops *types.UserQueryOpts, amount int64) error {
 	return c.DB.Transaction(func(tx *gorm.DB) error {
-		return c.updateBalance(tx, ops, amount, false, false)
+		return c.updateBalance(tx, ops, amount, false, false, types.LedgerAccountAdjustment, "")
 	})
 }
 
 func (c *Cockroach) AddDeductionBalance(ops *types.UserQueryOpts, amount int64) error {
 	return c.DB.Transaction(func(tx *gorm.DB) error {
-		return c.updateBalance(tx, ops, amount, true, true)
+		return c.updateBalance(tx, ops, amount, true, true, types.LedgerAccountConsumption, "")
 	})
 }
 
 func (c *Cockroach) AddDeductionBalanceWithDB(ops *types.UserQueryOpts, amount int64, tx *gorm.DB) error {
-	return c.updateBalance(tx, ops, amount, true, true)
+	return c.updateBalance(tx, ops, amount, true, true, types.LedgerAccountConsumption, "")
 }
 
 func (c *Cockroach) AddDeductionBalanceWithFunc(ops *types.UserQueryOpts, amount int64, preDo, postDo func() error) error {
@@ -607,7 +954,7 @@ func (c *Cockroach) AddDeductionBalanceWithFunc(ops *types.UserQueryOpts, amount
 		if err := preDo(); err != nil {
 			return err
 		}
-		if err := c.updateBalance(tx, ops, amount, true, true); err != nil {
+		if err := c.updateBalance(tx, ops, amount, true, true, types.LedgerAccountConsumption, ""); err != nil {
 			return err
 		}
 		return postDo()
@@ -667,20 +1014,375 @@ func (c *Cockroach) payment(payment *types.Payment, updateBalance bool) error {
 		payment.UserUID = user.UserUID
 	}
 
+	// the payment is settled once by its trade number, the polling and the callbacks of the
+	// payment provider may settle the same trade
 	return c.DB.Transaction(func(tx *gorm.DB) error {
-		if err := c.DB.First(&types.Payment{ID: payment.ID}).Error; err == nil {
+		var count int64
+		if err := tx.Model(&types.Payment{}).Where(`"id" = ? OR "trade_no" = ?`, payment.ID, payment.TradeNO).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to get payment: %w", err)
+		}
+		if count > 0 {
 			return nil
 		}
-		if err := c.DB.Create(payment).Error; err != nil {
+		if err := tx.Create(payment).Error; err != nil {
 			return fmt.Errorf("failed to save payment: %w", err)
 		}
 		if updateBalance {
-			if err := c.AddBalance(&types.UserQueryOpts{UID: payment.UserUID}, payment.Amount+payment.Gift); err != nil {
+			if err := c.updateBalance(tx, &types.UserQueryOpts{UID: payment.UserUID}, payment.Amount, false, true, types.LedgerAccountRecharge, payment.ID); err != nil {
 				return fmt.Errorf("failed to add balance: %w", err)
 			}
+			if payment.Gift != 0 {
+				if err := c.updateBalance(tx, &types.UserQueryOpts{UID: payment.UserUID}, payment.Gift, false, true, types.LedgerAccountGift, payment.ID); err != nil {
+					return fmt.Errorf("failed to add gift balance: %w", err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// RefundPayment refunds refund.Amount of the payment refund.PaymentID. The refunded amount is
+// checked against the locked payment, and the refund is saved as pending with its balance
+// deducted in one transaction. Then refundFunc refunds with the payment provider outside of the
+// transaction and returns the refund number of the provider, and the refund is finished with
+// the result: it succeeds, or it fails and the balance is restored if the provider rejects it
+// with types.ErrRefundRejected, or it is kept pending if the result is unknown. A retry of the
+// payment finishes its pending refund with the same refund number instead of creating a new one,
+// the pending refunds are also finished by ReconcileRefunds or canceled by CancelRefund. The
+// refund is refunded by the provider in the charged currency of the payment, see types.RefundCharge.
+func (c *Cockroach) RefundPayment(refund *types.Refund, refundFunc func(payment *types.Payment, refund *types.Refund) (string, error)) error {
+	if refund.Amount <= 0 {
+		return fmt.Errorf("refund amount must be greater than 0")
+	}
+	var pending types.Refund
+	if err := c.DB.Where(&types.Refund{PaymentID: refund.PaymentID, Status: types.RefundStatusPending}).
+		Order(`"createdAt"`).Limit(1).Find(&pending).Error; err != nil {
+		return fmt.Errorf("failed to get pending refund: %w", err)
+	}
+	if pending.ID == uuid.Nil {
+		payment, err := c.createRefund(refund)
+		if err != nil {
+			return err
+		}
+		return c.finishRefund(payment, refund, refundFunc)
+	}
+	var payment types.Payment
+	if err := c.DB.Where(&types.Payment{ID: pending.PaymentID}).First(&payment).Error; err != nil {
+		return fmt.Errorf("failed to get payment: %w", err)
+	}
+	amount := refund.Amount
+	*refund = pending
+	if err := c.finishRefund(&payment, refund, refundFunc); err != nil {
+		return err
+	}
+	if refund.Amount != amount {
+		return fmt.Errorf("the pending refund %s of amount %d of payment %s is finished, refund the payment again for amount %d", refund.ID, refund.Amount, payment.ID, amount)
+	}
+	return nil
+}
+
+// createRefund saves the refund as pending and deducts its balance.
+func (c *Cockroach) createRefund(refund *types.Refund) (*types.Payment, error) {
+	var payment types.Payment
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(&types.Payment{ID: refund.PaymentID}).First(&payment).Error; err != nil {
+			return fmt.Errorf("failed to get payment: %w", err)
+		}
+		var refunded struct {
+			Amount        int64
+			ChargedAmount int64
+		}
+		if err := tx.Model(&types.Refund{}).Where(&types.Refund{PaymentID: payment.ID}).
+			Where(`"status" <> ?`, types.RefundStatusFailed).
+			Select(`COALESCE(SUM("amount"), 0) AS amount, COALESCE(SUM("chargedAmount"), 0) AS charged_amount`).
+			Scan(&refunded).Error; err != nil {
+			return fmt.Errorf("failed to get refunded amount: %w", err)
+		}
+		if refunded.Amount+refund.Amount > payment.Amount {
+			return fmt.Errorf("refund amount %d exceeds the refundable amount %d of payment %s", refund.Amount, payment.Amount-refunded.Amount, payment.ID)
+		}
+		refund.ID = uuid.New()
+		refund.UserUID, refund.RegionUID = payment.UserUID, payment.RegionUID
+		refund.Method, refund.TradeNO = payment.Method, payment.TradeNO
+		refund.DeductedAmount = types.RefundDeduction(&payment, refund.Amount)
+		refund.ChargedAmount = types.RefundCharge(&payment, refunded.Amount, refunded.ChargedAmount, refund.Amount)
+		if payment.ChargedAmount > 0 && refund.ChargedAmount <= 0 {
+			return fmt.Errorf("the charged amount %d %s of payment %s is refunded", payment.ChargedAmount, payment.ChargedCurrency, payment.ID)
+		}
+		refund.Status, refund.RefundNO = types.RefundStatusPending, ""
+		refund.CreatedAt = time.Now().UTC()
+		if err := c.updateBalance(tx, &types.UserQueryOpts{UID: payment.UserUID}, refund.DeductedAmount, false, false, types.LedgerAccountRefund, refund.ID.String()); err != nil {
+			return fmt.Errorf("failed to deduct balance: %w", err)
+		}
+		if err := tx.Create(refund).Error; err != nil {
+			return fmt.Errorf("failed to save refund: %w", err)
 		}
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// finishRefund refunds the pending refund with the payment provider and saves the result.
+func (c *Cockroach) finishRefund(payment *types.Payment, refund *types.Refund, refundFunc func(payment *types.Payment, refund *types.Refund) (string, error)) error {
+	refundNO, refundErr := refundFunc(payment, refund)
+	if refundErr != nil && !errors.Is(refundErr, types.ErrRefundRejected) {
+		return fmt.Errorf("refund %s is pending, retry the refund of payment %s to finish it: %w", refund.ID, payment.ID, refundErr)
+	}
+	if err := c.saveRefundResult(payment, refund, refundNO, refundErr); err != nil {
+		return fmt.Errorf("refund %s is pending, retry the refund of payment %s to finish it: %w", refund.ID, payment.ID, err)
+	}
+	if refund.Status == types.RefundStatusFailed {
+		if refundErr == nil {
+			refundErr = types.ErrRefundRejected
+		}
+		return fmt.Errorf("refund %s of payment %s failed: %w", refund.ID, payment.ID, refundErr)
+	}
+	return nil
+}
+
+// saveRefundResult saves the pending refund as failed with its balance restored if refundErr is
+// not nil, otherwise as succeeded with the refund number of the provider. The refund finished
+// concurrently is kept as it is.
+func (c *Cockroach) saveRefundResult(payment *types.Payment, refund *types.Refund, refundNO string, refundErr error) error {
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		var current types.Refund
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(&types.Refund{ID: refund.ID}).First(&current).Error; err != nil {
+			return fmt.Errorf("failed to get refund: %w", err)
+		}
+		if current.Status != types.RefundStatusPending {
+			// finished by a concurrent retry
+			*refund = current
+			return nil
+		}
+		if refundErr != nil {
+			refund.Status = types.RefundStatusFailed
+			if err := c.updateBalance(tx, &types.UserQueryOpts{UID: refund.UserUID}, refund.DeductedAmount, false, true, types.LedgerAccountRefund, refund.ID.String()); err != nil {
+				return fmt.Errorf("failed to restore balance: %w", err)
+			}
+		} else {
+			refund.Status, refund.RefundNO = types.RefundStatusSucceeded, refundNO
+			message := fmt.Sprintf("refund of payment %s by %s: %s", payment.ID, refund.Operator, refund.Reason)
+			now := time.Now().UTC()
+			if err := tx.Create(&types.AccountTransaction{
+				ID:        uuid.New(),
+				Type:      types.AccountTransactionTypeRefund,
+				UserUID:   refund.UserUID,
+				Balance:   -refund.DeductedAmount,
+				Message:   &message,
+				CreatedAt: now,
+				UpdatedAt: now,
+				BillingID: refund.ID,
+			}).Error; err != nil {
+				return fmt.Errorf("failed to create account transaction: %w", err)
+			}
+		}
+		if err := tx.Model(&types.Refund{}).Where(&types.Refund{ID: refund.ID}).Updates(map[string]interface{}{
+			"status":   refund.Status,
+			"refundNo": refund.RefundNO,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update refund: %w", err)
+		}
+		return nil
+	})
+}
+
+// ReconcileRefunds finishes the refunds which are kept pending since before, e.g. after a
+// timeout of the payment provider. Each one is refunded again by refundFunc with its refund
+// number, which the provider does not refund twice, and its result is saved as by
+// RefundPayment. It returns the refunds with their results, and the errors of the refunds
+// which are still pending.
+func (c *Cockroach) ReconcileRefunds(before time.Time, refundFunc func(payment *types.Payment, refund *types.Refund) (string, error)) ([]types.Refund, error) {
+	var pending []types.Refund
+	if err := c.DB.Where(&types.Refund{Status: types.RefundStatusPending}).Where(`"createdAt" < ?`, before).
+		Order(`"createdAt"`).Find(&pending).Error; err != nil {
+		return nil, fmt.Errorf("failed to get pending refunds: %w", err)
+	}
+	var errs []error
+	for i := range pending {
+		refund := &pending[i]
+		var payment types.Payment
+		if err := c.DB.Where(&types.Payment{ID: refund.PaymentID}).First(&payment).Error; err != nil {
+			errs = append(errs, fmt.Errorf("failed to get payment %s of refund %s: %w", refund.PaymentID, refund.ID, err))
+			continue
+		}
+		if err := c.finishRefund(&payment, refund, refundFunc); err != nil && !errors.Is(err, types.ErrRefundRejected) {
+			errs = append(errs, err)
+		}
+	}
+	return pending, errors.Join(errs...)
+}
+
+// CancelRefund fails the pending refund refundID and restores its balance, it is for the
+// refunds which the payment provider confirms are not refunded but which can not be finished
+// by ReconcileRefunds. The refunds which are not pending are not canceled.
+func (c *Cockroach) CancelRefund(refundID uuid.UUID, operator, reason string) (*types.Refund, error) {
+	var refund types.Refund
+	if err := c.DB.Where(&types.Refund{ID: refundID}).First(&refund).Error; err != nil {
+		return nil, fmt.Errorf("failed to get refund: %w", err)
+	}
+	var payment types.Payment
+	if err := c.DB.Where(&types.Payment{ID: refund.PaymentID}).First(&payment).Error; err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+	if err := c.saveRefundResult(&payment, &refund, "", fmt.Errorf("%w: canceled by %s: %s", types.ErrRefundRejected, operator, reason)); err != nil {
+		return nil, fmt.Errorf("failed to cancel refund %s: %w", refund.ID, err)
+	}
+	if refund.Status != types.RefundStatusFailed {
+		return &refund, fmt.Errorf("refund %s is not pending, it is %s", refund.ID, refund.Status)
+	}
+	return &refund, nil
+}
+
+// ReconcileLedger checks the stored balances of a batch of at most batchSize accounts after the
+// user uid against the credits minus the debits of their ledger accounts, and saves the accounts
+// which do not match as discrepancies checked at checkedAt. The accounts without ledger entries
+// have not changed since the ledger and are skipped. It returns the last user uid of the batch,
+// the number of the scanned accounts and the discrepancies of the batch.
+func (c *Cockroach) ReconcileLedger(after uuid.UUID, batchSize int, checkedAt time.Time) (last uuid.UUID, scanned int, discrepancies []types.LedgerDiscrepancy, err error) {
+	last = after
+	err = c.DB.Transaction(func(tx *gorm.DB) error {
+		var accounts []types.Account
+		if err := tx.Select(`"userUid"`, "balance", "deduction_balance").
+			Where(`"userUid" > ?`, after).Order(`"userUid"`).Limit(batchSize).Find(&accounts).Error; err != nil {
+			return fmt.Errorf("failed to get accounts: %w", err)
+		}
+		if scanned = len(accounts); scanned == 0 {
+			return nil
+		}
+		last = accounts[scanned-1].UserUID
+		userUIDs := make([]uuid.UUID, scanned)
+		for i := range accounts {
+			userUIDs[i] = accounts[i].UserUID
+		}
+		var sums []struct {
+			UserUID uuid.UUID           `gorm:"column:userUid"`
+			Account types.LedgerAccount `gorm:"column:account"`
+			Amount  int64               `gorm:"column:amount"`
+		}
+		if err := tx.Model(&types.LedgerEntry{}).
+			Select(`"userUid", account, SUM(credit) - SUM(debit) AS amount`).
+			Where(`"userUid" IN ? AND account IN ?`, userUIDs, []types.LedgerAccount{types.LedgerAccountBalance, types.LedgerAccountDeductionBalance}).
+			Group(`"userUid", account`).Scan(&sums).Error; err != nil {
+			return fmt.Errorf("failed to sum ledger entries: %w", err)
+		}
+		ledger := make(map[uuid.UUID]map[types.LedgerAccount]int64)
+		for _, sum := range sums {
+			if ledger[sum.UserUID] == nil {
+				ledger[sum.UserUID] = make(map[types.LedgerAccount]int64)
+			}
+			ledger[sum.UserUID][sum.Account] = sum.Amount
+		}
+		for i := range accounts {
+			account := &accounts[i]
+			sum, ok := ledger[account.UserUID]
+			if !ok {
+				continue
+			}
+			if sum[types.LedgerAccountBalance] != account.Balance || sum[types.LedgerAccountDeductionBalance] != account.DeductionBalance {
+				discrepancies = append(discrepancies, types.LedgerDiscrepancy{
+					ID:                     uuid.New(),
+					UserUID:                account.UserUID,
+					CheckedAt:              checkedAt,
+					Balance:                account.Balance,
+					DeductionBalance:       account.DeductionBalance,
+					LedgerBalance:          sum[types.LedgerAccountBalance],
+					LedgerDeductionBalance: sum[types.LedgerAccountDeductionBalance],
+				})
+			}
+		}
+		if len(discrepancies) == 0 {
+			return nil
+		}
+		if err := tx.Create(&discrepancies).Error; err != nil {
+			return fmt.Errorf("failed to save ledger discrepancies: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return after, 0, nil, err
+	}
+	return last, scanned, discrepancies, nil
+}
+
+// GetLedgerDiscrepancies returns the discrepancies checked in [startTime, endTime), of the user
+// if userUID is not nil, the latest first.
+func (c *Cockroach) GetLedgerDiscrepancies(userUID uuid.UUID, startTime, endTime time.Time) ([]types.LedgerDiscrepancy, error) {
+	query := c.DB.Where(`"checkedAt" >= ? AND "checkedAt" < ?`, startTime, endTime)
+	if userUID != uuid.Nil {
+		query = query.Where(`"userUid" = ?`, userUID)
+	}
+	var discrepancies []types.LedgerDiscrepancy
+	if err := query.Order(`"checkedAt" DESC`).Find(&discrepancies).Error; err != nil {
+		return nil, fmt.Errorf("failed to get ledger discrepancies: %v", err)
+	}
+	return discrepancies, nil
+}
+
+// ReencryptAccounts re-encrypts the encrypted balances of a batch of at most batchSize accounts
+// after the user uid with the current key of the key ring, the batch is locked and updated in one
+// transaction. It returns the last user uid of the batch, the number of the scanned accounts and
+// the number of the re-encrypted accounts, the accounts are all scanned if scanned < batchSize.
+func (c *Cockroach) ReencryptAccounts(after uuid.UUID, batchSize int) (last uuid.UUID, scanned, migrated int, err error) {
+	ring, err := crypto.DefaultKeyRing()
+	if err != nil {
+		return after, 0, 0, err
+	}
+	last = after
+	err = c.DB.Transaction(func(tx *gorm.DB) error {
+		var accounts []types.Account
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select(`"userUid"`, `"encryptBalance"`, `"encryptDeductionBalance"`).
+			Where(`"userUid" > ?`, after).Order(`"userUid"`).Limit(batchSize).Find(&accounts).Error; err != nil {
+			return fmt.Errorf("failed to get accounts: %w", err)
+		}
+		for i := range accounts {
+			account := &accounts[i]
+			updates := make(map[string]interface{})
+			for column, ciphertext := range map[string]string{
+				"encryptBalance":          account.EncryptBalance,
+				"encryptDeductionBalance": account.EncryptDeductionBalance,
+			} {
+				if ciphertext == "" {
+					continue
+				}
+				newCiphertext, changed, err := ring.Reencrypt(ciphertext)
+				if err != nil {
+					return fmt.Errorf("failed to re-encrypt %s of account %s: %w", column, account.UserUID, err)
+				}
+				if changed {
+					updates[column] = newCiphertext
+				}
+			}
+			if len(updates) > 0 {
+				if err := tx.Model(&types.Account{}).Where(`"userUid" = ?`, account.UserUID).Updates(updates).Error; err != nil {
+					return fmt.Errorf("failed to update account %s: %w", account.UserUID, err)
+				}
+				migrated++
+			}
+		}
+		if scanned = len(accounts); scanned > 0 {
+			last = accounts[scanned-1].UserUID
+		}
+		return nil
+	})
+	if err != nil {
+		return after, 0, 0, err
+	}
+	return last, scanned, migrated, nil
+}
+
+// GetRefunds returns the refunds of the payment.
+func (c *Cockroach) GetRefunds(paymentID string) ([]types.Refund, error) {
+	var refunds []types.Refund
+	if err := c.DB.Where(&types.Refund{PaymentID: paymentID}).Order(`"createdAt"`).Find(&refunds).Error; err != nil {
+		return nil, fmt.Errorf("failed to get refunds: %v", err)
+	}
+	return refunds, nil
 }
 
 func (c *Cockroach) GetRegions() ([]types.Region, error) {
@@ -991,10 +1693,10 @@ func (c *Cockroach) transferAccount(from, to *types.UserQueryOpts, amount int64,
 			}
 		}
 
-		if err = c.updateBalance(tx, &types.UserQueryOpts{UID: from.UID}, -amount, false, true); err != nil {
+		if err = c.updateBalance(tx, &types.UserQueryOpts{UID: from.UID}, -amount, false, true, types.LedgerAccountTransfer, id); err != nil {
 			return fmt.Errorf("failed to update sender balance: %w", err)
 		}
-		if err = c.updateBalance(tx, &types.UserQueryOpts{UID: to.UID}, amount, false, true); err != nil {
+		if err = c.updateBalance(tx, &types.UserQueryOpts{UID: to.UID}, amount, false, true, types.LedgerAccountTransfer, id); err != nil {
 			return fmt.Errorf("failed to update receiver balance: %w", err)
 		}
 		if err = c.DB.Create(&types.Transfer{
@@ -1014,11 +1716,40 @@ func (c *Cockroach) transferAccount(from, to *types.UserQueryOpts, amount int64,
 }
 
 func (c *Cockroach) InitTables() error {
-	err := CreateTableIfNotExist(c.DB, types.Account{}, types.Payment{}, types.Transfer{}, types.Region{}, types.Invoice{}, types.InvoicePayment{}, types.Configs{})
+	err := CreateTableIfNotExist(c.DB, types.Account{}, types.Payment{}, types.Transfer{}, types.Region{}, types.Invoice{}, types.InvoicePayment{}, types.Configs{}, types.Budget{}, types.Refund{},
+		types.LedgerEntry{}, types.LedgerDiscrepancy{}, types.DebtPolicy{}, types.UserDebtPolicy{})
 	if err != nil {
 		return fmt.Errorf("failed to create table: %v", err)
 	}
+	// the billing exports are processed by the region of the billings
+	if err = CreateTableIfNotExist(c.Localdb, types.BillingExport{}, types.BillingExportSchedule{}); err != nil {
+		return fmt.Errorf("failed to create local table: %v", err)
+	}
 
+	for _, column := range []string{"Attempts", "NextAttemptAt"} {
+		if !c.Localdb.Migrator().HasColumn(&types.BillingExport{}, column) {
+			if err := c.Localdb.Migrator().AddColumn(&types.BillingExport{}, column); err != nil {
+				return fmt.Errorf("failed to add column %s of billing export: %v", column, err)
+			}
+		}
+	}
+	if !c.DB.Migrator().HasColumn(&types.Refund{}, "status") {
+		if err := c.DB.Migrator().AddColumn(&types.Refund{}, "Status"); err != nil {
+			return fmt.Errorf("failed to add column status of refund: %v", err)
+		}
+	}
+	if !c.DB.Migrator().HasColumn(&types.Refund{}, "chargedAmount") {
+		if err := c.DB.Migrator().AddColumn(&types.Refund{}, "ChargedAmount"); err != nil {
+			return fmt.Errorf("failed to add column chargedAmount of refund: %v", err)
+		}
+	}
+	for _, column := range []string{"ChargedAmount", "ChargedCurrency"} {
+		if !c.DB.Migrator().HasColumn(&types.Payment{}, column) {
+			if err := c.DB.Migrator().AddColumn(&types.Payment{}, column); err != nil {
+				return fmt.Errorf("failed to add column %s of payment: %v", column, err)
+			}
+		}
+	}
 	// TODO: remove this after migration
 	if !c.DB.Migrator().HasColumn(&types.Payment{}, `activityType`) {
 		//if err := c.DB.Migrator().AddColumn(&types.Payment{PaymentRaw: types.PaymentRaw{}}, `PaymentRaw."activityType"`); err != nil {
@@ -1063,8 +1794,12 @@ func NewCockRoach(globalURI, localURI string) (*Cockroach, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to open local url %s : %v", localURI, err)
 	}
-	baseBalance, err := crypto.DecryptInt64(os.Getenv(EnvBaseBalance))
-	if err == nil {
+	// BASE_BALANCE is decrypted by the key ring, re-encrypt it with the current key before the old key versions are removed
+	if encryptBaseBalance := os.Getenv(EnvBaseBalance); encryptBaseBalance != "" {
+		baseBalance, err := crypto.DecryptInt64(encryptBaseBalance)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt env %s: %w", EnvBaseBalance, err)
+		}
 		BaseBalance = baseBalance
 	}
 	newEncryptBalance, err := crypto.EncryptInt64(BaseBalance)
@@ -1075,7 +1810,7 @@ func NewCockRoach(globalURI, localURI string) (*Cockroach, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to encrypt zero value")
 	}
-	cockroach := &Cockroach{DB: db, Localdb: localdb, ZeroAccount: &types.Account{EncryptBalance: *newEncryptBalance, EncryptDeductionBalance: *newEncryptDeductionBalance, Balance: baseBalance, DeductionBalance: 0}}
+	cockroach := &Cockroach{DB: db, Localdb: localdb, ZeroAccount: &types.Account{EncryptBalance: *newEncryptBalance, EncryptDeductionBalance: *newEncryptDeductionBalance, Balance: BaseBalance, DeductionBalance: 0}}
 	//TODO region with local
 	localRegionStr := os.Getenv(EnvLocalRegion)
 	if localRegionStr != "" {
@@ -1139,7 +1874,7 @@ func (c *Cockroach) UseGiftCode(giftCode *types.GiftCode, userID string) error {
 
 		ops := &types.UserQueryOpts{ID: userID}
 		// Update the user's balance
-		if err := c.updateBalance(tx, ops, giftCode.CreditAmount, false, true); err != nil {
+		if err := c.updateBalance(tx, ops, giftCode.CreditAmount, false, true, types.LedgerAccountGift, giftCode.ID.String()); err != nil {
 			return fmt.Errorf("failed to update user balance: %w", err)
 		}
 
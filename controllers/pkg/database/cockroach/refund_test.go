@@ -0,0 +1,190 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cockroach
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/labring/sealos/controllers/pkg/types"
+)
+
+// newRefundTestPayment saves a payment of ¥100 charged as $14.08 with a gift of ¥20 for a user
+// whose balance is ¥200, and returns a func of the balance of the user.
+func newRefundTestPayment(t *testing.T) (*Cockroach, *types.Payment, func() int64) {
+	globalURI, localURI := os.Getenv("GLOBAL_COCKROACH_URI"), os.Getenv("LOCAL_COCKROACH_URI")
+	if globalURI == "" || localURI == "" {
+		t.Skip("GLOBAL_COCKROACH_URI and LOCAL_COCKROACH_URI are not set")
+	}
+	ck, err := NewCockRoach(globalURI, localURI)
+	if err != nil {
+		t.Fatalf("NewCockRoach() error = %v", err)
+	}
+	t.Cleanup(func() { _ = ck.Close() })
+
+	userUID := uuid.New()
+	payment := &types.Payment{ID: "refund-test-" + userUID.String(), PaymentRaw: types.PaymentRaw{
+		UserUID:         userUID,
+		RegionUID:       ck.LocalRegion.UID,
+		RegionUserOwner: "refund-test",
+		Method:          "test",
+		Amount:          100_000000,
+		Gift:            20_000000,
+		ChargedAmount:   1408,
+		ChargedCurrency: "usd",
+		TradeNO:         "refund-test-" + userUID.String(),
+		Message:         "refund test",
+	}}
+	account := &types.Account{UserUID: userUID, Balance: 200_000000, EncryptBalance: "-", EncryptDeductionBalance: "-", CreateRegionID: "refund-test"}
+	if err = ck.DB.Create(account).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err = ck.DB.Create(payment).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ck.DB.Where(`"paymentId" = ?`, payment.ID).Delete(&types.Refund{})
+		ck.DB.Where(`"userUid" = ?`, userUID).Delete(&types.LedgerEntry{})
+		ck.DB.Where(`"userUid" = ?`, userUID).Delete(&types.AccountTransaction{})
+		ck.DB.Where(&types.Payment{ID: payment.ID}).Delete(&types.Payment{})
+		ck.DB.Where(`"userUid" = ?`, userUID).Delete(&types.Account{})
+	})
+	balance := func() int64 {
+		var a types.Account
+		if err := ck.DB.Where(`"userUid" = ?`, userUID).First(&a).Error; err != nil {
+			t.Fatal(err)
+		}
+		return a.Balance
+	}
+	return ck, payment, balance
+}
+
+func TestCockroach_RefundPayment(t *testing.T) {
+	ck, payment, balance := newRefundTestPayment(t)
+	var err error
+
+	var refundNOs []string
+	provider := func(result error) func(*types.Payment, *types.Refund) (string, error) {
+		return func(_ *types.Payment, refund *types.Refund) (string, error) {
+			refundNOs = append(refundNOs, refund.OutRefundNO())
+			if result != nil {
+				return "", result
+			}
+			return "provider-" + refund.OutRefundNO(), nil
+		}
+	}
+
+	// the result of the provider is unknown, the refund is kept pending with the balance deducted
+	pending := &types.Refund{PaymentID: payment.ID, Amount: 50_000000, Operator: "admin", Reason: "test"}
+	if err = ck.RefundPayment(pending, provider(errors.New("timeout"))); err == nil {
+		t.Fatalf("RefundPayment() with unknown result is finished")
+	}
+	if pending.Status != types.RefundStatusPending || pending.DeductedAmount != 60_000000 || pending.ChargedAmount != 704 || balance() != 140_000000 {
+		t.Fatalf("pending refund = %+v, balance = %d, want deducted 60000000 of 200000000 and $7.04 charged", pending, balance())
+	}
+
+	// the retry finishes the pending refund with the same refund number instead of refunding again
+	retry := &types.Refund{PaymentID: payment.ID, Amount: 50_000000, Operator: "admin", Reason: "retry"}
+	if err = ck.RefundPayment(retry, provider(nil)); err != nil {
+		t.Fatalf("RefundPayment() retry error = %v", err)
+	}
+	if retry.ID != pending.ID || retry.Status != types.RefundStatusSucceeded || retry.RefundNO != "provider-"+pending.OutRefundNO() {
+		t.Errorf("retried refund = %+v, want the succeeded refund %s", retry, pending.ID)
+	}
+	if len(refundNOs) != 2 || refundNOs[0] != refundNOs[1] || balance() != 140_000000 {
+		t.Errorf("refund numbers = %v, balance = %d, want one refund number and balance 140000000", refundNOs, balance())
+	}
+
+	// 50 of the payment is left to refund
+	if err = ck.RefundPayment(&types.Refund{PaymentID: payment.ID, Amount: 60_000000}, provider(nil)); err == nil {
+		t.Errorf("RefundPayment() exceeding the payment is accepted")
+	}
+
+	// the rejected refund restores the balance and is not counted as refunded
+	rejected := &types.Refund{PaymentID: payment.ID, Amount: 50_000000, Operator: "admin", Reason: "test"}
+	if err = ck.RefundPayment(rejected, provider(fmt.Errorf("%w: closed", types.ErrRefundRejected))); !errors.Is(err, types.ErrRefundRejected) {
+		t.Fatalf("RefundPayment() rejected error = %v, want %v", err, types.ErrRefundRejected)
+	}
+	if rejected.Status != types.RefundStatusFailed || balance() != 140_000000 {
+		t.Errorf("rejected refund = %+v, balance = %d, want failed and balance 140000000", rejected, balance())
+	}
+	last := &types.Refund{PaymentID: payment.ID, Amount: 50_000000, Operator: "admin", Reason: "test"}
+	if err = ck.RefundPayment(last, provider(nil)); err != nil || balance() != 80_000000 || last.ChargedAmount != 704 {
+		t.Errorf("RefundPayment() after the rejected refund = %+v, %v, balance = %d, want 80000000 and $7.04 charged", last, err, balance())
+	}
+
+	refunds, err := ck.GetRefunds(payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statuses []string
+	for _, r := range refunds {
+		statuses = append(statuses, r.Status)
+	}
+	if fmt.Sprint(statuses) != fmt.Sprint([]string{types.RefundStatusSucceeded, types.RefundStatusFailed, types.RefundStatusSucceeded}) {
+		t.Errorf("statuses of refunds = %v", statuses)
+	}
+}
+
+func TestCockroach_ReconcileAndCancelRefunds(t *testing.T) {
+	ck, payment, balance := newRefundTestPayment(t)
+	timeout := func(*types.Payment, *types.Refund) (string, error) {
+		return "", errors.New("timeout")
+	}
+	reconciled := &types.Refund{PaymentID: payment.ID, Amount: 50_000000, Operator: "admin", Reason: "test"}
+	canceled := &types.Refund{PaymentID: payment.ID, Amount: 50_000000, Operator: "admin", Reason: "test"}
+	if err := ck.RefundPayment(reconciled, timeout); err == nil {
+		t.Fatalf("RefundPayment() with unknown result is finished")
+	}
+	// a new refund of the payment finishes its pending refund, so the second one is created directly
+	if _, err := ck.createRefund(canceled); err != nil {
+		t.Fatal(err)
+	}
+	if balance() != 80_000000 {
+		t.Fatalf("balance = %d, want 80000000 with both refunds pending", balance())
+	}
+
+	// the refunds which keep failing stay pending
+	if refunds, err := ck.ReconcileRefunds(time.Now().Add(time.Minute), timeout); err == nil || len(refunds) < 2 {
+		t.Errorf("ReconcileRefunds() with unknown results = %d refunds, %v, want errors", len(refunds), err)
+	}
+	if _, err := ck.CancelRefund(canceled.ID, "admin", "not refunded by the provider"); err != nil {
+		t.Fatalf("CancelRefund() error = %v", err)
+	}
+	if balance() != 140_000000 {
+		t.Errorf("balance after the canceled refund = %d, want 140000000", balance())
+	}
+	if _, err := ck.CancelRefund(canceled.ID, "admin", "again"); err == nil {
+		t.Errorf("CancelRefund() of a failed refund is accepted")
+	}
+
+	if _, err := ck.ReconcileRefunds(time.Now().Add(time.Minute), func(_ *types.Payment, refund *types.Refund) (string, error) {
+		return "provider-" + refund.OutRefundNO(), nil
+	}); err != nil {
+		t.Fatalf("ReconcileRefunds() error = %v", err)
+	}
+	refunds, err := ck.GetRefunds(payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refunds) != 2 || refunds[0].Status != types.RefundStatusSucceeded || refunds[1].Status != types.RefundStatusFailed || balance() != 140_000000 {
+		t.Errorf("refunds = %+v, balance = %d, want the reconciled refund succeeded and the canceled one failed", refunds, balance())
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -59,8 +60,15 @@ type Account interface {
 	GetUpdateTimeForCategoryAndPropertyFromMetering(category string, property string) (time.Time, error)
 	GetAllPayment() ([]resources.Billing, error)
 	InitDefaultPropertyTypeLS() error
+	GetPropertyTypeLS() (*resources.PropertyTypeLS, error)
 	SavePropertyTypes(types []resources.PropertyType) error
+	GetPricingRules() (resources.PricingRules, error)
+	SavePricingRule(rule *resources.PricingRule) error
+	GetExchangeRate(method, currency string) (float64, error)
+	ReencryptPropertyTypes(batchSize int) (int, error)
 	GetBillingCount(accountType common.Type, startTime, endTime time.Time) (count, amount int64, err error)
+	GetOwnerConsumption(owner string, startTime, endTime time.Time) (map[string]int64, error)
+	WalkConsumptionBillings(owner, namespace string, startTime, endTime time.Time, fn func(billing *resources.Billing) error) error
 	GenerateBillingData(startTime, endTime time.Time, prols *resources.PropertyTypeLS, ownerToNS map[string][]string) (map[string][]*resources.Billing, error)
 	InsertMonitor(ctx context.Context, monitors ...*resources.Monitor) error
 	GetDistinctMonitorCombinations(startTime, endTime time.Time) ([]resources.Monitor, error)
@@ -108,6 +116,13 @@ type AccountV2 interface {
 	NewAccount(user *types.UserQueryOpts) (*types.Account, error)
 	Payment(payment *types.Payment) error
 	SavePayment(payment *types.Payment) error
+	RefundPayment(refund *types.Refund, refundFunc func(payment *types.Payment, refund *types.Refund) (string, error)) error
+	GetRefunds(paymentID string) ([]types.Refund, error)
+	ReconcileRefunds(before time.Time, refundFunc func(payment *types.Payment, refund *types.Refund) (string, error)) ([]types.Refund, error)
+	CancelRefund(refundID uuid.UUID, operator, reason string) (*types.Refund, error)
+	ReencryptAccounts(after uuid.UUID, batchSize int) (last uuid.UUID, scanned, migrated int, err error)
+	ReconcileLedger(after uuid.UUID, batchSize int, checkedAt time.Time) (last uuid.UUID, scanned int, discrepancies []types.LedgerDiscrepancy, err error)
+	GetLedgerDiscrepancies(userUID uuid.UUID, startTime, endTime time.Time) ([]types.LedgerDiscrepancy, error)
 	GetUnInvoicedPaymentListWithIds(ids []string) ([]types.Payment, error)
 	CreateAccount(ops *types.UserQueryOpts, account *types.Account) (*types.Account, error)
 	TransferAccount(from, to *types.UserQueryOpts, amount int64) error
@@ -115,10 +130,27 @@ type AccountV2 interface {
 	AddDeductionBalance(user *types.UserQueryOpts, balance int64) error
 	AddDeductionBalanceWithDB(ops *types.UserQueryOpts, amount int64, tx *gorm.DB) error
 	AddDeductionBalanceWithFunc(ops *types.UserQueryOpts, amount int64, preDo, postDo func() error) error
+	GetBudgets(ops *types.UserQueryOpts) ([]types.Budget, error)
+	SetBudget(ops *types.UserQueryOpts, budget *types.Budget) error
+	GetUncheckedBudgets(period time.Time) ([]types.Budget, error)
+	UpdateBudgetStatus(budget *types.Budget) error
+	GetDebtPolicy(userUID uuid.UUID) (*types.DebtPolicy, error)
+	GetDebtPolicies() ([]types.DebtPolicy, error)
+	SaveDebtPolicy(policy *types.DebtPolicy) error
+	SetUserDebtPolicy(userUID uuid.UUID, policy string) error
+	CreateBillingExport(export *types.BillingExport) error
+	GetBillingExports(userUID uuid.UUID, limit int) ([]types.BillingExport, error)
+	GetPendingBillingExports(now time.Time, limit int) ([]types.BillingExport, error)
+	UpdateBillingExport(export *types.BillingExport) error
+	GetBillingExportSchedules(userUID uuid.UUID) ([]types.BillingExportSchedule, error)
+	SetBillingExportSchedule(schedule *types.BillingExportSchedule) error
+	DeleteBillingExportSchedule(userUID uuid.UUID, workspace string) error
+	CreateDueBillingExports(now time.Time) (int, error)
 }
 
 type Creator interface {
 	CreateBillingIfNotExist() error
+	CreatePricingRulesIndex() error
 	//suffix by day, eg： monitor_20200101
 	CreateMonitorTimeSeriesIfNotExist(collTime time.Time) error
 	CreateTTLTrafficTimeSeries() error
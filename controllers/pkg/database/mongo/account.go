@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -27,10 +28,12 @@ import (
 	"github.com/labring/sealos/controllers/pkg/utils/env"
 
 	"github.com/labring/sealos/controllers/pkg/common"
+	"github.com/labring/sealos/controllers/pkg/crypto"
 	"github.com/labring/sealos/controllers/pkg/database"
 
 	gonanoid "github.com/matoous/go-nanoid/v2"
 
+	"github.com/labring/sealos/controllers/pkg/pay"
 	"github.com/labring/sealos/controllers/pkg/resources"
 	"github.com/labring/sealos/controllers/pkg/utils/logger"
 
@@ -62,6 +65,10 @@ const (
 	DefaultUserConn       = "user"
 	DefaultPricesConn     = "prices"
 	DefaultPropertiesConn = "properties"
+	// DefaultPricingRulesConn is the collection of the versions of the pricing rules
+	DefaultPricingRulesConn = "pricing_rules"
+	// savePricingRuleAttempts is the number of the versions tried by SavePricingRule
+	savePricingRuleAttempts = 5
 	//TODO fix
 	DefaultTrafficConn = "traffic"
 )
@@ -79,6 +86,7 @@ type mongoDB struct {
 	BillingConn       string
 	ObjTrafficConn    string
 	PropertiesConn    string
+	PricingRulesConn  string
 	TrafficConn       string
 }
 
@@ -477,20 +485,31 @@ func (m *mongoDB) GetAllPayment() ([]resources.Billing, error) {
 }
 
 func (m *mongoDB) InitDefaultPropertyTypeLS() error {
+	ls, err := m.GetPropertyTypeLS()
+	if err != nil {
+		return err
+	}
+	resources.DefaultPropertyTypeLS = ls
+	return nil
+}
+
+// GetPropertyTypeLS returns the saved property types, including those created by the
+// extension controllers after the start, or the default ones if none is saved.
+func (m *mongoDB) GetPropertyTypeLS() (*resources.PropertyTypeLS, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	cursor, err := m.getPropertiesCollection().Find(ctx, bson.M{})
 	if err != nil {
-		return fmt.Errorf("get all prices error: %v", err)
+		return nil, fmt.Errorf("get all prices error: %v", err)
 	}
 	var properties []resources.PropertyType
 	if err = cursor.All(ctx, &properties); err != nil {
-		return fmt.Errorf("get all prices error: %v", err)
+		return nil, fmt.Errorf("get all prices error: %v", err)
 	}
-	if len(properties) != 0 {
-		resources.DefaultPropertyTypeLS = resources.NewPropertyTypeLS(properties)
+	if len(properties) == 0 {
+		return resources.DefaultPropertyTypeLS, nil
 	}
-	return nil
+	return resources.NewPropertyTypeLS(properties), nil
 }
 
 func (m *mongoDB) SavePropertyTypes(types []resources.PropertyType) error {
@@ -502,14 +521,168 @@ func (m *mongoDB) SavePropertyTypes(types []resources.PropertyType) error {
 	return err
 }
 
+// ReencryptPropertyTypes re-encrypts the encrypted unit prices of the property types with the
+// current key of the key ring, the property types are read in batches of batchSize.
+func (m *mongoDB) ReencryptPropertyTypes(batchSize int) (int, error) {
+	ring, err := crypto.DefaultKeyRing()
+	if err != nil {
+		return 0, err
+	}
+	ctx := context.Background()
+	cursor, err := m.getPropertiesCollection().Find(ctx, bson.M{"encrypt_unit_price": bson.M{"$nin": bson.A{"", nil}}},
+		options.Find().SetBatchSize(int32(batchSize)).SetProjection(bson.M{"encrypt_unit_price": 1}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to find property types: %w", err)
+	}
+	defer cursor.Close(ctx)
+	migrated := 0
+	for cursor.Next(ctx) {
+		var property struct {
+			ID               primitive.ObjectID `bson:"_id"`
+			EncryptUnitPrice string             `bson:"encrypt_unit_price"`
+		}
+		if err := cursor.Decode(&property); err != nil {
+			return migrated, fmt.Errorf("failed to decode property type: %w", err)
+		}
+		ciphertext, changed, err := ring.Reencrypt(property.EncryptUnitPrice)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to re-encrypt unit price of property type %s: %w", property.ID.Hex(), err)
+		}
+		if !changed {
+			continue
+		}
+		// the unit price is only updated if it is not changed since it is read
+		if _, err := m.getPropertiesCollection().UpdateOne(ctx,
+			bson.M{"_id": property.ID, "encrypt_unit_price": property.EncryptUnitPrice},
+			bson.M{"$set": bson.M{"encrypt_unit_price": ciphertext}}); err != nil {
+			return migrated, fmt.Errorf("failed to update property type %s: %w", property.ID.Hex(), err)
+		}
+		migrated++
+	}
+	return migrated, cursor.Err()
+}
+
+// GetPricingRules returns all versions of the pricing rules.
+func (m *mongoDB) GetPricingRules() (resources.PricingRules, error) {
+	cursor, err := m.getPricingRulesCollection().Find(context.Background(), bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pricing rules: %w", err)
+	}
+	defer cursor.Close(context.Background())
+	var rules []resources.PricingRule
+	if err := cursor.All(context.Background(), &rules); err != nil {
+		return nil, fmt.Errorf("failed to decode pricing rules: %w", err)
+	}
+	return resources.NewPricingRules(rules), nil
+}
+
+// GetExchangeRate returns the exchange rate of the pay method saved by service/pay.
+func (m *mongoDB) GetExchangeRate(method, currency string) (float64, error) {
+	return pay.GetMongoExchangeRate(m.Client, method, currency)
+}
+
+// SavePricingRule saves the rule as the next version of the pricing rule of its property,
+// the versions are never updated so that the billings keep the price they are computed with.
+// The version is unique by CreatePricingRulesIndex, it is retried with the next version if a
+// concurrent save takes it.
+func (m *mongoDB) SavePricingRule(rule *resources.PricingRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		latest := resources.PricingRule{}
+		err := m.getPricingRulesCollection().FindOne(context.Background(), bson.M{"property": rule.Property},
+			options.FindOne().SetSort(bson.M{"version": -1})).Decode(&latest)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return fmt.Errorf("failed to find latest pricing rule of %s: %w", rule.Property, err)
+		}
+		rule.Version = latest.Version + 1
+		_, err = m.getPricingRulesCollection().InsertOne(context.Background(), rule)
+		if err == nil {
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) || attempt >= savePricingRuleAttempts {
+			return fmt.Errorf("failed to save pricing rule of %s version %d: %w", rule.Property, rule.Version, err)
+		}
+	}
+}
+
+// GetOwnersMonthUsed returns the used values of the consumption of the owners in [startTime, endTime),
+// the volume tiers of the pricing rules are accumulated with them.
+func (m *mongoDB) GetOwnersMonthUsed(owners []string, startTime, endTime time.Time) (map[string]resources.EnumUsedMap, error) {
+	pipeline := bson.A{
+		bson.M{
+			"$match": bson.M{
+				"owner": bson.M{"$in": owners},
+				"type":  common.Consumption,
+				"time": bson.M{
+					"$gte": startTime,
+					"$lt":  endTime,
+				},
+			},
+		},
+		bson.M{"$unwind": "$app_costs"},
+		bson.M{"$project": bson.M{"owner": 1, "used": bson.M{"$objectToArray": "$app_costs.used"}}},
+		bson.M{"$unwind": "$used"},
+		bson.M{
+			"$group": bson.M{
+				"_id":  bson.M{"owner": "$owner", "property": "$used.k"},
+				"used": bson.M{"$sum": "$used.v"},
+			},
+		},
+	}
+	cursor, err := m.getBillingCollection().Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate month used: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	ownersUsed := make(map[string]resources.EnumUsedMap)
+	for cursor.Next(context.Background()) {
+		var result struct {
+			ID struct {
+				Owner    string `bson:"owner"`
+				Property string `bson:"property"`
+			} `bson:"_id"`
+			Used int64 `bson:"used"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, fmt.Errorf("failed to decode aggregation result: %w", err)
+		}
+		property, err := strconv.ParseUint(result.ID.Property, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid used property %s: %w", result.ID.Property, err)
+		}
+		if _, ok := ownersUsed[result.ID.Owner]; !ok {
+			ownersUsed[result.ID.Owner] = make(resources.EnumUsedMap)
+		}
+		ownersUsed[result.ID.Owner][uint8(property)] = result.Used
+	}
+	return ownersUsed, cursor.Err()
+}
+
 func (m *mongoDB) GenerateBillingData(startTime, endTime time.Time, prols *resources.PropertyTypeLS, ownerToNS map[string][]string) (map[string][]*resources.Billing, error) {
 	ownerMonitors, err := m.FetchOwnerMonitorRecords(startTime, endTime, ownerToNS)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch monitor records: %v", err)
 	}
+	pricing, err := m.GetPricingRules()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pricing rules: %v", err)
+	}
+	ownersMonthUsed := make(map[string]resources.EnumUsedMap)
+	if pricing.HasTiers(startTime) && len(ownerMonitors) > 0 {
+		owners := make([]string, 0, len(ownerMonitors))
+		for owner := range ownerMonitors {
+			owners = append(owners, owner)
+		}
+		if ownersMonthUsed, err = m.GetOwnersMonthUsed(owners, resources.PricingMonth(startTime), startTime); err != nil {
+			return nil, fmt.Errorf("failed to get month used: %v", err)
+		}
+	}
 	var ownerBillings = make(map[string][]*resources.Billing)
 	for owner, monitors := range ownerMonitors {
-		billings, err := GenerateBillingDataFromRecords(monitors, prols, startTime, endTime, owner)
+		billings, err := GenerateBillingDataFromRecords(monitors, prols, pricing, ownersMonthUsed[owner], startTime, endTime, owner)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate billing data: %v", err)
 		}
@@ -567,7 +740,11 @@ func (m *mongoDB) FetchOwnerMonitorRecords(startTime, endTime time.Time, ownerTo
 	return ownerMonitorRecords, nil
 }
 
-func GenerateBillingDataFromRecords(records []resources.Monitor, prols *resources.PropertyTypeLS, startTime, endTime time.Time, owner string) (billings []*resources.Billing, err error) {
+// GenerateBillingDataFromRecords generates the billings of the owner from the monitor records, the fees
+// are computed with the pricing rules effective at startTime, monthUsed is the used of the owner in the
+// month before startTime which the volume tiers are accumulated from.
+func GenerateBillingDataFromRecords(records []resources.Monitor, prols *resources.PropertyTypeLS, pricing resources.PricingRules, monthUsed resources.EnumUsedMap,
+	startTime, endTime time.Time, owner string) (billings []*resources.Billing, err error) {
 	// Calculate the interval (minutes) to ensure that the divisor is not 0
 	minutes := math.Max(endTime.Sub(startTime).Minutes(), 1)
 
@@ -580,7 +757,7 @@ func GenerateBillingDataFromRecords(records []resources.Monitor, prols *resource
 
 	// 分组 key 生成规则
 	genGroupKey := func(rec resources.Monitor) string {
-		return fmt.Sprintf("%s/%d/%s", rec.Category, rec.Type, rec.Name)
+		return fmt.Sprintf("%s/%d/%s/%s", rec.Category, rec.Type, rec.Name, rec.NodePool)
 	}
 
 	// 遍历所有记录，按分组键聚合
@@ -621,27 +798,47 @@ func GenerateBillingDataFromRecords(records []resources.Monitor, prols *resource
 		}
 		return finalUsed
 	}
+	// the volume tiers are accumulated in order, sort the keys to price the apps deterministically
+	aggregatedKeys := make([]string, 0, len(aggregatedMap))
+	for key := range aggregatedMap {
+		aggregatedKeys = append(aggregatedKeys, key)
+	}
+	sort.Strings(aggregatedKeys)
+	accumulatedUsed := make(resources.EnumUsedMap, len(monthUsed))
+	for propKey, used := range monthUsed {
+		accumulatedUsed[propKey] = used
+	}
 	// 计算最终 Used 数据
-	for _, agg := range aggregatedMap {
+	for _, key := range aggregatedKeys {
+		agg := aggregatedMap[key]
 		finalUsed := calculateFinalUsed(agg.UsedValues, prols, minutes)
 		// 计算费用
 		appCost := resources.AppCost{
 			Type:       agg.Type,
 			Name:       agg.Name,
+			NodePool:   agg.NodePool,
 			Used:       finalUsed,
 			UsedAmount: make(map[uint8]int64),
 		}
 		var totalAmount int64
 		for propKey, usedVal := range finalUsed {
 			if prop, ok := prols.EnumMap[propKey]; ok {
-				if prop.UnitPrice > 0 {
-					feeFloat := float64(usedVal) * prop.UnitPrice
-					if feeFloat > math.MaxInt64 {
-						return nil, fmt.Errorf("fee calculation overflow: %f", feeFloat)
+				feeFloat, version := pricing.Fee(prop, usedVal, accumulatedUsed[propKey], agg.NodePool, startTime)
+				accumulatedUsed[propKey] += usedVal
+				if feeFloat <= 0 {
+					continue
+				}
+				if feeFloat > math.MaxInt64 {
+					return nil, fmt.Errorf("fee calculation overflow: %f", feeFloat)
+				}
+				fee := int64(math.Ceil(feeFloat))
+				appCost.UsedAmount[propKey] = fee
+				totalAmount += fee
+				if version > 0 {
+					if appCost.PriceVersions == nil {
+						appCost.PriceVersions = make(resources.EnumUsedMap)
 					}
-					fee := int64(math.Ceil(feeFloat))
-					appCost.UsedAmount[propKey] = fee
-					totalAmount += fee
+					appCost.PriceVersions[propKey] = version
 				}
 			}
 		}
@@ -791,6 +988,79 @@ func (m *mongoDB) GetBillingCount(accountType common.Type, startTime, endTime ti
 	return result.Count, result.Amount, nil
 }
 
+// GetOwnerConsumption returns the consumption amount of the owner in [startTime, endTime) by namespace.
+func (m *mongoDB) GetOwnerConsumption(owner string, startTime, endTime time.Time) (map[string]int64, error) {
+	pipeline := bson.A{
+		bson.M{
+			"$match": bson.M{
+				"owner": owner,
+				"type":  common.Consumption,
+				"time": bson.M{
+					"$gte": startTime,
+					"$lt":  endTime,
+				},
+			},
+		},
+		bson.M{
+			"$group": bson.M{
+				"_id":    "$namespace",
+				"amount": bson.M{"$sum": "$amount"},
+			},
+		},
+	}
+	cursor, err := m.getBillingCollection().Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate consumption: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	consumption := make(map[string]int64)
+	for cursor.Next(context.Background()) {
+		var result struct {
+			Namespace string `bson:"_id"`
+			Amount    int64  `bson:"amount"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, fmt.Errorf("failed to decode aggregation result: %w", err)
+		}
+		consumption[result.Namespace] = result.Amount
+	}
+	return consumption, cursor.Err()
+}
+
+// WalkConsumptionBillings calls fn with the consumption billings of the owner in the time range sorted
+// by time as they are read from the cursor, only the billings of the namespace are walked if it is
+// not empty. The walk stops at the first error of fn.
+func (m *mongoDB) WalkConsumptionBillings(owner, namespace string, startTime, endTime time.Time, fn func(billing *resources.Billing) error) error {
+	filter := bson.M{
+		"owner": owner,
+		"type":  common.Consumption,
+		"time": bson.M{
+			"$gte": startTime,
+			"$lt":  endTime,
+		},
+	}
+	if namespace != "" {
+		filter["namespace"] = namespace
+	}
+	cursor, err := m.getBillingCollection().Find(context.Background(), filter, options.Find().SetSort(bson.D{{Key: "time", Value: 1}}))
+	if err != nil {
+		return fmt.Errorf("failed to find consumption billings: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var billing resources.Billing
+		if err := cursor.Decode(&billing); err != nil {
+			return fmt.Errorf("failed to decode consumption billing: %w", err)
+		}
+		if err := fn(&billing); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
 func (m *mongoDB) getMeteringCollection() *mongo.Collection {
 	return m.Client.Database(m.AccountDB).Collection(m.MeteringConn)
 }
@@ -816,6 +1086,22 @@ func (m *mongoDB) getPropertiesCollection() *mongo.Collection {
 	return m.Client.Database(m.AccountDB).Collection(m.PropertiesConn)
 }
 
+func (m *mongoDB) getPricingRulesCollection() *mongo.Collection {
+	return m.Client.Database(m.AccountDB).Collection(m.PricingRulesConn)
+}
+
+// CreatePricingRulesIndex creates the unique index of the versions of the pricing rules.
+func (m *mongoDB) CreatePricingRulesIndex() error {
+	_, err := m.getPricingRulesCollection().Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{primitive.E{Key: "property", Value: 1}, primitive.E{Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create index for pricing rules: %w", err)
+	}
+	return nil
+}
+
 func (m *mongoDB) CreateBillingIfNotExist() error {
 	if exist, err := m.collectionExist(m.AccountDB, m.BillingConn); exist || err != nil {
 		return err
@@ -928,6 +1214,7 @@ func NewMongoInterface(ctx context.Context, URL string) (database.Interface, err
 		BillingConn:       DefaultBillingConn,
 		ObjTrafficConn:    DefaultObjTrafficConn,
 		PropertiesConn:    DefaultPropertiesConn,
+		PricingRulesConn:  DefaultPricingRulesConn,
 		TrafficConn:       env.GetEnvWithDefault(EnvTrafficConn, DefaultTrafficConn),
 		CvmConn:           env.GetEnvWithDefault(EnvCVMConn, DefaultCVMConn),
 	}, err
@@ -17,6 +17,7 @@ package mongo
 import (
 	"context"
 	"fmt"
+	"math"
 	"os"
 	"reflect"
 	"testing"
@@ -27,6 +28,7 @@ import (
 	"github.com/labring/sealos/controllers/pkg/resources"
 
 	"go.mongodb.org/mongo-driver/mongo"
+	corev1 "k8s.io/api/core/v1"
 )
 
 var testTime = time.Date(2023, 5, 9, 5, 0, 0, 0, time.UTC)
@@ -507,6 +509,46 @@ func Test_mongoDB_GenerateBillingData(t *testing.T) {
 	}
 }
 
+func TestGenerateBillingDataFromRecordsWithPricing(t *testing.T) {
+	prols := resources.DefaultPropertyTypeLS
+	cpu := prols.StringMap[corev1.ResourceCPU.String()]
+	startTime := time.Date(2024, 2, 5, 10, 0, 0, 0, time.UTC)
+	pricing := resources.NewPricingRules([]resources.PricingRule{{
+		Property:       cpu.Name,
+		Version:        3,
+		EffectiveAt:    startTime.Add(-24 * time.Hour),
+		Tiers:          []resources.PriceTier{{UpTo: 100000, Ratio: 1}, {Ratio: 0.5}},
+		NodePoolPrices: map[string]float64{"spot": 0},
+	}})
+	records := []resources.Monitor{
+		{Category: "ns-a", Type: resources.AppType[resources.APP], Name: "a", Used: resources.EnumUsedMap{cpu.Enum: 1000}, Time: startTime},
+		{Category: "ns-a", Type: resources.AppType[resources.APP], Name: "b", Used: resources.EnumUsedMap{cpu.Enum: 1000}, Time: startTime},
+		{Category: "ns-a", Type: resources.AppType[resources.APP], Name: "c", NodePool: "spot", Used: resources.EnumUsedMap{cpu.Enum: 1000}, Time: startTime},
+	}
+	// a single record in a minute is the average used, the owner has used 99.5 core-hours in the month, app a crosses the first tier and app b is in the second tier
+	billings, err := GenerateBillingDataFromRecords(records, prols, pricing, resources.EnumUsedMap{cpu.Enum: 99500}, startTime, startTime.Add(time.Minute), "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(billings) != 1 {
+		t.Fatalf("billings = %v, want 1 billing", billings)
+	}
+	amounts := make(map[string]int64)
+	for _, appCost := range billings[0].AppCosts {
+		amounts[appCost.Name] = appCost.UsedAmount[cpu.Enum]
+		if appCost.PriceVersions[cpu.Enum] != 3 {
+			t.Errorf("price version of %s = %d, want 3", appCost.Name, appCost.PriceVersions[cpu.Enum])
+		}
+	}
+	want := map[string]int64{
+		"a": int64(math.Ceil(500*cpu.UnitPrice + 500*cpu.UnitPrice*0.5)),
+		"b": int64(math.Ceil(1000 * cpu.UnitPrice * 0.5)),
+	}
+	if !reflect.DeepEqual(amounts, want) {
+		t.Errorf("amounts = %v, want %v", amounts, want)
+	}
+}
+
 func Test_mongoDB_GetOwnersWithoutRecentUpdates(t *testing.T) {
 	dbCTX := context.Background()
 
@@ -527,3 +569,42 @@ func Test_mongoDB_GetOwnersWithoutRecentUpdates(t *testing.T) {
 	}
 	t.Logf("get owners without recent updates success: %v", owners)
 }
+
+func Test_mongoDB_SavePricingRuleConcurrently(t *testing.T) {
+	if os.Getenv("MONGODB_URI") == "" {
+		t.Skip("MONGODB_URI is not set")
+	}
+	dbCTX := context.Background()
+	m, err := NewMongoInterface(dbCTX, os.Getenv("MONGODB_URI"))
+	if err != nil {
+		t.Fatalf("failed to connect mongo: error = %v", err)
+	}
+	defer m.Disconnect(dbCTX)
+	db := m.(*mongoDB)
+	db.PricingRulesConn = fmt.Sprintf("pricing_rules_test_%d", time.Now().UnixNano())
+	defer db.getPricingRulesCollection().Drop(dbCTX)
+	if err = db.CreatePricingRulesIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 4
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			errs <- db.SavePricingRule(&resources.PricingRule{Property: "cpu", EffectiveAt: testTime, UnitPrice: 1})
+		}()
+	}
+	for i := 0; i < n; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("SavePricingRule() error = %v", err)
+		}
+	}
+	rules, err := db.GetPricingRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the concurrent saves take distinct versions
+	if got := len(rules["cpu"]); got != n {
+		t.Errorf("versions of cpu = %d, want %d", got, n)
+	}
+}
@@ -0,0 +1,396 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pay
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ENV keys
+const (
+	AlipayAppID      = "ALIPAY_APP_ID"
+	AlipayPrivateKey = "ALIPAY_PRIVATE_KEY"
+	// AlipayPublicKey is the public key of alipay to verify the responses.
+	AlipayPublicKey = "ALIPAY_PUBLIC_KEY"
+	AlipayGateway   = "ALIPAY_GATEWAY"
+	// AlipayNotifyURL is the notify_url of the trades, it is the payment webhook server of
+	// the account controller with the path /payment/alipay/notify.
+	AlipayNotifyURL = "ALIPAY_NOTIFY_URL"
+
+	DefaultAlipayGateway = "https://openapi.alipay.com/gateway.do"
+)
+
+// alipay trade status
+const (
+	alipayTradeWaitBuyerPay = "WAIT_BUYER_PAY"
+	alipayTradeClosed       = "TRADE_CLOSED"
+	alipayTradeSuccess      = "TRADE_SUCCESS"
+	alipayTradeFinished     = "TRADE_FINISHED"
+
+	alipayCodeSuccess       = "10000"
+	alipayTradeNotExistCode = "ACQ.TRADE_NOT_EXIST"
+)
+
+// alipayRefundRejectedCodes are the sub codes of the refunds which alipay rejects for good, the
+// other errors, e.g. ACQ.SYSTEM_ERROR or ACQ.SELLER_BALANCE_NOT_ENOUGH, may succeed on retry.
+var alipayRefundRejectedCodes = map[string]bool{
+	alipayTradeNotExistCode:                     true,
+	"ACQ.TRADE_STATUS_ERROR":                    true,
+	"ACQ.TRADE_HAS_CLOSE":                       true,
+	"ACQ.TRADE_HAS_FINISHED":                    true,
+	"ACQ.TRADE_NOT_ALLOW_REFUND":                true,
+	"ACQ.REFUND_AMT_NOT_EQUAL_TOTAL":            true,
+	"ACQ.REFUND_FEE_ERROR":                      true,
+	"ACQ.REASON_TRADE_REFUND_FEE_ERR":           true,
+	"ACQ.REASON_TRADE_BEEN_FREEZEN":             true,
+	"ACQ.DISCORDANT_REPEAT_REQUEST":             true,
+	"ACQ.BUYER_ERROR":                           true,
+	"ACQ.ONLINE_TRADE_VOUCHER_NOT_ALLOW_REFUND": true,
+}
+
+var alipayLocation = time.FixedZone("CST", 8*60*60)
+
+// AlipayPayment pays with the QR code of alipay face-to-face payment, 1 ¥ = amount 100.
+type AlipayPayment struct {
+	AppID      string
+	PrivateKey *rsa.PrivateKey
+	// PublicKey is the public key of alipay, the responses are not verified if it is nil.
+	PublicKey *rsa.PublicKey
+	Gateway   string
+	NotifyURL string
+	Client    *http.Client
+}
+
+// AlipayError is the error code of an alipay response.
+type AlipayError struct {
+	Code    string `json:"code"`
+	Msg     string `json:"msg"`
+	SubCode string `json:"sub_code,omitempty"`
+	SubMsg  string `json:"sub_msg,omitempty"`
+}
+
+func (e *AlipayError) Error() string {
+	return fmt.Sprintf("alipay error: code=%s, msg=%s, sub_code=%s, sub_msg=%s", e.Code, e.Msg, e.SubCode, e.SubMsg)
+}
+
+// newAlipayPayment creates the alipay provider with the credentials in env, the gateway and
+// notify_url may be overridden by the options of the config.
+func newAlipayPayment(config ProviderConfig) (Interface, error) {
+	if config.Currency != "" && config.Currency != CNY {
+		return nil, fmt.Errorf("unsupported currency of alipay: %s", config.Currency)
+	}
+	privateKey, err := ParseRSAPrivateKey(os.Getenv(AlipayPrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("parse alipay private key failed: %w", err)
+	}
+	a := &AlipayPayment{
+		AppID:      os.Getenv(AlipayAppID),
+		PrivateKey: privateKey,
+		Gateway:    os.Getenv(AlipayGateway),
+		NotifyURL:  os.Getenv(AlipayNotifyURL),
+	}
+	if a.PublicKey, err = ParseRSAPublicKey(os.Getenv(AlipayPublicKey)); err != nil {
+		return nil, fmt.Errorf("parse alipay public key failed: %w", err)
+	}
+	if gateway := config.Options["gateway"]; gateway != "" {
+		a.Gateway = gateway
+	}
+	if notifyURL := config.Options["notifyURL"]; notifyURL != "" {
+		a.NotifyURL = notifyURL
+	}
+	if a.AppID == "" {
+		return nil, fmt.Errorf("env %s is not set", AlipayAppID)
+	}
+	return a, nil
+}
+
+func (a *AlipayPayment) CreatePayment(amount int64, user, describe string) (string, string, error) {
+	tradeNO := GetRandomString(32)
+	if describe == "" {
+		describe = "sealos cloud recharge"
+	}
+	var resp struct {
+		OutTradeNo string `json:"out_trade_no"`
+		QrCode     string `json:"qr_code"`
+	}
+	if err := a.call("alipay.trade.precreate", map[string]string{
+		"out_trade_no":    tradeNO,
+		"total_amount":    formatAlipayAmount(amount),
+		"subject":         describe,
+		"passback_params": url.QueryEscape(user),
+	}, &resp); err != nil {
+		return "", "", err
+	}
+	return tradeNO, resp.QrCode, nil
+}
+
+func (a *AlipayPayment) GetPaymentDetails(sessionID string) (string, int64, error) {
+	var resp struct {
+		TradeStatus string `json:"trade_status"`
+		TotalAmount string `json:"total_amount"`
+	}
+	if err := a.call("alipay.trade.query", map[string]string{"out_trade_no": sessionID}, &resp); err != nil {
+		// the trade of the QR code is created when the buyer scans it
+		if isAlipayTradeNotExist(err) {
+			return PaymentNotPaid, 0, nil
+		}
+		return "", 0, err
+	}
+	switch resp.TradeStatus {
+	case alipayTradeSuccess, alipayTradeFinished:
+		amount, err := parseAlipayAmount(resp.TotalAmount)
+		if err != nil {
+			return "", 0, err
+		}
+		return PaymentSuccess, amount, nil
+	case alipayTradeWaitBuyerPay:
+		return PaymentNotPaid, 0, nil
+	case alipayTradeClosed:
+		return PaymentExpired, 0, nil
+	default:
+		return PaymentUnknown, 0, fmt.Errorf("unknown order status: %s", resp.TradeStatus)
+	}
+}
+
+func (a *AlipayPayment) ExpireSession(payment string) error {
+	err := a.call("alipay.trade.close", map[string]string{"out_trade_no": payment}, nil)
+	if err != nil && !isAlipayTradeNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (a *AlipayPayment) Refund(tradeNO, refundNO string, amount int64) (string, error) {
+	if err := a.call("alipay.trade.refund", map[string]string{
+		"out_trade_no":   tradeNO,
+		"refund_amount":  formatAlipayAmount(amount),
+		"out_request_no": refundNO,
+	}, nil); err != nil {
+		if alipayErr, ok := err.(*AlipayError); ok && alipayRefundRejectedCodes[alipayErr.SubCode] {
+			return "", fmt.Errorf("%w: refund %s of trade %s: %v", ErrRefundRejected, refundNO, tradeNO, err)
+		}
+		return "", err
+	}
+	return refundNO, nil
+}
+
+// call calls the method of the alipay open api with the biz content, and unmarshal the
+// verified response into result.
+func (a *AlipayPayment) call(method string, bizContent map[string]string, result interface{}) error {
+	content, err := json.Marshal(bizContent)
+	if err != nil {
+		return fmt.Errorf("marshal biz content failed: %w", err)
+	}
+	params := url.Values{}
+	params.Set("app_id", a.AppID)
+	params.Set("method", method)
+	params.Set("format", "JSON")
+	params.Set("charset", "utf-8")
+	params.Set("sign_type", "RSA2")
+	params.Set("timestamp", time.Now().In(alipayLocation).Format("2006-01-02 15:04:05"))
+	params.Set("version", "1.0")
+	params.Set("biz_content", string(content))
+	if a.NotifyURL != "" && method == "alipay.trade.precreate" {
+		params.Set("notify_url", a.NotifyURL)
+	}
+	sign, err := SignWithRSA2(a.PrivateKey, alipaySignContent(params))
+	if err != nil {
+		return fmt.Errorf("sign alipay request failed: %w", err)
+	}
+	params.Set("sign", sign)
+
+	gateway := a.Gateway
+	if gateway == "" {
+		gateway = DefaultAlipayGateway
+	}
+	client := a.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.PostForm(gateway, params)
+	if err != nil {
+		return fmt.Errorf("call %s err:%s", method, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxNotificationBodySize))
+	if err != nil {
+		return fmt.Errorf("read %s response failed: %w", method, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("call %s failed with status %d: %s", method, resp.StatusCode, body)
+	}
+	return a.parseResponse(method, body, result)
+}
+
+func (a *AlipayPayment) parseResponse(method string, body []byte, result interface{}) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return fmt.Errorf("unmarshal %s response failed: %w", method, err)
+	}
+	content, ok := raw[strings.ReplaceAll(method, ".", "_")+"_response"]
+	if !ok {
+		return fmt.Errorf("no response of %s: %s", method, body)
+	}
+	var sign string
+	if s, ok := raw["sign"]; ok {
+		if err := json.Unmarshal(s, &sign); err != nil {
+			return fmt.Errorf("unmarshal sign of %s failed: %w", method, err)
+		}
+	}
+	var alipayErr AlipayError
+	if err := json.Unmarshal(content, &alipayErr); err != nil {
+		return fmt.Errorf("unmarshal %s response failed: %w", method, err)
+	}
+	// a failed request may be answered without sign, e.g. the app id is invalid
+	if alipayErr.Code != alipayCodeSuccess && sign == "" {
+		return &alipayErr
+	}
+	if a.PublicKey != nil {
+		if err := VerifyWithRSA2(a.PublicKey, string(content), sign); err != nil {
+			return fmt.Errorf("verify %s response failed: %w", method, err)
+		}
+	}
+	if alipayErr.Code != alipayCodeSuccess {
+		return &alipayErr
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(content, result); err != nil {
+		return fmt.Errorf("unmarshal %s response failed: %w", method, err)
+	}
+	return nil
+}
+
+// alipaySignContent returns the sorted key=value pairs of the non-empty params except sign.
+func alipaySignContent(params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		if k != "sign" && params.Get(k) != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+params.Get(k))
+	}
+	return strings.Join(pairs, "&")
+}
+
+func isAlipayTradeNotExist(err error) bool {
+	alipayErr, ok := err.(*AlipayError)
+	return ok && alipayErr.SubCode == alipayTradeNotExistCode
+}
+
+// formatAlipayAmount formats amount in the unit of 0.01 ¥ to the yuan of alipay.
+func formatAlipayAmount(amount int64) string {
+	return fmt.Sprintf("%d.%02d", amount/100, amount%100)
+}
+
+// parseAlipayAmount parses the yuan of alipay to the amount in the unit of 0.01 ¥.
+func parseAlipayAmount(amount string) (int64, error) {
+	yuan, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid alipay amount %q: %w", amount, err)
+	}
+	return int64(math.Round(yuan * 100)), nil
+}
+
+// SignWithRSA2 signs content with SHA256WithRSA and returns the base64 encoded signature.
+func SignWithRSA2(key *rsa.PrivateKey, content string) (string, error) {
+	hashed := sha256.Sum256([]byte(content))
+	sign, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sign), nil
+}
+
+// VerifyWithRSA2 verifies the base64 encoded SHA256WithRSA signature of content.
+func VerifyWithRSA2(key *rsa.PublicKey, content, sign string) error {
+	signature, err := base64.StdEncoding.DecodeString(sign)
+	if err != nil {
+		return fmt.Errorf("decode sign failed: %w", err)
+	}
+	hashed := sha256.Sum256([]byte(content))
+	return rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], signature)
+}
+
+// ParseRSAPrivateKey parses a PKCS8 or PKCS1 private key in PEM or in base64 encoded DER,
+// alipay generates the keys without the PEM header.
+func ParseRSAPrivateKey(key string) (*rsa.PrivateKey, error) {
+	der, err := decodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if k, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		rsaKey, ok := k.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("not a rsa private key")
+		}
+		return rsaKey, nil
+	}
+	return x509.ParsePKCS1PrivateKey(der)
+}
+
+// ParseRSAPublicKey parses a PKIX public key in PEM or in base64 encoded DER.
+func ParseRSAPublicKey(key string) (*rsa.PublicKey, error) {
+	der, err := decodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	k, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := k.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("not a rsa public key")
+	}
+	return rsaKey, nil
+}
+
+func decodeKey(key string) ([]byte, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil, fmt.Errorf("empty key")
+	}
+	if strings.HasPrefix(key, "-----BEGIN") {
+		block, _ := pem.Decode([]byte(key))
+		if block == nil {
+			return nil, fmt.Errorf("invalid pem key")
+		}
+		return block.Bytes, nil
+	}
+	return base64.StdEncoding.DecodeString(key)
+}
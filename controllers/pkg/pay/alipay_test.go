@@ -0,0 +1,239 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pay
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// mockAlipay is a local alipay gateway which verifies the requests with the public key of
+// the app and signs the responses with its own key.
+type mockAlipay struct {
+	t        *testing.T
+	appKey   *rsa.PublicKey
+	key      *rsa.PrivateKey
+	mu       sync.Mutex
+	trades   map[string]string
+	refunded map[string]string
+	// requests are the out_request_no of the refunds
+	requests []string
+	// refundSubCode is the sub code of the failed refunds
+	refundSubCode string
+	badSigned     bool
+}
+
+func (m *mockAlipay) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		m.t.Errorf("parse form failed: %v", err)
+		return
+	}
+	if err := VerifyWithRSA2(m.appKey, alipaySignContent(r.PostForm), r.PostForm.Get("sign")); err != nil {
+		m.t.Errorf("verify request failed: %v", err)
+		return
+	}
+	var biz map[string]string
+	if err := json.Unmarshal([]byte(r.PostForm.Get("biz_content")), &biz); err != nil {
+		m.t.Errorf("unmarshal biz content failed: %v", err)
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	method := r.PostForm.Get("method")
+	content := map[string]string{"code": alipayCodeSuccess, "msg": "Success"}
+	switch method {
+	case "alipay.trade.precreate":
+		m.trades[biz["out_trade_no"]] = biz["total_amount"]
+		content["out_trade_no"] = biz["out_trade_no"]
+		content["qr_code"] = "https://qr.alipay.com/" + biz["out_trade_no"]
+	case "alipay.trade.query":
+		amount, ok := m.trades[biz["out_trade_no"]]
+		if !ok {
+			content = map[string]string{"code": "40004", "msg": "Business Failed", "sub_code": alipayTradeNotExistCode}
+			break
+		}
+		content["trade_status"] = alipayTradeSuccess
+		content["total_amount"] = amount
+	case "alipay.trade.refund":
+		if _, ok := m.trades[biz["out_trade_no"]]; !ok || m.refundSubCode != "" {
+			subCode := m.refundSubCode
+			if !ok {
+				subCode = alipayTradeNotExistCode
+			}
+			content = map[string]string{"code": "40004", "msg": "Business Failed", "sub_code": subCode}
+			break
+		}
+		m.refunded[biz["out_trade_no"]] = biz["refund_amount"]
+		m.requests = append(m.requests, biz["out_request_no"])
+		content["fund_change"] = "Y"
+	case "alipay.trade.close":
+		content = map[string]string{"code": "40004", "msg": "Business Failed", "sub_code": alipayTradeNotExistCode}
+	}
+	data, _ := json.Marshal(content)
+	sign, err := SignWithRSA2(m.key, string(data))
+	if err != nil {
+		m.t.Errorf("sign response failed: %v", err)
+		return
+	}
+	if m.badSigned {
+		data = []byte(strings.Replace(string(data), "Success", "success", 1))
+	}
+	_, _ = fmt.Fprintf(w, `{"%s_response":%s,"sign":%q}`, strings.ReplaceAll(method, ".", "_"), data, sign)
+}
+
+func newTestAlipay(t *testing.T) (*AlipayPayment, *mockAlipay) {
+	appKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alipayKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := &mockAlipay{t: t, appKey: &appKey.PublicKey, key: alipayKey, trades: map[string]string{}, refunded: map[string]string{}}
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+	return &AlipayPayment{
+		AppID:      "2021000000000000",
+		PrivateKey: appKey,
+		PublicKey:  &alipayKey.PublicKey,
+		Gateway:    server.URL,
+		Client:     server.Client(),
+	}, mock
+}
+
+func TestAlipayPayment(t *testing.T) {
+	alipay, mock := newTestAlipay(t)
+
+	status, _, err := alipay.GetPaymentDetails("not-scanned")
+	if err != nil || status != PaymentNotPaid {
+		t.Fatalf("GetPaymentDetails() of not scanned trade = %s, %v, want %s", status, err, PaymentNotPaid)
+	}
+
+	tradeNO, codeURL, err := alipay.CreatePayment(12345, "user", "")
+	if err != nil {
+		t.Fatalf("CreatePayment() error = %v", err)
+	}
+	if codeURL != "https://qr.alipay.com/"+tradeNO || mock.trades[tradeNO] != "123.45" {
+		t.Errorf("CreatePayment() = %s, %s, trades = %v", tradeNO, codeURL, mock.trades)
+	}
+
+	status, amount, err := alipay.GetPaymentDetails(tradeNO)
+	if err != nil || status != PaymentSuccess || amount != 12345 {
+		t.Errorf("GetPaymentDetails() = %s, %d, %v, want %s, 12345", status, amount, err, PaymentSuccess)
+	}
+
+	// the retry of a refund is sent with the same out_request_no, so alipay does not refund twice
+	for i := 0; i < 2; i++ {
+		if refundNO, err := alipay.Refund(tradeNO, "refund", 5); err != nil || refundNO != "refund" {
+			t.Fatalf("Refund() = %s, %v, want refund", refundNO, err)
+		}
+	}
+	if mock.refunded[tradeNO] != "0.05" || len(mock.requests) != 2 || mock.requests[0] != "refund" || mock.requests[1] != "refund" {
+		t.Errorf("refunded = %v, out_request_no = %v, want 0.05 of refund", mock.refunded, mock.requests)
+	}
+
+	if _, err = alipay.Refund("not-scanned", "refund", 5); !errors.Is(err, ErrRefundRejected) {
+		t.Errorf("Refund() of unknown trade error = %v, want %v", err, ErrRefundRejected)
+	}
+	mock.mu.Lock()
+	mock.refundSubCode = "ACQ.SYSTEM_ERROR"
+	mock.mu.Unlock()
+	if _, err = alipay.Refund(tradeNO, "retry", 5); err == nil || errors.Is(err, ErrRefundRejected) {
+		t.Errorf("Refund() with system error = %v, want an error to retry", err)
+	}
+	mock.mu.Lock()
+	mock.refundSubCode = ""
+	mock.mu.Unlock()
+
+	if err = alipay.ExpireSession("not-scanned"); err != nil {
+		t.Errorf("ExpireSession() of not scanned trade error = %v", err)
+	}
+
+	mock.mu.Lock()
+	mock.badSigned = true
+	mock.mu.Unlock()
+	if _, _, err = alipay.CreatePayment(100, "user", ""); err == nil || !strings.Contains(err.Error(), "verify") {
+		t.Errorf("CreatePayment() with bad signed response error = %v, want verify error", err)
+	}
+}
+
+func TestAlipayAmount(t *testing.T) {
+	for amount, want := range map[int64]string{0: "0.00", 5: "0.05", 100: "1.00", 123456: "1234.56"} {
+		if got := formatAlipayAmount(amount); got != want {
+			t.Errorf("formatAlipayAmount(%d) = %s, want %s", amount, got, want)
+		}
+		if got, err := parseAlipayAmount(want); err != nil || got != amount {
+			t.Errorf("parseAlipayAmount(%s) = %d, %v, want %d", want, got, err, amount)
+		}
+	}
+}
+
+func TestParseAlipayNotification(t *testing.T) {
+	alipayKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&alipayKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(AlipayPublicKey, base64.StdEncoding.EncodeToString(der))
+	t.Setenv(AlipayAppID, "2021000000000000")
+	notify := func(tamper func(url.Values)) *http.Request {
+		params := url.Values{
+			"app_id":       {"2021000000000000"},
+			"out_trade_no": {"trade"},
+			"trade_status": {alipayTradeSuccess},
+			"total_amount": {"123.45"},
+			"notify_time":  {"2024-03-15 08:00:00"},
+		}
+		sign, err := SignWithRSA2(alipayKey, alipaySignContent(params))
+		if err != nil {
+			t.Fatal(err)
+		}
+		params.Set("sign", sign)
+		params.Set("sign_type", "RSA2")
+		if tamper != nil {
+			tamper(params)
+		}
+		r := httptest.NewRequest(http.MethodPost, "/payment/alipay/notify", strings.NewReader(params.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return r
+	}
+
+	n, err := ParseAlipayNotification(notify(nil))
+	if err != nil || n.TradeNO != "trade" || n.Status != PaymentSuccess || n.Amount != 12345 {
+		t.Fatalf("ParseAlipayNotification() = %+v, %v, want paid trade of 12345", n, err)
+	}
+	if _, err = ParseAlipayNotification(notify(func(p url.Values) { p.Set("total_amount", "1.00") })); err == nil {
+		t.Errorf("ParseAlipayNotification() of tampered amount is accepted")
+	}
+	t.Setenv(AlipayAppID, "2021000000000001")
+	if _, err = ParseAlipayNotification(notify(nil)); err == nil {
+		t.Errorf("ParseAlipayNotification() of another app is accepted")
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pay
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/labring/sealos/controllers/pkg/utils/env"
+)
+
+const (
+	// EnvPayDBName is the env key of the mongo database of service/pay, its collection
+	// paymethod holds the exchange rates of the pay methods.
+	EnvPayDBName = "PAY_DB_NAME"
+
+	DefaultPayDBName     = "xy"
+	DefaultPayMethodColl = "paymethod"
+
+	exchangeRateTTL = 10 * time.Minute
+)
+
+// ExchangeRateFunc returns the CNY of one unit of currency of the payment method.
+type ExchangeRateFunc func(method, currency string) (float64, error)
+
+type cachedExchangeRate struct {
+	rate    float64
+	expires time.Time
+}
+
+var (
+	exchangeRateMu    sync.Mutex
+	exchangeRateFunc  ExchangeRateFunc
+	exchangeRateCache = map[string]cachedExchangeRate{}
+)
+
+// SetExchangeRateFunc makes the providers whose ExchangeRate is not configured convert their
+// currencies with the exchange rates of f, the rates are cached for 10 minutes.
+func SetExchangeRateFunc(f ExchangeRateFunc) {
+	exchangeRateMu.Lock()
+	defer exchangeRateMu.Unlock()
+	exchangeRateFunc = f
+	exchangeRateCache = map[string]cachedExchangeRate{}
+}
+
+// GetMongoExchangeRate returns the exchange rate of the pay method saved by service/pay.
+func GetMongoExchangeRate(client *mongo.Client, method, currency string) (float64, error) {
+	coll := client.Database(env.GetEnvWithDefault(EnvPayDBName, DefaultPayDBName)).Collection(DefaultPayMethodColl)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var detail struct {
+		ExchangeRate float64 `bson:"exchangeRate"`
+	}
+	filter := bson.M{
+		"payMethod": method,
+		"currency":  bson.M{"$in": []string{strings.ToLower(currency), strings.ToUpper(currency)}},
+	}
+	if err := coll.FindOne(ctx, filter).Decode(&detail); err != nil {
+		return 0, fmt.Errorf("get exchange rate of pay method %s in %s failed: %w", method, currency, err)
+	}
+	return detail.ExchangeRate, nil
+}
+
+// resolveExchangeRate sets the exchange rate of the config of method from the exchange rates
+// of SetExchangeRateFunc if it is not configured and the currency is not CNY.
+func resolveExchangeRate(method string, config ProviderConfig) (ProviderConfig, error) {
+	if config.ExchangeRate > 0 || config.Currency == "" || config.Currency == CNY {
+		return config, nil
+	}
+	exchangeRateMu.Lock()
+	defer exchangeRateMu.Unlock()
+	if exchangeRateFunc == nil {
+		return config, nil
+	}
+	key := method + "/" + config.Currency
+	if cached, ok := exchangeRateCache[key]; ok && time.Now().Before(cached.expires) {
+		config.ExchangeRate = cached.rate
+		return config, nil
+	}
+	rate, err := exchangeRateFunc(method, config.Currency)
+	if err != nil {
+		return config, err
+	}
+	if rate <= 0 {
+		return config, fmt.Errorf("invalid exchange rate %v of payment method %s in %s", rate, method, config.Currency)
+	}
+	exchangeRateCache[key] = cachedExchangeRate{rate: rate, expires: time.Now().Add(exchangeRateTTL)}
+	config.ExchangeRate = rate
+	return config, nil
+}
@@ -14,21 +14,45 @@
 
 package pay
 
+import "errors"
+
+// Interface is a payment provider, the amounts are in the minor unit of the currency of the
+// provider, e.g. 1 ¥ = amount 100.
 type Interface interface {
 	CreatePayment(amount int64, user, describe string) (string, string, error)
 	GetPaymentDetails(sessionID string) (string, int64, error)
 	ExpireSession(payment string) error
+	// Refund refunds amount of the paid trade, the amount is in the same unit as CreatePayment,
+	// and it returns the refund number of the payment provider. refundNO is the unique number of
+	// the refund, the retries with the same refundNO do not refund twice. It returns an error
+	// wrapping ErrRefundRejected if the provider rejects the refund for good.
+	Refund(tradeNO, refundNO string, amount int64) (string, error)
 }
 
+// ErrRefundRejected is the error of the refunds which are closed or failed by the provider.
+var ErrRefundRejected = errors.New("refund is rejected")
+
+// NewPayHandler returns the Interface of the payment method enabled in EnvPaymentProviders,
+// the amounts of the Interface are in CNY and converted to the currency of the provider.
 func NewPayHandler(paymentMethod string) (Interface, error) {
-	switch paymentMethod {
-	case "stripe":
-		return &StripePayment{}, nil
-	case "wechat":
-		return &WechatPayment{}, nil
-	default:
-		//return nil, fmt.Errorf("unsupported payment method: %s", paymentMethod)
-		//TODO Now set it as the default wechat, and modify it a few days later
-		return &WechatPayment{}, nil
+	config, err := GetProviderConfig(paymentMethod)
+	if err != nil {
+		return nil, err
+	}
+	return newProvider(paymentMethod, config)
+}
+
+// NewChargeHandler returns the Interface of the payment method enabled in EnvPaymentProviders
+// whose amounts are in the currency charged by the provider, and the config of the method to
+// convert them to CNY. It is used to settle and refund the payments in the charged amounts.
+func NewChargeHandler(paymentMethod string) (Interface, ProviderConfig, error) {
+	config, err := GetProviderConfig(paymentMethod)
+	if err != nil {
+		return nil, ProviderConfig{}, err
+	}
+	handler, err := createProvider(paymentMethod, config)
+	if err != nil {
+		return nil, ProviderConfig{}, err
 	}
+	return handler, config, nil
 }
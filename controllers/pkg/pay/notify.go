@@ -0,0 +1,157 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/stripe/stripe-go/v74"
+	"github.com/stripe/stripe-go/v74/webhook"
+	"github.com/wechatpay-apiv3/wechatpay-go/core/auth/verifiers"
+	"github.com/wechatpay-apiv3/wechatpay-go/core/downloader"
+	"github.com/wechatpay-apiv3/wechatpay-go/core/notify"
+	"github.com/wechatpay-apiv3/wechatpay-go/services/payments"
+)
+
+// StripeWebhookSecret is the env key of the signing secret of the stripe webhook endpoint.
+const StripeWebhookSecret = "STRIPE_WEBHOOK_SECRET"
+
+const maxNotificationBodySize = 1 << 20
+
+// Notification is a payment notification verified with the signature of the payment provider.
+type Notification struct {
+	TradeNO string
+	// Status is one of the payment status, PaymentSuccess if the trade is paid.
+	Status string
+	// Amount is the paid amount in the minor unit of the currency of the payment provider.
+	Amount int64
+}
+
+// ParseStripeNotification verifies the Stripe-Signature of the webhook event and returns the
+// notification of the checkout session, or nil if the event is not about a paid checkout session.
+func ParseStripeNotification(r *http.Request) (*Notification, error) {
+	secret := os.Getenv(StripeWebhookSecret)
+	if secret == "" {
+		return nil, fmt.Errorf("env %s is not set", StripeWebhookSecret)
+	}
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxNotificationBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("read body failed: %w", err)
+	}
+	// only the stable fields of the checkout session are read, so the api version of the event is not checked
+	event, err := webhook.ConstructEventWithOptions(payload, r.Header.Get("Stripe-Signature"), secret,
+		webhook.ConstructEventOptions{IgnoreAPIVersionMismatch: true})
+	if err != nil {
+		return nil, fmt.Errorf("verify stripe event failed: %w", err)
+	}
+	switch event.Type {
+	case "checkout.session.completed", "checkout.session.async_payment_succeeded":
+	default:
+		return nil, nil
+	}
+	var ses stripe.CheckoutSession
+	if err := json.Unmarshal(event.Data.Raw, &ses); err != nil {
+		return nil, fmt.Errorf("unmarshal checkout session failed: %w", err)
+	}
+	if ses.PaymentStatus != stripe.CheckoutSessionPaymentStatusPaid {
+		return nil, nil
+	}
+	return &Notification{TradeNO: ses.ID, Status: PaymentSuccess, Amount: ses.AmountTotal}, nil
+}
+
+// ParseWechatNotification verifies the signature of the wechat pay notify with the platform
+// certificates, decrypts the transaction and returns its notification.
+func ParseWechatNotification(r *http.Request) (*Notification, error) {
+	// the client registers the downloader of the platform certificates of the merchant
+	if _, err := NewClient(r.Context()); err != nil {
+		return nil, fmt.Errorf("new wechat pay client err:%s", err)
+	}
+	r.Body = http.MaxBytesReader(nil, r.Body, maxNotificationBodySize)
+	certificateVisitor := downloader.MgrInstance().GetCertificateVisitor(os.Getenv(MchID))
+	handler := notify.NewNotifyHandler(os.Getenv(MchAPIv3Key), verifiers.NewSHA256WithRSAVerifier(certificateVisitor))
+	transaction := &payments.Transaction{}
+	if _, err := handler.ParseNotifyRequest(context.Background(), r, transaction); err != nil {
+		return nil, fmt.Errorf("verify wechat notify failed: %w", err)
+	}
+	if transaction.OutTradeNo == nil || transaction.TradeState == nil {
+		return nil, fmt.Errorf("invalid wechat transaction: %v", transaction)
+	}
+	n := &Notification{TradeNO: *transaction.OutTradeNo, Status: PaymentUnknown}
+	switch *transaction.TradeState {
+	case StatusSuccess:
+		n.Status = PaymentSuccess
+		if transaction.Amount != nil && transaction.Amount.Total != nil {
+			n.Amount = *transaction.Amount.Total
+		}
+	case StatusNotPay:
+		n.Status = PaymentNotPaid
+	case StatusProcessing:
+		n.Status = PaymentProcessing
+	}
+	return n, nil
+}
+
+// AlipayNotifyAck is the response body of a handled alipay notify, alipay retries the notify
+// until it is answered with it.
+const AlipayNotifyAck = "success"
+
+// ParseAlipayNotification verifies the RSA2 signature of the alipay async notify with the public
+// key of alipay and returns the notification of the trade.
+func ParseAlipayNotification(r *http.Request) (*Notification, error) {
+	publicKey, err := ParseRSAPublicKey(os.Getenv(AlipayPublicKey))
+	if err != nil {
+		return nil, fmt.Errorf("parse alipay public key failed: %w", err)
+	}
+	r.Body = http.MaxBytesReader(nil, r.Body, maxNotificationBodySize)
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("parse alipay notify failed: %w", err)
+	}
+	// the sign_type of the notify is not signed
+	params := url.Values{}
+	for k, v := range r.PostForm {
+		if k != "sign_type" {
+			params[k] = v
+		}
+	}
+	if err := VerifyWithRSA2(publicKey, alipaySignContent(params), params.Get("sign")); err != nil {
+		return nil, fmt.Errorf("verify alipay notify failed: %w", err)
+	}
+	if appID := os.Getenv(AlipayAppID); params.Get("app_id") != appID {
+		return nil, fmt.Errorf("alipay notify of app %q is not of app %q", params.Get("app_id"), appID)
+	}
+	n := &Notification{TradeNO: params.Get("out_trade_no"), Status: PaymentUnknown}
+	if n.TradeNO == "" {
+		return nil, fmt.Errorf("no out_trade_no of alipay notify")
+	}
+	switch params.Get("trade_status") {
+	case alipayTradeSuccess, alipayTradeFinished:
+		amount, err := parseAlipayAmount(params.Get("total_amount"))
+		if err != nil {
+			return nil, err
+		}
+		n.Status, n.Amount = PaymentSuccess, amount
+	case alipayTradeWaitBuyerPay:
+		n.Status = PaymentNotPaid
+	case alipayTradeClosed:
+		n.Status = PaymentExpired
+	}
+	return n, nil
+}
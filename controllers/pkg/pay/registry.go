@@ -0,0 +1,193 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pay
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"sigs.k8s.io/yaml"
+)
+
+// EnvPaymentProviders is the env key of the configuration of the enabled payment providers,
+// it is a yaml or json map from the payment method to its ProviderConfig, e.g.
+//
+//	wechat: {}
+//	stripe: {currency: usd}
+//	alipay: {options: {gateway: https://openapi.alipay.com/gateway.do}}
+//
+// stripe and wechat are enabled if it is not set.
+const EnvPaymentProviders = "PAYMENT_PROVIDERS"
+
+// payment methods
+const (
+	MethodWechat = "wechat"
+	MethodStripe = "stripe"
+	MethodAlipay = "alipay"
+)
+
+// ProviderConfig is the configuration of a payment provider.
+type ProviderConfig struct {
+	// Currency is the currency charged by the provider, default is the currency of the provider.
+	Currency string `json:"currency,omitempty"`
+	// ExchangeRate is the CNY of one unit of Currency. If it is not set, the exchange rate of
+	// the pay method of service/pay is used when it is set by SetExchangeRateFunc, otherwise 1.
+	ExchangeRate float64 `json:"exchangeRate,omitempty"`
+	// Options are the provider specific settings.
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// ToProviderAmount converts amount in CNY to the amount in Currency, 1 ¥ = amount 100.
+func (c ProviderConfig) ToProviderAmount(amount int64) int64 {
+	if c.ExchangeRate <= 0 || c.ExchangeRate == 1 {
+		return amount
+	}
+	return int64(math.Round(float64(amount) / c.ExchangeRate))
+}
+
+// FromProviderAmount converts amount in Currency to the amount in CNY, 1 ¥ = amount 100.
+func (c ProviderConfig) FromProviderAmount(amount int64) int64 {
+	if c.ExchangeRate <= 0 || c.ExchangeRate == 1 {
+		return amount
+	}
+	return int64(math.Round(float64(amount) * c.ExchangeRate))
+}
+
+// ChargedCurrency returns the currency charged by the provider of the payment method.
+func (c ProviderConfig) ChargedCurrency(method string) string {
+	switch {
+	case c.Currency != "":
+		return c.Currency
+	case method == MethodStripe:
+		return Currency
+	default:
+		return CNY
+	}
+}
+
+// ProviderFactory creates the Interface of a payment provider with its configuration.
+type ProviderFactory func(config ProviderConfig) (Interface, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]ProviderFactory{
+		MethodWechat: newWechatPayment,
+		MethodStripe: newStripePayment,
+		MethodAlipay: newAlipayPayment,
+	}
+
+	providerConfigsOnce sync.Once
+	providerConfigs     map[string]ProviderConfig
+	providerConfigsErr  error
+)
+
+// RegisterProvider registers the factory of the payment method, it replaces the factory
+// registered with the same method.
+func RegisterProvider(method string, factory ProviderFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	factories[method] = factory
+}
+
+// ParseProviderConfigs parses the configuration of the payment providers, see EnvPaymentProviders.
+func ParseProviderConfigs(data string) (map[string]ProviderConfig, error) {
+	if strings.TrimSpace(data) == "" {
+		return map[string]ProviderConfig{
+			MethodWechat: {},
+			MethodStripe: {},
+		}, nil
+	}
+	configs := make(map[string]ProviderConfig)
+	if err := yaml.Unmarshal([]byte(data), &configs); err != nil {
+		return nil, fmt.Errorf("parse payment providers failed: %w", err)
+	}
+	for method, config := range configs {
+		if config.ExchangeRate < 0 {
+			return nil, fmt.Errorf("invalid exchange rate %v of payment method %s", config.ExchangeRate, method)
+		}
+		config.Currency = strings.ToLower(strings.TrimSpace(config.Currency))
+		configs[method] = config
+	}
+	return configs, nil
+}
+
+// GetProviderConfig returns the configuration of the enabled payment method with its exchange rate.
+func GetProviderConfig(method string) (ProviderConfig, error) {
+	providerConfigsOnce.Do(func() {
+		providerConfigs, providerConfigsErr = ParseProviderConfigs(os.Getenv(EnvPaymentProviders))
+	})
+	if providerConfigsErr != nil {
+		return ProviderConfig{}, providerConfigsErr
+	}
+	config, ok := providerConfigs[method]
+	if !ok {
+		return ProviderConfig{}, fmt.Errorf("unsupported payment method: %q, enabled methods: %s", method, strings.Join(enabledMethods(), ", "))
+	}
+	return resolveExchangeRate(method, config)
+}
+
+func enabledMethods() []string {
+	methods := make([]string, 0, len(providerConfigs))
+	for method := range providerConfigs {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+func newProvider(method string, config ProviderConfig) (Interface, error) {
+	handler, err := createProvider(method, config)
+	if err != nil {
+		return nil, err
+	}
+	return &exchangeHandler{Interface: handler, config: config}, nil
+}
+
+func createProvider(method string, config ProviderConfig) (Interface, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[method]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("payment method %q is enabled but not registered", method)
+	}
+	handler, err := factory(config)
+	if err != nil {
+		return nil, fmt.Errorf("create payment provider %s failed: %w", method, err)
+	}
+	return handler, nil
+}
+
+// exchangeHandler converts the amounts in CNY to the currency of the provider.
+type exchangeHandler struct {
+	Interface
+	config ProviderConfig
+}
+
+func (h *exchangeHandler) CreatePayment(amount int64, user, describe string) (string, string, error) {
+	return h.Interface.CreatePayment(h.config.ToProviderAmount(amount), user, describe)
+}
+
+func (h *exchangeHandler) GetPaymentDetails(sessionID string) (string, int64, error) {
+	status, amount, err := h.Interface.GetPaymentDetails(sessionID)
+	return status, h.config.FromProviderAmount(amount), err
+}
+
+func (h *exchangeHandler) Refund(tradeNO, refundNO string, amount int64) (string, error) {
+	return h.Interface.Refund(tradeNO, refundNO, h.config.ToProviderAmount(amount))
+}
@@ -0,0 +1,150 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pay
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseProviderConfigs(t *testing.T) {
+	configs, err := ParseProviderConfigs("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := configs[MethodWechat]; !ok || len(configs) != 2 {
+		t.Errorf("default configs = %v, want wechat and stripe", configs)
+	}
+
+	configs, err = ParseProviderConfigs(`
+stripe: {currency: USD, exchangeRate: 7.1}
+alipay:
+  options:
+    gateway: http://localhost:8080/gateway.do
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 2 || configs[MethodStripe].Currency != USD || configs[MethodAlipay].Options["gateway"] != "http://localhost:8080/gateway.do" {
+		t.Errorf("configs = %v", configs)
+	}
+	if _, err = ParseProviderConfigs(`{"stripe": {"exchangeRate": -1}}`); err == nil {
+		t.Errorf("negative exchange rate is accepted")
+	}
+}
+
+func TestProviderConfigExchange(t *testing.T) {
+	usd := ProviderConfig{Currency: USD, ExchangeRate: 7.1}
+	// ¥71 is $10
+	if got := usd.ToProviderAmount(7100); got != 1000 {
+		t.Errorf("ToProviderAmount() = %d, want 1000", got)
+	}
+	if got := usd.FromProviderAmount(1000); got != 7100 {
+		t.Errorf("FromProviderAmount() = %d, want 7100", got)
+	}
+	if got := (ProviderConfig{}).ToProviderAmount(7100); got != 7100 {
+		t.Errorf("ToProviderAmount() without exchange rate = %d, want 7100", got)
+	}
+	if got := usd.ChargedCurrency(MethodWechat); got != USD {
+		t.Errorf("ChargedCurrency() = %s, want %s", got, USD)
+	}
+	if got := (ProviderConfig{}).ChargedCurrency(MethodAlipay); got != CNY {
+		t.Errorf("ChargedCurrency() of alipay = %s, want %s", got, CNY)
+	}
+	if got := (ProviderConfig{}).ChargedCurrency(MethodStripe); got != Currency {
+		t.Errorf("ChargedCurrency() of stripe = %s, want %s", got, Currency)
+	}
+}
+
+type fakeProvider struct {
+	amount int64
+}
+
+func (f *fakeProvider) CreatePayment(amount int64, _, _ string) (string, string, error) {
+	f.amount = amount
+	return "trade", "", nil
+}
+
+func (f *fakeProvider) GetPaymentDetails(_ string) (string, int64, error) {
+	return PaymentSuccess, f.amount, nil
+}
+
+func (f *fakeProvider) ExpireSession(_ string) error {
+	return nil
+}
+
+func (f *fakeProvider) Refund(_, _ string, amount int64) (string, error) {
+	f.amount -= amount
+	return "refund", nil
+}
+
+func TestNewProvider(t *testing.T) {
+	fake := &fakeProvider{}
+	RegisterProvider("fake", func(ProviderConfig) (Interface, error) {
+		return fake, nil
+	})
+	handler, err := newProvider("fake", ProviderConfig{Currency: USD, ExchangeRate: 7.1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = handler.CreatePayment(7100, "user", ""); err != nil || fake.amount != 1000 {
+		t.Errorf("CreatePayment() amount of provider = %d, %v, want 1000", fake.amount, err)
+	}
+	if _, amount, _ := handler.GetPaymentDetails("trade"); amount != 7100 {
+		t.Errorf("GetPaymentDetails() = %d, want 7100", amount)
+	}
+	if _, err = handler.Refund("trade", "refund", 710); err != nil || fake.amount != 900 {
+		t.Errorf("Refund() amount of provider = %d, %v, want 900", fake.amount, err)
+	}
+	if _, err = newProvider("unknown", ProviderConfig{}); err == nil {
+		t.Errorf("newProvider() of unregistered method is accepted")
+	}
+	if _, err = NewPayHandler("unknown"); err == nil {
+		t.Errorf("NewPayHandler() of unknown method is accepted")
+	}
+}
+
+func TestResolveExchangeRate(t *testing.T) {
+	calls := 0
+	SetExchangeRateFunc(func(method, currency string) (float64, error) {
+		calls++
+		if method != "stripe" || currency != USD {
+			return 0, fmt.Errorf("no exchange rate of %s in %s", method, currency)
+		}
+		return 7.1, nil
+	})
+	t.Cleanup(func() { SetExchangeRateFunc(nil) })
+
+	// the rate of service/pay is used and cached
+	for i := 0; i < 2; i++ {
+		config, err := resolveExchangeRate("stripe", ProviderConfig{Currency: USD})
+		if err != nil || config.ExchangeRate != 7.1 {
+			t.Fatalf("resolveExchangeRate() = %v, %v, want 7.1", config.ExchangeRate, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("exchange rate is read %d times, want 1", calls)
+	}
+	// the configured rate and CNY are not read
+	if config, err := resolveExchangeRate("stripe", ProviderConfig{Currency: USD, ExchangeRate: 7}); err != nil || config.ExchangeRate != 7 {
+		t.Errorf("resolveExchangeRate() of configured rate = %v, %v, want 7", config.ExchangeRate, err)
+	}
+	if config, err := resolveExchangeRate("wechat", ProviderConfig{Currency: CNY}); err != nil || config.ExchangeRate != 0 {
+		t.Errorf("resolveExchangeRate() of cny = %v, %v, want 0", config.ExchangeRate, err)
+	}
+	if _, err := resolveExchangeRate("other", ProviderConfig{Currency: USD}); err == nil {
+		t.Errorf("resolveExchangeRate() without exchange rate is accepted")
+	}
+}
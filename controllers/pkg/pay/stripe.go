@@ -45,8 +45,20 @@ func init() {
 	Currency = currency
 }
 
+func newStripePayment(config ProviderConfig) (Interface, error) {
+	currency := config.Currency
+	if currency == "" {
+		currency = Currency
+	}
+	return &StripePayment{Currency: currency}, nil
+}
+
 func (s StripePayment) CreatePayment(amount int64, _, _ string) (string, string, error) {
-	session, err := CreateCheckoutSession(amount, Currency, DefaultURL+os.Getenv(stripeSuccessPostfix), DefaultURL+os.Getenv(stripeCancelPostfix))
+	currency := s.Currency
+	if currency == "" {
+		currency = Currency
+	}
+	session, err := CreateCheckoutSession(amount, currency, DefaultURL+os.Getenv(stripeSuccessPostfix), DefaultURL+os.Getenv(stripeCancelPostfix))
 	if err != nil {
 		return "", "", err
 	}
@@ -81,3 +93,14 @@ func (s StripePayment) ExpireSession(sessionID string) error {
 	}
 	return nil
 }
+
+func (s StripePayment) Refund(sessionID, refundNO string, amount int64) (string, error) {
+	r, err := RefundSession(sessionID, refundNO, amount)
+	if err != nil {
+		return "", err
+	}
+	if r.Status == stripe.RefundStatusFailed || r.Status == stripe.RefundStatusCanceled {
+		return "", fmt.Errorf("%w: refund %s of session %s is %s", ErrRefundRejected, r.ID, sessionID, r.Status)
+	}
+	return r.ID, nil
+}
@@ -15,16 +15,20 @@
 package pay
 
 import (
+	"fmt"
 	"os"
 	"time"
 
 	"github.com/stripe/stripe-go/v74"
 	"github.com/stripe/stripe-go/v74/checkout/session"
+	"github.com/stripe/stripe-go/v74/refund"
 )
 
 const StripeAPIKEY = "STRIPE_API_KEY"
 
 type StripePayment struct {
+	// Currency is the currency of the checkout sessions, default is Currency.
+	Currency string
 }
 
 func init() {
@@ -39,6 +43,9 @@ const (
 
 const sessionExpirationTime = 30 * time.Minute
 
+// stripeRefundNOKey is the metadata key of the refund number of the refunds.
+const stripeRefundNOKey = "refund_no"
+
 func CreateCheckoutSession(amount int64, currency, successURL, cancelURL string) (*stripe.CheckoutSession, error) {
 	expireAt := time.Now().UTC().Add(sessionExpirationTime).Unix()
 	params := &stripe.CheckoutSessionParams{
@@ -80,3 +87,32 @@ func GetSession(sessionID string) (*stripe.CheckoutSession, error) {
 func ExpireSession(sessionID string) (*stripe.CheckoutSession, error) {
 	return session.Expire(sessionID, nil)
 }
+
+// RefundSession refunds amount of the payment intent of the completed checkout session.
+func RefundSession(sessionID, refundNO string, amount int64) (*stripe.Refund, error) {
+	ses, err := GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if ses.PaymentIntent == nil {
+		return nil, fmt.Errorf("no payment intent of session %s", sessionID)
+	}
+	// the idempotency key expires in 24 hours, the refund of a later retry is found by its metadata
+	refunds := refund.List(&stripe.RefundListParams{PaymentIntent: stripe.String(ses.PaymentIntent.ID)})
+	for refunds.Next() {
+		if r := refunds.Refund(); r.Metadata[stripeRefundNOKey] == refundNO {
+			return r, nil
+		}
+	}
+	if err = refunds.Err(); err != nil {
+		return nil, err
+	}
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(ses.PaymentIntent.ID),
+		Amount:        stripe.Int64(amount),
+		Reason:        stripe.String(string(stripe.RefundReasonRequestedByCustomer)),
+	}
+	params.AddMetadata(stripeRefundNOKey, refundNO)
+	params.SetIdempotencyKey(refundNO)
+	return refund.New(params)
+}
@@ -14,11 +14,23 @@
 
 package pay
 
-import "fmt"
+import (
+	"fmt"
+	"os"
+
+	"github.com/wechatpay-apiv3/wechatpay-go/services/refunddomestic"
+)
+
+func newWechatPayment(config ProviderConfig) (Interface, error) {
+	if config.Currency != "" && config.Currency != CNY {
+		return nil, fmt.Errorf("unsupported currency of wechat pay: %s", config.Currency)
+	}
+	return &WechatPayment{}, nil
+}
 
 func (w WechatPayment) CreatePayment(amount int64, user, describe string) (string, string, error) {
 	tradeNO := GetRandomString(32)
-	codeURL, err := WechatPay(amount, user, tradeNO, describe, "")
+	codeURL, err := WechatPay(amount, user, tradeNO, describe, os.Getenv(NotifyCallbackURL))
 	if err != nil {
 		return "", "", err
 	}
@@ -47,3 +59,21 @@ func (w WechatPayment) GetPaymentDetails(sessionID string) (string, int64, error
 func (w WechatPayment) ExpireSession(_ string) error {
 	return nil
 }
+
+func (w WechatPayment) Refund(tradeNO, refundNO string, amount int64) (string, error) {
+	orderResp, err := QueryOrder(tradeNO)
+	if err != nil {
+		return "", err
+	}
+	if *orderResp.TradeState != StatusSuccess && *orderResp.TradeState != StatusRefund {
+		return "", fmt.Errorf("order %s is not paid: %s", tradeNO, *orderResp.TradeState)
+	}
+	resp, err := RefundOrder(tradeNO, refundNO, amount, *orderResp.Amount.Total)
+	if err != nil {
+		return "", err
+	}
+	if resp.Status != nil && (*resp.Status == refunddomestic.STATUS_CLOSED || *resp.Status == refunddomestic.STATUS_ABNORMAL) {
+		return "", fmt.Errorf("%w: refund %s of order %s is %s", ErrRefundRejected, refundNO, tradeNO, *resp.Status)
+	}
+	return refundNO, nil
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/wechatpay-apiv3/wechatpay-go/core/option"
 	"github.com/wechatpay-apiv3/wechatpay-go/services/payments"
 	"github.com/wechatpay-apiv3/wechatpay-go/services/payments/native"
+	"github.com/wechatpay-apiv3/wechatpay-go/services/refunddomestic"
 	"github.com/wechatpay-apiv3/wechatpay-go/utils"
 )
 
@@ -45,6 +46,7 @@ const (
 	StatusProcessing   = "PROCESSING"
 	StatusNotPay       = "NOTPAY"
 	StatusFail         = "FAILED"
+	StatusRefund       = "REFUND"
 	DefaultCallbackURL = "https://sealos.io/payment/wechat/callback"
 )
 
@@ -80,6 +82,32 @@ func QueryOrder(orderID string) (*payments.Transaction, error) {
 	return resp, nil
 }
 
+// RefundOrder refunds amount of the order, the total is the amount paid of the order, 1 ¥ = amount 100.
+func RefundOrder(orderID, refundNO string, amount, total int64) (*refunddomestic.Refund, error) {
+	ctx := context.Background()
+	client, err := NewClient(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("new wechat pay client err:%s", err)
+	}
+	svc := refunddomestic.RefundsApiService{Client: client}
+	resp, _, err := svc.Create(ctx,
+		refunddomestic.CreateRequest{
+			OutTradeNo:  core.String(orderID),
+			OutRefundNo: core.String(refundNO),
+			Reason:      core.String("sealos cloud refund"),
+			Amount: &refunddomestic.AmountReq{
+				Refund:   core.Int64(amount),
+				Total:    core.Int64(total),
+				Currency: core.String("CNY"),
+			},
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("call Refund err:%s", err)
+	}
+	return resp, nil
+}
+
 // 1 ¥ = amount 100
 func WechatPay(amount int64, user, tradeNO, describe, callback string) (string, error) {
 	ctx := context.Background()
@@ -44,6 +44,7 @@ type ResourceNamed struct {
 	_type      string
 	parentType string
 	parentName string
+	nodePool   string
 	labels     map[string]string
 }
 
@@ -144,6 +145,18 @@ func (r *ResourceNamed) Name() string {
 	return r._name
 }
 
+// SetNodePool sets the node pool of the pod, the pods of the app in different node pools are priced separately.
+func (r *ResourceNamed) SetNodePool(pool string) {
+	r.nodePool = pool
+}
+
+func (r *ResourceNamed) NodePool() string {
+	return r.nodePool
+}
+
 func (r *ResourceNamed) String() string {
+	if r.nodePool != "" {
+		return r._type + "/" + r._name + "/" + r.nodePool
+	}
 	return r._type + "/" + r._name
 }
@@ -0,0 +1,242 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// PricingRule is a version of the pricing of a property, it is effective from EffectiveAt
+// until the EffectiveAt of the next version of the property.
+//
+// The unit price of an used value is UnitPrice, or the price of the node pool of the pod if
+// it is in NodePoolPrices. The price is then multiplied by the Ratio of the volume tier the
+// used value falls in, and by the Ratio of the first time window the billing hour is in.
+type PricingRule struct {
+	// Property is the name of the property type, e.g. cpu, memory, gpu-tesla-t4
+	Property string `json:"property" bson:"property"`
+	// Version increases with each version of the pricing of Property.
+	Version     int64     `json:"version" bson:"version"`
+	EffectiveAt time.Time `json:"effective_at" bson:"effective_at"`
+	// UnitPrice is the price of an used unit, default is the UnitPrice of the property type.
+	UnitPrice float64 `json:"unit_price,omitempty" bson:"unit_price,omitempty"`
+	// NodePoolPrices are the unit prices of the node pools, the node pool of a pod is the
+	// value of the node pool label of its node.
+	NodePoolPrices map[string]float64 `json:"node_pool_prices,omitempty" bson:"node_pool_prices,omitempty"`
+	// Tiers are the volume tiers of the used value of the owner accumulated in a calendar
+	// month, e.g. with the cpu unit 1m, the first 100 core-hours is the tier UpTo 100000.
+	Tiers []PriceTier `json:"tiers,omitempty" bson:"tiers,omitempty"`
+	// TimeWindows are the time-of-use ratios, the first window matched by the billing hour is applied.
+	TimeWindows []TimeWindow `json:"time_windows,omitempty" bson:"time_windows,omitempty"`
+	// TimeZone is the location of TimeWindows, default is UTC.
+	TimeZone string `json:"time_zone,omitempty" bson:"time_zone,omitempty"`
+}
+
+// PriceTier is a volume tier, the used value up to UpTo in the month is priced with Ratio.
+type PriceTier struct {
+	// UpTo is the upper bound of the tier, 0 for an unbounded tier.
+	UpTo  int64   `json:"up_to" bson:"up_to"`
+	Ratio float64 `json:"ratio" bson:"ratio"`
+}
+
+// TimeWindow is a time of the week priced with Ratio, e.g. Ratio 0.5 is a 50% discount.
+type TimeWindow struct {
+	// Weekdays are the days of the window, 0 is Sunday, empty for every day.
+	Weekdays []time.Weekday `json:"weekdays,omitempty" bson:"weekdays,omitempty"`
+	// StartHour and EndHour are the hours [StartHour, EndHour) of the window, the window
+	// crosses midnight if EndHour <= StartHour, and it lasts the whole day if both are 0.
+	StartHour int     `json:"start_hour" bson:"start_hour"`
+	EndHour   int     `json:"end_hour" bson:"end_hour"`
+	Ratio     float64 `json:"ratio" bson:"ratio"`
+}
+
+// Validate checks the rule is consistent.
+func (r *PricingRule) Validate() error {
+	if r.Property == "" {
+		return fmt.Errorf("empty property of pricing rule")
+	}
+	if r.EffectiveAt.IsZero() {
+		return fmt.Errorf("empty effective time of pricing rule %s", r.Property)
+	}
+	if r.UnitPrice < 0 {
+		return fmt.Errorf("negative unit price of pricing rule %s", r.Property)
+	}
+	for pool, price := range r.NodePoolPrices {
+		if price < 0 {
+			return fmt.Errorf("negative unit price of node pool %s of pricing rule %s", pool, r.Property)
+		}
+	}
+	for i, tier := range r.Tiers {
+		if tier.Ratio < 0 || tier.UpTo < 0 {
+			return fmt.Errorf("invalid tier %d of pricing rule %s", i, r.Property)
+		}
+		if tier.UpTo == 0 && i != len(r.Tiers)-1 {
+			return fmt.Errorf("only the last tier of pricing rule %s can be unbounded", r.Property)
+		}
+		if i > 0 && tier.UpTo != 0 && tier.UpTo <= r.Tiers[i-1].UpTo {
+			return fmt.Errorf("tiers of pricing rule %s must be in ascending order", r.Property)
+		}
+	}
+	for i, w := range r.TimeWindows {
+		if w.Ratio < 0 || w.StartHour < 0 || w.StartHour > 23 || w.EndHour < 0 || w.EndHour > 24 {
+			return fmt.Errorf("invalid time window %d of pricing rule %s", i, r.Property)
+		}
+	}
+	if _, err := time.LoadLocation(r.TimeZone); err != nil {
+		return fmt.Errorf("invalid time zone of pricing rule %s: %w", r.Property, err)
+	}
+	return nil
+}
+
+// Fee returns the fee of used of the property in the billing hour starting at t. monthUsed
+// is the used value of the owner in the month before t, and pool is the node pool of the used.
+func (r *PricingRule) Fee(prop PropertyType, used, monthUsed int64, pool string, t time.Time) float64 {
+	unitPrice := prop.UnitPrice
+	if r.UnitPrice > 0 {
+		unitPrice = r.UnitPrice
+	}
+	if price, ok := r.NodePoolPrices[pool]; ok && pool != "" {
+		unitPrice = price
+	}
+	return r.tieredUsed(used, monthUsed) * unitPrice * r.timeRatio(t)
+}
+
+// tieredUsed returns used weighted by the ratios of the tiers.
+func (r *PricingRule) tieredUsed(used, monthUsed int64) float64 {
+	if len(r.Tiers) == 0 || used <= 0 {
+		return float64(used)
+	}
+	var weighted float64
+	from, remaining := monthUsed, used
+	for _, tier := range r.Tiers {
+		if remaining == 0 {
+			break
+		}
+		if tier.UpTo != 0 && from >= tier.UpTo {
+			continue
+		}
+		n := remaining
+		if tier.UpTo != 0 && from+n > tier.UpTo {
+			n = tier.UpTo - from
+		}
+		weighted += float64(n) * tier.Ratio
+		from += n
+		remaining -= n
+	}
+	// the used value above the last bounded tier is priced with the unit price
+	return weighted + float64(remaining)
+}
+
+func (r *PricingRule) timeRatio(t time.Time) float64 {
+	if len(r.TimeWindows) == 0 {
+		return 1
+	}
+	loc, err := time.LoadLocation(r.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	for _, w := range r.TimeWindows {
+		if w.contains(t) {
+			return w.Ratio
+		}
+	}
+	return 1
+}
+
+func (w TimeWindow) contains(t time.Time) bool {
+	if len(w.Weekdays) > 0 {
+		found := false
+		for _, d := range w.Weekdays {
+			if d == t.Weekday() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	hour := t.Hour()
+	switch {
+	case w.StartHour == 0 && w.EndHour == 0:
+		return true
+	case w.StartHour < w.EndHour:
+		return hour >= w.StartHour && hour < w.EndHour
+	default:
+		return hour >= w.StartHour || hour < w.EndHour
+	}
+}
+
+// PricingRules are the versions of the pricing rules of the properties.
+type PricingRules map[string][]PricingRule
+
+// NewPricingRules groups the rules by property and sorts the versions by effective time.
+func NewPricingRules(rules []PricingRule) PricingRules {
+	prs := make(PricingRules)
+	for _, rule := range rules {
+		prs[rule.Property] = append(prs[rule.Property], rule)
+	}
+	for _, versions := range prs {
+		sort.SliceStable(versions, func(i, j int) bool {
+			if versions[i].EffectiveAt.Equal(versions[j].EffectiveAt) {
+				return versions[i].Version < versions[j].Version
+			}
+			return versions[i].EffectiveAt.Before(versions[j].EffectiveAt)
+		})
+	}
+	return prs
+}
+
+// Effective returns the version of the rule of the property effective at t, nil if the
+// property has no rule effective at t.
+func (prs PricingRules) Effective(property string, t time.Time) *PricingRule {
+	versions := prs[property]
+	for i := len(versions) - 1; i >= 0; i-- {
+		if !versions[i].EffectiveAt.After(t) {
+			return &versions[i]
+		}
+	}
+	return nil
+}
+
+// HasTiers returns whether any rule effective at t has volume tiers.
+func (prs PricingRules) HasTiers(t time.Time) bool {
+	for property := range prs {
+		if rule := prs.Effective(property, t); rule != nil && len(rule.Tiers) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Fee returns the fee of used of the property in the billing hour starting at t with the
+// rule effective at t, and the version of the rule, 0 if the property has no rule.
+func (prs PricingRules) Fee(prop PropertyType, used, monthUsed int64, pool string, t time.Time) (float64, int64) {
+	rule := prs.Effective(prop.Name, t)
+	if rule == nil {
+		return float64(used) * prop.UnitPrice, 0
+	}
+	return rule.Fee(prop, used, monthUsed, pool, t), rule.Version
+}
+
+// PricingMonth returns the start of the month of t in UTC, the volume tiers are accumulated
+// in it.
+func PricingMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,96 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPricingRulesFee(t *testing.T) {
+	cpu := PropertyType{Name: "cpu", UnitPrice: 2}
+	v1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	v2 := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	rules := NewPricingRules([]PricingRule{
+		{
+			Property:    "cpu",
+			Version:     2,
+			EffectiveAt: v2,
+			UnitPrice:   4,
+			// the first 100 core-hours at full price, the next 100 at half price, then 25%
+			Tiers:          []PriceTier{{UpTo: 100000, Ratio: 1}, {UpTo: 200000, Ratio: 0.5}, {Ratio: 0.25}},
+			NodePoolPrices: map[string]float64{"spot": 1},
+			// half price at weekends and 20% off between 22:00 and 06:00
+			TimeWindows: []TimeWindow{
+				{Weekdays: []time.Weekday{time.Saturday, time.Sunday}, Ratio: 0.5},
+				{StartHour: 22, EndHour: 6, Ratio: 0.8},
+			},
+		},
+		{Property: "cpu", Version: 1, EffectiveAt: v1, UnitPrice: 3},
+	})
+	// 2024-02-05 is a Monday
+	monday := time.Date(2024, 2, 5, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		used        int64
+		monthUsed   int64
+		pool        string
+		t           time.Time
+		wantFee     float64
+		wantVersion int64
+	}{
+		{"no rule effective", 1000, 0, "", time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), 2000, 0},
+		{"first version", 1000, 0, "", v1.Add(time.Hour), 3000, 1},
+		{"first tier", 1000, 0, "", monday, 4000, 2},
+		{"crossing tiers", 2000, 99000, "", monday, 1000*4 + 1000*4*0.5, 2},
+		{"last unbounded tier", 1000, 300000, "", monday, 1000 * 4 * 0.25, 2},
+		{"node pool", 1000, 0, "spot", monday, 1000, 2},
+		{"unknown node pool", 1000, 0, "gpu", monday, 4000, 2},
+		{"weekend", 1000, 0, "", time.Date(2024, 2, 10, 10, 0, 0, 0, time.UTC), 2000, 2},
+		{"night before midnight", 1000, 0, "", time.Date(2024, 2, 5, 23, 0, 0, 0, time.UTC), 3200, 2},
+		{"night after midnight", 1000, 0, "", time.Date(2024, 2, 6, 5, 0, 0, 0, time.UTC), 3200, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fee, version := rules.Fee(cpu, tt.used, tt.monthUsed, tt.pool, tt.t)
+			if fee != tt.wantFee || version != tt.wantVersion {
+				t.Errorf("Fee() = %v, %d, want %v, %d", fee, version, tt.wantFee, tt.wantVersion)
+			}
+		})
+	}
+	if !rules.HasTiers(monday) || rules.HasTiers(v1) {
+		t.Errorf("HasTiers() is not effective by time")
+	}
+}
+
+func TestPricingRuleValidate(t *testing.T) {
+	valid := PricingRule{Property: "cpu", EffectiveAt: time.Now(), Tiers: []PriceTier{{UpTo: 10, Ratio: 1}, {Ratio: 0.5}}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	for name, rule := range map[string]PricingRule{
+		"no effective time":   {Property: "cpu"},
+		"unbounded tier":      {Property: "cpu", EffectiveAt: time.Now(), Tiers: []PriceTier{{Ratio: 1}, {UpTo: 10, Ratio: 0.5}}},
+		"descending tiers":    {Property: "cpu", EffectiveAt: time.Now(), Tiers: []PriceTier{{UpTo: 10, Ratio: 1}, {UpTo: 5, Ratio: 0.5}}},
+		"invalid hour":        {Property: "cpu", EffectiveAt: time.Now(), TimeWindows: []TimeWindow{{StartHour: 25}}},
+		"invalid time zone":   {Property: "cpu", EffectiveAt: time.Now(), TimeZone: "Mars/Base"},
+		"negative pool price": {Property: "cpu", EffectiveAt: time.Now(), NodePoolPrices: map[string]float64{"spot": -1}},
+	} {
+		if err := rule.Validate(); err == nil {
+			t.Errorf("Validate() of %s rule is accepted", name)
+		}
+	}
+}
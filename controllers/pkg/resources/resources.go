@@ -86,6 +86,8 @@ type Monitor struct {
 	Name       string      `json:"name" bson:"name"`
 	Used       EnumUsedMap `json:"used" bson:"used"`
 	Property   string      `json:"property,omitempty" bson:"property,omitempty"`
+	// NodePool is the node pool of the node of the pod, it is priced by the NodePoolPrices of PricingRule.
+	NodePool string `json:"node_pool,omitempty" bson:"node_pool,omitempty"`
 }
 
 type ActiveBilling struct {
@@ -159,6 +161,9 @@ type AppCost struct {
 	UsedAmount EnumUsedMap `json:"used_amount" bson:"used_amount"`
 	Amount     int64       `json:"amount" bson:"amount,omitempty"`
 	Name       string      `json:"name" bson:"name"`
+	NodePool   string      `json:"node_pool,omitempty" bson:"node_pool,omitempty"`
+	// PriceVersions are the versions of the pricing rules the UsedAmount is computed with.
+	PriceVersions EnumUsedMap `json:"price_versions,omitempty" bson:"price_versions,omitempty"`
 }
 
 type BillingHandler struct {
@@ -0,0 +1,130 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type BillingExportFormat string
+
+const (
+	BillingExportFormatCSV     BillingExportFormat = "csv"
+	BillingExportFormatParquet BillingExportFormat = "parquet"
+)
+
+type BillingExportStatus string
+
+const (
+	BillingExportStatusPending BillingExportStatus = "pending"
+	BillingExportStatusDone    BillingExportStatus = "done"
+	BillingExportStatusFailed  BillingExportStatus = "failed"
+)
+
+// BillingExport is an export of the billing line items of a user, or of one of its workspaces if
+// Workspace is set, in a time range. The pending exports are written to the object storage of the
+// user by the account controller, which notifies the user with the download link.
+type BillingExport struct {
+	ID      uuid.UUID `gorm:"column:id;type:uuid;default:gen_random_uuid();primary_key" json:"id"`
+	UserUID uuid.UUID `gorm:"column:userUid;type:uuid;not null;index" json:"userUid"`
+	// Workspace is the namespace of the workspace, empty for all workspaces of the user.
+	Workspace string              `gorm:"column:workspace;type:text;not null;default:''" json:"workspace,omitempty"`
+	Format    BillingExportFormat `gorm:"column:format;type:text;not null" json:"format"`
+	StartTime time.Time           `gorm:"column:startTime;type:timestamp(3) with time zone;not null" json:"startTime"`
+	EndTime   time.Time           `gorm:"column:endTime;type:timestamp(3) with time zone;not null" json:"endTime"`
+	// ScheduleID is the schedule which created the export, nil for the exports requested by the user.
+	ScheduleID *uuid.UUID          `gorm:"column:scheduleId;type:uuid" json:"scheduleId,omitempty"`
+	Status     BillingExportStatus `gorm:"column:status;type:text;not null;index" json:"status"`
+	// Bucket and Object are the location of the file in the object storage.
+	Bucket string `gorm:"column:bucket;type:text" json:"bucket,omitempty"`
+	Object string `gorm:"column:object;type:text" json:"object,omitempty"`
+	// URL is the presigned download link of the file, it expires at URLExpireAt.
+	URL         string     `gorm:"column:url;type:text" json:"url,omitempty"`
+	URLExpireAt *time.Time `gorm:"column:urlExpireAt;type:timestamp(3) with time zone" json:"urlExpireAt,omitempty"`
+	Rows        int64      `gorm:"column:rows;type:bigint;not null;default:0" json:"rows"`
+	Error       string     `gorm:"column:error;type:text" json:"error,omitempty"`
+	// Attempts is the number of the failed attempts, the pending export is retried at NextAttemptAt
+	// until it fails MaxBillingExportAttempts times.
+	Attempts      int        `gorm:"column:attempts;type:int;not null;default:0" json:"attempts,omitempty"`
+	NextAttemptAt *time.Time `gorm:"column:nextAttemptAt;type:timestamp(3) with time zone" json:"-"`
+	CreatedAt     time.Time  `gorm:"column:createdAt;type:timestamp(3) with time zone;default:current_timestamp()" json:"createdAt"`
+	UpdatedAt     time.Time  `gorm:"column:updatedAt;type:timestamp(3) with time zone;default:current_timestamp()" json:"updatedAt"`
+}
+
+// ErrBillingExportPending is returned by the creation of a billing export of a user whose
+// other billing export is pending.
+var ErrBillingExportPending = errors.New("a billing export of the user is pending")
+
+// MaxBillingExportAttempts is the number of the failed attempts after which an export is failed.
+const MaxBillingExportAttempts = 5
+
+func (BillingExport) TableName() string {
+	return "BillingExport"
+}
+
+// Validate checks the format and the time range of the export.
+func (e *BillingExport) Validate() error {
+	if err := e.Format.Validate(); err != nil {
+		return err
+	}
+	if !e.StartTime.Before(e.EndTime) {
+		return fmt.Errorf("start time of billing export must be before end time")
+	}
+	if e.EndTime.Sub(e.StartTime) > MaxBillingExportRange {
+		return fmt.Errorf("time range of billing export must not exceed %s", MaxBillingExportRange)
+	}
+	return nil
+}
+
+// MaxBillingExportRange is the longest time range of an export.
+const MaxBillingExportRange = 366 * 24 * time.Hour
+
+func (f BillingExportFormat) Validate() error {
+	if f != BillingExportFormatCSV && f != BillingExportFormatParquet {
+		return fmt.Errorf("invalid billing export format %q", f)
+	}
+	return nil
+}
+
+// BillingExportSchedule exports the billing line items of the previous month on the first day of
+// every month.
+type BillingExportSchedule struct {
+	ID        uuid.UUID           `gorm:"column:id;type:uuid;default:gen_random_uuid();primary_key" json:"id"`
+	UserUID   uuid.UUID           `gorm:"column:userUid;type:uuid;not null;uniqueIndex:idx_billing_export_schedule" json:"userUid"`
+	Workspace string              `gorm:"column:workspace;type:text;not null;default:'';uniqueIndex:idx_billing_export_schedule" json:"workspace,omitempty"`
+	Format    BillingExportFormat `gorm:"column:format;type:text;not null" json:"format"`
+	// LastPeriod is the start of the last month exported by the schedule.
+	LastPeriod *time.Time `gorm:"column:lastPeriod;type:timestamp(3) with time zone" json:"lastPeriod,omitempty"`
+	CreatedAt  time.Time  `gorm:"column:createdAt;type:timestamp(3) with time zone;default:current_timestamp()" json:"createdAt"`
+	UpdatedAt  time.Time  `gorm:"column:updatedAt;type:timestamp(3) with time zone;default:current_timestamp()" json:"updatedAt"`
+}
+
+func (BillingExportSchedule) TableName() string {
+	return "BillingExportSchedule"
+}
+
+// DuePeriod returns the start of the month to export at now, it is the previous month, and false
+// if the month is already exported.
+func (s *BillingExportSchedule) DuePeriod(now time.Time) (time.Time, bool) {
+	period := BudgetPeriod(now).AddDate(0, -1, 0)
+	if s.LastPeriod != nil && !s.LastPeriod.Before(period) {
+		return period, false
+	}
+	return period, true
+}
@@ -0,0 +1,58 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBillingExportScheduleDuePeriod(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 30, 0, 0, time.UTC)
+	february := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	january := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	schedule := BillingExportSchedule{}
+	if period, due := schedule.DuePeriod(now); !due || !period.Equal(february) {
+		t.Errorf("DuePeriod() of new schedule = %s, %v, want %s, true", period, due, february)
+	}
+	schedule.LastPeriod = &january
+	if period, due := schedule.DuePeriod(now); !due || !period.Equal(february) {
+		t.Errorf("DuePeriod() after january = %s, %v, want %s, true", period, due, february)
+	}
+	schedule.LastPeriod = &february
+	if _, due := schedule.DuePeriod(now.AddDate(0, 0, 20)); due {
+		t.Errorf("DuePeriod() after february is due in march")
+	}
+}
+
+func TestBillingExportValidate(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		export BillingExport
+		valid  bool
+	}{
+		{BillingExport{Format: BillingExportFormatCSV, StartTime: start, EndTime: start.AddDate(0, 1, 0)}, true},
+		{BillingExport{Format: BillingExportFormatParquet, StartTime: start, EndTime: start.AddDate(0, 1, 0)}, true},
+		{BillingExport{Format: "xlsx", StartTime: start, EndTime: start.AddDate(0, 1, 0)}, false},
+		{BillingExport{Format: BillingExportFormatCSV, StartTime: start, EndTime: start}, false},
+		{BillingExport{Format: BillingExportFormatCSV, StartTime: start, EndTime: start.AddDate(2, 0, 0)}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.export.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate() of %s %s-%s = %v, want valid %v", tt.export.Format, tt.export.StartTime, tt.export.EndTime, err, tt.valid)
+		}
+	}
+}
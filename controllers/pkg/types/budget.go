@@ -0,0 +1,91 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultBudgetThresholds are the percentages of the budget which are alerted by default.
+var DefaultBudgetThresholds = []int{50, 80, 100}
+
+// Budget is a monthly spending limit of a user in a region, or of one of its workspaces
+// if Workspace is set. The spending is the consumption of the billings of the month.
+type Budget struct {
+	ID        uuid.UUID `gorm:"column:id;type:uuid;default:gen_random_uuid();primary_key" json:"id"`
+	UserUID   uuid.UUID `gorm:"column:userUid;type:uuid;not null;uniqueIndex:idx_budget_scope" json:"userUid"`
+	RegionUID uuid.UUID `gorm:"column:regionUid;type:uuid;not null;uniqueIndex:idx_budget_scope" json:"regionUid"`
+	// Workspace is the namespace of the workspace, empty for the budget of the user.
+	Workspace string `gorm:"column:workspace;type:text;not null;default:'';uniqueIndex:idx_budget_scope" json:"workspace,omitempty"`
+	// MonthlyAmount is the budget of a calendar month, 1000000 = 1¥.
+	MonthlyAmount int64 `gorm:"column:monthlyAmount;type:bigint;not null" json:"monthlyAmount"`
+	// Thresholds are the percentages of MonthlyAmount to alert, default is DefaultBudgetThresholds.
+	Thresholds []int `gorm:"column:thresholds;type:jsonb;serializer:json" json:"thresholds,omitempty"`
+	// HardLimit suspends the namespaces of the budget when the spending reaches MonthlyAmount.
+	HardLimit bool `gorm:"column:hardLimit;type:boolean;not null;default:false" json:"hardLimit"`
+
+	// Period is the start of the month of AlertedThreshold and Suspended.
+	Period time.Time `gorm:"column:period;type:timestamp(3) with time zone" json:"period"`
+	// AlertedThreshold is the highest threshold alerted in Period.
+	AlertedThreshold int `gorm:"column:alertedThreshold;type:integer;not null;default:0" json:"alertedThreshold"`
+	// Suspended is whether the namespaces are suspended by the hard limit.
+	Suspended bool `gorm:"column:suspended;type:boolean;not null;default:false" json:"suspended"`
+
+	CreatedAt time.Time `gorm:"column:createdAt;type:timestamp(3) with time zone;default:current_timestamp()" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"column:updatedAt;type:timestamp(3) with time zone;default:current_timestamp()" json:"updatedAt"`
+}
+
+func (Budget) TableName() string {
+	return "Budget"
+}
+
+// BudgetPeriod returns the start of the month of t in UTC.
+func BudgetPeriod(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// GetThresholds returns the thresholds in ascending order.
+func (b *Budget) GetThresholds() []int {
+	thresholds := b.Thresholds
+	if len(thresholds) == 0 {
+		thresholds = DefaultBudgetThresholds
+	}
+	thresholds = append([]int(nil), thresholds...)
+	sort.Ints(thresholds)
+	return thresholds
+}
+
+// CrossedThreshold returns the highest threshold reached by spent, 0 if none is reached.
+func (b *Budget) CrossedThreshold(spent int64) int {
+	if b.MonthlyAmount <= 0 {
+		return 0
+	}
+	crossed := 0
+	for _, t := range b.GetThresholds() {
+		if t > 0 && spent*100 >= b.MonthlyAmount*int64(t) {
+			crossed = t
+		}
+	}
+	return crossed
+}
+
+// ExceedsHardLimit returns whether spent reaches the budget with the hard limit enabled.
+func (b *Budget) ExceedsHardLimit(spent int64) bool {
+	return b.HardLimit && b.MonthlyAmount > 0 && spent >= b.MonthlyAmount
+}
@@ -0,0 +1,184 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultDebtPolicyName is the policy of the users which are not assigned to a policy.
+const DefaultDebtPolicyName = "default"
+
+// The debt statuses of the stages, they are the same as the debt statuses of the Debt CR.
+const (
+	DebtStatusWarning             = "WarningPeriod"
+	DebtStatusApproachingDeletion = "ApproachingDeletionPeriod"
+	DebtStatusImminentDeletion    = "ImminentDeletionPeriod"
+	DebtStatusFinalDeletion       = "FinalDeletionPeriod"
+)
+
+// DebtStatusOrder is the order of the debt statuses in the lifecycle.
+var DebtStatusOrder = []string{DebtStatusWarning, DebtStatusApproachingDeletion, DebtStatusImminentDeletion, DebtStatusFinalDeletion}
+
+type DebtAction string
+
+const (
+	// DebtActionNotify sends the notice of the stage to the channels of the stage.
+	DebtActionNotify DebtAction = "notify"
+	// DebtActionSuspend suspends the resources of the namespaces of the user.
+	DebtActionSuspend DebtAction = "suspend"
+	// DebtActionDelete marks the namespaces of the user to be released, it is applied again
+	// while the user stays in the stage.
+	DebtActionDelete DebtAction = "delete"
+)
+
+type DebtChannel string
+
+const (
+	DebtChannelNotification DebtChannel = "notification"
+	DebtChannelSMS          DebtChannel = "sms"
+	DebtChannelVMS          DebtChannel = "vms"
+	DebtChannelEmail        DebtChannel = "email"
+)
+
+// DebtStage is a stage of the debt lifecycle of a policy.
+type DebtStage struct {
+	// Status is the debt status of the stage, one of DebtStatusOrder.
+	Status string `json:"status"`
+	// WaitSeconds is the time after entering the previous stage to enter this stage.
+	WaitSeconds int64 `json:"waitSeconds"`
+	// DebtPercent enters this stage before WaitSeconds when the debt reaches the percentage
+	// of the balance of the account, 0 is disabled.
+	DebtPercent int64         `json:"debtPercent,omitempty"`
+	Actions     []DebtAction  `json:"actions,omitempty"`
+	Channels    []DebtChannel `json:"channels,omitempty"`
+}
+
+// HasAction returns whether the stage runs the action.
+func (s *DebtStage) HasAction(action DebtAction) bool {
+	for _, a := range s.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// HasChannel returns whether the notices of the stage are sent to the channel.
+func (s *DebtStage) HasChannel(channel DebtChannel) bool {
+	for _, c := range s.Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// DebtPolicy is a tier of the debt lifecycle. A user in debt enters the stages in order, a
+// policy without the final deletion stage never releases the resources of its users.
+type DebtPolicy struct {
+	Name        string      `gorm:"column:name;type:text;primary_key" json:"name"`
+	Description string      `gorm:"column:description;type:text" json:"description,omitempty"`
+	Stages      []DebtStage `gorm:"column:stages;type:jsonb;serializer:json;not null" json:"stages"`
+	CreatedAt   time.Time   `gorm:"column:createdAt;type:timestamp(3) with time zone;default:current_timestamp()" json:"createdAt"`
+	UpdatedAt   time.Time   `gorm:"column:updatedAt;type:timestamp(3) with time zone;default:current_timestamp()" json:"updatedAt"`
+}
+
+func (DebtPolicy) TableName() string {
+	return "DebtPolicy"
+}
+
+// Validate checks that the stages are in the order of the lifecycle with valid actions and channels.
+func (p *DebtPolicy) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("name of debt policy is empty")
+	}
+	if len(p.Stages) == 0 {
+		return fmt.Errorf("debt policy %s has no stage", p.Name)
+	}
+	last := -1
+	for _, s := range p.Stages {
+		order := debtStatusIndex(s.Status)
+		if order < 0 {
+			return fmt.Errorf("invalid debt status %q of debt policy %s", s.Status, p.Name)
+		}
+		if order <= last {
+			return fmt.Errorf("debt status %s of debt policy %s is out of order", s.Status, p.Name)
+		}
+		last = order
+		if s.WaitSeconds < 0 || s.DebtPercent < 0 {
+			return fmt.Errorf("wait seconds and debt percent of stage %s must not be negative", s.Status)
+		}
+		for _, a := range s.Actions {
+			if a != DebtActionNotify && a != DebtActionSuspend && a != DebtActionDelete {
+				return fmt.Errorf("invalid action %q of stage %s", a, s.Status)
+			}
+		}
+		for _, c := range s.Channels {
+			if c != DebtChannelNotification && c != DebtChannelSMS && c != DebtChannelVMS && c != DebtChannelEmail {
+				return fmt.Errorf("invalid channel %q of stage %s", c, s.Status)
+			}
+		}
+	}
+	return nil
+}
+
+// StageIndex returns the index of the stage of the status, -1 if the policy has no such stage.
+func (p *DebtPolicy) StageIndex(status string) int {
+	for i := range p.Stages {
+		if p.Stages[i].Status == status {
+			return i
+		}
+	}
+	return -1
+}
+
+// NextStage returns the index of the stage to enter from the stage current, -1 is the normal
+// status, or current if the user stays. debt is the owed amount and elapsed is the seconds
+// since entering the current stage.
+func (p *DebtPolicy) NextStage(current int, balance, debt, elapsed int64) int {
+	next := current + 1
+	if next >= len(p.Stages) {
+		return current
+	}
+	s := p.Stages[next]
+	if elapsed >= s.WaitSeconds || (s.DebtPercent > 0 && debt*100 >= balance*s.DebtPercent) {
+		return next
+	}
+	return current
+}
+
+func debtStatusIndex(status string) int {
+	for i := range DebtStatusOrder {
+		if DebtStatusOrder[i] == status {
+			return i
+		}
+	}
+	return -1
+}
+
+// UserDebtPolicy assigns a user to a debt policy.
+type UserDebtPolicy struct {
+	UserUID   uuid.UUID `gorm:"column:userUid;type:uuid;primary_key" json:"userUid"`
+	Policy    string    `gorm:"column:policy;type:text;not null;index" json:"policy"`
+	UpdatedAt time.Time `gorm:"column:updatedAt;type:timestamp(3) with time zone;default:current_timestamp()" json:"updatedAt"`
+}
+
+func (UserDebtPolicy) TableName() string {
+	return "UserDebtPolicy"
+}
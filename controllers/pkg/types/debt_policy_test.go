@@ -0,0 +1,67 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import "testing"
+
+func TestDebtPolicyNextStage(t *testing.T) {
+	policy := DebtPolicy{Name: "enterprise", Stages: []DebtStage{
+		{Status: DebtStatusWarning},
+		{Status: DebtStatusApproachingDeletion, WaitSeconds: 100, DebtPercent: 50},
+		{Status: DebtStatusImminentDeletion, WaitSeconds: 200, Actions: []DebtAction{DebtActionSuspend}},
+	}}
+	if err := policy.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		current                int
+		balance, debt, elapsed int64
+		want                   int
+	}{
+		{-1, 100, 1, 0, 0},
+		{0, 100, 10, 99, 0},
+		{0, 100, 10, 100, 1},
+		// the debt reaches half of the balance
+		{0, 100, 50, 0, 1},
+		{1, 100, 100, 199, 1},
+		{1, 100, 100, 200, 2},
+		// no stage after the last one
+		{2, 100, 1000, 1 << 40, 2},
+	}
+	for _, tt := range tests {
+		if got := policy.NextStage(tt.current, tt.balance, tt.debt, tt.elapsed); got != tt.want {
+			t.Errorf("NextStage(%d, %d, %d, %d) = %d, want %d", tt.current, tt.balance, tt.debt, tt.elapsed, got, tt.want)
+		}
+	}
+	if policy.StageIndex(DebtStatusFinalDeletion) != -1 {
+		t.Errorf("StageIndex() of final deletion = %d, want -1", policy.StageIndex(DebtStatusFinalDeletion))
+	}
+}
+
+func TestDebtPolicyValidate(t *testing.T) {
+	for _, stages := range [][]DebtStage{
+		nil,
+		{{Status: "NormalPeriod"}},
+		{{Status: DebtStatusImminentDeletion}, {Status: DebtStatusWarning}},
+		{{Status: DebtStatusWarning, WaitSeconds: -1}},
+		{{Status: DebtStatusWarning, Actions: []DebtAction{"pause"}}},
+		{{Status: DebtStatusWarning, Channels: []DebtChannel{"fax"}}},
+	} {
+		policy := DebtPolicy{Name: "p", Stages: stages}
+		if err := policy.Validate(); err == nil {
+			t.Errorf("Validate() of stages %+v is accepted", stages)
+		}
+	}
+}
@@ -202,6 +202,10 @@ type PaymentRaw struct {
 	Remark       string       `gorm:"type:text"`
 	ActivityType ActivityType `gorm:"type:text;column:activityType"`
 	Message      string       `gorm:"type:text;not null"`
+	// ChargedAmount is the amount charged by the payment provider in the minor unit of
+	// ChargedCurrency, e.g. 1 $ = 100, the refunds of the payment are refunded in it.
+	ChargedAmount   int64  `gorm:"column:chargedAmount;type:bigint"`
+	ChargedCurrency string `gorm:"column:chargedCurrency;type:text"`
 }
 
 type ActivityType string
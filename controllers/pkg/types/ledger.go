@@ -0,0 +1,105 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LedgerAccount is an account of the double-entry ledger. The balance and the deduction balance
+// of a user are ledger accounts which are credited when they increase, the other ledger accounts
+// are the counterparts of the changes.
+type LedgerAccount string
+
+const (
+	LedgerAccountBalance          LedgerAccount = "balance"
+	LedgerAccountDeductionBalance LedgerAccount = "deduction_balance"
+
+	// LedgerAccountOpening is the counterpart of the balances of a user before the ledger.
+	LedgerAccountOpening  LedgerAccount = "opening"
+	LedgerAccountRecharge LedgerAccount = "recharge"
+	// LedgerAccountGift is the counterpart of the gifts of the recharges, the gift codes, the
+	// activity bonuses and the task rewards.
+	LedgerAccountGift        LedgerAccount = "gift"
+	LedgerAccountConsumption LedgerAccount = "consumption"
+	LedgerAccountRefund      LedgerAccount = "refund"
+	// LedgerAccountTransfer is the clearing account of the transfers between users, it is
+	// balanced by the two sides of every transfer.
+	LedgerAccountTransfer LedgerAccount = "transfer"
+	// LedgerAccountAdjustment is the counterpart of the balance changes made by admins.
+	LedgerAccountAdjustment LedgerAccount = "adjustment"
+)
+
+// LedgerEntry is an immutable entry of the double-entry ledger, the entries are only appended.
+// Each change of a balance writes a transaction of entries whose debits equal their credits.
+type LedgerEntry struct {
+	ID uuid.UUID `gorm:"column:id;type:uuid;default:gen_random_uuid();primary_key" json:"id"`
+	// TransactionID groups the entries of a balance change.
+	TransactionID uuid.UUID     `gorm:"column:transactionId;type:uuid;not null;index" json:"transactionId"`
+	Account       LedgerAccount `gorm:"column:account;type:text;not null;index:idx_ledger_user_account,priority:2" json:"account"`
+	// UserUID is the user whose balance is changed, it is also set on the counterpart entries.
+	UserUID uuid.UUID `gorm:"column:userUid;type:uuid;not null;index:idx_ledger_user_account,priority:1" json:"userUid"`
+	Debit   int64     `gorm:"column:debit;type:bigint;not null;default:0" json:"debit"`
+	Credit  int64     `gorm:"column:credit;type:bigint;not null;default:0" json:"credit"`
+	// Reference is the id of the record which caused the change, e.g. the payment id.
+	Reference string    `gorm:"column:reference;type:text" json:"reference,omitempty"`
+	CreatedAt time.Time `gorm:"column:createdAt;type:timestamp(3) with time zone;default:current_timestamp()" json:"createdAt"`
+}
+
+func (LedgerEntry) TableName() string {
+	return "LedgerEntry"
+}
+
+// NewLedgerEntries returns the balanced entries of changing the ledger account of the user by
+// amount, the account is credited and the counterpart is debited if amount is positive.
+func NewLedgerEntries(userUID uuid.UUID, account, counterpart LedgerAccount, amount int64, reference string) []LedgerEntry {
+	transactionID, now := uuid.New(), time.Now().UTC()
+	entry := LedgerEntry{
+		ID:            uuid.New(),
+		TransactionID: transactionID,
+		Account:       account,
+		UserUID:       userUID,
+		Reference:     reference,
+		CreatedAt:     now,
+	}
+	counterEntry := entry
+	counterEntry.ID, counterEntry.Account = uuid.New(), counterpart
+	if amount >= 0 {
+		entry.Credit, counterEntry.Debit = amount, amount
+	} else {
+		entry.Debit, counterEntry.Credit = -amount, -amount
+	}
+	return []LedgerEntry{entry, counterEntry}
+}
+
+// LedgerDiscrepancy is an account whose stored balances do not match its ledger, found by the
+// daily reconciliation.
+type LedgerDiscrepancy struct {
+	ID        uuid.UUID `gorm:"column:id;type:uuid;default:gen_random_uuid();primary_key" json:"id"`
+	UserUID   uuid.UUID `gorm:"column:userUid;type:uuid;not null;index" json:"userUid"`
+	CheckedAt time.Time `gorm:"column:checkedAt;type:timestamp(3) with time zone;not null;index" json:"checkedAt"`
+	// Balance and DeductionBalance are the stored balances of the account.
+	Balance          int64 `gorm:"column:balance;type:bigint;not null" json:"balance"`
+	DeductionBalance int64 `gorm:"column:deductionBalance;type:bigint;not null" json:"deductionBalance"`
+	// LedgerBalance and LedgerDeductionBalance are the credits minus the debits of the ledger accounts.
+	LedgerBalance          int64 `gorm:"column:ledgerBalance;type:bigint;not null" json:"ledgerBalance"`
+	LedgerDeductionBalance int64 `gorm:"column:ledgerDeductionBalance;type:bigint;not null" json:"ledgerDeductionBalance"`
+}
+
+func (LedgerDiscrepancy) TableName() string {
+	return "LedgerDiscrepancy"
+}
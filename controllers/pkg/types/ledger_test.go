@@ -0,0 +1,46 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNewLedgerEntries(t *testing.T) {
+	user := uuid.New()
+	for _, amount := range []int64{100, -100, 0} {
+		entries := NewLedgerEntries(user, LedgerAccountBalance, LedgerAccountRecharge, amount, "payment")
+		if len(entries) != 2 || entries[0].TransactionID != entries[1].TransactionID {
+			t.Fatalf("entries of %d = %+v, want 2 entries of one transaction", amount, entries)
+		}
+		var debit, credit int64
+		for _, e := range entries {
+			debit += e.Debit
+			credit += e.Credit
+			if e.UserUID != user || e.Reference != "payment" {
+				t.Errorf("entry = %+v, want user %s and reference payment", e, user)
+			}
+		}
+		if debit != credit {
+			t.Errorf("entries of %d are not balanced, debit %d, credit %d", amount, debit, credit)
+		}
+		// the user account increases by amount
+		if got := entries[0].Credit - entries[0].Debit; entries[0].Account != LedgerAccountBalance || got != amount {
+			t.Errorf("balance entry of %d = %+v, want change %d", amount, entries[0], amount)
+		}
+	}
+}
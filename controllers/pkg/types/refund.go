@@ -0,0 +1,103 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const AccountTransactionTypeRefund = "Refund"
+
+const (
+	// RefundStatusPending is a refund whose balance is deducted and whose result of the payment
+	// provider is unknown, it is finished by retrying it with the same refund number.
+	RefundStatusPending   = "PENDING"
+	RefundStatusSucceeded = "SUCCEEDED"
+	// RefundStatusFailed is a refund rejected by the payment provider, its balance is restored.
+	RefundStatusFailed = "FAILED"
+)
+
+// ErrRefundRejected is returned by the refund of the payment provider when the refund is
+// rejected for good, otherwise the refund is kept pending to be retried.
+var ErrRefundRejected = errors.New("refund is rejected by the payment provider")
+
+// Refund is a full or partial refund of a Payment, the amounts are in the unit of the
+// payment, 1000000 = 1¥.
+type Refund struct {
+	ID        uuid.UUID `gorm:"column:id;type:uuid;default:gen_random_uuid();primary_key" json:"id"`
+	PaymentID string    `gorm:"column:paymentId;type:text;not null;index" json:"paymentId"`
+	UserUID   uuid.UUID `gorm:"column:userUid;type:uuid;not null" json:"userUid"`
+	RegionUID uuid.UUID `gorm:"column:regionUid;type:uuid;not null" json:"regionUid"`
+	Method    string    `gorm:"column:method;type:text;not null" json:"method"`
+	TradeNO   string    `gorm:"column:tradeNo;type:text;not null" json:"tradeNo"`
+	// RefundNO is the refund number returned by the payment provider.
+	RefundNO string `gorm:"column:refundNo;type:text;not null" json:"refundNo"`
+	Status   string `gorm:"column:status;type:text;not null;default:'SUCCEEDED'" json:"status"`
+	// Amount is the refunded amount of the payment.
+	Amount int64 `gorm:"column:amount;type:bigint;not null" json:"amount"`
+	// DeductedAmount is the amount deducted from the balance, the refunded amount plus
+	// the gift of the payment in proportion.
+	DeductedAmount int64 `gorm:"column:deductedAmount;type:bigint;not null" json:"deductedAmount"`
+	// ChargedAmount is the amount refunded by the payment provider in the charged currency of
+	// the payment, it is 0 for the payments whose charged amount is unknown.
+	ChargedAmount int64 `gorm:"column:chargedAmount;type:bigint" json:"chargedAmount"`
+
+	// Operator is the admin who issued the refund.
+	Operator  string    `gorm:"column:operator;type:text;not null" json:"operator"`
+	Reason    string    `gorm:"column:reason;type:text;not null" json:"reason"`
+	CreatedAt time.Time `gorm:"column:createdAt;type:timestamp(3) with time zone;default:current_timestamp()" json:"createdAt"`
+}
+
+func (Refund) TableName() string {
+	return "Refund"
+}
+
+// OutRefundNO returns the refund number sent to the payment provider, it is derived from the
+// id so that the retries of the refund are not refunded twice.
+func (r *Refund) OutRefundNO() string {
+	return strings.ReplaceAll(r.ID.String(), "-", "")
+}
+
+// RefundDeduction returns the balance to deduct for refunding amount of the payment, the gift
+// of the payment is taken back in proportion to the refunded amount.
+func RefundDeduction(payment *Payment, amount int64) int64 {
+	if payment.Amount <= 0 || payment.Gift <= 0 {
+		return amount
+	}
+	gift := new(big.Int).Mul(big.NewInt(payment.Gift), big.NewInt(amount))
+	return amount + gift.Quo(gift, big.NewInt(payment.Amount)).Int64()
+}
+
+// RefundCharge returns the amount in the charged currency of the payment to refund amount of the
+// payment, the refunded and refundedCharge are the amount and the charged amount of its other
+// refunds. The charged amount is refunded in proportion to the refunded amount rounded down, and
+// the refund of the rest of the payment refunds the rest of the charged amount, so the refunds
+// never exceed the charged amount whatever the exchange rate is now.
+func RefundCharge(payment *Payment, refunded, refundedCharge, amount int64) int64 {
+	if payment.ChargedAmount <= 0 || payment.Amount <= 0 {
+		return 0
+	}
+	rest := payment.ChargedAmount - refundedCharge
+	if refunded+amount >= payment.Amount {
+		return max(rest, 0)
+	}
+	charge := new(big.Int).Mul(big.NewInt(payment.ChargedAmount), big.NewInt(amount))
+	return max(min(charge.Quo(charge, big.NewInt(payment.Amount)).Int64(), rest), 0)
+}
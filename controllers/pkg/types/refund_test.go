@@ -0,0 +1,78 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRefundDeduction(t *testing.T) {
+	tests := []struct {
+		name    string
+		payment Payment
+		amount  int64
+		want    int64
+	}{
+		{"no gift", Payment{PaymentRaw: PaymentRaw{Amount: 100_000000}}, 30_000000, 30_000000},
+		{"full refund takes the gift back", Payment{PaymentRaw: PaymentRaw{Amount: 100_000000, Gift: 20_000000}}, 100_000000, 120_000000},
+		{"partial refund takes the gift in proportion", Payment{PaymentRaw: PaymentRaw{Amount: 100_000000, Gift: 20_000000}}, 25_000000, 30_000000},
+		{"gift is rounded down", Payment{PaymentRaw: PaymentRaw{Amount: 3, Gift: 1}}, 1, 1},
+		{"empty payment", Payment{}, 10, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RefundDeduction(&tt.payment, tt.amount); got != tt.want {
+				t.Errorf("RefundDeduction() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefundCharge(t *testing.T) {
+	// ¥100 charged as $14.08
+	payment := Payment{PaymentRaw: PaymentRaw{Amount: 100_000000, ChargedAmount: 1408, ChargedCurrency: "usd"}}
+	tests := []struct {
+		name                             string
+		payment                          Payment
+		refunded, refundedCharge, amount int64
+		want                             int64
+	}{
+		{"full refund", payment, 0, 0, 100_000000, 1408},
+		{"partial refund is rounded down", payment, 0, 0, 33_000000, 464},
+		{"last refund refunds the rest", payment, 67_000000, 943, 33_000000, 465},
+		{"refunds never exceed the charge", payment, 50_000000, 1408, 10_000000, 0},
+		{"unknown charge", Payment{PaymentRaw: PaymentRaw{Amount: 100_000000}}, 0, 0, 100_000000, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RefundCharge(&tt.payment, tt.refunded, tt.refundedCharge, tt.amount); got != tt.want {
+				t.Errorf("RefundCharge() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefundOutRefundNO(t *testing.T) {
+	refund := &Refund{ID: uuid.MustParse("0d6b3c7e-8a4f-4d2b-9c1e-5f7a8b9c0d1e")}
+	// the retries of the refund are sent with the same number, which fits the 64 characters of the providers
+	if got := refund.OutRefundNO(); got != "0d6b3c7e8a4f4d2b9c1e5f7a8b9c0d1e" {
+		t.Errorf("OutRefundNO() = %s", got)
+	}
+	if (&Refund{ID: uuid.New()}).OutRefundNO() == refund.OutRefundNO() {
+		t.Errorf("OutRefundNO() of different refunds are equal")
+	}
+}
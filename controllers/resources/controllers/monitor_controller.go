@@ -88,6 +88,8 @@ type MonitorReconciler struct {
 	ObjStorageMetricsClient  *objstorage.MetricsClient
 	ObjStorageUserBackupSize map[string]int64
 	ObjectStorageInstance    string
+	// NodePoolLabel is the label of the nodes whose value is the node pool of the pods on them
+	NodePoolLabel string
 }
 
 type quantity struct {
@@ -99,6 +101,7 @@ const (
 	PrometheusURL         = "PROM_URL"
 	ObjectStorageInstance = "OBJECT_STORAGE_INSTANCE"
 	ConcurrentLimit       = "CONCURRENT_LIMIT"
+	NodePoolLabel         = "NODE_POOL_LABEL"
 )
 
 var concurrentLimit = int64(DefaultConcurrencyLimit)
@@ -128,6 +131,7 @@ func NewMonitorReconciler(mgr ctrl.Manager) (*MonitorReconciler, error) {
 		periodicReconcile:     1 * time.Minute,
 		PromURL:               os.Getenv(PrometheusURL),
 		ObjectStorageInstance: os.Getenv(ObjectStorageInstance),
+		NodePoolLabel:         os.Getenv(NodePoolLabel),
 		NvidiaGpu:             make(map[string]gpu.NvidiaGPU),
 	}
 	concurrentLimit = env.GetInt64EnvWithDefault(ConcurrentLimit, DefaultConcurrencyLimit)
@@ -384,6 +388,7 @@ func (r *MonitorReconciler) monitorResourceUsage(namespace *corev1.Namespace) er
 			Name:       resNamed[name].Name(),
 			ParentType: resNamed[name].ParentType(),
 			ParentName: resNamed[name].ParentName(),
+			NodePool:   resNamed[name].NodePool(),
 		})
 	}
 	return r.DBClient.InsertMonitor(context.Background(), monitors...)
@@ -414,6 +419,7 @@ func (r *MonitorReconciler) monitorPodResourceUsage(namespace string, resUsed ma
 		return fmt.Errorf("failed to list pods: %v", err)
 	}
 
+	nodePools := make(map[string]string)
 	for i := range podList.Items {
 		pod := &podList.Items[i]
 		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded && time.Since(pod.Status.StartTime.Time) > 1*time.Minute {
@@ -421,6 +427,13 @@ func (r *MonitorReconciler) monitorPodResourceUsage(namespace string, resUsed ma
 		}
 		podResNamed := resources.NewResourceNamed(pod)
 		podResNamed.SetInstanceParent(instances)
+		if r.NodePoolLabel != "" {
+			pool, err := r.getNodePool(pod.Spec.NodeName, nodePools)
+			if err != nil {
+				r.Logger.Error(err, "get node pool failed", "pod", pod.Name, "node", pod.Spec.NodeName)
+			}
+			podResNamed.SetNodePool(pool)
+		}
 		resNamed[podResNamed.String()] = podResNamed
 		if resUsed[podResNamed.String()] == nil {
 			resUsed[podResNamed.String()] = initResources()
@@ -452,6 +465,19 @@ func (r *MonitorReconciler) monitorPodResourceUsage(namespace string, resUsed ma
 	return nil
 }
 
+// getNodePool returns the node pool of the node, the node pools are cached in nodePools.
+func (r *MonitorReconciler) getNodePool(nodeName string, nodePools map[string]string) (string, error) {
+	if pool, ok := nodePools[nodeName]; ok {
+		return pool, nil
+	}
+	node := &corev1.Node{}
+	if err := r.Get(context.Background(), client.ObjectKey{Name: nodeName}, node); err != nil {
+		return "", fmt.Errorf("failed to get node: %v", err)
+	}
+	nodePools[nodeName] = node.Labels[r.NodePoolLabel]
+	return nodePools[nodeName], nil
+}
+
 func (r *MonitorReconciler) monitorPVCResourceUsage(namespace string, resUsed map[string]map[corev1.ResourceName]*quantity, resNamed map[string]*resources.ResourceNamed, instances map[string]struct{}) error {
 	pvcList := &corev1.PersistentVolumeClaimList{}
 	if err := r.List(context.Background(), pvcList, &client.ListOptions{
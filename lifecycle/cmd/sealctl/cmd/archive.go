@@ -26,6 +26,7 @@ func newTarCmd() *cobra.Command {
 		compressionF flags.Compression
 		output       string
 		clear        bool
+		digests      bool
 	)
 	cmd := &cobra.Command{
 		Use:           "tar",
@@ -33,12 +34,13 @@ func newTarCmd() *cobra.Command {
 		Args:          cobra.ExactArgs(1),
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return archive.Tar(args[0], output, compressionF, clear)
+			return archive.Tar(args[0], output, compressionF, clear, digests)
 		},
 	}
 	cmd.Flags().Var(&compressionF, "compression", "compression algorithm, available options are tar/gzip/zstd/disable")
 	cmd.Flags().StringVarP(&output, "output", "o", "", "path of archive file")
 	cmd.Flags().BoolVar(&clear, "clear", false, "remove source after compression finished")
+	cmd.Flags().BoolVar(&digests, "digests", false, "write sha256 digests of the files to `output`"+archive.DigestSuffix+", which are verified by untar")
 	_ = cmd.MarkFlagRequired("output")
 	_ = cmd.MarkFlagRequired("compression")
 	return cmd
@@ -51,7 +53,7 @@ func newUntarCmd() *cobra.Command {
 	)
 	cmd := &cobra.Command{
 		Use:           "untar",
-		Short:         "looks for archive files match glob patterns at filesystem path `src`, and unpacks it at `dst`",
+		Short:         "looks for archive files match glob patterns at filesystem path `src`, and unpacks it at `dst`, verifying the digests written by tar if any",
 		Args:          cobra.MinimumNArgs(1),
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -34,6 +34,18 @@ func newInitSystemCmd() *cobra.Command {
 		Use:   "initsystem",
 		Short: "init system management",
 	}
+	initsystemCmd.AddCommand(&cobra.Command{
+		Use:   "name",
+		Short: "print the name of the detected initsystem",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initsystemInit(); err != nil {
+				return err
+			}
+			fmt.Println(initsystemInterface.Name())
+			return nil
+		},
+	})
 	initsystemCmd.AddCommand(createInitSystemSubCommand("enable", func(s string) error {
 		return initsystemInterface.ServiceEnable(s)
 	}))
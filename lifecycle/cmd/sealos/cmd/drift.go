@@ -0,0 +1,54 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/labring/sealos/pkg/checker"
+	"github.com/labring/sealos/pkg/clusterfile"
+)
+
+var exampleDrift = `
+show the differences between the Clusterfile and the live cluster:
+	sealos drift -c default
+
+fix the lvscare static pods and hosts entries which have drifted:
+	sealos drift -c default --fix
+`
+
+func newDriftCmd() *cobra.Command {
+	var fix bool
+	driftCmd := &cobra.Command{
+		Use:     "drift",
+		Short:   "Detect the drift between the Clusterfile and the live cluster",
+		Example: exampleDrift,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cluster, err := clusterfile.GetClusterFromName(clusterName)
+			if err != nil {
+				return fmt.Errorf("get default cluster failed, %v", err)
+			}
+			d := checker.NewDriftChecker(fix)
+			d.Out = cmd.OutOrStdout()
+			return d.Check(cluster, checker.PhasePost)
+		},
+	}
+	driftCmd.Flags().StringVarP(&clusterName, "cluster", "c", "default", "name of cluster to detect drift")
+	driftCmd.Flags().BoolVar(&fix, "fix", false, "reconcile the differences which are safe to fix, i.e. lvscare static pods and hosts entries")
+	return driftCmd
+}
@@ -22,9 +22,9 @@ import (
 	"github.com/spf13/cobra"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/labring/sealos/pkg/audit"
 	"github.com/labring/sealos/pkg/clusterfile"
 	"github.com/labring/sealos/pkg/exec"
-	"github.com/labring/sealos/pkg/ssh"
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 )
 
@@ -53,6 +53,7 @@ func newExecCmd() *cobra.Command {
 		Example: exampleExec,
 		Args:    cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			audit.SetCluster(cluster.Name, cluster.GetAllIPS()...)
 			targets := getTargets(cluster, ips, roles)
 			return runCommand(cluster, targets, args)
 		},
@@ -82,7 +83,7 @@ func getTargets(cluster *v2.Cluster, ips []string, roles []string) []string {
 }
 
 func runCommand(cluster *v2.Cluster, targets []string, args []string) error {
-	execer, err := exec.New(ssh.NewCacheClientFromCluster(cluster, true))
+	execer, err := exec.NewFromCluster(cluster, true)
 	if err != nil {
 		return err
 	}
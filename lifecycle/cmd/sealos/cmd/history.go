@@ -0,0 +1,179 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/modood/table"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/labring/sealos/pkg/audit"
+)
+
+var exampleHistory = `
+list all operations applied to the default cluster:
+	sealos history
+show details of a single operation, including the Clusterfile diff and per-host outcomes:
+	sealos history 20230801120000-abcdef
+specify the cluster name and print as json:
+	sealos history -c my-cluster -o json
+`
+
+func newHistoryCmd() *cobra.Command {
+	var output string
+	historyCmd := &cobra.Command{
+		Use:     "history [ID]",
+		Short:   "Show the audit log of operations applied to cluster",
+		Example: exampleHistory,
+		Args:    cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				record, err := audit.Get(clusterName, args[0])
+				if err != nil {
+					return err
+				}
+				if output == "json" {
+					return printJSON(os.Stdout, record)
+				}
+				printRecord(os.Stdout, record)
+				return nil
+			}
+			records, err := audit.List(clusterName)
+			if err != nil {
+				return err
+			}
+			if output == "json" {
+				return printJSON(os.Stdout, records)
+			}
+			printRecords(records)
+			return nil
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if output != "" && output != "json" {
+				return fmt.Errorf("unsupported output format %s", output)
+			}
+			return nil
+		},
+	}
+	historyCmd.Flags().StringVarP(&clusterName, "cluster", "c", "default", "name of cluster to show history")
+	historyCmd.Flags().StringVarP(&output, "output", "o", "", "output format, optional value: json")
+	return historyCmd
+}
+
+// withAudit records every execution of a mutating command into the audit log of cluster.
+func withAudit(cmd *cobra.Command) *cobra.Command {
+	runE := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		flags := map[string]string{}
+		cmd.Flags().Visit(func(f *pflag.Flag) {
+			flags[f.Name] = f.Value.String()
+		})
+		audit.Begin(cmd.Name(), args, flags)
+		err := runE(cmd, args)
+		audit.Finish(err)
+		return err
+	}
+	return cmd
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+type historyPrint struct {
+	ID       string
+	Start    string
+	User     string
+	Command  string
+	Status   string
+	Duration string
+	Hosts    int
+}
+
+func printRecords(records []audit.Record) {
+	prints := make([]historyPrint, 0, len(records))
+	for _, r := range records {
+		prints = append(prints, historyPrint{
+			ID:       r.ID,
+			Start:    r.StartTime.Format(time.RFC3339),
+			User:     r.User,
+			Command:  r.Command,
+			Status:   r.Status,
+			Duration: r.Duration.Round(time.Second).String(),
+			Hosts:    len(r.Hosts),
+		})
+	}
+	table.OutputA(prints)
+}
+
+type hostPrint struct {
+	Host       string
+	Operations int
+	Failures   int
+	Duration   string
+	LastError  string
+}
+
+func printRecord(w io.Writer, r *audit.Record) {
+	fmt.Fprintf(w, "ID:       %s\n", r.ID)
+	fmt.Fprintf(w, "Cluster:  %s\n", r.Cluster)
+	fmt.Fprintf(w, "User:     %s\n", r.User)
+	fmt.Fprintf(w, "Command:  %s %s\n", r.Command, strings.Join(r.Args, " "))
+	if len(r.Flags) > 0 {
+		fmt.Fprintln(w, "Flags:")
+		keys := make([]string, 0, len(r.Flags))
+		for k := range r.Flags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(w, "  --%s=%s\n", k, r.Flags[k])
+		}
+	}
+	fmt.Fprintf(w, "Start:    %s\n", r.StartTime.Format(time.RFC3339))
+	fmt.Fprintf(w, "Duration: %s\n", r.Duration.Round(time.Millisecond))
+	fmt.Fprintf(w, "Status:   %s\n", r.Status)
+	if r.Error != "" {
+		fmt.Fprintf(w, "Error:    %s\n", r.Error)
+	}
+	if len(r.Hosts) > 0 {
+		fmt.Fprintln(w, "Hosts:")
+		prints := make([]hostPrint, 0, len(r.Hosts))
+		for _, h := range r.Hosts {
+			prints = append(prints, hostPrint{
+				Host:       h.Host,
+				Operations: h.Operations,
+				Failures:   h.Failures,
+				Duration:   h.EndTime.Sub(h.StartTime).Round(time.Millisecond).String(),
+				LastError:  h.LastError,
+			})
+		}
+		fmt.Fprintln(w, table.AsciiTable(prints))
+	}
+	if r.ClusterfileDiff != "" {
+		fmt.Fprintln(w, "Clusterfile diff:")
+		fmt.Fprintln(w, r.ClusterfileDiff)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/progress"
+)
+
+const outputJSONL = "jsonl"
+
+// withProgressOutput adds the --output flag to cmd. With jsonl the progress
+// events are written to the output of cmd as json lines, the logs, the output
+// of the commands run on the hosts and the prompts go to its error output.
+func withProgressOutput(cmd *cobra.Command) *cobra.Command {
+	var output string
+	cmd.Flags().StringVar(&output, "output", "", fmt.Sprintf("output format of the progress, optional value: %s", outputJSONL))
+	runE := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		switch output {
+		case "":
+		case outputJSONL:
+			logger.SetConsoleOutput(cmd.ErrOrStderr())
+			defer progress.AddSink(progress.NewJSONLSink(cmd.OutOrStdout()))()
+		default:
+			return fmt.Errorf("unsupported output format %s", output)
+		}
+		return runE(cmd, args)
+	}
+	return cmd
+}
@@ -63,24 +63,27 @@ func init() {
 		{
 			Message: "Cluster Management Commands:",
 			Commands: []*cobra.Command{
-				newApplyCmd(),
-				newCertCmd(),
-				newRunCmd(),
-				newResetCmd(),
+				withAudit(withProgressOutput(newApplyCmd())),
+				withAudit(newCertCmd()),
+				withAudit(withProgressOutput(newRunCmd())),
+				withAudit(newResetCmd()),
 				newStatusCmd(),
+				withAudit(newDriftCmd()),
+				newHistoryCmd(),
+				newServeCmd(),
 			},
 		},
 		{
 			Message: "Node Management Commands:",
 			Commands: []*cobra.Command{
-				newAddCmd(),
-				newDeleteCmd(),
+				withAudit(withProgressOutput(newAddCmd())),
+				withAudit(withProgressOutput(newDeleteCmd())),
 			},
 		},
 		{
 			Message: "Remote Operation Commands:",
 			Commands: []*cobra.Command{
-				newExecCmd(),
+				withAudit(newExecCmd()),
 				newScpCmd(),
 			},
 		},
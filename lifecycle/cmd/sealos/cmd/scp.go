@@ -24,7 +24,6 @@ import (
 
 	"github.com/labring/sealos/pkg/clusterfile"
 	"github.com/labring/sealos/pkg/exec"
-	"github.com/labring/sealos/pkg/ssh"
 	"github.com/labring/sealos/pkg/types/v1beta1"
 	"github.com/labring/sealos/pkg/utils/logger"
 )
@@ -67,7 +66,7 @@ func newScpCmd() *cobra.Command {
 }
 
 func runCopy(cluster *v1beta1.Cluster, targets []string, args []string) error {
-	execer, err := exec.New(ssh.NewCacheClientFromCluster(cluster, true))
+	execer, err := exec.NewFromCluster(cluster, true)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,133 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/daemon"
+	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/rand"
+)
+
+const serveTokenEnv = "SEALOS_SERVE_TOKEN"
+
+var exampleServe = `
+serve the API on localhost with a generated token stored in $HOME/.sealos/serve-token:
+	sealos serve
+serve the API on all interfaces with TLS and a given token:
+	SEALOS_SERVE_TOKEN=xxx sealos serve --listen :9443 --tls-cert server.crt --tls-key server.key
+run a cluster and follow its progress:
+	curl -H "Authorization: Bearer $TOKEN" -XPOST localhost:9888/v1/clusters/default/run \
+		-d '{"images":["labring/kubernetes:v1.25.0"],"masters":"192.168.0.2"}'
+	curl -H "Authorization: Bearer $TOKEN" localhost:9888/v1/operations/<ID>/events
+`
+
+func newServeCmd() *cobra.Command {
+	var (
+		listen    string
+		tokenFile string
+		tlsCert   string
+		tlsKey    string
+	)
+	serveCmd := &cobra.Command{
+		Use:     "serve",
+		Short:   "Run a daemon which serves the cluster operations as an authenticated REST API",
+		Example: exampleServe,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			token, err := loadServeToken(tokenFile)
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			server, err := daemon.NewServer(ctx, daemon.Options{Token: token})
+			if err != nil {
+				return err
+			}
+			srv := &http.Server{Addr: listen, Handler: server, ReadHeaderTimeout: 10 * time.Second}
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				_ = srv.Shutdown(shutdownCtx)
+			}()
+			logger.Info("serving sealos API on %s", listen)
+			if tlsCert != "" {
+				err = srv.ListenAndServeTLS(tlsCert, tlsKey)
+			} else {
+				err = srv.ListenAndServe()
+			}
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if (tlsCert == "") != (tlsKey == "") {
+				return errors.New("--tls-cert and --tls-key must be set together")
+			}
+			return nil
+		},
+	}
+	setRequireBuildahAnnotation(serveCmd)
+	serveCmd.Flags().StringVar(&listen, "listen", "127.0.0.1:9888", "address to listen on")
+	serveCmd.Flags().StringVar(&tokenFile, "token-file", "",
+		fmt.Sprintf("file of the bearer token, defaults to $%s or a token generated in %s", serveTokenEnv, filepath.Join(constants.WorkDir(), "serve-token")))
+	serveCmd.Flags().StringVar(&tlsCert, "tls-cert", "", "path of the TLS certificate")
+	serveCmd.Flags().StringVar(&tlsKey, "tls-key", "", "path of the TLS private key")
+	return serveCmd
+}
+
+// loadServeToken reads the token from file, or the env, or generates one in the work dir.
+func loadServeToken(file string) (string, error) {
+	if file == "" {
+		if v := os.Getenv(serveTokenEnv); v != "" {
+			return v, nil
+		}
+		file = filepath.Join(constants.WorkDir(), "serve-token")
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+				return "", err
+			}
+			if err = os.WriteFile(file, []byte(rand.Generator(32)+"\n"), 0600); err != nil {
+				return "", err
+			}
+			logger.Info("generated the token of the API in %s", file)
+		}
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", file)
+	}
+	return token, nil
+}
@@ -26,6 +26,8 @@ import (
 	"github.com/labring/sealos/pkg/apply/applydrivers"
 	"github.com/labring/sealos/pkg/clusterfile"
 	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/hooks"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 )
 
 func NewApplierFromFile(cmd *cobra.Command, path string, args *Args) (applydrivers.Interface, error) {
@@ -62,7 +64,7 @@ func NewApplierFromFile(cmd *cobra.Command, path string, args *Args) (applydrive
 	}
 	currentCluster := cf.GetCluster()
 
-	ctx := withCommonContext(cmd.Context(), cmd)
+	ctx := hooks.WithPhase(withCommonContext(cmd.Context(), cmd), v2.HookPhaseApply)
 
 	return &applydrivers.Applier{
 		Context:        ctx,
@@ -26,10 +26,11 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"github.com/labring/sealos/pkg/apply/processor"
+	"github.com/labring/sealos/pkg/audit"
 	"github.com/labring/sealos/pkg/clusterfile"
 	"github.com/labring/sealos/pkg/constants"
 	"github.com/labring/sealos/pkg/exec"
-	"github.com/labring/sealos/pkg/ssh"
+	"github.com/labring/sealos/pkg/hooks"
 	"github.com/labring/sealos/pkg/system"
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 	"github.com/labring/sealos/pkg/utils/confirm"
@@ -83,6 +84,7 @@ type Applier struct {
 func (c *Applier) Apply() error {
 	// clusterErr and appErr should not appear in the same time
 	var clusterErr, appErr error
+	c.setAuditCluster()
 	defer func() {
 		var checkError *processor.CheckError
 		var preProcessError *processor.PreProcessError
@@ -95,9 +97,13 @@ func (c *Applier) Apply() error {
 		c.applyAfter()
 	}()
 	c.initStatus()
+	if clusterErr = c.runHooks(v2.HookStagePre); clusterErr != nil {
+		clusterErr = processor.NewPreProcessError(clusterErr)
+		return clusterErr
+	}
 	if c.ClusterCurrent == nil || c.ClusterCurrent.CreationTimestamp.IsZero() {
 		if !c.ClusterDesired.CreationTimestamp.IsZero() {
-			if yes, _ := confirm.Confirm("Desired cluster CreationTimestamp is not zero, do you want to initialize it again?", "you have canceled to create cluster"); !yes {
+			if yes, _ := confirm.ConfirmContext(c.Context, "Desired cluster CreationTimestamp is not zero, do you want to initialize it again?", "you have canceled to create cluster"); !yes {
 				clusterErr = processor.NewPreProcessError(fmt.Errorf("canceled to create cluster"))
 				return clusterErr
 			}
@@ -112,6 +118,10 @@ func (c *Applier) Apply() error {
 		clusterErr, appErr = c.reconcileCluster()
 		c.ClusterDesired.CreationTimestamp = c.ClusterCurrent.CreationTimestamp
 	}
+	// post hooks are part of the operation, their failure is recorded in the status
+	if clusterErr == nil && appErr == nil {
+		clusterErr = c.runHooks(v2.HookStagePost)
+	}
 	c.updateStatus(clusterErr, appErr)
 
 	// return app error if not nil
@@ -185,6 +195,9 @@ func (c *Applier) reconcileCluster() (clusterErr error, appErr error) {
 		if appErr = c.installApp(c.RunNewImages); appErr != nil {
 			return nil, appErr
 		}
+	} else if err := processor.ValidateValues(c.ClusterFile, c.ClusterCurrent.Status.Mounts); err != nil {
+		// the images were verified and mounted when they were run
+		return processor.NewPreProcessError(err), nil
 	}
 	mj, md := iputils.GetDiffHosts(c.ClusterCurrent.GetMasterIPAndPortList(), c.ClusterDesired.GetMasterIPAndPortList())
 	nj, nd := iputils.GetDiffHosts(c.ClusterCurrent.GetNodeIPAndPortList(), c.ClusterDesired.GetNodeIPAndPortList())
@@ -231,7 +244,7 @@ func (c *Applier) scaleCluster(mj, md, nj, nd []string) error {
 
 	localpath := constants.Clusterfile(c.ClusterDesired.Name)
 	cf := clusterfile.NewClusterFile(localpath)
-	scaleProcessor, err := processor.NewScaleProcessor(cf, c.ClusterDesired.Name, c.ClusterDesired.Spec.Image, mj, md, nj, nd)
+	scaleProcessor, err := processor.NewScaleProcessor(c.Context, cf, c.ClusterDesired.Name, c.ClusterDesired.Spec.Image, mj, md, nj, nd)
 	if err != nil {
 		return err
 	}
@@ -245,6 +258,11 @@ func (c *Applier) scaleCluster(mj, md, nj, nd []string) error {
 }
 
 func (c *Applier) Delete() error {
+	c.setAuditCluster()
+	// a failed pre hook aborts the reset before the cluster is marked deleted and archived
+	if err := c.runHooks(v2.HookStagePre); err != nil {
+		return err
+	}
 	t := metav1.Now()
 	c.ClusterDesired.DeletionTimestamp = &t
 	defer func() {
@@ -256,7 +274,35 @@ func (c *Applier) Delete() error {
 		}
 		_ = os.Rename(cfPath, target)
 	}()
-	return c.deleteCluster()
+	if err := c.deleteCluster(); err != nil {
+		return err
+	}
+	return c.runHooks(v2.HookStagePost)
+}
+
+// setAuditCluster binds the audit log to the cluster and the hosts, desired or
+// current, the operation runs on.
+func (c *Applier) setAuditCluster() {
+	hosts := c.ClusterDesired.GetAllIPS()
+	if c.ClusterCurrent != nil {
+		hosts = append(hosts, c.ClusterCurrent.GetAllIPS()...)
+	}
+	audit.SetCluster(c.ClusterDesired.Name, hosts...)
+}
+
+// runHooks runs the hooks of the phase set in the context of the applier.
+func (c *Applier) runHooks(stage v2.HookStage) error {
+	phase := hooks.GetPhase(c.Context)
+	if phase == "" {
+		return nil
+	}
+	cluster := hooks.Hosts(c.ClusterDesired, c.ClusterCurrent)
+	changed := cluster.GetAllIPS()
+	if phase != v2.HookPhaseReset && c.ClusterCurrent != nil && !c.ClusterCurrent.CreationTimestamp.IsZero() {
+		add, sub := iputils.GetDiffHosts(c.ClusterCurrent.GetAllIPS(), c.ClusterDesired.GetAllIPS())
+		changed = append(add, sub...)
+	}
+	return hooks.Run(cluster, phase, stage, changed)
 }
 
 func (c *Applier) deleteCluster() error {
@@ -283,7 +329,7 @@ func (c *Applier) syncWorkdir() {
 	workDir := constants.ClusterDir(c.ClusterDesired.Name)
 	logger.Debug("sync workdir: %s", workDir)
 	ipList := c.ClusterDesired.GetMasterIPAndPortList()
-	execer, err := exec.New(ssh.NewCacheClientFromCluster(c.ClusterDesired, true))
+	execer, err := exec.NewFromCluster(c.ClusterDesired, true)
 	if err != nil {
 		logger.Error("failed to create ssh client: %v", err)
 	}
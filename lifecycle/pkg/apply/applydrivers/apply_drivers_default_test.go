@@ -0,0 +1,62 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package applydrivers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/exec/fake"
+	"github.com/labring/sealos/pkg/hooks"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+func TestDeleteAbortedByPreHook(t *testing.T) {
+	rootDir := constants.DefaultClusterRootFsDir
+	constants.DefaultClusterRootFsDir = t.TempDir()
+	t.Cleanup(func() {
+		constants.DefaultClusterRootFsDir = rootDir
+	})
+	cluster := &v2.Cluster{}
+	cluster.Name = "default"
+	cluster.Spec.Hosts = []v2.Host{{IPS: []string{"192.168.0.2:22"}, Roles: []string{v2.MASTER, "amd64"}}}
+	cluster.Spec.Hooks = []v2.Hook{{Name: "backup", Phase: v2.HookPhaseReset, Stage: v2.HookStagePre,
+		Commands: []string{"backup etcd"}, FailurePolicy: v2.HookFailurePolicyFail}}
+	cfPath := constants.Clusterfile(cluster.Name)
+	if err := os.MkdirAll(filepath.Dir(cfPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cfPath, []byte("kind: Cluster\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e := fake.NewFromCluster(cluster)
+	defer e.Install()()
+	e.Fail(fake.Failure{Match: "backup"})
+
+	applier := &Applier{Context: hooks.WithPhase(context.Background(), v2.HookPhaseReset), ClusterDesired: cluster}
+	if err := applier.Delete(); err == nil {
+		t.Fatal("Delete() expected the failure of the pre hook")
+	}
+	// the cluster which is not reset is neither marked deleted nor archived
+	if cluster.DeletionTimestamp != nil {
+		t.Errorf("deletion timestamp = %v, want nil", cluster.DeletionTimestamp)
+	}
+	if _, err := os.Stat(cfPath); err != nil {
+		t.Errorf("Clusterfile is archived: %v", err)
+	}
+}
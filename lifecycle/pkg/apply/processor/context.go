@@ -18,9 +18,12 @@ package processor
 
 import "context"
 
-var (
-	commandKey struct{}
-	envKey     struct{}
+type contextKey int
+
+const (
+	commandKey contextKey = iota
+	envKey
+	forceOverrideKey
 )
 
 //nolint:staticcheck
@@ -42,9 +45,22 @@ func WithEnvs(ctx context.Context, envs map[string]string) context.Context {
 }
 
 func GetEnvs(ctx context.Context) map[string]string {
-	v := ctx.Value(commandKey)
+	v := ctx.Value(envKey)
 	if v != nil {
 		return v.(map[string]string)
 	}
 	return nil
 }
+
+// WithForceOverride returns a context in which the installed apps are overridden
+// without confirmation.
+//
+//nolint:staticcheck
+func WithForceOverride(ctx context.Context, force bool) context.Context {
+	return context.WithValue(ctx, forceOverrideKey, force)
+}
+
+func GetForceOverride(ctx context.Context) bool {
+	v, _ := ctx.Value(forceOverrideKey).(bool)
+	return v
+}
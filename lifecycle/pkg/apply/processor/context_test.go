@@ -0,0 +1,35 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContextValues(t *testing.T) {
+	ctx := WithCommands(context.Background(), []string{"cmd"})
+	ctx = WithEnvs(ctx, map[string]string{"k": "v"})
+	ctx = WithForceOverride(ctx, true)
+	if cmds := GetCommands(ctx); len(cmds) != 1 || cmds[0] != "cmd" {
+		t.Errorf("unexpected commands %v", cmds)
+	}
+	if envs := GetEnvs(ctx); envs["k"] != "v" {
+		t.Errorf("unexpected envs %v", envs)
+	}
+	if !GetForceOverride(ctx) || GetForceOverride(context.Background()) {
+		t.Error("unexpected force override")
+	}
+}
@@ -29,7 +29,6 @@ import (
 	"github.com/labring/sealos/pkg/filesystem/rootfs"
 	"github.com/labring/sealos/pkg/guest"
 	"github.com/labring/sealos/pkg/runtime"
-	"github.com/labring/sealos/pkg/runtime/factory"
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 	"github.com/labring/sealos/pkg/utils/logger"
 	"github.com/labring/sealos/pkg/utils/maps"
@@ -42,6 +41,7 @@ type CreateProcessor struct {
 	Runtime     runtime.Interface
 	Guest       guest.Interface
 	ExtraEnvs   map[string]string // parsing from CLI arguments
+	ctx         context.Context
 }
 
 func (c *CreateProcessor) Execute(cluster *v2.Cluster) error {
@@ -49,30 +49,24 @@ func (c *CreateProcessor) Execute(cluster *v2.Cluster) error {
 	if err != nil {
 		return err
 	}
-	for _, f := range pipeLine {
-		if err = f(cluster); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return runPipeline(cluster, pipeLine)
 }
 
-func (c *CreateProcessor) GetPipeLine() ([]func(cluster *v2.Cluster) error, error) {
-	var todoList []func(cluster *v2.Cluster) error
+func (c *CreateProcessor) GetPipeLine() ([]Phase, error) {
+	var todoList []Phase
 	todoList = append(todoList,
 		// c.GetPhasePluginFunc(plugin.PhaseOriginally),
-		c.Check,
-		c.PreProcess,
-		c.RunConfig,
-		c.MountRootfs,
-		c.MirrorRegistry,
-		c.Bootstrap,
+		Phase{"Check", c.Check},
+		Phase{"PreProcess", c.PreProcess},
+		Phase{"RunConfig", c.RunConfig},
+		Phase{"MountRootfs", c.MountRootfs},
+		Phase{"MirrorRegistry", c.MirrorRegistry},
+		Phase{"Bootstrap", c.Bootstrap},
 		// c.GetPhasePluginFunc(plugin.PhasePreInit),
-		c.Init,
-		c.Join,
+		Phase{"Init", c.Init},
+		Phase{"Join", c.Join},
 		// c.GetPhasePluginFunc(plugin.PhasePreGuest),
-		c.RunGuest,
+		Phase{"RunGuest", c.RunGuest},
 		// c.GetPhasePluginFunc(plugin.PhasePostInstall),
 	)
 
@@ -85,7 +79,7 @@ func (c *CreateProcessor) Check(cluster *v2.Cluster) error {
 	// the order doesn't matter
 	ips = append(ips, cluster.GetMasterIPAndPortList()...)
 	ips = append(ips, cluster.GetNodeIPAndPortList()...)
-	return NewCheckError(checker.RunCheckList([]checker.Interface{checker.NewIPsHostChecker(ips), checker.NewContainerdChecker(ips)}, cluster, checker.PhasePre))
+	return NewCheckError(checker.RunCheckList([]checker.Interface{checker.NewIPsHostChecker(c.ctx, ips), checker.NewContainerdChecker(ips)}, cluster, checker.PhasePre))
 }
 
 func (c *CreateProcessor) PreProcess(cluster *v2.Cluster) error {
@@ -97,6 +91,9 @@ func (c *CreateProcessor) preProcess(cluster *v2.Cluster) error {
 	if err := MountClusterImages(c.Buildah, cluster, false); err != nil {
 		return err
 	}
+	if err := ValidateValues(c.ClusterFile, cluster.Status.Mounts); err != nil {
+		return err
+	}
 	// env in cluster.spec will be merged into every mounts object
 	env := maps.FromSlice(cluster.Spec.Env)
 	// extra env must been set at the very first
@@ -104,7 +101,7 @@ func (c *CreateProcessor) preProcess(cluster *v2.Cluster) error {
 		cluster.Status.Mounts[i].Env = maps.Merge(cluster.Status.Mounts[i].Env, env, c.ExtraEnvs)
 	}
 
-	rt, err := factory.New(cluster, c.ClusterFile.GetRuntimeConfig())
+	rt, err := newRuntime(cluster, c.ClusterFile.GetRuntimeConfig())
 	if err != nil {
 		return fmt.Errorf("failed to init runtime, %v", err)
 	}
@@ -190,5 +187,6 @@ func NewCreateProcessor(ctx context.Context, name string, clusterFile clusterfil
 		Buildah:     bder,
 		Guest:       gs,
 		ExtraEnvs:   GetEnvs(ctx),
+		ctx:         ctx,
 	}, nil
 }
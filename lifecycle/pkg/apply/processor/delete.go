@@ -25,10 +25,10 @@ import (
 	"github.com/labring/sealos/pkg/clusterfile"
 	"github.com/labring/sealos/pkg/constants"
 	"github.com/labring/sealos/pkg/filesystem/rootfs"
-	"github.com/labring/sealos/pkg/runtime/factory"
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 	fileutil "github.com/labring/sealos/pkg/utils/file"
 	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/progress"
 	"github.com/labring/sealos/pkg/utils/strings"
 )
 
@@ -47,22 +47,23 @@ func (d DeleteProcessor) Execute(cluster *v2.Cluster) (err error) {
 	}
 	// TODO if error is exec net process ???
 	for _, f := range pipLine {
-		if err = f(cluster); err != nil {
+		f := f
+		if err = progress.RunPhase(f.Name, func() error { return f.Run(cluster) }); err != nil {
 			logger.Warn("failed to exec delete process, %s", err.Error())
 		}
 	}
 
 	return nil
 }
-func (d DeleteProcessor) GetPipeLine() ([]func(cluster *v2.Cluster) error, error) {
-	var todoList []func(cluster *v2.Cluster) error
+func (d DeleteProcessor) GetPipeLine() ([]Phase, error) {
+	var todoList []Phase
 	todoList = append(todoList,
-		d.PreProcess,
-		d.Reset,
-		d.UndoBootstrap,
-		d.UnMountRootfs,
-		d.UnMountImage,
-		d.CleanFS,
+		Phase{"PreProcess", d.PreProcess},
+		Phase{"Reset", d.Reset},
+		Phase{"UndoBootstrap", d.UndoBootstrap},
+		Phase{"UnMountRootfs", d.UnMountRootfs},
+		Phase{"UnMountImage", d.UnMountImage},
+		Phase{"CleanFS", d.CleanFS},
 	)
 	return todoList, nil
 }
@@ -85,7 +86,7 @@ func (d *DeleteProcessor) UndoBootstrap(cluster *v2.Cluster) error {
 }
 
 func (d *DeleteProcessor) Reset(cluster *v2.Cluster) error {
-	rt, err := factory.New(cluster, d.ClusterFile.GetRuntimeConfig())
+	rt, err := newRuntime(cluster, d.ClusterFile.GetRuntimeConfig())
 	if err != nil {
 		return fmt.Errorf("failed to delete runtime, %v", err)
 	}
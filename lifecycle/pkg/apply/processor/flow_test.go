@@ -0,0 +1,364 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/containers/buildah"
+	ociv1 "github.com/opencontainers/image-spec/specs-go/v1"
+
+	sbuildah "github.com/labring/sealos/pkg/buildah"
+	"github.com/labring/sealos/pkg/clusterfile"
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/exec/fake"
+	"github.com/labring/sealos/pkg/runtime"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+const (
+	testRootfsImage = "labring/kubernetes:v1.25.0"
+	testAppImage    = "labring/helm:v3.8.2"
+)
+
+// fakeBuildah mounts the images to local directories with a file of the image name.
+type fakeBuildah struct {
+	sbuildah.Interface
+	dir        string
+	mu         sync.Mutex
+	containers map[string]string
+	deleted    []string
+	// files are written to the mounts of the rootfs image
+	files map[string]string
+}
+
+func newFakeBuildah(t *testing.T) *fakeBuildah {
+	return &fakeBuildah{dir: t.TempDir(), containers: map[string]string{}}
+}
+
+func (b *fakeBuildah) Pull([]string, ...sbuildah.FlagSetter) error {
+	return nil
+}
+
+func (b *fakeBuildah) InspectImage(name string, _ ...string) (*sbuildah.InspectOutput, error) {
+	config := ociv1.ImageConfig{Labels: map[string]string{"sealos.io.type": string(v2.AppImage)}, Cmd: []string{"helm install app"}}
+	if name == testRootfsImage {
+		config.Labels = map[string]string{"sealos.io.type": string(v2.RootfsImage), "sealos.io.version": v2.ImageTypeVersionKeyV1Beta1, v2.ImageKubeVersionKey: "v1.25.0"}
+		config.Cmd = nil
+	}
+	return &sbuildah.InspectOutput{Name: name, OCIv1: &ociv1.Image{Config: config}}, nil
+}
+
+func (b *fakeBuildah) Create(name, image string, _ ...sbuildah.FlagSetter) (buildah.BuilderInfo, error) {
+	mountPoint := filepath.Join(b.dir, name)
+	if err := os.MkdirAll(filepath.Join(mountPoint, "etc"), 0755); err != nil {
+		return buildah.BuilderInfo{}, err
+	}
+	if err := os.WriteFile(filepath.Join(mountPoint, "etc", "image"), []byte(image), 0644); err != nil {
+		return buildah.BuilderInfo{}, err
+	}
+	for name, data := range b.files {
+		if image != testRootfsImage {
+			break
+		}
+		if err := os.WriteFile(filepath.Join(mountPoint, name), []byte(data), 0644); err != nil {
+			return buildah.BuilderInfo{}, err
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.containers[name] = image
+	return buildah.BuilderInfo{Container: name, MountPoint: mountPoint, FromImage: image}, nil
+}
+
+func (b *fakeBuildah) Delete(name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.containers, name)
+	b.deleted = append(b.deleted, name)
+	return nil
+}
+
+func (b *fakeBuildah) VerifyImageSignature(name string, _ [][]byte) (string, error) {
+	return name, nil
+}
+
+// fakeRuntime records the operations of the runtime.
+type fakeRuntime struct {
+	runtime.Interface
+	ops []string
+}
+
+func (r *fakeRuntime) Init() error {
+	r.ops = append(r.ops, "init")
+	return nil
+}
+
+func (r *fakeRuntime) Reset() error {
+	r.ops = append(r.ops, "reset")
+	return nil
+}
+
+func (r *fakeRuntime) ScaleUp(masters, nodes []string) error {
+	r.ops = append(r.ops, fmt.Sprintf("scale up %v %v", masters, nodes))
+	return nil
+}
+
+func (r *fakeRuntime) ScaleDown(masters, nodes []string) error {
+	r.ops = append(r.ops, fmt.Sprintf("scale down %v %v", masters, nodes))
+	return nil
+}
+
+func (r *fakeRuntime) SyncNodeIPVS(masters, nodes []string) error {
+	r.ops = append(r.ops, fmt.Sprintf("sync ipvs %v %v", masters, nodes))
+	return nil
+}
+
+// fakeGuest records the hosts the images are applied to.
+type fakeGuest struct {
+	hosts  []string
+	images []string
+}
+
+func (g *fakeGuest) Apply(_ *v2.Cluster, mounts []v2.MountImage, hosts []string) error {
+	g.hosts = append(g.hosts, hosts...)
+	for _, m := range mounts {
+		g.images = append(g.images, m.ImageName)
+	}
+	return nil
+}
+
+func (g *fakeGuest) Delete(*v2.Cluster) error {
+	return nil
+}
+
+type flowEnv struct {
+	executor *fake.Executor
+	buildah  *fakeBuildah
+	runtime  *fakeRuntime
+}
+
+// setupFlow runs the processors on simulated hosts with a fake runtime.
+func setupFlow(t *testing.T, cluster *v2.Cluster, hosts ...string) *flowEnv {
+	env := &flowEnv{
+		executor: fake.New(hosts),
+		buildah:  newFakeBuildah(t),
+		runtime:  &fakeRuntime{},
+	}
+	env.executor.RespondFunc(`^hostname$`, func(host, _ string) ([]byte, error) {
+		return []byte("node-" + strings.ReplaceAll(host, ".", "-")), nil
+	})
+	env.executor.RespondFunc(`^date \+%s$`, func(string, string) ([]byte, error) {
+		return []byte(strconv.FormatInt(time.Now().Unix(), 10)), nil
+	})
+	env.executor.RespondFunc(`^containerd --version$`, func(string, string) ([]byte, error) {
+		return nil, fmt.Errorf("containerd: command not found")
+	})
+	restore := env.executor.Install()
+	t.Cleanup(restore)
+
+	runtimeRoot, clusterRootfs := constants.DefaultRuntimeRootDir, constants.DefaultClusterRootFsDir
+	constants.DefaultRuntimeRootDir, constants.DefaultClusterRootFsDir = t.TempDir(), "/var/lib/sealos/data"
+	t.Cleanup(func() {
+		constants.DefaultRuntimeRootDir, constants.DefaultClusterRootFsDir = runtimeRoot, clusterRootfs
+	})
+
+	oldNewRuntime := newRuntime
+	newRuntime = func(*v2.Cluster, runtime.Config) (runtime.Interface, error) {
+		return env.runtime, nil
+	}
+	t.Cleanup(func() { newRuntime = oldNewRuntime })
+
+	if err := os.MkdirAll(constants.ClusterDir(cluster.Name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return env
+}
+
+func newTestCluster(masters, nodes []string) *v2.Cluster {
+	cluster := &v2.Cluster{}
+	cluster.Name = "default"
+	cluster.Spec.Image = []string{testRootfsImage, testAppImage}
+	cluster.Spec.Hosts = []v2.Host{{IPS: masters, Roles: []string{v2.MASTER}}}
+	if len(nodes) > 0 {
+		cluster.Spec.Hosts = append(cluster.Spec.Hosts, v2.Host{IPS: nodes, Roles: []string{v2.NODE}})
+	}
+	cluster.Spec.SSH.Port = 22
+	return cluster
+}
+
+func writeClusterfile(t *testing.T, cluster *v2.Cluster) clusterfile.Interface {
+	path := filepath.Join(t.TempDir(), "Clusterfile")
+	data := fmt.Sprintf("apiVersion: apps.sealos.io/v1beta1\nkind: Cluster\nmetadata:\n  name: %s\nspec:\n  image:\n", cluster.Name)
+	for _, img := range cluster.Spec.Image {
+		data += fmt.Sprintf("  - %s\n", img)
+	}
+	data += "  hosts:\n"
+	for _, h := range cluster.Spec.Hosts {
+		data += fmt.Sprintf("  - ips: %q\n    roles: %q\n", h.IPS, h.Roles)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cf := clusterfile.NewClusterFile(path)
+	if err := cf.Process(); err != nil {
+		t.Fatal(err)
+	}
+	return cf
+}
+
+func createCluster(t *testing.T, env *flowEnv, cluster *v2.Cluster) *fakeGuest {
+	guest := &fakeGuest{}
+	p := &CreateProcessor{
+		ClusterFile: writeClusterfile(t, cluster),
+		Buildah:     env.buildah,
+		Guest:       guest,
+		ctx:         context.Background(),
+	}
+	if err := p.Execute(cluster); err != nil {
+		t.Fatalf("create cluster: %v", err)
+	}
+	return guest
+}
+
+func TestCreateProcessor(t *testing.T) {
+	masters, nodes := []string{"192.168.0.2:22"}, []string{"192.168.0.3:22"}
+	cluster := newTestCluster(masters, nodes)
+	env := setupFlow(t, cluster, "192.168.0.2", "192.168.0.3")
+	guest := createCluster(t, env, cluster)
+
+	if len(cluster.Status.Mounts) != 2 || len(env.buildah.containers) != 2 {
+		t.Fatalf("got mounts %+v, containers %v", cluster.Status.Mounts, env.buildah.containers)
+	}
+	rootfs := constants.NewPathResolver(cluster.Name).RootFSPath()
+	for _, host := range []string{"192.168.0.2", "192.168.0.3"} {
+		data, err := env.executor.ReadFile(host, filepath.Join(rootfs, "etc", "image"))
+		if err != nil || string(data) != testRootfsImage {
+			t.Errorf("rootfs on %s = %q, %v", host, data, err)
+		}
+	}
+	wantOps := []string{"init", fmt.Sprintf("scale up %v %v", []string{}, nodes), fmt.Sprintf("sync ipvs %v %v", masters, nodes)}
+	if !reflect.DeepEqual(env.runtime.ops, wantOps) {
+		t.Errorf("runtime ops = %q, want %q", env.runtime.ops, wantOps)
+	}
+	if !reflect.DeepEqual(guest.images, []string{testRootfsImage, testAppImage}) {
+		t.Errorf("guest images = %v", guest.images)
+	}
+	if _, err := os.Stat(constants.Clusterfile(cluster.Name)); err != nil {
+		t.Errorf("Clusterfile is not saved: %v", err)
+	}
+}
+
+func TestCreateProcessorValidateValues(t *testing.T) {
+	cluster := newTestCluster([]string{"192.168.0.2:22"}, nil)
+	env := setupFlow(t, cluster, "192.168.0.2")
+	// the schema is read from the mount of the image, after it was verified
+	env.buildah.files = map[string]string{
+		constants.ValuesSchemaFileName: `{"type": "object", "required": ["clusterName"]}`,
+	}
+	p := &CreateProcessor{
+		ClusterFile: writeClusterfile(t, cluster),
+		Buildah:     env.buildah,
+		Guest:       &fakeGuest{},
+		ctx:         context.Background(),
+	}
+	err := p.Execute(cluster)
+	if err == nil || !strings.Contains(err.Error(), "clusterName") {
+		t.Fatalf("Execute() error = %v, want the error of the values schema", err)
+	}
+	if len(env.runtime.ops) != 0 || env.executor.Exists("192.168.0.2", constants.NewPathResolver(cluster.Name).RootFSPath()) {
+		t.Errorf("hosts are changed with invalid values")
+	}
+}
+
+func TestScaleProcessor(t *testing.T) {
+	masters, nodes := []string{"192.168.0.2:22"}, []string{"192.168.0.3:22"}
+	cluster := newTestCluster(masters, nil)
+	env := setupFlow(t, cluster, "192.168.0.2", "192.168.0.3")
+	createCluster(t, env, cluster)
+	env.runtime.ops = nil
+	cf := writeClusterfile(t, cluster)
+
+	// scale up a node
+	desired := newTestCluster(masters, nodes)
+	desired.Status = *cluster.Status.DeepCopy()
+	guest := &fakeGuest{}
+	up := &ScaleProcessor{ClusterFile: cf, Buildah: env.buildah, Guest: guest, NodesToJoin: nodes, IsScaleUp: true, ctx: context.Background()}
+	if err := up.Execute(desired); err != nil {
+		t.Fatalf("scale up: %v", err)
+	}
+	rootfs := constants.NewPathResolver(cluster.Name).RootFSPath()
+	if !env.executor.Exists("192.168.0.3", filepath.Join(rootfs, "etc", "image")) {
+		t.Errorf("rootfs is not copied to the joined node")
+	}
+	// only the rootfs is applied to the joined node
+	if !reflect.DeepEqual(guest.hosts, nodes) || !reflect.DeepEqual(guest.images, []string{testRootfsImage}) {
+		t.Errorf("guest applied %v to %v", guest.images, guest.hosts)
+	}
+	wantOps := []string{fmt.Sprintf("scale up %v %v", []string(nil), nodes), fmt.Sprintf("sync ipvs %v %v", masters, nodes)}
+	if !reflect.DeepEqual(env.runtime.ops, wantOps) {
+		t.Errorf("scale up runtime ops = %q, want %q", env.runtime.ops, wantOps)
+	}
+
+	// scale down the node
+	env.runtime.ops = nil
+	down := &ScaleProcessor{ClusterFile: writeClusterfile(t, desired), Buildah: env.buildah, NodesToDelete: nodes, ctx: context.Background()}
+	if err := down.Execute(cluster); err != nil {
+		t.Fatalf("scale down: %v", err)
+	}
+	if env.executor.Exists("192.168.0.3", filepath.Join(rootfs, "etc", "image")) {
+		t.Errorf("rootfs is not removed from the deleted node")
+	}
+	if want := []string{fmt.Sprintf("scale down %v %v", []string(nil), nodes)}; !reflect.DeepEqual(env.runtime.ops, want) {
+		t.Errorf("scale down runtime ops = %q, want %q", env.runtime.ops, want)
+	}
+}
+
+func TestDeleteProcessor(t *testing.T) {
+	masters, nodes := []string{"192.168.0.2:22"}, []string{"192.168.0.3:22"}
+	cluster := newTestCluster(masters, nodes)
+	env := setupFlow(t, cluster, "192.168.0.2", "192.168.0.3")
+	createCluster(t, env, cluster)
+	env.runtime.ops = nil
+
+	// a failed host does not stop the deletion of the others
+	env.executor.Fail(fake.Failure{Host: "192.168.0.3", Op: fake.OpCmd})
+	d := DeleteProcessor{ClusterFile: writeClusterfile(t, cluster), Buildah: env.buildah}
+	if err := d.Execute(cluster); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(env.runtime.ops, []string{"reset"}) {
+		t.Errorf("runtime ops = %q, want reset", env.runtime.ops)
+	}
+	rootfs := constants.NewPathResolver(cluster.Name).RootFSPath()
+	if env.executor.Exists("192.168.0.2", filepath.Join(rootfs, "etc", "image")) {
+		t.Errorf("rootfs is not removed from the master")
+	}
+	if len(env.buildah.containers) != 0 || len(env.buildah.deleted) != 2 {
+		t.Errorf("containers = %v, deleted %v", env.buildah.containers, env.buildah.deleted)
+	}
+	if _, err := os.Stat(constants.ClusterDir(cluster.Name)); !os.IsNotExist(err) {
+		t.Errorf("cluster dir is not cleaned: %v", err)
+	}
+}
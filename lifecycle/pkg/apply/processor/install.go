@@ -28,8 +28,8 @@ import (
 	"github.com/labring/sealos/pkg/config"
 	"github.com/labring/sealos/pkg/filesystem/rootfs"
 	"github.com/labring/sealos/pkg/guest"
+	"github.com/labring/sealos/pkg/hooks"
 	"github.com/labring/sealos/pkg/runtime"
-	"github.com/labring/sealos/pkg/runtime/factory"
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 	"github.com/labring/sealos/pkg/utils/confirm"
 	"github.com/labring/sealos/pkg/utils/logger"
@@ -38,6 +38,8 @@ import (
 	stringsutil "github.com/labring/sealos/pkg/utils/strings"
 )
 
+// ForceOverride overrides the installed apps without confirmation, it is the
+// --force flag of the command line, use WithForceOverride for a single operation.
 var ForceOverride bool
 
 type InstallProcessor struct {
@@ -49,6 +51,8 @@ type InstallProcessor struct {
 	NewImages        []string
 	ExtraEnvs        map[string]string // parsing from CLI arguments
 	imagesToOverride []string
+	ctx              context.Context
+	forceOverride    bool
 }
 
 func (c *InstallProcessor) Execute(cluster *v2.Cluster) error {
@@ -56,29 +60,22 @@ func (c *InstallProcessor) Execute(cluster *v2.Cluster) error {
 	if err != nil {
 		return err
 	}
-
-	for _, f := range pipLine {
-		if err = f(cluster); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return runPipeline(cluster, pipLine)
 }
 
-func (c *InstallProcessor) GetPipeLine() ([]func(cluster *v2.Cluster) error, error) {
-	var todoList []func(cluster *v2.Cluster) error
+func (c *InstallProcessor) GetPipeLine() ([]Phase, error) {
+	var todoList []Phase
 	todoList = append(todoList,
-		c.SyncStatusAndCheck,
-		c.ConfirmOverrideApps,
-		c.PreProcess,
-		c.RunConfig,
-		c.MountRootfs,
-		c.MirrorRegistry,
-		c.UpgradeIfNeed,
+		Phase{"SyncStatusAndCheck", c.SyncStatusAndCheck},
+		Phase{"ConfirmOverrideApps", c.ConfirmOverrideApps},
+		Phase{"PreProcess", c.PreProcess},
+		Phase{"RunConfig", c.RunConfig},
+		Phase{"MountRootfs", c.MountRootfs},
+		Phase{"MirrorRegistry", c.MirrorRegistry},
+		Phase{"UpgradeIfNeed", c.UpgradeIfNeed},
 		// i.GetPhasePluginFunc(plugin.PhasePreGuest),
-		c.RunGuest,
-		c.PostProcess,
+		Phase{"RunGuest", c.RunGuest},
+		Phase{"PostProcess", c.PostProcess},
 		// i.GetPhasePluginFunc(plugin.PhasePostInstall),
 	)
 	return todoList, nil
@@ -106,13 +103,13 @@ func (c *InstallProcessor) SyncStatusAndCheck(_ *v2.Cluster) error {
 func (c *InstallProcessor) ConfirmOverrideApps(_ *v2.Cluster) error {
 	logger.Info("Executing ConfirmOverrideApps Pipeline in InstallProcessor")
 
-	if ForceOverride || len(c.imagesToOverride) == 0 {
+	if c.forceOverride || len(c.imagesToOverride) == 0 {
 		return nil
 	}
 
 	prompt := fmt.Sprintf("are you sure to override these following apps? \n%s\t", strings.Join(c.imagesToOverride, "\n"))
 	cancelledMsg := "you have canceled to override these apps"
-	pass, err := confirm.Confirm(prompt, cancelledMsg)
+	pass, err := confirm.ConfirmContext(c.ctx, prompt, cancelledMsg)
 	if err != nil {
 		return err
 	}
@@ -120,18 +117,19 @@ func (c *InstallProcessor) ConfirmOverrideApps(_ *v2.Cluster) error {
 		// return a cancelled error to stop apply process.
 		return ErrCancelled
 	}
-	ForceOverride = true
+	c.forceOverride = true
 	return nil
 }
 
 func (c *InstallProcessor) PreProcess(cluster *v2.Cluster) error {
 	logger.Info("Executing PreProcess Pipeline in InstallProcessor")
-	if err := c.Buildah.Pull(c.NewImages, buildah.WithPullPolicyOption(buildah.PullIfMissing.String())); err != nil {
+	refs, err := pullClusterImages(c.Buildah, cluster, c.NewImages)
+	if err != nil {
 		return err
 	}
 	imageTypes := sets.NewString()
 	for _, image := range c.NewImages {
-		oci, err := c.Buildah.InspectImage(image)
+		oci, err := c.Buildah.InspectImage(refs[image])
 		if err != nil {
 			return err
 		}
@@ -163,7 +161,7 @@ func (c *InstallProcessor) PreProcess(cluster *v2.Cluster) error {
 		index, mount := cluster.FindImage(img)
 		var ctrName string
 		if mount != nil {
-			if !ForceOverride {
+			if !c.forceOverride {
 				continue
 			}
 			logger.Debug("trying to override app %s", img)
@@ -173,7 +171,7 @@ func (c *InstallProcessor) PreProcess(cluster *v2.Cluster) error {
 		}
 		ctrName = rand.Generator(8)
 		cluster.Spec.Image = stringsutil.Merge(cluster.Spec.Image, img)
-		bderInfo, err := c.Buildah.Create(ctrName, img)
+		bderInfo, err := c.Buildah.Create(ctrName, refs[img])
 		if err != nil {
 			return err
 		}
@@ -183,7 +181,7 @@ func (c *InstallProcessor) PreProcess(cluster *v2.Cluster) error {
 			ImageName:  img,
 		}
 
-		if err = OCIToImageMount(c.Buildah, mount); err != nil {
+		if err = ociToImageMount(c.Buildah, mount, refs[img]); err != nil {
 			return err
 		}
 		mount.Env = maps.Merge(mount.Env, c.ExtraEnvs)
@@ -194,8 +192,11 @@ func (c *InstallProcessor) PreProcess(cluster *v2.Cluster) error {
 		cluster.Status.Mounts = append(cluster.Status.Mounts, *mount)
 		c.NewMounts = append(c.NewMounts, *mount)
 	}
+	if err := ValidateValues(c.ClusterFile, cluster.Status.Mounts); err != nil {
+		return err
+	}
 
-	rt, err := factory.New(cluster, c.ClusterFile.GetRuntimeConfig())
+	rt, err := newRuntime(cluster, c.ClusterFile.GetRuntimeConfig())
 	if err != nil {
 		return fmt.Errorf("failed to init runtime, %v", err)
 	}
@@ -210,11 +211,17 @@ func (c *InstallProcessor) UpgradeIfNeed(cluster *v2.Cluster) error {
 		if version == "" {
 			continue
 		}
+		if err := hooks.Run(cluster, v2.HookPhaseUpgrade, v2.HookStagePre, nil); err != nil {
+			return err
+		}
 		err := c.Runtime.Upgrade(version)
 		if err != nil {
 			logger.Error("upgrade cluster failed")
 			return err
 		}
+		if err = hooks.Run(cluster, v2.HookPhaseUpgrade, v2.HookStagePost, nil); err != nil {
+			return err
+		}
 		//upgrade success; replace the old cluster mount
 		cluster.ReplaceRootfsImage()
 	}
@@ -282,10 +289,12 @@ func NewInstallProcessor(ctx context.Context, clusterFile clusterfile.Interface,
 	}
 
 	return &InstallProcessor{
-		ClusterFile: clusterFile,
-		Buildah:     bder,
-		Guest:       gs,
-		NewImages:   images,
-		ExtraEnvs:   GetEnvs(ctx),
+		ClusterFile:   clusterFile,
+		Buildah:       bder,
+		Guest:         gs,
+		NewImages:     images,
+		ExtraEnvs:     GetEnvs(ctx),
+		ctx:           ctx,
+		forceOverride: ForceOverride || GetForceOverride(ctx),
 	}, nil
 }
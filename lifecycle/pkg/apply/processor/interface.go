@@ -27,12 +27,13 @@ import (
 	"github.com/labring/sealos/pkg/constants"
 	"github.com/labring/sealos/pkg/exec"
 	"github.com/labring/sealos/pkg/filesystem/registry"
-	"github.com/labring/sealos/pkg/ssh"
+	"github.com/labring/sealos/pkg/runtime/factory"
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 	"github.com/labring/sealos/pkg/utils/confirm"
 	"github.com/labring/sealos/pkg/utils/file"
 	"github.com/labring/sealos/pkg/utils/logger"
 	"github.com/labring/sealos/pkg/utils/maps"
+	"github.com/labring/sealos/pkg/utils/progress"
 	"github.com/labring/sealos/pkg/utils/rand"
 )
 
@@ -41,6 +42,26 @@ type Interface interface {
 	Execute(cluster *v2.Cluster) error
 }
 
+// newRuntime creates the runtime of the cluster, replaced in tests.
+var newRuntime = factory.New
+
+// Phase is a step of a pipeline, Name is the phase reported in the progress events.
+type Phase struct {
+	Name string
+	Run  func(cluster *v2.Cluster) error
+}
+
+// runPipeline runs the pipeline in order, each step of it is reported as a progress phase.
+func runPipeline(cluster *v2.Cluster, pipeline []Phase) error {
+	for _, f := range pipeline {
+		f := f
+		if err := progress.RunPhase(f.Name, func() error { return f.Run(cluster) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // compatible with older sealos versions
 func SyncNewVersionConfig(clusterName string) {
 	d := constants.NewPathResolver(clusterName)
@@ -102,7 +123,13 @@ func inspectImage(iist imageInspector, image string) (*buildah.InspectOutput, er
 }
 
 func OCIToImageMount(inspector imageInspector, mount *v2.MountImage) error {
-	oci, err := inspectImage(inspector, mount.ImageName)
+	return ociToImageMount(inspector, mount, mount.ImageName)
+}
+
+// ociToImageMount fills the mount with the config of the image ref, which is the verified
+// reference of the image of the mount.
+func ociToImageMount(inspector imageInspector, mount *v2.MountImage, ref string) error {
+	oci, err := inspectImage(inspector, ref)
 	if err != nil {
 		return err
 	}
@@ -140,10 +167,16 @@ func OCIToImageMount(inspector imageInspector, mount *v2.MountImage) error {
 }
 
 func ConfirmDeleteNodes() error {
-	if !ForceDelete {
+	return ConfirmDeleteNodesContext(context.Background(), ForceDelete)
+}
+
+// ConfirmDeleteNodesContext confirms the deletion of the nodes unless force, by the
+// Confirmer of ctx or the terminal.
+func ConfirmDeleteNodesContext(ctx context.Context, force bool) error {
+	if !force {
 		prompt := "are you sure to delete these nodes?"
 		cancel := "you have canceled to delete these nodes !"
-		if pass, err := confirm.Confirm(prompt, cancel); err != nil {
+		if pass, err := confirm.ConfirmContext(ctx, prompt, cancel); err != nil {
 			return err
 		} else if !pass {
 			return ErrCancelled
@@ -155,8 +188,7 @@ func ConfirmDeleteNodes() error {
 func MirrorRegistry(cluster *v2.Cluster, mounts []v2.MountImage) error {
 	registries := cluster.GetRegistryIPAndPortList()
 	logger.Debug("registry nodes is: %+v", registries)
-	sshClient := ssh.NewCacheClientFromCluster(cluster, true)
-	execer, err := exec.New(sshClient)
+	execer, err := exec.NewFromCluster(cluster, true)
 	if err != nil {
 		return err
 	}
@@ -203,7 +235,7 @@ func MountClusterImages(bdah buildah.Interface, cluster *v2.Cluster, skipApp boo
 			continue
 		}
 
-		err = bdah.Pull([]string{img}, buildah.WithPullPolicyOption(buildah.PullIfMissing.String()))
+		refs, err := pullClusterImages(bdah, cluster, []string{img})
 		if err != nil {
 			return err
 		}
@@ -216,7 +248,7 @@ func MountClusterImages(bdah buildah.Interface, cluster *v2.Cluster, skipApp boo
 		}
 		// recreate container anyway, this function call will remount the mount point of the image
 		// since after the host reboot, the `merged` dir will become an empty dir when we using `overlayfs` as driver
-		bderInfo, err := bdah.Create(ctrName, img)
+		bderInfo, err := bdah.Create(ctrName, refs[img])
 		if err != nil {
 			return err
 		}
@@ -225,7 +257,7 @@ func MountClusterImages(bdah buildah.Interface, cluster *v2.Cluster, skipApp boo
 			ImageName:  img,
 			MountPoint: bderInfo.MountPoint,
 		}
-		if err = OCIToImageMount(bdah, mount); err != nil {
+		if err = ociToImageMount(bdah, mount, refs[img]); err != nil {
 			return err
 		}
 		if idx >= 0 {
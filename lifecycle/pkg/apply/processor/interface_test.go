@@ -0,0 +1,40 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPipelineNames(t *testing.T) {
+	create, _ := (&CreateProcessor{}).GetPipeLine()
+	remove, _ := DeleteProcessor{}.GetPipeLine()
+	for _, tc := range []struct {
+		pipeline []Phase
+		want     string
+	}{
+		{create, "Check,PreProcess,RunConfig,MountRootfs,MirrorRegistry,Bootstrap,Init,Join,RunGuest"},
+		{remove, "PreProcess,Reset,UndoBootstrap,UnMountRootfs,UnMountImage,CleanFS"},
+	} {
+		var names []string
+		for _, p := range tc.pipeline {
+			names = append(names, p.Name)
+		}
+		if got := strings.Join(names, ","); got != tc.want {
+			t.Errorf("got phases %s, want %s", got, tc.want)
+		}
+	}
+}
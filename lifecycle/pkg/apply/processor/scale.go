@@ -29,7 +29,6 @@ import (
 	"github.com/labring/sealos/pkg/filesystem/rootfs"
 	"github.com/labring/sealos/pkg/guest"
 	"github.com/labring/sealos/pkg/runtime"
-	"github.com/labring/sealos/pkg/runtime/factory"
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 	fileutil "github.com/labring/sealos/pkg/utils/file"
 	"github.com/labring/sealos/pkg/utils/logger"
@@ -47,6 +46,7 @@ type ScaleProcessor struct {
 	NodesToDelete   []string
 	IsScaleUp       bool
 	Guest           guest.Interface
+	ctx             context.Context
 }
 
 func (c *ScaleProcessor) Execute(cluster *v2.Cluster) error {
@@ -54,41 +54,34 @@ func (c *ScaleProcessor) Execute(cluster *v2.Cluster) error {
 	if err != nil {
 		return err
 	}
-
-	for _, f := range pipLine {
-		if err = f(cluster); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return runPipeline(cluster, pipLine)
 }
 
-func (c *ScaleProcessor) GetPipeLine() ([]func(cluster *v2.Cluster) error, error) {
-	var todoList []func(cluster *v2.Cluster) error
+func (c *ScaleProcessor) GetPipeLine() ([]Phase, error) {
+	var todoList []Phase
 	if c.IsScaleUp {
 		todoList = append(todoList,
-			c.JoinCheck,
-			c.PreProcess,
-			c.PreProcessImage,
-			c.RunConfig,
-			c.MountRootfs,
-			c.Bootstrap,
+			Phase{"JoinCheck", c.JoinCheck},
+			Phase{"PreProcess", c.PreProcess},
+			Phase{"PreProcessImage", c.PreProcessImage},
+			Phase{"RunConfig", c.RunConfig},
+			Phase{"MountRootfs", c.MountRootfs},
+			Phase{"Bootstrap", c.Bootstrap},
 			//s.GetPhasePluginFunc(plugin.PhasePreJoin),
-			c.Join,
-			c.RunGuest,
+			Phase{"Join", c.Join},
+			Phase{"RunGuest", c.RunGuest},
 			//s.GetPhasePluginFunc(plugin.PhasePostJoin),
 		)
 		return todoList, nil
 	}
 
 	todoList = append(todoList,
-		c.DeleteCheck,
-		c.PreProcess,
-		c.Delete,
-		c.UndoBootstrap,
+		Phase{"DeleteCheck", c.DeleteCheck},
+		Phase{"PreProcess", c.PreProcess},
+		Phase{"Delete", c.Delete},
+		Phase{"UndoBootstrap", c.UndoBootstrap},
 		//c.ApplyCleanPlugin,
-		c.UnMountRootfs,
+		Phase{"UnMountRootfs", c.UnMountRootfs},
 	)
 	return todoList, nil
 }
@@ -158,7 +151,7 @@ func (c *ScaleProcessor) JoinCheck(cluster *v2.Cluster) error {
 	ips = append(ips, cluster.GetMaster0IPAndPort())
 	scales = append(c.MastersToJoin, c.NodesToJoin...)
 	ips = append(ips, scales...)
-	return NewCheckError(checker.RunCheckList([]checker.Interface{checker.NewIPsHostChecker(ips), checker.NewContainerdChecker(scales)}, cluster, checker.PhasePre))
+	return NewCheckError(checker.RunCheckList([]checker.Interface{checker.NewIPsHostChecker(c.ctx, ips), checker.NewContainerdChecker(scales)}, cluster, checker.PhasePre))
 }
 
 func (c *ScaleProcessor) DeleteCheck(cluster *v2.Cluster) error {
@@ -167,7 +160,7 @@ func (c *ScaleProcessor) DeleteCheck(cluster *v2.Cluster) error {
 	ips = append(ips, cluster.GetMaster0IPAndPort())
 	//ips = append(ips, c.MastersToDelete...)
 	//ips = append(ips, c.NodesToDelete...)
-	return NewCheckError(checker.RunCheckList([]checker.Interface{checker.NewIPsHostChecker(ips)}, cluster, checker.PhasePre))
+	return NewCheckError(checker.RunCheckList([]checker.Interface{checker.NewIPsHostChecker(c.ctx, ips)}, cluster, checker.PhasePre))
 }
 
 func (c *ScaleProcessor) PreProcess(cluster *v2.Cluster) error {
@@ -205,9 +198,9 @@ func (c *ScaleProcessor) preProcess(cluster *v2.Cluster) error {
 
 	var rt runtime.Interface
 	if c.IsScaleUp {
-		rt, err = factory.New(cluster, c.ClusterFile.GetRuntimeConfig())
+		rt, err = newRuntime(cluster, c.ClusterFile.GetRuntimeConfig())
 	} else {
-		rt, err = factory.New(c.ClusterFile.GetCluster(), c.ClusterFile.GetRuntimeConfig())
+		rt, err = newRuntime(c.ClusterFile.GetCluster(), c.ClusterFile.GetRuntimeConfig())
 	}
 	if err != nil {
 		return fmt.Errorf("failed to init runtime: %v", err)
@@ -226,7 +219,11 @@ func (c *ScaleProcessor) PreProcessImage(cluster *v2.Cluster) error {
 		}
 		dirs, _ := fileutil.GetAllSubDirs(mount.MountPoint)
 		if len(dirs) == 0 {
-			clusterManifest, err := c.Buildah.Create(mount.Name, mount.ImageName)
+			refs, err := pullClusterImages(c.Buildah, cluster, []string{mount.ImageName})
+			if err != nil {
+				return err
+			}
+			clusterManifest, err := c.Buildah.Create(mount.Name, refs[mount.ImageName])
 			if err != nil {
 				return err
 			}
@@ -297,7 +294,7 @@ func (c *ScaleProcessor) UndoBootstrap(_ *v2.Cluster) error {
 	return bs.Delete(hosts...)
 }
 
-func NewScaleProcessor(clusterFile clusterfile.Interface, name string, images v2.ImageList, masterToJoin, masterToDelete, nodeToJoin, nodeToDelete []string) (Interface, error) {
+func NewScaleProcessor(ctx context.Context, clusterFile clusterfile.Interface, name string, images v2.ImageList, masterToJoin, masterToDelete, nodeToJoin, nodeToDelete []string) (Interface, error) {
 	bder, err := buildah.New(name)
 	if err != nil {
 		return nil, err
@@ -317,5 +314,6 @@ func NewScaleProcessor(clusterFile clusterfile.Interface, name string, images v2
 		pullImages:      images,
 		IsScaleUp:       len(masterToJoin) > 0 || len(nodeToJoin) > 0,
 		Guest:           gs,
+		ctx:             ctx,
 	}, nil
 }
@@ -0,0 +1,49 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/labring/sealos/pkg/clusterfile"
+	"github.com/labring/sealos/pkg/constants"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+// ValidateValues validates the values of the Clusterfile against the schemas shipped
+// in the mounted images, the images without a schema are skipped. The images are
+// mounted by the processors after they were verified, so no image is read before
+// its verification.
+func ValidateValues(cf clusterfile.Interface, mounts []v2.MountImage) error {
+	schemas := make(map[string][]byte)
+	for _, m := range mounts {
+		if m.MountPoint == "" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(m.MountPoint, constants.ValuesSchemaFileName))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		logger.Debug("found values schema in image %s", m.ImageName)
+		schemas[m.ImageName] = data
+	}
+	return cf.ValidateValues(schemas)
+}
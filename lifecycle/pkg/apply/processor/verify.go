@@ -0,0 +1,109 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/labring/sealos/pkg/buildah"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+type signatureVerifier interface {
+	VerifyImageSignature(name string, publicKeys [][]byte) (string, error)
+}
+
+type verifiedPuller interface {
+	signatureVerifier
+	Pull(imageNames []string, opts ...buildah.FlagSetter) error
+}
+
+// pullClusterImages verifies the images by the policy of the cluster and pulls the missing ones,
+// it is the only way the processors pull the images of the cluster. It returns the references to
+// create the containers from by image, the verified images are pulled and mounted by their digests.
+func pullClusterImages(bdah verifiedPuller, cluster *v2.Cluster, images []string) (map[string]string, error) {
+	refs, err := VerifyClusterImages(bdah, cluster, images)
+	if err != nil {
+		return nil, err
+	}
+	pulls := make([]string, 0, len(images))
+	for _, img := range images {
+		pulls = append(pulls, refs[img])
+	}
+	if err = bdah.Pull(pulls, buildah.WithPullPolicyOption(buildah.PullIfMissing.String())); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+// VerifyClusterImages enforces the image verification policy of the cluster on images, it must be
+// called before the images are pulled and mounted. It returns the reference to use by image, the
+// digested reference for the verified images and the image itself for the others.
+func VerifyClusterImages(verifier signatureVerifier, cluster *v2.Cluster, images []string) (map[string]string, error) {
+	refs := make(map[string]string, len(images))
+	for _, img := range images {
+		refs[img] = img
+	}
+	policy := cluster.Spec.ImageVerification
+	if policy == nil || policy.Mode == "" || policy.Mode == v2.ImageVerificationDisabled {
+		return refs, nil
+	}
+	if policy.Mode != v2.ImageVerificationWarn && policy.Mode != v2.ImageVerificationEnforce {
+		return nil, fmt.Errorf("unknown image verification mode %s", policy.Mode)
+	}
+	keys, err := loadPublicKeys(policy)
+	if err != nil {
+		return nil, err
+	}
+	for _, img := range images {
+		if slices.Contains(policy.Exclude, img) {
+			logger.Debug("skip verifying signature of image %s", img)
+			continue
+		}
+		ref, err := verifier.VerifyImageSignature(img, keys)
+		if err != nil {
+			if policy.Mode == v2.ImageVerificationWarn {
+				logger.Warn("failed to verify signature of image %s: %v", img, err)
+				continue
+			}
+			return nil, fmt.Errorf("failed to verify signature of image %s: %w", img, err)
+		}
+		logger.Info("signature of image %s is verified as %s", img, ref)
+		refs[img] = ref
+	}
+	return refs, nil
+}
+
+func loadPublicKeys(policy *v2.ImageVerification) ([][]byte, error) {
+	var keys [][]byte
+	for _, p := range policy.PublicKeys {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public key: %w", err)
+		}
+		keys = append(keys, data)
+	}
+	for _, data := range policy.PublicKeyData {
+		keys = append(keys, []byte(data))
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("image verification is %s but no public keys are configured", policy.Mode)
+	}
+	return keys, nil
+}
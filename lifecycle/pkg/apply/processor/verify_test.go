@@ -0,0 +1,135 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/labring/sealos/pkg/buildah"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+type fakeVerifier struct {
+	signed   map[string]bool
+	verified []string
+}
+
+func (f *fakeVerifier) VerifyImageSignature(name string, publicKeys [][]byte) (string, error) {
+	f.verified = append(f.verified, name)
+	if len(publicKeys) == 0 {
+		return "", errors.New("no public keys")
+	}
+	if !f.signed[name] {
+		return "", errors.New("not signed")
+	}
+	return digestRef(name), nil
+}
+
+func digestRef(name string) string {
+	return strings.Split(name, ":")[0] + "@sha256:" + strings.Repeat("0", 64)
+}
+
+type fakePuller struct {
+	fakeVerifier
+	pulled []string
+}
+
+func (f *fakePuller) Pull(imageNames []string, _ ...buildah.FlagSetter) error {
+	f.pulled = append(f.pulled, imageNames...)
+	return nil
+}
+
+func TestVerifyClusterImages(t *testing.T) {
+	images := []string{"labring/kubernetes:v1.25.0", "labring/helm:v3.8.2"}
+	tests := []struct {
+		name         string
+		policy       *v2.ImageVerification
+		wantErr      bool
+		wantVerified int
+	}{
+		{
+			name:         "no policy",
+			policy:       nil,
+			wantVerified: 0,
+		},
+		{
+			name:         "disabled",
+			policy:       &v2.ImageVerification{Mode: v2.ImageVerificationDisabled, PublicKeyData: []string{"key"}},
+			wantVerified: 0,
+		},
+		{
+			name:         "enforce with unsigned image",
+			policy:       &v2.ImageVerification{Mode: v2.ImageVerificationEnforce, PublicKeyData: []string{"key"}},
+			wantErr:      true,
+			wantVerified: 2,
+		},
+		{
+			name:         "enforce with excluded unsigned image",
+			policy:       &v2.ImageVerification{Mode: v2.ImageVerificationEnforce, PublicKeyData: []string{"key"}, Exclude: []string{"labring/helm:v3.8.2"}},
+			wantVerified: 1,
+		},
+		{
+			name:         "warn with unsigned image",
+			policy:       &v2.ImageVerification{Mode: v2.ImageVerificationWarn, PublicKeyData: []string{"key"}},
+			wantVerified: 2,
+		},
+		{
+			name:    "enforce without keys",
+			policy:  &v2.ImageVerification{Mode: v2.ImageVerificationEnforce},
+			wantErr: true,
+		},
+		{
+			name:    "unknown mode",
+			policy:  &v2.ImageVerification{Mode: "Audit", PublicKeyData: []string{"key"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &v2.Cluster{}
+			cluster.Spec.ImageVerification = tt.policy
+			verifier := &fakeVerifier{signed: map[string]bool{"labring/kubernetes:v1.25.0": true}}
+			_, err := VerifyClusterImages(verifier, cluster, images)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyClusterImages() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(verifier.verified) != tt.wantVerified {
+				t.Errorf("VerifyClusterImages() verified %v, want %d images", verifier.verified, tt.wantVerified)
+			}
+		})
+	}
+}
+
+func TestPullClusterImages(t *testing.T) {
+	images := []string{"labring/kubernetes:v1.25.0", "labring/helm:v3.8.2"}
+	cluster := &v2.Cluster{}
+	cluster.Spec.ImageVerification = &v2.ImageVerification{Mode: v2.ImageVerificationWarn, PublicKeyData: []string{"key"}}
+	puller := &fakePuller{fakeVerifier: fakeVerifier{signed: map[string]bool{"labring/kubernetes:v1.25.0": true}}}
+	refs, err := pullClusterImages(puller, cluster, images)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the verified image is pulled and mounted by the digest it was verified with
+	want := map[string]string{images[0]: digestRef(images[0]), images[1]: images[1]}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("pullClusterImages() = %v, want %v", refs, want)
+	}
+	if wantPulled := []string{digestRef(images[0]), images[1]}; !reflect.DeepEqual(puller.pulled, wantPulled) {
+		t.Errorf("pulled %v, want %v", puller.pulled, wantPulled)
+	}
+}
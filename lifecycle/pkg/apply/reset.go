@@ -23,7 +23,9 @@ import (
 	"github.com/labring/sealos/pkg/apply/applydrivers"
 	"github.com/labring/sealos/pkg/clusterfile"
 	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/hooks"
 	"github.com/labring/sealos/pkg/ssh"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 	"github.com/labring/sealos/pkg/utils/logger"
 )
 
@@ -46,7 +48,7 @@ func NewApplierFromResetArgs(cmd *cobra.Command, args *ResetArgs) (applydrivers.
 	if err = c.resetArgs(cmd, args); err != nil {
 		return nil, err
 	}
-	return applydrivers.NewDefaultApplier(cmd.Context(), c.cluster, cf, nil)
+	return applydrivers.NewDefaultApplier(hooks.WithPhase(cmd.Context(), v2.HookPhaseReset), c.cluster, cf, nil)
 }
 
 func (r *ClusterArgs) resetArgs(cmd *cobra.Command, args *ResetArgs) error {
@@ -29,7 +29,7 @@ import (
 	"github.com/labring/sealos/pkg/clusterfile"
 	"github.com/labring/sealos/pkg/constants"
 	"github.com/labring/sealos/pkg/exec"
-	"github.com/labring/sealos/pkg/ssh"
+	"github.com/labring/sealos/pkg/hooks"
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 	"github.com/labring/sealos/pkg/utils/iputils"
 	"github.com/labring/sealos/pkg/utils/logger"
@@ -73,7 +73,7 @@ func NewApplierFromArgs(cmd *cobra.Command, args *RunArgs, imageName []string) (
 		return nil, err
 	}
 
-	ctx := withCommonContext(cmd.Context(), cmd)
+	ctx := hooks.WithPhase(withCommonContext(cmd.Context(), cmd), v2.HookPhaseRun)
 
 	return applydrivers.NewDefaultApplier(ctx, c.cluster, cf, imageName)
 }
@@ -124,8 +124,7 @@ func (r *ClusterArgs) runArgs(cmd *cobra.Command, args *RunArgs, imageList []str
 	nodes := stringsutil.FilterNonEmptyFromString(args.Cluster.Nodes, ",")
 	r.hosts = []v2.Host{}
 
-	sshClient := ssh.NewCacheClientFromCluster(r.cluster, true)
-	execer, err := exec.New(sshClient)
+	execer, err := exec.NewFromCluster(r.cluster, true)
 	if err != nil {
 		return err
 	}
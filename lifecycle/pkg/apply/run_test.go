@@ -15,6 +15,7 @@
 package apply
 
 import (
+	"context"
 	"reflect"
 	"testing"
 
@@ -23,6 +24,7 @@ import (
 
 	"github.com/labring/sealos/pkg/clusterfile"
 	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/hooks"
 	"github.com/labring/sealos/pkg/ssh"
 	"github.com/labring/sealos/pkg/utils/iputils"
 
@@ -156,6 +158,7 @@ func TestNewApplierFromArgs(t *testing.T) {
 				},
 			},
 			want: &applydrivers.Applier{
+				Context: hooks.WithPhase(context.Background(), v2.HookPhaseRun),
 				ClusterDesired: &v2.Cluster{
 					TypeMeta: metav1.TypeMeta{
 						Kind:       "Cluster",
@@ -27,6 +27,7 @@ import (
 	"github.com/labring/sealos/pkg/clusterfile"
 	"github.com/labring/sealos/pkg/constants"
 	"github.com/labring/sealos/pkg/exec"
+	"github.com/labring/sealos/pkg/hooks"
 	"github.com/labring/sealos/pkg/ssh"
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 	fileutil "github.com/labring/sealos/pkg/utils/file"
@@ -65,7 +66,7 @@ func NewScaleApplierFromArgs(cmd *cobra.Command, scaleArgs *ScaleArgs) (applydri
 		return nil, err
 	}
 
-	return applydrivers.NewDefaultScaleApplier(cmd.Context(), curr, cluster)
+	return applydrivers.NewDefaultScaleApplier(hooks.WithPhase(cmd.Context(), v2.HookPhase(cmd.Name())), curr, cluster)
 }
 
 func getSSHFromCommand(cmd *cobra.Command) *v2.SSH {
@@ -24,8 +24,10 @@ import (
 
 	"k8s.io/apimachinery/pkg/util/sets"
 
+	"github.com/labring/sealos/pkg/bootstrap"
 	"github.com/labring/sealos/pkg/constants"
 	"github.com/labring/sealos/pkg/exec"
+	"github.com/labring/sealos/pkg/hooks"
 	"github.com/labring/sealos/pkg/ssh"
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 	"github.com/labring/sealos/pkg/utils/hash"
@@ -138,6 +140,12 @@ func GetNewImages(currentCluster, desiredCluster *v2.Cluster) []string {
 }
 
 func CheckAndInitialize(cluster *v2.Cluster) error {
+	if err := hooks.Validate(cluster.Spec.Hooks); err != nil {
+		return err
+	}
+	if err := bootstrap.ValidateHostConfig(cluster.Spec.HostConfig); err != nil {
+		return err
+	}
 	cluster.Spec.SSH.Port = cluster.Spec.SSH.DefaultPort()
 
 	if cluster.Spec.SSH.Pk == "" {
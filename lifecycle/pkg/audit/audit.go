@@ -0,0 +1,380 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/utils/file"
+	"github.com/labring/sealos/pkg/utils/iputils"
+	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/rand"
+)
+
+const (
+	// LogFileName is the append-only audit log stored under the cluster workdir,
+	// one JSON encoded Record per line.
+	LogFileName = "audit.log"
+
+	StatusSucceeded = "Succeeded"
+	StatusFailed    = "Failed"
+
+	redacted = "******"
+)
+
+// sensitiveFlags are never written into the audit log in plain text.
+var sensitiveFlags = map[string]struct{}{
+	"passwd":    {},
+	"pk-passwd": {},
+}
+
+var sensitiveLine = regexp.MustCompile(`^(\s*[-+]?\s*(?:passwd|pkPasswd|pkData|password):\s*).+$`)
+
+// HostResult is the aggregated outcome of all remote operations against a single host.
+type HostResult struct {
+	Host       string        `json:"host"`
+	Operations int           `json:"operations"`
+	Failures   int           `json:"failures"`
+	StartTime  time.Time     `json:"startTime"`
+	EndTime    time.Time     `json:"endTime"`
+	Duration   time.Duration `json:"duration"`
+	LastError  string        `json:"lastError,omitempty"`
+}
+
+// Record is a single entry of the audit log.
+type Record struct {
+	ID              string            `json:"id"`
+	Cluster         string            `json:"cluster"`
+	Command         string            `json:"command"`
+	Args            []string          `json:"args,omitempty"`
+	Flags           map[string]string `json:"flags,omitempty"`
+	User            string            `json:"user"`
+	StartTime       time.Time         `json:"startTime"`
+	EndTime         time.Time         `json:"endTime"`
+	Duration        time.Duration     `json:"duration"`
+	Status          string            `json:"status"`
+	Error           string            `json:"error,omitempty"`
+	ClusterfileDiff string            `json:"clusterfileDiff,omitempty"`
+	Hosts           []HostResult      `json:"hosts,omitempty"`
+}
+
+// Recorder collects a Record while a mutating command is running.
+type Recorder struct {
+	mu       sync.Mutex
+	record   Record
+	hosts    map[string]*HostResult
+	before   string
+	previous []byte
+	// ips are the hosts of the bound cluster, used to tell the recorders of
+	// the operations running on different clusters at the same time apart.
+	ips map[string]struct{}
+}
+
+var (
+	activeMu sync.RWMutex
+	active   []*Recorder
+)
+
+// Begin starts recording a new operation and makes it active. The sealos
+// command line runs exactly one mutating operation per process, the daemon
+// runs one per cluster at a time and binds each recorder to its cluster.
+func Begin(command string, args []string, flags map[string]string) *Recorder {
+	r := &Recorder{
+		record: Record{
+			ID:        fmt.Sprintf("%s-%s", time.Now().Format("20060102150405"), rand.Generator(6)),
+			Command:   command,
+			Args:      args,
+			Flags:     RedactFlags(flags),
+			User:      currentUser(),
+			StartTime: time.Now(),
+		},
+		hosts: make(map[string]*HostResult),
+		ips:   make(map[string]struct{}),
+	}
+	activeMu.Lock()
+	active = append(active, r)
+	activeMu.Unlock()
+	return r
+}
+
+// SetCluster binds the active recorder to a cluster, the Clusterfile at this
+// moment is used as the baseline of the diff. hosts are the hosts the operation
+// runs on, the recorder already bound to the cluster is preferred.
+func SetCluster(name string, hosts ...string) {
+	activeMu.RLock()
+	var r *Recorder
+	for i := len(active) - 1; i >= 0; i-- {
+		if c := active[i].cluster(); c == name {
+			r = active[i]
+			break
+		} else if c == "" && r == nil {
+			r = active[i]
+		}
+	}
+	activeMu.RUnlock()
+	if r != nil {
+		r.SetCluster(name)
+		r.AddHosts(hosts...)
+	}
+}
+
+// ObserveHost records the outcome of one remote operation for the active
+// recorder whose cluster has the host.
+func ObserveHost(host string, start time.Time, err error) {
+	if r := getActive(host); r != nil {
+		r.ObserveHost(host, start, err)
+	}
+}
+
+// Finish completes the active recorders and appends them to the audit log.
+func Finish(err error) {
+	activeMu.Lock()
+	recorders := active
+	active = nil
+	activeMu.Unlock()
+	for _, r := range recorders {
+		finish(r, err)
+	}
+}
+
+// End completes r and appends it to the audit log.
+func End(r *Recorder, err error) {
+	activeMu.Lock()
+	for i := range active {
+		if active[i] == r {
+			active = append(active[:i:i], active[i+1:]...)
+			break
+		}
+	}
+	activeMu.Unlock()
+	finish(r, err)
+}
+
+func finish(r *Recorder, err error) {
+	if saveErr := r.Finish(err); saveErr != nil {
+		logger.Warn("failed to write audit log: %v", saveErr)
+	}
+}
+
+func getActive(host string) *Recorder {
+	activeMu.RLock()
+	defer activeMu.RUnlock()
+	if len(active) == 1 {
+		return active[0]
+	}
+	ip := iputils.GetHostIP(host)
+	for _, r := range active {
+		if r.hasHost(ip) {
+			return r
+		}
+	}
+	if len(active) > 0 {
+		logger.Debug("no audit recorder of host %s in %d running operations", host, len(active))
+	}
+	return nil
+}
+
+func (r *Recorder) SetCluster(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.record.Cluster == name {
+		return
+	}
+	r.record.Cluster = name
+	r.before = readClusterfile(name)
+	// keep what we had so far, `reset` removes the whole cluster workdir
+	// before the record of the reset itself is written.
+	r.previous, _ = os.ReadFile(LogPath(name))
+}
+
+// AddHosts adds the hosts, with or without ports, of the operation.
+func (r *Recorder) AddHosts(hosts ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, h := range hosts {
+		r.ips[iputils.GetHostIP(h)] = struct{}{}
+	}
+}
+
+func (r *Recorder) cluster() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.record.Cluster
+}
+
+func (r *Recorder) hasHost(ip string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.ips[ip]
+	return ok
+}
+
+func (r *Recorder) ObserveHost(host string, start time.Time, err error) {
+	end := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.hosts[host]
+	if !ok {
+		h = &HostResult{Host: host, StartTime: start}
+		r.hosts[host] = h
+	}
+	h.Operations++
+	if start.Before(h.StartTime) {
+		h.StartTime = start
+	}
+	if end.After(h.EndTime) {
+		h.EndTime = end
+	}
+	h.Duration += end.Sub(start)
+	if err != nil {
+		h.Failures++
+		h.LastError = err.Error()
+	}
+}
+
+// Record returns a snapshot of the current record.
+func (r *Recorder) Record() Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ret := r.record
+	ret.Hosts = make([]HostResult, 0, len(r.hosts))
+	for _, h := range r.hosts {
+		ret.Hosts = append(ret.Hosts, *h)
+	}
+	sort.Slice(ret.Hosts, func(i, j int) bool {
+		return ret.Hosts[i].Host < ret.Hosts[j].Host
+	})
+	return ret
+}
+
+func (r *Recorder) Finish(err error) error {
+	r.mu.Lock()
+	r.record.EndTime = time.Now()
+	r.record.Duration = r.record.EndTime.Sub(r.record.StartTime)
+	if err != nil {
+		r.record.Status = StatusFailed
+		r.record.Error = err.Error()
+	} else {
+		r.record.Status = StatusSucceeded
+	}
+	if r.record.Cluster != "" {
+		r.record.ClusterfileDiff = diff(r.before, readClusterfile(r.record.Cluster))
+	}
+	previous := r.previous
+	r.mu.Unlock()
+
+	record := r.Record()
+	if record.Cluster == "" {
+		logger.Debug("skip writing audit log of %s, no cluster is bound", record.Command)
+		return nil
+	}
+	return appendRecord(LogPath(record.Cluster), previous, record)
+}
+
+func appendRecord(path string, previous []byte, record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if !file.IsExist(path) && len(previous) > 0 {
+		if err = os.WriteFile(path, previous, 0600); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// LogPath returns the audit log path of the cluster.
+func LogPath(clusterName string) string {
+	return filepath.Join(constants.ClusterDir(clusterName), LogFileName)
+}
+
+// RedactFlags masks the value of sensitive flags.
+func RedactFlags(flags map[string]string) map[string]string {
+	if len(flags) == 0 {
+		return nil
+	}
+	ret := make(map[string]string, len(flags))
+	for k, v := range flags {
+		if _, ok := sensitiveFlags[k]; ok && v != "" {
+			v = redacted
+		}
+		ret[k] = v
+	}
+	return ret
+}
+
+func readClusterfile(name string) string {
+	data, err := os.ReadFile(constants.Clusterfile(name))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func diff(before, after string) string {
+	if before == after {
+		return ""
+	}
+	out, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(before),
+		B:        difflib.SplitLines(after),
+		FromFile: "Clusterfile.before",
+		ToFile:   "Clusterfile.after",
+		Context:  3,
+	})
+	if err != nil {
+		logger.Debug("failed to diff Clusterfile: %v", err)
+		return ""
+	}
+	lines := strings.Split(out, "\n")
+	for i := range lines {
+		lines[i] = sensitiveLine.ReplaceAllString(lines[i], "${1}"+redacted)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func currentUser() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	// record the real user behind sudo
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" && sudoUser != name {
+		name = fmt.Sprintf("%s(%s)", sudoUser, name)
+	}
+	return name
+}
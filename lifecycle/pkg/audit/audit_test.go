@@ -0,0 +1,128 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labring/sealos/pkg/constants"
+)
+
+func TestRecorder(t *testing.T) {
+	constants.DefaultRuntimeRootDir = t.TempDir()
+	cfPath := constants.Clusterfile("default")
+	if err := os.MkdirAll(filepath.Dir(cfPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cfPath, []byte("spec:\n  ssh:\n    passwd: old\n  hosts: []\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	Begin("add", nil, map[string]string{"nodes": "192.168.0.3", "passwd": "secret"})
+	SetCluster("default")
+	start := time.Now()
+	ObserveHost("192.168.0.3:22", start, nil)
+	ObserveHost("192.168.0.3:22", start, errors.New("connection refused"))
+	ObserveHost("192.168.0.2:22", start, nil)
+	if err := os.WriteFile(cfPath, []byte("spec:\n  ssh:\n    passwd: new\n  hosts:\n  - 192.168.0.3\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	Finish(errors.New("failed to join"))
+
+	// simulate `reset` removing the workdir in the middle of an operation
+	Begin("reset", nil, nil)
+	SetCluster("default")
+	if err := os.RemoveAll(constants.ClusterDir("default")); err != nil {
+		t.Fatal(err)
+	}
+	Finish(nil)
+
+	records, err := List("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	add := records[0]
+	if add.Status != StatusFailed || add.Error != "failed to join" {
+		t.Errorf("unexpected status %s, error %s", add.Status, add.Error)
+	}
+	if add.Flags["passwd"] != redacted {
+		t.Errorf("passwd flag is not redacted: %s", add.Flags["passwd"])
+	}
+	if strings.Contains(add.ClusterfileDiff, "secret") || strings.Contains(add.ClusterfileDiff, "passwd: new") {
+		t.Errorf("Clusterfile diff is not redacted: %s", add.ClusterfileDiff)
+	}
+	if !strings.Contains(add.ClusterfileDiff, "+  - 192.168.0.3") {
+		t.Errorf("unexpected Clusterfile diff: %s", add.ClusterfileDiff)
+	}
+	if len(add.Hosts) != 2 || add.Hosts[1].Host != "192.168.0.3:22" || add.Hosts[1].Operations != 2 || add.Hosts[1].Failures != 1 {
+		t.Errorf("unexpected host results: %+v", add.Hosts)
+	}
+	if records[1].Command != "reset" || records[1].Status != StatusSucceeded {
+		t.Errorf("unexpected reset record: %+v", records[1])
+	}
+
+	if _, err = Get("default", records[1].ID); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConcurrentRecorders(t *testing.T) {
+	constants.DefaultRuntimeRootDir = t.TempDir()
+	a := Begin("add", nil, nil)
+	a.SetCluster("a")
+	b := Begin("exec", nil, nil)
+	b.SetCluster("b")
+	// bound by the apply of each cluster
+	SetCluster("a", "192.168.0.2:22")
+	SetCluster("b", "192.168.0.3")
+	ObserveHost("192.168.0.2:22", time.Now(), nil)
+	ObserveHost("192.168.0.3:22", time.Now(), nil)
+	End(b, nil)
+	End(a, errors.New("failed"))
+
+	for cluster, want := range map[string]string{"a": "192.168.0.2:22", "b": "192.168.0.3:22"} {
+		records, err := List(cluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 1 || len(records[0].Hosts) != 1 || records[0].Hosts[0].Host != want {
+			t.Errorf("unexpected records of cluster %s: %+v", cluster, records)
+		}
+	}
+}
+
+func TestFinishWithoutCluster(t *testing.T) {
+	constants.DefaultRuntimeRootDir = t.TempDir()
+	Begin("exec", []string{"ls"}, nil)
+	Finish(nil)
+	entries, err := os.ReadDir(constants.DefaultRuntimeRootDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected nothing to be written, got %d entries", len(entries))
+	}
+	// no active recorder, must not panic
+	ObserveHost("127.0.0.1", time.Now(), nil)
+	Finish(nil)
+}
@@ -22,6 +22,7 @@ import (
 
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/progress"
 )
 
 type Phase string
@@ -73,7 +74,9 @@ func (bs *realBootstrap) Apply(hosts ...string) error {
 				return nil
 			}
 			logger.Debug("apply %s on host %s", applier, host)
-			return applier.Apply(bs.ctx, host)
+			return progress.RunStep(host, fmt.Sprintf("bootstrap %s", applier), func() error {
+				return applier.Apply(bs.ctx, host)
+			})
 		}); err != nil {
 			return err
 		}
@@ -108,7 +111,9 @@ func (bs *realBootstrap) Delete(hosts ...string) error {
 				continue
 			}
 			logger.Debug("undo %s on host %s", applier, host)
-			if err := applier.Undo(bs.ctx, host); err != nil {
+			if err := progress.RunStep(host, fmt.Sprintf("undo bootstrap %s", applier), func() error {
+				return applier.Undo(bs.ctx, host)
+			}); err != nil {
 				return err
 			}
 		}
@@ -134,6 +139,9 @@ func (c *defaultChecker) String() string {
 }
 
 func (c *defaultChecker) Apply(ctx Context, host string) error {
+	if err := checkInitSystem(ctx, host); err != nil {
+		return err
+	}
 	cmds := []string{ctx.GetBash().CheckBash(host)}
 	return ctx.GetExecer().CmdAsync(host, cmds...)
 }
@@ -154,7 +162,7 @@ func (initializer *defaultInitializer) Undo(ctx Context, host string) error {
 
 func init() {
 	defaultPreflights = append(defaultPreflights, &defaultChecker{})
-	defaultInitializers = append(defaultInitializers, &registryHostApplier{}, &registryApplier{}, &defaultCRIInitializer{}, &apiServerHostApplier{}, &lvscareHostApplier{}, &defaultInitializer{})
+	defaultInitializers = append(defaultInitializers, &hostConfigApplier{}, &registryHostApplier{}, &registryApplier{}, &defaultCRIInitializer{}, &apiServerHostApplier{}, &lvscareHostApplier{}, &defaultInitializer{})
 }
 
 func RegisterApplier(phase Phase, appliers ...Applier) error {
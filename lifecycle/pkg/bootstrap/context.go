@@ -61,9 +61,8 @@ func (ctx realContext) GetRemoter() *ssh.Remote {
 }
 
 func NewContextFrom(cluster *v2.Cluster) Context {
-	execer := ssh.NewCacheClientFromCluster(cluster, true)
 	// if we can get this far, ignore error is ok
-	execer, _ = exec.New(execer)
+	execer, _ := exec.NewFromCluster(cluster, true)
 	envProcessor := env.NewEnvProcessor(cluster)
 	remoter := ssh.NewRemoteFromSSH(cluster.GetName(), execer)
 
@@ -16,7 +16,12 @@ limitations under the License.
 
 package bootstrap
 
-import "github.com/labring/sealos/pkg/utils/logger"
+import (
+	"fmt"
+
+	"github.com/labring/sealos/pkg/utils/initsystem"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
 
 type defaultCRIInitializer struct{ common }
 
@@ -39,3 +44,15 @@ func (initializer *defaultCRIInitializer) Undo(ctx Context, host string) error {
 	}
 	return ctx.GetExecer().CmdAsync(host, cleanCRI)
 }
+
+// checkInitSystem fails on hosts which are not running systemd, the init scripts
+// of the cluster images install and start containerd, image-cri-shim, the registry
+// and k3s by systemctl only. An old sealctl does not tell the init system, the
+// host is taken as systemd then.
+func checkInitSystem(ctx Context, host string) error {
+	name := ctx.GetRemoter().InitSystem(host).Name()
+	if name == "" || name == initsystem.Systemd {
+		return nil
+	}
+	return fmt.Errorf("host %s runs %s, the init scripts of the cluster images support systemd only", host, name)
+}
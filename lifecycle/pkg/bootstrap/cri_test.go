@@ -0,0 +1,47 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"testing"
+
+	"github.com/labring/sealos/pkg/exec/fake"
+)
+
+func TestCheckInitSystem(t *testing.T) {
+	tests := []struct {
+		name    string
+		initsys string
+		wantErr bool
+	}{
+		{"systemd", "systemd", false},
+		{"old sealctl without name", "", false},
+		{"openrc", "openrc", true},
+		{"sysvinit", "sysvinit", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := hostConfigCluster(nil)
+			e := fake.NewFromCluster(cluster)
+			defer e.Install()()
+			e.Respond("initsystem name", tt.initsys)
+
+			err := checkInitSystem(NewContextFrom(cluster), "192.168.0.2:22")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,411 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/exec"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+const (
+	sysctlConfFile  = "/etc/sysctl.d/99-sealos.conf"
+	modulesConfFile = "/etc/modules-load.d/sealos.conf"
+	limitsConfFile  = "/etc/security/limits.d/99-sealos.conf"
+	timesyncdConf   = "/etc/systemd/timesyncd.conf.d/sealos.conf"
+	chronyConf      = "/etc/chrony.d/sealos.conf"
+	fstabMark       = "#sealos#"
+)
+
+// hostConfigApplier applies the HostConfig of the cluster, the original state of
+// every setting it changes is kept on the host so that Undo can restore it.
+type hostConfigApplier struct{ common }
+
+func (*hostConfigApplier) String() string { return "host_config_applier" }
+
+func (*hostConfigApplier) Filter(ctx Context, _ string) bool {
+	return ctx.GetCluster().Spec.HostConfig != nil
+}
+
+func (*hostConfigApplier) Apply(ctx Context, host string) error {
+	for _, s := range hostSettings(ctx.GetCluster().Spec.HostConfig, hostConfigStateDir(ctx.GetPathResolver())) {
+		if err := ctx.GetExecer().CmdAsync(host, s.apply...); err != nil {
+			return fmt.Errorf("failed to apply %s: %v", s.name, err)
+		}
+	}
+	return nil
+}
+
+func (*hostConfigApplier) Undo(ctx Context, host string) error {
+	settings := hostSettings(ctx.GetCluster().Spec.HostConfig, hostConfigStateDir(ctx.GetPathResolver()))
+	for i := len(settings) - 1; i >= 0; i-- {
+		if err := ctx.GetExecer().CmdAsync(host, settings[i].undo...); err != nil {
+			return fmt.Errorf("failed to undo %s: %v", settings[i].name, err)
+		}
+	}
+	return nil
+}
+
+func hostConfigStateDir(pathResolver constants.PathResolver) string {
+	return path.Join(pathResolver.Root(), "hostconfig")
+}
+
+// ValidateHostConfig checks the values of cfg, nil is valid.
+func ValidateHostConfig(cfg *v2.HostConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	switch cfg.Firewall {
+	case "", v2.FirewallModeDisabled, v2.FirewallModeEnabled:
+	default:
+		return fmt.Errorf("unknown firewall mode %q", cfg.Firewall)
+	}
+	switch cfg.SELinux {
+	case "", v2.SELinuxModeDisabled, v2.SELinuxModePermissive, v2.SELinuxModeEnforcing:
+	default:
+		return fmt.Errorf("unknown selinux mode %q", cfg.SELinux)
+	}
+	for k := range cfg.Sysctls {
+		if k == "" || strings.ContainsAny(k, "= \t") {
+			return fmt.Errorf("invalid sysctl key %q", k)
+		}
+	}
+	for _, m := range cfg.KernelModules {
+		if m == "" || strings.ContainsAny(m, " \t/") {
+			return fmt.Errorf("invalid kernel module %q", m)
+		}
+	}
+	for _, u := range cfg.Ulimits {
+		if u.Item == "" || u.Value == "" {
+			return fmt.Errorf("item and value of ulimit cannot be empty")
+		}
+		switch u.Type {
+		case "", "-", "soft", "hard":
+		default:
+			return fmt.Errorf("unknown ulimit type %q", u.Type)
+		}
+	}
+	return nil
+}
+
+// probe reads the state of a setting on the host, any of want is expected.
+type probe struct {
+	key  string
+	cmd  string
+	want []string
+}
+
+type hostSetting struct {
+	name   string
+	apply  []string
+	undo   []string
+	probes []probe
+}
+
+func hostSettings(cfg *v2.HostConfig, stateDir string) []hostSetting {
+	if cfg == nil {
+		return nil
+	}
+	var ret []hostSetting
+	if len(cfg.KernelModules) > 0 {
+		ret = append(ret, kernelModulesSetting(cfg.KernelModules, stateDir))
+	}
+	if len(cfg.Sysctls) > 0 {
+		ret = append(ret, sysctlSetting(cfg.Sysctls, stateDir))
+	}
+	if cfg.DisableSwap {
+		ret = append(ret, swapSetting(stateDir))
+	}
+	if len(cfg.Ulimits) > 0 {
+		ret = append(ret, ulimitSetting(cfg.Ulimits))
+	}
+	if cfg.TimeSync != nil {
+		ret = append(ret, timeSyncSetting(cfg.TimeSync, stateDir))
+	}
+	if cfg.Firewall != "" {
+		ret = append(ret, firewallSetting(cfg.Firewall, stateDir))
+	}
+	if cfg.SELinux != "" {
+		ret = append(ret, selinuxSetting(cfg.SELinux, stateDir))
+	}
+	return ret
+}
+
+// saveOnce keeps the output of cmd in the state file name unless it was saved
+// before, so that the state before the first apply is restored.
+func saveOnce(stateDir, name, cmd string) string {
+	f := path.Join(stateDir, name)
+	return fmt.Sprintf("mkdir -p %s && if [ ! -f %s ]; then { %s; } > %s.tmp && mv %s.tmp %s; fi", stateDir, f, cmd, f, f, f)
+}
+
+func writeFile(file string, lines []string) string {
+	quoted := make([]string, len(lines))
+	for i := range lines {
+		quoted[i] = shellQuote(lines[i])
+	}
+	return fmt.Sprintf("mkdir -p %s && printf '%%s\\n' %s > %s", path.Dir(file), strings.Join(quoted, " "), file)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func kernelModulesSetting(modules []string, stateDir string) hostSetting {
+	list := strings.Join(modules, " ")
+	notLoaded := make([]string, len(modules))
+	for i, m := range modules {
+		notLoaded[i] = fmt.Sprintf("{ %s; } || echo %s", moduleLoaded(m), m)
+	}
+	s := hostSetting{
+		name: "kernel modules",
+		apply: []string{
+			saveOnce(stateDir, "modules", strings.Join(notLoaded, "; ")),
+			writeFile(modulesConfFile, modules),
+			fmt.Sprintf("for m in %s; do modprobe $m || exit 1; done", list),
+		},
+		undo: []string{
+			"rm -f " + modulesConfFile,
+			fmt.Sprintf(`if [ -f %[1]s ]; then for m in $(cat %[1]s); do modprobe -r $m || true; done; rm -f %[1]s; fi`, path.Join(stateDir, "modules")),
+		},
+	}
+	for _, m := range modules {
+		s.probes = append(s.probes, probe{
+			key:  "module " + m,
+			cmd:  fmt.Sprintf("{ %s; } && echo loaded || echo unloaded", moduleLoaded(m)),
+			want: []string{"loaded"},
+		})
+	}
+	return s
+}
+
+// moduleLoaded returns the shell condition of whether the module m is loaded,
+// modules built into the kernel are not listed in /proc/modules.
+func moduleLoaded(m string) string {
+	name := strings.ReplaceAll(m, "-", "_")
+	return fmt.Sprintf(`grep -qs "^%[1]s " /proc/modules || [ -d /sys/module/%[1]s ] || grep -qs "/%[2]s.ko" /lib/modules/$(uname -r)/modules.builtin`, name, m)
+}
+
+func sysctlSetting(sysctls map[string]string, stateDir string) hostSetting {
+	keys := make([]string, 0, len(sysctls))
+	for k := range sysctls {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	lines := make([]string, len(keys))
+	for i, k := range keys {
+		lines[i] = fmt.Sprintf("%s = %s", k, sysctls[k])
+	}
+	orig := path.Join(stateDir, "sysctl")
+	s := hostSetting{
+		name: "sysctl",
+		apply: []string{
+			saveOnce(stateDir, "sysctl", fmt.Sprintf(`for k in %s; do echo "$k=$(sysctl -n $k 2>/dev/null)"; done`, strings.Join(keys, " "))),
+			writeFile(sysctlConfFile, lines),
+			"sysctl -p " + sysctlConfFile,
+		},
+		undo: []string{
+			"rm -f " + sysctlConfFile,
+			fmt.Sprintf(`if [ -f %[1]s ]; then while IFS='=' read -r k v; do [ -z "$v" ] || sysctl -w "$k=$v" >/dev/null || true; done < %[1]s; rm -f %[1]s; fi`, orig),
+		},
+	}
+	for _, k := range keys {
+		s.probes = append(s.probes, probe{key: "sysctl " + k, cmd: "sysctl -n " + k, want: []string{sysctls[k]}})
+	}
+	return s
+}
+
+func swapSetting(stateDir string) hostSetting {
+	orig := path.Join(stateDir, "swap")
+	return hostSetting{
+		name: "swap",
+		apply: []string{
+			saveOnce(stateDir, "swap", "tail -n +2 /proc/swaps"),
+			"swapoff -a",
+			fmt.Sprintf(`sed -i '/^[^#].*[[:space:]]swap[[:space:]]/s/^/%s/' /etc/fstab`, fstabMark),
+		},
+		undo: []string{
+			fmt.Sprintf(`sed -i 's/^%s//' /etc/fstab`, fstabMark),
+			fmt.Sprintf(`if [ -f %[1]s ]; then if [ -s %[1]s ]; then swapon -a || true; fi; rm -f %[1]s; fi`, orig),
+		},
+		probes: []probe{{key: "swap devices", cmd: "tail -n +2 /proc/swaps | wc -l", want: []string{"0"}}},
+	}
+}
+
+func ulimitSetting(ulimits []v2.Ulimit) hostSetting {
+	lines := make([]string, len(ulimits))
+	for i, u := range ulimits {
+		domain, typ := u.Domain, u.Type
+		if domain == "" {
+			domain = "*"
+		}
+		if typ == "" {
+			typ = "-"
+		}
+		lines[i] = fmt.Sprintf("%s %s %s %s", domain, typ, u.Item, u.Value)
+	}
+	return hostSetting{
+		name:   "ulimits",
+		apply:  []string{writeFile(limitsConfFile, lines)},
+		undo:   []string{"rm -f " + limitsConfFile},
+		probes: []probe{{key: limitsConfFile, cmd: "cat " + limitsConfFile, want: []string{strings.Join(lines, "\n")}}},
+	}
+}
+
+func timeSyncSetting(ts *v2.TimeSync, stateDir string) hostSetting {
+	orig := path.Join(stateDir, "ntp")
+	restart := "for s in systemd-timesyncd chronyd chrony; do systemctl is-active -q $s && systemctl restart $s; done; true"
+	s := hostSetting{
+		name:  "time sync",
+		apply: []string{saveOnce(stateDir, "ntp", "timedatectl show -p NTP --value")},
+		undo: []string{
+			fmt.Sprintf("rm -f %s %s", timesyncdConf, chronyConf),
+			fmt.Sprintf(`if [ -f %[1]s ]; then timedatectl set-ntp "$(cat %[1]s)" || true; rm -f %[1]s; fi`, orig),
+			restart,
+		},
+		probes: []probe{{key: "ntp", cmd: "timedatectl show -p NTP --value", want: []string{"yes"}}},
+	}
+	if len(ts.Servers) > 0 {
+		chrony := make([]string, len(ts.Servers))
+		for i := range ts.Servers {
+			chrony[i] = fmt.Sprintf("server %s iburst", ts.Servers[i])
+		}
+		s.apply = append(s.apply,
+			writeFile(timesyncdConf, []string{"[Time]", "NTP=" + strings.Join(ts.Servers, " ")}),
+			fmt.Sprintf("if [ -d %s ]; then %s; fi", path.Dir(chronyConf), writeFile(chronyConf, chrony)),
+		)
+	}
+	s.apply = append(s.apply, "timedatectl set-ntp true", restart)
+	return s
+}
+
+func firewallSetting(mode v2.FirewallMode, stateDir string) hostSetting {
+	orig := path.Join(stateDir, "firewall")
+	action, want := "disable --now", "inactive"
+	if mode == v2.FirewallModeEnabled {
+		action, want = "enable --now", "active"
+	}
+	services := "firewalld ufw"
+	return hostSetting{
+		name: "firewall",
+		apply: []string{
+			saveOnce(stateDir, "firewall", fmt.Sprintf(`for s in %s; do systemctl cat $s >/dev/null 2>&1 && echo "$s=$(systemctl is-enabled $s)"; done; true`, services)),
+			fmt.Sprintf("for s in %s; do systemctl cat $s >/dev/null 2>&1 && systemctl %s $s; done; true", services, action),
+		},
+		undo: []string{
+			fmt.Sprintf(`if [ -f %[1]s ]; then while IFS='=' read -r s st; do if [ "$st" = enabled ]; then systemctl enable --now $s; else systemctl disable --now $s; fi; done < %[1]s; rm -f %[1]s; fi`, orig),
+		},
+		probes: []probe{{
+			key:  "firewall",
+			cmd:  "{ systemctl is-active -q firewalld || systemctl is-active -q ufw; } && echo active || echo inactive",
+			want: []string{want},
+		}},
+	}
+}
+
+func selinuxSetting(mode v2.SELinuxMode, stateDir string) hostSetting {
+	orig := path.Join(stateDir, "selinux")
+	setenforce := "setenforce 0"
+	// disabling selinux takes effect after reboot, until then it is permissive
+	want := []string{"Disabled", "Permissive"}
+	switch mode {
+	case v2.SELinuxModePermissive:
+		want = []string{"Permissive"}
+	case v2.SELinuxModeEnforcing:
+		setenforce, want = "setenforce 1", []string{"Enforcing"}
+	}
+	return hostSetting{
+		name: "selinux",
+		apply: []string{
+			fmt.Sprintf("if [ -f /etc/selinux/config ]; then %s && sed -i 's/^SELINUX=.*/SELINUX=%s/' /etc/selinux/config && { %s || true; }; fi",
+				saveOnce(stateDir, "selinux", "sed -n 's/^SELINUX=//p' /etc/selinux/config"), mode, setenforce),
+		},
+		undo: []string{
+			fmt.Sprintf(`if [ -f %[1]s ]; then m=$(cat %[1]s); sed -i "s/^SELINUX=.*/SELINUX=$m/" /etc/selinux/config; if [ "$m" = enforcing ]; then setenforce 1 || true; fi; rm -f %[1]s; fi`, orig),
+		},
+		probes: []probe{{key: "selinux", cmd: "getenforce 2>/dev/null || echo Disabled", want: want}},
+	}
+}
+
+// HostConfigDrift is a setting of the HostConfig which differs on a host.
+type HostConfigDrift struct {
+	Host    string
+	Setting string
+	Want    string
+	Got     string
+}
+
+// CheckHostConfig reads the settings of the HostConfig of cluster on hosts,
+// all hosts of the cluster if empty, and returns the ones which differ.
+func CheckHostConfig(cluster *v2.Cluster, hosts ...string) ([]HostConfigDrift, error) {
+	settings := hostSettings(cluster.Spec.HostConfig, hostConfigStateDir(constants.NewPathResolver(cluster.GetName())))
+	if len(settings) == 0 {
+		return nil, nil
+	}
+	if len(hosts) == 0 {
+		hosts = cluster.GetAllIPS()
+	}
+	execer, err := exec.NewFromCluster(cluster, false)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		mu  sync.Mutex
+		ret []HostConfigDrift
+	)
+	err = runParallel(hosts, func(host string) error {
+		for _, s := range settings {
+			for _, p := range s.probes {
+				out, err := execer.Cmd(host, p.cmd)
+				got := normalizeProbe(string(out))
+				if err != nil && got == "" {
+					got = fmt.Sprintf("<%v>", err)
+				}
+				if !probeMatched(p.want, got) {
+					mu.Lock()
+					ret = append(ret, HostConfigDrift{Host: host, Setting: p.key, Want: p.want[0], Got: got})
+					mu.Unlock()
+				}
+			}
+		}
+		return nil
+	})
+	sort.SliceStable(ret, func(i, j int) bool { return ret[i].Host < ret[j].Host })
+	return ret, err
+}
+
+// normalizeProbe trims the output and collapses the whitespaces within lines,
+// e.g. the tabs in the value of net.ipv4.ip_local_port_range.
+func normalizeProbe(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i := range lines {
+		lines[i] = strings.Join(strings.Fields(lines[i]), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func probeMatched(want []string, got string) bool {
+	for _, w := range want {
+		if normalizeProbe(w) == got {
+			return true
+		}
+	}
+	return false
+}
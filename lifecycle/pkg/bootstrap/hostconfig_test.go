@@ -0,0 +1,137 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/labring/sealos/pkg/exec/fake"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+func hostConfigCluster(cfg *v2.HostConfig) *v2.Cluster {
+	cluster := &v2.Cluster{}
+	cluster.Name = "default"
+	cluster.Spec.Hosts = []v2.Host{
+		{IPS: []string{"192.168.0.2:22"}, Roles: []string{v2.MASTER, "amd64"}},
+		{IPS: []string{"192.168.0.3:22"}, Roles: []string{v2.NODE, "amd64"}},
+	}
+	cluster.Spec.HostConfig = cfg
+	return cluster
+}
+
+func TestHostConfigApplier(t *testing.T) {
+	cluster := hostConfigCluster(&v2.HostConfig{
+		Sysctls:       map[string]string{"net.ipv4.ip_forward": "1", "vm.swappiness": "0"},
+		KernelModules: []string{"br_netfilter", "overlay"},
+		DisableSwap:   true,
+		Ulimits:       []v2.Ulimit{{Item: "nofile", Value: "1048576"}},
+		Firewall:      v2.FirewallModeDisabled,
+		SELinux:       v2.SELinuxModePermissive,
+	})
+	e := fake.NewFromCluster(cluster)
+	defer e.Install()()
+
+	ctx := NewContextFrom(cluster)
+	a := &hostConfigApplier{}
+	if !a.Filter(ctx, "192.168.0.2:22") {
+		t.Fatal("Filter() = false with a host config")
+	}
+	if err := a.Apply(ctx, "192.168.0.2:22"); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	applied := strings.Join(e.Commands("192.168.0.2"), "\n")
+	for _, want := range []string{
+		"'net.ipv4.ip_forward = 1' 'vm.swappiness = 0' > " + sysctlConfFile,
+		"modprobe $m",
+		"[ -d /sys/module/br_netfilter ] || grep -qs \"/br_netfilter.ko\"",
+		"swapoff -a",
+		"'* - nofile 1048576' > " + limitsConfFile,
+		"systemctl disable --now $s",
+		"SELINUX=permissive",
+	} {
+		if !strings.Contains(applied, want) {
+			t.Errorf("Apply() did not run %q", want)
+		}
+	}
+	// modules are loaded before sysctls of the modules are set
+	if strings.Index(applied, "modprobe") > strings.Index(applied, "sysctl -p") {
+		t.Errorf("Apply() set sysctls before loading kernel modules")
+	}
+
+	e.Reset()
+	if err := a.Undo(ctx, "192.168.0.2:22"); err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	undone := e.Commands("192.168.0.2")
+	if len(undone) == 0 || !strings.Contains(undone[0], "SELINUX=$m") || !strings.Contains(undone[len(undone)-1], "modprobe -r") {
+		t.Errorf("Undo() did not run in reverse order: %v", undone)
+	}
+
+	if a.Filter(NewContextFrom(hostConfigCluster(nil)), "192.168.0.2:22") {
+		t.Errorf("Filter() = true without a host config")
+	}
+}
+
+func TestCheckHostConfig(t *testing.T) {
+	cluster := hostConfigCluster(&v2.HostConfig{
+		Sysctls:     map[string]string{"net.ipv4.ip_local_port_range": "1024 65000"},
+		DisableSwap: true,
+		SELinux:     v2.SELinuxModeDisabled,
+	})
+	e := fake.NewFromCluster(cluster)
+	defer e.Install()()
+	e.RespondFunc(`^sysctl -n`, func(host, _ string) ([]byte, error) {
+		if host == "192.168.0.3" {
+			return []byte("32768\t60999\n"), nil
+		}
+		return []byte("1024\t65000\n"), nil
+	})
+	e.Respond(`/proc/swaps`, "0\n")
+	e.Respond(`getenforce`, "Permissive\n")
+
+	drifts, err := CheckHostConfig(cluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []HostConfigDrift{{Host: "192.168.0.3:22", Setting: "sysctl net.ipv4.ip_local_port_range", Want: "1024 65000", Got: "32768 60999"}}
+	if !reflect.DeepEqual(drifts, want) {
+		t.Errorf("CheckHostConfig() = %+v, want %+v", drifts, want)
+	}
+}
+
+func TestValidateHostConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *v2.HostConfig
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"valid", &v2.HostConfig{Firewall: v2.FirewallModeEnabled, SELinux: v2.SELinuxModeEnforcing}, false},
+		{"firewall", &v2.HostConfig{Firewall: "off"}, true},
+		{"selinux", &v2.HostConfig{SELinux: "Permissive"}, true},
+		{"sysctl", &v2.HostConfig{Sysctls: map[string]string{"vm.swappiness=0": ""}}, true},
+		{"ulimit", &v2.HostConfig{Ulimits: []v2.Ulimit{{Item: "nofile", Value: "65535", Type: "both"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateHostConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateHostConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -43,6 +43,7 @@ func newBuildCommand() *cobra.Command {
 	userNSResults := buildahcli.UserNSResults{}
 	namespaceResults := buildahcli.NameSpaceResults{}
 	sopts := saverOptions{}
+	var lintEnabled bool
 
 	buildCommand := &cobra.Command{
 		Use:     "build [CONTEXT]",
@@ -57,7 +58,7 @@ func newBuildCommand() *cobra.Command {
 				FromAndBudResults: &fromAndBudResults,
 				NameSpaceResults:  &namespaceResults,
 			}
-			return buildCmd(cmd, args, sopts, br)
+			return buildCmd(cmd, args, sopts, lintEnabled, br)
 		},
 		Args: cobra.MaximumNArgs(1),
 		Example: fmt.Sprintf(`%[1]s build
@@ -78,6 +79,7 @@ func newBuildCommand() *cobra.Command {
 	bailOnError(err, "failed to setup From and Build flags")
 
 	sopts.RegisterFlags(flags)
+	flags.BoolVar(&lintEnabled, "lint", false, "check Kubefiles and the build context before building, see the lint command")
 	flags.AddFlagSet(&buildFlags)
 	flags.AddFlagSet(&layerFlags)
 	flags.AddFlagSet(&fromAndBudFlags)
@@ -90,7 +92,7 @@ func newBuildCommand() *cobra.Command {
 	return buildCommand
 }
 
-func buildCmd(c *cobra.Command, inputArgs []string, sopts saverOptions, iopts buildahcli.BuildOptions) error {
+func buildCmd(c *cobra.Command, inputArgs []string, sopts saverOptions, lintEnabled bool, iopts buildahcli.BuildOptions) error {
 	if flagChanged(c, "logfile") {
 		logfile, err := os.OpenFile(iopts.Logfile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
 		if err != nil {
@@ -118,14 +120,13 @@ func buildCmd(c *cobra.Command, inputArgs []string, sopts saverOptions, iopts bu
 			return "", fmt.Errorf("assumed %s is a file but it's not", filename)
 		}
 
-		defaultFileNames := []string{"Sealfile", "Kubefile", "Dockerfile", "Containerfile"}
-		for _, name := range defaultFileNames {
+		for _, name := range defaultKubefileNames {
 			if foundFile, err := getFile(name); err == nil && foundFile != "" {
 				iopts.File = append(iopts.File, foundFile)
 			}
 		}
 		if len(iopts.File) == 0 {
-			return fmt.Errorf("cannot find any of %v in context directory", strings.Join(defaultFileNames, ", "))
+			return fmt.Errorf("cannot find any of %v in context directory", strings.Join(defaultKubefileNames, ", "))
 		}
 	}
 	if err := setDefaultFlagsWithSetters(c, setDefaultTLSVerifyFlag); err != nil {
@@ -149,6 +150,11 @@ func buildCmd(c *cobra.Command, inputArgs []string, sopts saverOptions, iopts bu
 	if err = runSaveImages(options.ContextDirectory, platforms, options.SystemContext, &sopts); err != nil {
 		return err
 	}
+	if lintEnabled {
+		if err = lintBuild(options.ContextDirectory, containerfiles, sopts.enabled); err != nil {
+			return err
+		}
+	}
 	if globalFlagResults.DefaultMountsFile != "" {
 		options.DefaultMountsFilePath = globalFlagResults.DefaultMountsFile
 	}
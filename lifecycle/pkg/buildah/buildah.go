@@ -156,7 +156,7 @@ func AllContainerSubCommands() []*cobra.Command {
 }
 
 func AllSubCommands() []*cobra.Command {
-	return append(AllContainerSubCommands(), append(AllImageSubCommands(), newUnshareCommand())...)
+	return append(AllContainerSubCommands(), append(AllImageSubCommands(), newLintCommand(), newUnshareCommand())...)
 }
 
 func RegisterRootCommand(cmd *cobra.Command) {
@@ -167,6 +167,14 @@ func RegisterRootCommand(cmd *cobra.Command) {
 	wrapPostPersistentRun(cmd)
 }
 
+// EnsureRootCommand registers a root command named name with the default global flags
+// if none is registered yet, so that the package can be used without the command line.
+func EnsureRootCommand(name string) {
+	if rootCmd == nil {
+		RegisterRootCommand(&cobra.Command{Use: name})
+	}
+}
+
 func RegisterPostRun(fn func() error) {
 	if rootCmd == nil {
 		logger.Fatal("Must not register post run function before RegisterRootCommand")
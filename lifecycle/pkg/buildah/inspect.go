@@ -40,6 +40,7 @@ import (
 	"github.com/spf13/pflag"
 	"golang.org/x/term"
 
+	"github.com/labring/sealos/pkg/sbom"
 	"github.com/labring/sealos/pkg/utils/logger"
 )
 
@@ -53,11 +54,14 @@ const (
 type inspectResults struct {
 	format      string
 	inspectType string
+	sbom        bool
+	sbomFormat  string
 }
 
 func newDefaultInspectResults() *inspectResults {
 	return &inspectResults{
 		inspectType: inspectTypeApp,
+		sbomFormat:  sbom.FormatSPDX,
 	}
 }
 
@@ -65,6 +69,8 @@ func (opts *inspectResults) RegisterFlags(fs *pflag.FlagSet) {
 	fs.SetInterspersed(false)
 	fs.StringVarP(&opts.format, "format", "f", opts.format, "use `format` as a Go template to format the output")
 	fs.StringVarP(&opts.inspectType, "type", "t", opts.inspectType, "look at the item of the specified `type` (container or image) and name")
+	fs.BoolVar(&opts.sbom, "sbom", false, "print the software bill of materials of the image, container or local directory, including embedded container images and helm charts")
+	fs.StringVar(&opts.sbomFormat, "sbom-format", opts.sbomFormat, fmt.Sprintf("`format` of the software bill of materials, available options are %s", strings.Join(sbom.Formats, ", ")))
 }
 
 func newInspectCommand() *cobra.Command {
@@ -86,7 +92,9 @@ func newInspectCommand() *cobra.Command {
   %[1]s inspect --type image docker://alpine:latest
   %[1]s inspect --type image oci-archive:/abs/path/of/oci/tarfile.tar
   %[1]s inspect --type image docker-archive:/abs/path/of/docker/tarfile.tar
-  %[1]s inspect --format '{{.OCIv1.Config.Env}}' alpine`, rootCmd.CommandPath()),
+  %[1]s inspect --format '{{.OCIv1.Config.Env}}' alpine
+  %[1]s inspect --sbom labring/kubernetes:v1.25.0
+  %[1]s inspect --sbom --sbom-format cyclonedx /path/to/rootfs`, rootCmd.CommandPath()),
 	}
 	inspectCommand.SetUsageTemplate(UsageTemplate())
 
@@ -127,6 +135,10 @@ func inspectCmd(c *cobra.Command, args []string, iopts *inspectResults) error {
 		return err
 	}
 
+	if iopts.sbom {
+		return writeSBOM(os.Stdout, systemContext, store, name, iopts.sbomFormat)
+	}
+
 	ctx := getContext()
 
 	switch iopts.inspectType {
@@ -40,6 +40,9 @@ type Interface interface {
 	Delete(name string) error
 	InspectContainer(name string) (buildah.BuilderInfo, error)
 	ListContainers() ([]JSONContainer, error)
+	// VerifyImageSignature checks that the image is signed by any of the sigstore public keys and
+	// returns the digested reference of the verified image, which must be used instead of name.
+	VerifyImageSignature(name string, publicKeys [][]byte) (string, error)
 	Runtime() *Runtime
 }
 
@@ -0,0 +1,124 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buildah
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/labring/sealos/pkg/lint"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+type lintOptions struct {
+	files  []string
+	output string
+}
+
+func (opts *lintOptions) RegisterFlags(fs *pflag.FlagSet) {
+	fs.StringSliceVarP(&opts.files, "file", "f", nil, "`pathname or URL` of a Kubefile, default to the Sealfile/Kubefile/Dockerfile/Containerfile in the context directory")
+	fs.StringVarP(&opts.output, "output", "o", "", "output format of the issues, available options are json")
+}
+
+func newLintCommand() *cobra.Command {
+	opts := &lintOptions{}
+	lintCommand := &cobra.Command{
+		Use:   "lint [CONTEXT]",
+		Short: "Check Kubefiles and the build context of cluster images",
+		Long: `
+  Checks Kubefiles for mistakes which would otherwise only surface when the image is
+  mounted or its CMD runs on the cluster: missing or unsupported sealos.io.type and
+  sealos.io.version labels, COPY sources and CMD paths that don't exist in the build
+  context, and images referenced by charts/manifests that are not cached in registry/.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			contextDir, err := getContextDir(args)
+			if err != nil {
+				return err
+			}
+			files, err := findKubefiles(contextDir, opts.files)
+			if err != nil {
+				return err
+			}
+			result, err := lint.Lint(contextDir, files, lint.Options{})
+			if err != nil {
+				return err
+			}
+			if opts.output == "json" {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err = enc.Encode(result); err != nil {
+					return err
+				}
+			} else {
+				result.Print(os.Stdout)
+			}
+			return result.Err()
+		},
+		Example: fmt.Sprintf(`%[1]s lint
+  %[1]s lint -f Kubefile.simple .
+  %[1]s lint -o json ./build`, rootCmd.CommandPath()),
+	}
+	lintCommand.SetUsageTemplate(UsageTemplate())
+	opts.RegisterFlags(lintCommand.Flags())
+	return lintCommand
+}
+
+var defaultKubefileNames = []string{"Sealfile", "Kubefile", "Dockerfile", "Containerfile"}
+
+func findKubefiles(contextDir string, files []string) ([]string, error) {
+	if len(files) > 0 {
+		ret := make([]string, 0, len(files))
+		for _, f := range files {
+			if !filepath.IsAbs(f) {
+				if _, err := os.Stat(f); err != nil {
+					f = filepath.Join(contextDir, f)
+				}
+			}
+			ret = append(ret, f)
+		}
+		return ret, nil
+	}
+	var ret []string
+	for _, name := range defaultKubefileNames {
+		p := filepath.Join(contextDir, name)
+		if info, err := os.Stat(p); err == nil && info.Mode().IsRegular() {
+			ret = append(ret, p)
+		}
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("cannot find any of %v in context directory", defaultKubefileNames)
+	}
+	return ret, nil
+}
+
+// lintBuild runs the linter for `sealos build --lint`, after images were cached into the context.
+func lintBuild(contextDir string, containerfiles []string, requireCachedImages bool) error {
+	result, err := lint.Lint(contextDir, containerfiles, lint.Options{RequireCachedImages: requireCachedImages})
+	if err != nil {
+		return err
+	}
+	for _, i := range result.Issues {
+		if i.Severity == lint.SeverityWarning {
+			logger.Warn(i.String())
+		}
+	}
+	return result.Err()
+}
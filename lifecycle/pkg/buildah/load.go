@@ -29,6 +29,9 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"golang.org/x/term"
+
+	"github.com/labring/sealos/pkg/utils/archive"
+	"github.com/labring/sealos/pkg/utils/flags"
 )
 
 type loadOptions struct {
@@ -98,6 +101,12 @@ func load(cmd *cobra.Command, _ []string, loadOpts *loadOptions) error {
 		}
 		loadOpts.input = outFile.Name()
 	}
+	input, cleanup, err := decompressInput(loadOpts.input)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	loadOpts.input = input
 	r, err := getRuntime(cmd)
 	if err != nil {
 		return err
@@ -113,3 +122,37 @@ func load(cmd *cobra.Command, _ []string, loadOpts *loadOptions) error {
 	fmt.Println("Loaded image: " + strings.Join(loadedImages, "\nLoaded image: "))
 	return nil
 }
+
+// decompressInput decompresses the gzip or zstd archive in parallel to a
+// temporary file, other archives are loaded as is.
+func decompressInput(input string) (string, func(), error) {
+	noop := func() {}
+	f, err := os.Open(input)
+	if err != nil {
+		return "", noop, err
+	}
+	compression, err := archive.DetectCompression(f)
+	_ = f.Close()
+	if err != nil || compression == flags.Uncompressed {
+		return input, noop, err
+	}
+	containerConfig, err := config.Default()
+	if err != nil {
+		return "", noop, err
+	}
+	tmpdir, err := containerConfig.ImageCopyTmpDir()
+	if err != nil {
+		return "", noop, err
+	}
+	tmpfile, err := os.CreateTemp(tmpdir, rootCmd.Name())
+	if err != nil {
+		return "", noop, err
+	}
+	_ = tmpfile.Close()
+	cleanup := func() { _ = os.Remove(tmpfile.Name()) }
+	if err = archive.DecompressFile(input, tmpfile.Name()); err != nil {
+		cleanup()
+		return "", noop, err
+	}
+	return tmpfile.Name(), cleanup, nil
+}
@@ -56,7 +56,7 @@ func newMergeCommand() *cobra.Command {
 				NameSpaceResults:  &namespaceResults,
 			}
 			logger.Debug("save enable: %+v", sopts.enabled)
-			return buildCmd(cmd, []string{buildahInfo.MountPoint}, sopts, br)
+			return buildCmd(cmd, []string{buildahInfo.MountPoint}, sopts, false, br)
 		},
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			tag := getTagsFromFlags(cmd)
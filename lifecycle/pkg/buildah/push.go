@@ -17,6 +17,7 @@ package buildah
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
@@ -31,6 +32,7 @@ import (
 	"github.com/containers/image/v5/pkg/compression"
 	"github.com/containers/image/v5/transports"
 	"github.com/containers/image/v5/transports/alltransports"
+	"github.com/containers/image/v5/types"
 	"github.com/containers/storage"
 	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/spf13/cobra"
@@ -58,6 +60,8 @@ type pushOptions struct {
 	removeSignatures   bool
 	signaturePolicy    string
 	signBy             string
+	sigstoreKey        string
+	sigstorePassFile   string
 	tlsVerify          bool
 	encryptionKeys     []string
 	encryptLayers      []int
@@ -90,6 +94,8 @@ func (opts *pushOptions) RegisterFlags(fs *pflag.FlagSet) error {
 	fs.BoolVar(&opts.rm, "rm", opts.rm, "remove the manifest list if push succeeds")
 	fs.BoolVarP(&opts.removeSignatures, "remove-signatures", "", opts.removeSignatures, "don't copy signatures when pushing image")
 	fs.StringVar(&opts.signBy, "sign-by", opts.signBy, "sign the image using a GPG key with the specified `FINGERPRINT`")
+	fs.StringVar(&opts.sigstoreKey, "sign-by-sigstore-private-key", opts.sigstoreKey, "sign the image and store the signature as an OCI attachment using a sigstore private key at `PATH`, e.g. generated by cosign generate-key-pair")
+	fs.StringVar(&opts.sigstorePassFile, "sign-passphrase-file", opts.sigstorePassFile, "read the passphrase of the sigstore private key from `PATH`")
 	fs.StringVar(&opts.signaturePolicy, "signature-policy", opts.signaturePolicy, "`pathname` of signature policy file (not usually used)")
 	fs.StringSliceVar(&opts.encryptionKeys, "encryption-key", opts.encryptionKeys, "key with the encryption protocol to use needed to encrypt the image (e.g. jwe:/path/to/key.pem)")
 	fs.IntSliceVar(&opts.encryptLayers, "encrypt-layer", opts.encryptLayers, "layers to encrypt, 0-indexed layer indices with support for negative indexing (e.g. 0 is the first layer, -1 is the last layer). If not defined, will encrypt all layers if encryption-key flag is specified")
@@ -245,7 +251,7 @@ func pushCmd(c *cobra.Command, args []string, iopts *pushOptions) error {
 		if !errors.Is(err, storage.ErrImageUnknown) {
 			// Image might be a manifest so attempt a manifest push
 			if manifestsErr := manifestPush(systemContext, store, src, destSpec, *iopts); manifestsErr == nil {
-				return nil
+				return signIfRequested(systemContext, dest, iopts, options.ReportWriter)
 			}
 		}
 		return util.GetFailureCause(err, fmt.Errorf("pushing image %q to %q: %w", src, destSpec, err))
@@ -258,6 +264,10 @@ func pushCmd(c *cobra.Command, args []string, iopts *pushOptions) error {
 
 	logger.Debug("Successfully pushed %s with digest %s", transports.ImageName(dest), digest.String())
 
+	if err = signIfRequested(systemContext, dest, iopts, options.ReportWriter); err != nil {
+		return err
+	}
+
 	if iopts.digestfile != "" {
 		if err = os.WriteFile(iopts.digestfile, []byte(digest.String()), 0644); err != nil {
 			return util.GetFailureCause(err, fmt.Errorf("failed to write digest to file %q: %w", iopts.digestfile, err))
@@ -267,6 +277,33 @@ func pushCmd(c *cobra.Command, args []string, iopts *pushOptions) error {
 	return nil
 }
 
+func signIfRequested(sc *types.SystemContext, dest types.ImageReference, iopts *pushOptions, reportWriter io.Writer) error {
+	if iopts.sigstoreKey == "" {
+		return nil
+	}
+	passphrase, err := readSigstorePassphrase(iopts.sigstorePassFile)
+	if err != nil {
+		return err
+	}
+	if err = signImage(getContext(), sc, dest, iopts.sigstoreKey, passphrase, reportWriter); err != nil {
+		return err
+	}
+	logger.Info("image %s is signed with %s", transports.ImageName(dest), iopts.sigstoreKey)
+	return nil
+}
+
+func readSigstorePassphrase(path string) ([]byte, error) {
+	if path == "" {
+		// sigstore signer requires a non-nil passphrase, unencrypted keys use an empty one
+		return []byte{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase file: %w", err)
+	}
+	return []byte(strings.TrimRight(string(data), "\r\n")), nil
+}
+
 // getListOfTransports gets the transports supported from the image library
 // and strips of the "tarball" transport from the string of transports returned
 func getListOfTransports() string {
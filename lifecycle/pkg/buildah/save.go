@@ -20,11 +20,15 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/containers/common/libimage"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+
+	"github.com/labring/sealos/pkg/utils/archive"
+	"github.com/labring/sealos/pkg/utils/flags"
 )
 
 type saveOptions struct {
@@ -34,6 +38,7 @@ type saveOptions struct {
 	ociAcceptUncompressedLayers bool
 	format                      string
 	output                      string
+	compression                 flags.Compression
 }
 
 func (o *saveOptions) RegisterFlags(fs *pflag.FlagSet) {
@@ -44,12 +49,24 @@ func (o *saveOptions) RegisterFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.format, "format", OCIArchive, "save image to oci-archive, oci-dir (directory with oci manifest type), "+
 		"docker-archive, docker-dir (directory with v2s2 manifest type)")
 	fs.StringVarP(&o.output, "output", "o", "", "write to a specified file (default: stdout, which must be redirected)")
+	fs.Var(&o.compression, "compression", "compress the archive file in parallel, available options are gzip/zstd, it can be loaded by load directly")
 }
 
 func (o *saveOptions) Validate() error {
 	if strings.Contains(o.output, ":") {
 		return fmt.Errorf("invalid filename (should not contain ':') %q", o.output)
 	}
+	if o.compression != flags.Disable {
+		if o.compression != flags.Gzip && o.compression != flags.Zstd {
+			return fmt.Errorf("unsupported compression %s, available options are gzip/zstd", o.compression.String())
+		}
+		if o.format != OCIArchive && o.format != DockerArchive {
+			return fmt.Errorf("--compression can only be set when --format is '%s' or '%s'", OCIArchive, DockerArchive)
+		}
+		if o.output == "" {
+			return errors.New("--compression can only be set with --output, it compresses the archive file")
+		}
+	}
 	return nil
 }
 
@@ -105,5 +122,18 @@ func runSave(cmd *cobra.Command, args []string, saveOpts *saveOptions) error {
 	} else {
 		saveOptions.AdditionalTags = tags
 	}
-	return r.Save(getContext(), names, saveOpts.format, saveOpts.output, saveOptions)
+	if saveOpts.compression == flags.Disable {
+		return r.Save(getContext(), names, saveOpts.format, saveOpts.output, saveOptions)
+	}
+	// save to a temporary archive next to the output, then compress it in parallel
+	tmpDir, err := os.MkdirTemp(filepath.Dir(saveOpts.output), ".save-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	tmpFile := filepath.Join(tmpDir, "image.tar")
+	if err = r.Save(getContext(), names, saveOpts.format, tmpFile, saveOptions); err != nil {
+		return err
+	}
+	return archive.CompressFile(tmpFile, saveOpts.output, saveOpts.compression)
 }
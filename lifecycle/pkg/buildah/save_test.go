@@ -0,0 +1,41 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buildah
+
+import (
+	"testing"
+
+	"github.com/labring/sealos/pkg/utils/flags"
+)
+
+func TestSaveOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    saveOptions
+		wantErr bool
+	}{
+		{"uncompressed to stdout", saveOptions{format: OCIArchive}, false},
+		{"compressed file", saveOptions{format: OCIArchive, output: "a.tar", compression: flags.Zstd}, false},
+		{"compressed stdout", saveOptions{format: OCIArchive, compression: flags.Gzip}, true},
+		{"compressed directory", saveOptions{format: OCIManifestDir, output: "a", compression: flags.Gzip}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
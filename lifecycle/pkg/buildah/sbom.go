@@ -0,0 +1,71 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buildah
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/containers/buildah/util"
+	"github.com/containers/image/v5/types"
+	"github.com/containers/storage"
+
+	"github.com/labring/sealos/pkg/sbom"
+	"github.com/labring/sealos/pkg/utils/file"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+// writeSBOM walks the rootfs of name and writes its software bill of materials to w.
+// name is resolved as a local directory, a working container and a local image, in that order.
+func writeSBOM(w io.Writer, sc *types.SystemContext, store storage.Store, name, format string) error {
+	inv, err := collectInventory(sc, store, name)
+	if err != nil {
+		return err
+	}
+	return sbom.Encode(w, format, inv)
+}
+
+func collectInventory(sc *types.SystemContext, store storage.Store, name string) (*sbom.Inventory, error) {
+	if file.IsDir(name) {
+		return sbom.Collect(name, "", name)
+	}
+	if builder, err := openBuilder(getContext(), store, name); err == nil {
+		mountPoint, err := builder.Mount(builder.MountLabel)
+		if err != nil {
+			return nil, fmt.Errorf("mounting container %q: %w", name, err)
+		}
+		defer func() {
+			if err := builder.Unmount(); err != nil {
+				logger.Warn("failed to unmount container %s: %v", name, err)
+			}
+		}()
+		return sbom.Collect(name, builder.FromImageDigest, mountPoint)
+	}
+
+	_, img, err := util.FindImage(store, "", sc, name)
+	if err != nil {
+		return nil, fmt.Errorf("%s is neither a directory, a container nor an image: %w", name, err)
+	}
+	mountPoint, err := store.MountImage(img.ID, []string{"ro"}, "")
+	if err != nil {
+		return nil, fmt.Errorf("mounting image %q: %w", name, err)
+	}
+	defer func() {
+		if _, err := store.UnmountImage(img.ID, false); err != nil {
+			logger.Warn("failed to unmount image %s: %v", name, err)
+		}
+	}()
+	return sbom.Collect(name, img.Digest.String(), mountPoint)
+}
@@ -0,0 +1,213 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buildah
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/containers/image/v5/copy"
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/docker/reference"
+	"github.com/containers/image/v5/image"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/types"
+	"github.com/containers/storage"
+	"github.com/opencontainers/go-digest"
+
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+// sigstoreRegistriesConfig enables reading and writing sigstore signatures as
+// OCI attachments (the `sha256-<digest>.sig` tags used by cosign) on every registry.
+const sigstoreRegistriesConfig = `default-docker:
+  use-sigstore-attachments: true
+`
+
+// withSigstoreAttachments returns a copy of sc which stores and looks up sigstore
+// signatures as registry attachments, the returned func must be called to clean up.
+func withSigstoreAttachments(sc *types.SystemContext) (*types.SystemContext, func(), error) {
+	dir, err := os.MkdirTemp("", "sealos-registries.d")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { _ = os.RemoveAll(dir) }
+	if err = os.WriteFile(filepath.Join(dir, "sealos-sigstore.yaml"), []byte(sigstoreRegistriesConfig), 0644); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	ret := &types.SystemContext{}
+	if sc != nil {
+		*ret = *sc
+	}
+	ret.RegistriesDirPath = dir
+	return ret, cleanup, nil
+}
+
+// signImage attaches a sigstore signature created by the private key to an image that was
+// already pushed to ref, only the manifest and the signature are uploaded again.
+func signImage(ctx context.Context, sc *types.SystemContext, ref types.ImageReference, privateKeyFile string, passphrase []byte, reportWriter io.Writer) error {
+	if ref.Transport().Name() != docker.Transport.Name() {
+		return fmt.Errorf("sigstore signing is only supported for %s:// destinations, got %s", docker.Transport.Name(), ref.Transport().Name())
+	}
+	sc, cleanup, err := withSigstoreAttachments(sc)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	policyContext, err := signature.NewPolicyContext(&signature.Policy{
+		Default: signature.PolicyRequirements{signature.NewPRInsecureAcceptAnything()},
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = policyContext.Destroy()
+	}()
+
+	_, err = copy.Image(ctx, policyContext, ref, ref, &copy.Options{
+		SourceCtx:                        sc,
+		DestinationCtx:                   sc,
+		ReportWriter:                     reportWriter,
+		SignBySigstorePrivateKeyFile:     privateKeyFile,
+		SignSigstorePrivateKeyPassphrase: passphrase,
+		// sign the manifest list as well if a multi-arch image was pushed
+		ImageListSelection: copy.CopyAllImages,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to sign image %s: %w", ref.StringWithinTransport(), err)
+	}
+	return nil
+}
+
+// ErrImageNotSigned is returned when none of the public keys accepts any signature of the image.
+var ErrImageNotSigned = errors.New("image is not signed by any trusted key")
+
+// verifyImageSignature resolves the digest of the image once and checks that the image of that
+// digest carries a sigstore signature made by any of publicKeys. It returns the digested reference
+// name@digest, which must be pulled and mounted instead of name, so that neither a tag moved after
+// the verification nor another local image of the same tag is used.
+//
+// The digest is the one of the local copy if there is one, since it is the image a pull with the
+// missing policy keeps, otherwise the one of the registry. The signatures of the digest are always
+// read from the registry, since pulling an image into local storage does not fetch its attachments.
+func verifyImageSignature(ctx context.Context, sc *types.SystemContext, store storage.Store, name string, publicKeys [][]byte) (string, error) {
+	if len(publicKeys) == 0 {
+		return "", errors.New("no public keys configured for signature verification")
+	}
+	sc, cleanup, err := withSigstoreAttachments(sc)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	named, err := reference.ParseNormalizedNamed(strings.TrimPrefix(name, docker.Transport.Name()+"://"))
+	if err != nil {
+		return "", fmt.Errorf("invalid image name %s: %w", name, err)
+	}
+	dgst, err := resolveImageDigest(ctx, sc, store, reference.TagNameOnly(named))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve digest of image %s: %w", name, err)
+	}
+	digested, err := reference.WithDigest(reference.TrimNamed(named), dgst)
+	if err != nil {
+		return "", err
+	}
+	ref, err := docker.NewReference(digested)
+	if err != nil {
+		return "", err
+	}
+	if err = verifyReference(ctx, sc, ref, publicKeys); err != nil {
+		return "", fmt.Errorf("%w: %s, %v", ErrImageNotSigned, transportsName(ref), err)
+	}
+	logger.Debug("signature of image %s is verified using %s", name, transportsName(ref))
+	return digested.String(), nil
+}
+
+// resolveImageDigest returns the manifest digest of the local copy of the image, or of the
+// registry copy if there is no local copy.
+func resolveImageDigest(ctx context.Context, sc *types.SystemContext, store storage.Store, named reference.Named) (digest.Digest, error) {
+	if digested, ok := named.(reference.Digested); ok {
+		return digested.Digest(), nil
+	}
+	img, err := store.Image(named.String())
+	if err == nil {
+		if img.Digest == "" {
+			return "", fmt.Errorf("local image %s has no manifest digest", img.ID)
+		}
+		return img.Digest, nil
+	}
+	if !errors.Is(err, storage.ErrImageUnknown) {
+		return "", err
+	}
+	ref, err := docker.NewReference(named)
+	if err != nil {
+		return "", err
+	}
+	return docker.GetDigest(ctx, sc, ref)
+}
+
+func transportsName(ref types.ImageReference) string {
+	return ref.Transport().Name() + ":" + ref.StringWithinTransport()
+}
+
+func verifyReference(ctx context.Context, sc *types.SystemContext, ref types.ImageReference, publicKeys [][]byte) error {
+	src, err := ref.NewImageSource(ctx, sc)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	unparsed := image.UnparsedInstance(src, nil)
+
+	var lastErr error
+	for i := range publicKeys {
+		allowed, err := isAllowedByKey(ctx, unparsed, publicKeys[i])
+		if allowed {
+			return nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = ErrImageNotSigned
+	}
+	return lastErr
+}
+
+func isAllowedByKey(ctx context.Context, unparsed types.UnparsedImage, publicKey []byte) (bool, error) {
+	req, err := signature.NewPRSigstoreSignedKeyData(publicKey, signature.NewPRMMatchRepoDigestOrExact())
+	if err != nil {
+		return false, err
+	}
+	policyContext, err := signature.NewPolicyContext(&signature.Policy{
+		Default: signature.PolicyRequirements{req},
+	})
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = policyContext.Destroy()
+	}()
+	return policyContext.IsRunningImageAllowed(ctx, unparsed)
+}
+
+func (impl *realImpl) VerifyImageSignature(name string, publicKeys [][]byte) (string, error) {
+	return verifyImageSignature(getContext(), impl.systemContext, impl.store, name, publicKeys)
+}
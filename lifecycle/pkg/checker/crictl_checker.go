@@ -29,7 +29,6 @@ import (
 	"github.com/labring/sealos/pkg/constants"
 	"github.com/labring/sealos/pkg/exec"
 	"github.com/labring/sealos/pkg/registry/helpers"
-	"github.com/labring/sealos/pkg/ssh"
 	"github.com/labring/sealos/pkg/template"
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 	exec2 "github.com/labring/sealos/pkg/utils/exec"
@@ -107,8 +106,7 @@ func (n *CRICtlChecker) Check(cluster *v2.Cluster, phase string) error {
 			}
 		}
 	}
-	sshCtx := ssh.NewCacheClientFromCluster(cluster, false)
-	sshCtx, err = exec.New(sshCtx)
+	sshCtx, err := exec.NewFromCluster(cluster, false)
 	if err != nil {
 		return err
 	}
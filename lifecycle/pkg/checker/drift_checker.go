@@ -0,0 +1,505 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checker
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"github.com/labring/sealos/pkg/buildah"
+	"github.com/labring/sealos/pkg/client-go/kubernetes"
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/exec"
+	"github.com/labring/sealos/pkg/registry/helpers"
+	"github.com/labring/sealos/pkg/ssh"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	fileutil "github.com/labring/sealos/pkg/utils/file"
+	"github.com/labring/sealos/pkg/utils/iputils"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+const (
+	DriftKindNode          = "node"
+	DriftKindKubeadmConfig = "kubeadm-config"
+	DriftKindLvscare       = "lvscare"
+	DriftKindHosts         = "hosts"
+	DriftKindMount         = "mount"
+)
+
+const (
+	staticPodPath             = "/etc/kubernetes/manifests"
+	kubeadmInitConfigFileName = "kubeadm-init.yaml"
+)
+
+// ErrDriftDetected is returned by DriftChecker when the live state is different from
+// the Clusterfile and is not fixed.
+var ErrDriftDetected = errors.New("the cluster has drifted from the Clusterfile")
+
+// DriftFinding is a difference between the Clusterfile and the live state.
+type DriftFinding struct {
+	Kind        string
+	Host        string
+	Field       string
+	Expected    string
+	Actual      string
+	Remediation string
+	// fix reconciles the finding, nil if it is not safe to do automatically
+	fix func() error
+}
+
+func (f *DriftFinding) Fixable() bool {
+	return f.fix != nil
+}
+
+func (f *DriftFinding) String() string {
+	target := f.Kind
+	if f.Host != "" {
+		target = fmt.Sprintf("%s %s", target, f.Host)
+	}
+	if f.Field != "" {
+		target = fmt.Sprintf("%s %s", target, f.Field)
+	}
+	return fmt.Sprintf("%s: expected %s, actual %s", target, orNone(f.Expected), orNone(f.Actual))
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
+
+// DriftChecker compares the Clusterfile with the nodes and kubeadm config in the
+// kubernetes API, the lvscare static pods and the hosts entries on the hosts, and
+// the mounted images of its status with the local containers.
+type DriftChecker struct {
+	// Fix reconciles the findings which are safe to fix, e.g. lvscare and hosts entries.
+	Fix bool
+	Out io.Writer
+}
+
+func NewDriftChecker(fix bool) *DriftChecker {
+	return &DriftChecker{Fix: fix, Out: os.Stdout}
+}
+
+func (d *DriftChecker) Check(cluster *v2.Cluster, phase string) error {
+	if phase != PhasePost {
+		return nil
+	}
+	findings, err := d.Detect(cluster)
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		fmt.Fprintln(d.Out, "No drift detected")
+		return nil
+	}
+	var unresolved int
+	for _, f := range findings {
+		fmt.Fprintln(d.Out, f.String())
+		if !d.Fix || !f.Fixable() {
+			fmt.Fprintf(d.Out, "  remediation: %s\n", f.Remediation)
+			unresolved++
+			continue
+		}
+		if err = f.fix(); err != nil {
+			fmt.Fprintf(d.Out, "  fix failed: %v\n  remediation: %s\n", err, f.Remediation)
+			unresolved++
+			continue
+		}
+		fmt.Fprintln(d.Out, "  fixed")
+	}
+	if unresolved > 0 {
+		return fmt.Errorf("%w: %d of %d differences are not fixed", ErrDriftDetected, unresolved, len(findings))
+	}
+	return nil
+}
+
+// Detect collects the live state over SSH and the kubernetes API and returns the
+// differences. A part which cannot be collected is skipped with a warning.
+func (d *DriftChecker) Detect(cluster *v2.Cluster) ([]*DriftFinding, error) {
+	execer, err := exec.NewFromCluster(cluster, false)
+	if err != nil {
+		return nil, err
+	}
+	remote := ssh.NewRemoteFromSSH(cluster.GetName(), execer)
+	pathResolver := constants.NewPathResolver(cluster.GetName())
+
+	var findings []*DriftFinding
+	if c, err := kubernetes.NewKubernetesClient(pathResolver.AdminFile(), ""); err != nil {
+		logger.Warn("skip checking the kubernetes API: %v", err)
+	} else {
+		nodes, err := c.Kubernetes().CoreV1().Nodes().List(context.Background(), v1.ListOptions{})
+		if err != nil {
+			logger.Warn("skip checking the nodes: %v", err)
+		} else {
+			findings = append(findings, diffNodes(cluster, nodes.Items)...)
+		}
+		if f, err := detectKubeadmConfigDrift(c, path.Join(pathResolver.ConfigsPath(), kubeadmInitConfigFileName)); err != nil {
+			logger.Warn("skip checking the kubeadm config: %v", err)
+		} else {
+			findings = append(findings, f...)
+		}
+	}
+
+	if bder, err := buildah.New(cluster.GetName()); err != nil {
+		logger.Warn("skip checking the mounted images: %v", err)
+	} else if containers, err := bder.ListContainers(); err != nil {
+		logger.Warn("skip checking the mounted images: %v", err)
+	} else {
+		findings = append(findings, diffMounts(cluster.Status.Mounts, containers, fileutil.IsExist)...)
+	}
+
+	rc := helpers.GetRegistryInfo(execer, pathResolver.RootFSPath(), cluster.GetRegistryIPAndPort())
+	var lvscareVIP string
+	manifests := map[string][]byte{}
+	for _, node := range cluster.GetNodeIPAndPortList() {
+		out, err := execer.Cmd(node, fmt.Sprintf("cat %s/%s.yaml 2>/dev/null || true", staticPodPath, constants.LvsCareStaticPodName))
+		if err != nil {
+			logger.Warn("skip checking the lvscare of %s: %v", node, err)
+			continue
+		}
+		manifests[node] = out
+		if vip, _, err := parseLvscareManifest(out); err == nil && vip != "" {
+			lvscareVIP = vip
+		}
+	}
+	for _, node := range cluster.GetNodeIPAndPortList() {
+		manifest, ok := manifests[node]
+		if !ok {
+			continue
+		}
+		if f := diffLvscare(cluster, node, manifest, lvscareVIP); f != nil {
+			f.fix = lvscareFix(cluster, remote, node, f.Expected, lvscareVIP)
+			findings = append(findings, f)
+		}
+	}
+
+	for _, host := range append(cluster.GetMasterIPAndPortList(), cluster.GetNodeIPAndPortList()...) {
+		out, err := execer.Cmd(host, "cat /etc/hosts")
+		if err != nil {
+			logger.Warn("skip checking the hosts of %s: %v", host, err)
+			continue
+		}
+		for _, f := range diffHosts(host, expectedHosts(cluster, host, rc), string(out)) {
+			f := f
+			domain, ip := f.Field, f.Expected
+			f.fix = func() error { return remote.HostsAdd(host, ip, domain) }
+			findings = append(findings, f)
+		}
+	}
+	return findings, nil
+}
+
+// diffNodes compares the hosts of cluster with the nodes registered in the kubernetes API.
+func diffNodes(cluster *v2.Cluster, nodes []corev1.Node) []*DriftFinding {
+	live := map[string]bool{}
+	for _, node := range nodes {
+		ip, _ := getNodeStatus(node)
+		live[ip] = true
+	}
+	expected := map[string]bool{}
+	var findings []*DriftFinding
+	for _, host := range append(cluster.GetMasterIPList(), cluster.GetNodeIPList()...) {
+		ip := iputils.GetHostIP(host)
+		expected[ip] = true
+		if !live[ip] {
+			findings = append(findings, &DriftFinding{
+				Kind:        DriftKindNode,
+				Host:        ip,
+				Expected:    "registered",
+				Actual:      "not registered",
+				Remediation: fmt.Sprintf("check the kubelet of %s, or remove it from the Clusterfile with `sealos delete --nodes %s`", ip, ip),
+			})
+		}
+	}
+	var extra []string
+	for ip := range live {
+		if ip != "" && !expected[ip] {
+			extra = append(extra, ip)
+		}
+	}
+	sort.Strings(extra)
+	for _, ip := range extra {
+		findings = append(findings, &DriftFinding{
+			Kind:        DriftKindNode,
+			Host:        ip,
+			Expected:    "not registered",
+			Actual:      "registered",
+			Remediation: fmt.Sprintf("manage it with `sealos add --nodes %s`, or remove it with `kubectl delete node`", ip),
+		})
+	}
+	return findings
+}
+
+// kubeadmConfigFields are the fields of ClusterConfiguration which are compared.
+var kubeadmConfigFields = [][]string{
+	{"kubernetesVersion"},
+	{"controlPlaneEndpoint"},
+	{"networking", "podSubnet"},
+	{"networking", "serviceSubnet"},
+	{"networking", "dnsDomain"},
+	{"apiServer", "certSANs"},
+}
+
+func detectKubeadmConfigDrift(c kubernetes.Client, savedFile string) ([]*DriftFinding, error) {
+	if !fileutil.IsExist(savedFile) {
+		return nil, fmt.Errorf("saved kubeadm config %s is not found", savedFile)
+	}
+	data, err := fileutil.ReadAll(savedFile)
+	if err != nil {
+		return nil, err
+	}
+	saved, err := clusterConfigurationFromDocuments(data)
+	if err != nil {
+		return nil, err
+	}
+	cm, err := c.Kubernetes().CoreV1().ConfigMaps("kube-system").Get(context.Background(), "kubeadm-config", v1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, errors.New("configmap kube-system/kubeadm-config is not found")
+		}
+		return nil, err
+	}
+	live := map[string]interface{}{}
+	if err = yaml.Unmarshal([]byte(cm.Data["ClusterConfiguration"]), &live); err != nil {
+		return nil, err
+	}
+	return diffClusterConfiguration(saved, live, savedFile), nil
+}
+
+// clusterConfigurationFromDocuments returns the ClusterConfiguration of a kubeadm config file.
+func clusterConfigurationFromDocuments(data []byte) (map[string]interface{}, error) {
+	for _, doc := range strings.Split(string(data), "\n---") {
+		obj := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+			return nil, err
+		}
+		if obj["kind"] == "ClusterConfiguration" {
+			return obj, nil
+		}
+	}
+	return nil, errors.New("ClusterConfiguration is not found")
+}
+
+func diffClusterConfiguration(saved, live map[string]interface{}, savedFile string) []*DriftFinding {
+	var findings []*DriftFinding
+	for _, fields := range kubeadmConfigFields {
+		expected, actual := nestedString(saved, fields...), nestedString(live, fields...)
+		// the field is not managed by sealos if it is not in the saved config
+		if expected == "" || expected == actual {
+			continue
+		}
+		findings = append(findings, &DriftFinding{
+			Kind:     DriftKindKubeadmConfig,
+			Field:    strings.Join(fields, "."),
+			Expected: expected,
+			Actual:   actual,
+			Remediation: fmt.Sprintf("revert the change of configmap kube-system/kubeadm-config, "+
+				"or update the Clusterfile and %s to adopt it", savedFile),
+		})
+	}
+	return findings
+}
+
+func nestedString(obj map[string]interface{}, fields ...string) string {
+	v, ok, err := unstructured.NestedFieldNoCopy(obj, fields...)
+	if !ok || err != nil || v == nil {
+		return ""
+	}
+	if list, ok := v.([]interface{}); ok {
+		items := make([]string, 0, len(list))
+		for i := range list {
+			items = append(items, fmt.Sprint(list[i]))
+		}
+		sort.Strings(items)
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(v)
+}
+
+// parseLvscareManifest returns the virtual server and the real servers of the lvscare static pod.
+func parseLvscareManifest(data []byte) (vip string, rs []string, err error) {
+	pod := &corev1.Pod{}
+	if err = yaml.Unmarshal(data, pod); err != nil {
+		return "", nil, err
+	}
+	for _, c := range pod.Spec.Containers {
+		for i := 0; i+1 < len(c.Args); i++ {
+			switch c.Args[i] {
+			case "--vs":
+				vip = c.Args[i+1]
+			case "--rs":
+				rs = append(rs, c.Args[i+1])
+			}
+		}
+	}
+	return vip, rs, nil
+}
+
+// diffLvscare compares the real servers of the lvscare static pod on node with the masters,
+// vip is the virtual server found on the nodes which gives the port of the apiserver.
+func diffLvscare(cluster *v2.Cluster, node string, manifest []byte, vip string) *DriftFinding {
+	f := &DriftFinding{Kind: DriftKindLvscare, Host: node, Field: "rs",
+		Remediation: fmt.Sprintf("regenerate %s/%s.yaml on %s with `sealos drift --fix`", staticPodPath, constants.LvsCareStaticPodName, node)}
+	if len(strings.TrimSpace(string(manifest))) == 0 {
+		if vip == "" {
+			// none of the nodes has lvscare, it is not deployed by the runtime
+			return nil
+		}
+		f.Expected = strings.Join(lvscareRealServers(cluster, vip), ",")
+		return f
+	}
+	liveVIP, rs, err := parseLvscareManifest(manifest)
+	if err != nil {
+		f.Actual = fmt.Sprintf("invalid manifest: %v", err)
+		f.Expected = strings.Join(lvscareRealServers(cluster, vip), ",")
+		return f
+	}
+	expected := lvscareRealServers(cluster, liveVIP)
+	sort.Strings(rs)
+	if slices.Equal(expected, rs) {
+		return nil
+	}
+	f.Expected, f.Actual = strings.Join(expected, ","), strings.Join(rs, ",")
+	return f
+}
+
+func lvscareRealServers(cluster *v2.Cluster, vip string) []string {
+	port := fmt.Sprint(constants.DefaultAPIServerPort)
+	if i := strings.LastIndex(vip, ":"); i >= 0 {
+		port = vip[i+1:]
+	}
+	var rs []string
+	for _, master := range cluster.GetMasterIPList() {
+		rs = append(rs, fmt.Sprintf("%s:%s", iputils.GetHostIP(master), port))
+	}
+	sort.Strings(rs)
+	return rs
+}
+
+func lvscareFix(cluster *v2.Cluster, remote *ssh.Remote, node, expected, vip string) func() error {
+	if vip == "" || expected == "" {
+		return nil
+	}
+	return func() error {
+		return remote.StaticPod(node, vip, constants.LvsCareStaticPodName, cluster.GetLvscareImage(),
+			strings.Split(expected, ","), staticPodPath)
+	}
+}
+
+// diffMounts compares the mounted images of the cluster status with the local
+// containers, exists tells whether a mount point exists.
+func diffMounts(mounts []v2.MountImage, containers []buildah.JSONContainer, exists func(string) bool) []*DriftFinding {
+	live := map[string]bool{}
+	for _, ctr := range containers {
+		live[ctr.ContainerName] = true
+	}
+	var findings []*DriftFinding
+	for _, m := range mounts {
+		remediation := fmt.Sprintf("run `sealos run %s` again to mount the image", m.ImageName)
+		if !live[m.Name] {
+			findings = append(findings, &DriftFinding{
+				Kind:        DriftKindMount,
+				Field:       m.ImageName,
+				Expected:    "container " + m.Name,
+				Actual:      "no container",
+				Remediation: remediation,
+			})
+			continue
+		}
+		if m.MountPoint == "" || !exists(m.MountPoint) {
+			findings = append(findings, &DriftFinding{
+				Kind:        DriftKindMount,
+				Field:       m.ImageName,
+				Expected:    "mounted at " + orNone(m.MountPoint),
+				Actual:      "not mounted",
+				Remediation: remediation,
+			})
+		}
+	}
+	return findings
+}
+
+// expectedHosts returns the hosts entries which are added to host by the bootstrap
+// and the runtime: apiserver.cluster.local is the master itself on the masters, as
+// the joined masters are pointed to themselves, and the VIP on the nodes.
+func expectedHosts(cluster *v2.Cluster, host string, rc *v2.RegistryConfig) map[string]string {
+	entries := map[string]string{rc.Domain: iputils.GetHostIP(rc.IP)}
+	if slices.Contains(cluster.GetMasterIPAndPortList(), host) {
+		entries[constants.DefaultAPIServerDomain] = iputils.GetHostIP(host)
+	} else {
+		entries[constants.DefaultAPIServerDomain] = cluster.GetVIP()
+		entries[constants.DefaultLvscareDomain] = iputils.GetHostIP(host)
+	}
+	return entries
+}
+
+// diffHosts compares the content of /etc/hosts of host with the expected entries by domain.
+func diffHosts(host string, expected map[string]string, content string) []*DriftFinding {
+	live := map[string][]string{}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		for _, domain := range fields[1:] {
+			live[domain] = append(live[domain], fields[0])
+		}
+	}
+	domains := make([]string, 0, len(expected))
+	for domain := range expected {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	var findings []*DriftFinding
+	for _, domain := range domains {
+		ip := expected[domain]
+		if domain == "" || ip == "" {
+			continue
+		}
+		if ips := live[domain]; len(ips) == 1 && ips[0] == ip {
+			continue
+		}
+		findings = append(findings, &DriftFinding{
+			Kind:        DriftKindHosts,
+			Host:        host,
+			Field:       domain,
+			Expected:    ip,
+			Actual:      strings.Join(live[domain], ","),
+			Remediation: fmt.Sprintf("run `sealctl hosts add --ip %s --domain %s` on %s", ip, domain, host),
+		})
+	}
+	return findings
+}
@@ -0,0 +1,157 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checker
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/labring/sealos/pkg/buildah"
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/ipvs"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/iputils"
+)
+
+func testDriftCluster() *v2.Cluster {
+	return &v2.Cluster{Spec: v2.ClusterSpec{Hosts: []v2.Host{
+		{IPS: []string{"192.168.0.2:22", "192.168.0.3:22"}, Roles: []string{v2.MASTER}},
+		{IPS: []string{"192.168.0.4:22"}, Roles: []string{v2.NODE}},
+	}}}
+}
+
+func testNode(ip string) corev1.Node {
+	return corev1.Node{Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: ip}}}}
+}
+
+func TestDiffNodes(t *testing.T) {
+	cluster := testDriftCluster()
+	if f := diffNodes(cluster, []corev1.Node{testNode("192.168.0.2"), testNode("192.168.0.3"), testNode("192.168.0.4")}); len(f) != 0 {
+		t.Errorf("unexpected findings %v", f)
+	}
+	f := diffNodes(cluster, []corev1.Node{testNode("192.168.0.2"), testNode("192.168.0.3"), testNode("192.168.0.5")})
+	if len(f) != 2 || f[0].Host != "192.168.0.4" || f[0].Actual != "not registered" || f[1].Host != "192.168.0.5" || f[1].Fixable() {
+		t.Errorf("unexpected findings %v", f)
+	}
+}
+
+func TestDiffClusterConfiguration(t *testing.T) {
+	saved, err := clusterConfigurationFromDocuments([]byte(`apiVersion: kubeadm.k8s.io/v1beta3
+kind: InitConfiguration
+---
+apiVersion: kubeadm.k8s.io/v1beta3
+kind: ClusterConfiguration
+kubernetesVersion: v1.25.0
+controlPlaneEndpoint: apiserver.cluster.local:6443
+networking:
+  podSubnet: 100.64.0.0/10
+apiServer:
+  certSANs:
+  - 127.0.0.1
+  - apiserver.cluster.local
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := map[string]interface{}{
+		"kubernetesVersion":    "v1.25.0",
+		"controlPlaneEndpoint": "apiserver.cluster.local:6443",
+		"networking":           map[string]interface{}{"podSubnet": "10.244.0.0/16", "serviceSubnet": "10.96.0.0/12"},
+		"apiServer":            map[string]interface{}{"certSANs": []interface{}{"apiserver.cluster.local", "127.0.0.1"}},
+	}
+	f := diffClusterConfiguration(saved, live, "kubeadm-init.yaml")
+	if len(f) != 1 || f[0].Field != "networking.podSubnet" || f[0].Expected != "100.64.0.0/10" || f[0].Actual != "10.244.0.0/16" {
+		t.Errorf("unexpected findings %v", f)
+	}
+}
+
+func TestDiffLvscare(t *testing.T) {
+	cluster := testDriftCluster()
+	manifest, err := ipvs.LvsStaticPodYaml("10.103.97.2:6443", []string{"192.168.0.2:6443"}, "", constants.LvsCareStaticPodName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := diffLvscare(cluster, "192.168.0.4:22", []byte(manifest), "")
+	if f == nil || f.Expected != "192.168.0.2:6443,192.168.0.3:6443" || f.Actual != "192.168.0.2:6443" {
+		t.Fatalf("unexpected finding %v", f)
+	}
+
+	manifest, err = ipvs.LvsStaticPodYaml("10.103.97.2:6443", []string{"192.168.0.3:6443", "192.168.0.2:6443"}, "", constants.LvsCareStaticPodName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f = diffLvscare(cluster, "192.168.0.4:22", []byte(manifest), ""); f != nil {
+		t.Errorf("unexpected finding %v", f)
+	}
+	if f = diffLvscare(cluster, "192.168.0.4:22", nil, ""); f != nil {
+		t.Errorf("lvscare is not deployed, unexpected finding %v", f)
+	}
+	if f = diffLvscare(cluster, "192.168.0.4:22", nil, "10.103.97.2:6443"); f == nil || f.Actual != "" {
+		t.Errorf("expected missing manifest, got %v", f)
+	}
+}
+
+func TestDiffHosts(t *testing.T) {
+	cluster := testDriftCluster()
+	rc := &v2.RegistryConfig{Domain: "sealos.hub", IP: "192.168.0.2:22"}
+	content := `127.0.0.1 localhost
+192.168.0.2 sealos.hub # registry
+10.103.97.2 apiserver.cluster.local
+`
+	f := diffHosts("192.168.0.4:22", expectedHosts(cluster, "192.168.0.4:22", rc), content)
+	if len(f) != 1 || f[0].Field != constants.DefaultLvscareDomain || f[0].Expected != "192.168.0.4" {
+		t.Errorf("unexpected findings %v", f)
+	}
+	f = diffHosts("192.168.0.3:22", expectedHosts(cluster, "192.168.0.3:22", rc), content)
+	if len(f) != 1 || f[0].Field != constants.DefaultAPIServerDomain || f[0].Expected != "192.168.0.3" || f[0].Actual != "10.103.97.2" {
+		t.Errorf("unexpected findings %v", f)
+	}
+}
+
+func TestExpectedHostsMultiMaster(t *testing.T) {
+	cluster := testDriftCluster()
+	rc := &v2.RegistryConfig{Domain: "sealos.hub", IP: "192.168.0.2:22"}
+	// every master is the apiserver of itself, the nodes go through the VIP
+	for host, want := range map[string]string{
+		"192.168.0.2:22": "192.168.0.2",
+		"192.168.0.3:22": "192.168.0.3",
+		"192.168.0.4:22": cluster.GetVIP(),
+	} {
+		entries := expectedHosts(cluster, host, rc)
+		if got := entries[constants.DefaultAPIServerDomain]; got != want {
+			t.Errorf("%s: got apiserver %s, want %s", host, got, want)
+		}
+		content := "192.168.0.2 sealos.hub\n" + want + " apiserver.cluster.local\n" + iputils.GetHostIP(host) + " lvscare.node.ip\n"
+		if f := diffHosts(host, entries, content); len(f) != 0 {
+			t.Errorf("%s: unexpected findings %v", host, f)
+		}
+	}
+}
+
+func TestDiffMounts(t *testing.T) {
+	mounts := []v2.MountImage{
+		{Name: "default-a", ImageName: "labring/kubernetes:v1.25.0", MountPoint: "/var/lib/containers/a/merged"},
+		{Name: "default-b", ImageName: "labring/helm:v3.8.2", MountPoint: "/var/lib/containers/b/merged"},
+		{Name: "default-c", ImageName: "labring/calico:v3.24.1", MountPoint: "/var/lib/containers/c/merged"},
+	}
+	containers := []buildah.JSONContainer{{ContainerName: "default-a"}, {ContainerName: "default-b"}, {ContainerName: "other"}}
+	exists := func(p string) bool { return p == "/var/lib/containers/a/merged" }
+	f := diffMounts(mounts, containers, exists)
+	if len(f) != 2 || f[0].Field != "labring/helm:v3.8.2" || f[0].Actual != "not mounted" ||
+		f[1].Field != "labring/calico:v3.24.1" || f[1].Actual != "no container" || f[1].Fixable() {
+		t.Errorf("unexpected findings %v", f)
+	}
+}
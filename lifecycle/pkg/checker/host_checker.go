@@ -15,13 +15,13 @@
 package checker
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/labring/sealos/pkg/exec"
-	"github.com/labring/sealos/pkg/ssh"
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 	"github.com/labring/sealos/pkg/utils/confirm"
 	"github.com/labring/sealos/pkg/utils/logger"
@@ -29,18 +29,18 @@ import (
 
 type HostChecker struct {
 	IPs []string
+	ctx context.Context
 }
 
 func (a HostChecker) Check(cluster *v2.Cluster, _ string) error {
 	var ipList []string
 	if len(cluster.GetMasterIPList())&1 == 0 {
-		return confirmNonOddMasters()
+		return confirmNonOddMasters(a.ctx)
 	}
 	if len(a.IPs) != 0 {
 		ipList = a.IPs
 	}
-	sshClient := ssh.NewCacheClientFromCluster(cluster, false)
-	execer, err := exec.New(sshClient)
+	execer, err := exec.NewFromCluster(cluster, false)
 	if err != nil {
 		return err
 	}
@@ -50,8 +50,10 @@ func (a HostChecker) Check(cluster *v2.Cluster, _ string) error {
 	return checkTimeSync(execer, ipList)
 }
 
-func NewIPsHostChecker(ips []string) Interface {
-	return &HostChecker{IPs: ips}
+// NewIPsHostChecker returns a checker of ips, the confirmations are answered
+// by the Confirmer of ctx if there is one.
+func NewIPsHostChecker(ctx context.Context, ips []string) Interface {
+	return &HostChecker{IPs: ips, ctx: ctx}
 }
 
 func checkHostnameUnique(s exec.Interface, ipList []string) error {
@@ -91,12 +93,12 @@ func checkTimeSync(s exec.Interface, ipList []string) error {
 	return nil
 }
 
-func confirmNonOddMasters() error {
+func confirmNonOddMasters(ctx context.Context) error {
 	prompt := "Warning: Using an even number of master nodes is a risky operation and can lead to reduced high availability and potential resource wastage. " +
 		"It is strongly recommended to use an odd number of master nodes for optimal cluster stability. " +
 		"Are you sure you want to proceed?"
 	cancel := "The number of masters needs to be set to an odd number."
-	yes, err := confirm.Confirm(prompt, cancel)
+	yes, err := confirm.ConfirmContext(ctx, prompt, cancel)
 	if err != nil {
 		return err
 	}
@@ -119,8 +121,7 @@ func (a ContainerdChecker) Check(cluster *v2.Cluster, _ string) error {
 	if len(a.IPs) != 0 {
 		ipList = a.IPs
 	}
-	sshClient := ssh.NewCacheClientFromCluster(cluster, false)
-	execer, err := exec.New(sshClient)
+	execer, err := exec.NewFromCluster(cluster, false)
 	if err != nil {
 		return err
 	}
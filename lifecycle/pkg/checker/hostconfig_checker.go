@@ -0,0 +1,82 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/labring/sealos/pkg/bootstrap"
+	"github.com/labring/sealos/pkg/template"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+// HostConfigChecker reports the settings of the hostConfig of the Clusterfile which drifted on the hosts.
+type HostConfigChecker struct {
+}
+
+type HostConfigStatus struct {
+	Error  string
+	Drifts []bootstrap.HostConfigDrift
+}
+
+func (n *HostConfigChecker) Check(cluster *v2.Cluster, phase string) error {
+	if phase != PhasePost || cluster.Spec.HostConfig == nil {
+		return nil
+	}
+	status := &HostConfigStatus{}
+	defer func() {
+		err := n.Output(status)
+		if err != nil {
+			logger.Error("error output: %+v", err)
+		}
+	}()
+	drifts, err := bootstrap.CheckHostConfig(cluster)
+	if err != nil {
+		status.Error = fmt.Errorf("check host config error: %w", err).Error()
+		return nil
+	}
+	status.Drifts = drifts
+	status.Error = Nil
+	return nil
+}
+
+func (n *HostConfigChecker) Output(status *HostConfigStatus) error {
+	tpl, isOk, err := template.TryParse(`
+Host Config Status
+  Error: {{ .Error }}
+  {{ if .Drifts -}}
+  Drifts:
+    {{- range .Drifts }}
+    Host: {{ .Host }} Setting: {{ .Setting }} Want: {{ printf "%q" .Want }} Got: {{ printf "%q" .Got }}
+    {{- end }}
+  {{ else -}}
+  Drifts: None
+  {{ end }}`)
+	if err != nil || !isOk {
+		if err != nil {
+			logger.Error("failed to render host config checkers template. error: %s", err.Error())
+			return err
+		}
+		return errors.New("convert host config template failed")
+	}
+	return tpl.Execute(os.Stdout, status)
+}
+
+func NewHostConfigChecker() Interface {
+	return &HostConfigChecker{}
+}
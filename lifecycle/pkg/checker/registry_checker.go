@@ -31,7 +31,6 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	"github.com/labring/sealos/pkg/constants"
-	"github.com/labring/sealos/pkg/ssh"
 	"github.com/labring/sealos/pkg/template"
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 	fileutil "github.com/labring/sealos/pkg/utils/file"
@@ -88,8 +87,7 @@ func (n *RegistryChecker) Check(cluster *v2.Cluster, phase string) error {
 		}
 	}
 
-	sshCtx := ssh.NewCacheClientFromCluster(cluster, false)
-	execer, err := exec.New(sshCtx)
+	execer, err := exec.NewFromCluster(cluster, false)
 	if err != nil {
 		return err
 	}
@@ -31,6 +31,8 @@ type ClusterFile struct {
 	customValues             []string
 	customSets               []string
 	customEnvs               []string
+	// values are the merged values the Clusterfile is rendered with
+	values map[string]interface{}
 
 	cluster       *v2.Cluster
 	configs       []v2.Config
@@ -44,6 +46,7 @@ type Interface interface {
 	GetCluster() *v2.Cluster
 	GetConfigs() []v2.Config
 	GetRuntimeConfig() runtime.Config
+	ValidateValues(schemas map[string][]byte) error
 }
 
 func (c *ClusterFile) GetCluster() *v2.Cluster {
@@ -15,6 +15,8 @@
 package clusterfile
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -276,3 +278,52 @@ func Test_NewClusterFileWithSingleSchedule(t *testing.T) {
 		})
 	}
 }
+
+func Test_ValuesSchema(t *testing.T) {
+	schema := []byte(`{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "required": ["clusterName"],
+  "properties": {
+    "clusterName": {"type": "string", "pattern": "^[a-z]+$"}
+  }
+}`)
+	clusterfile := filepath.Join(t.TempDir(), "Clusterfile")
+	data := `apiVersion: apps.sealos.io/v1beta1
+kind: Cluster
+metadata:
+  name: {{ .Values.clusterName | default "default" }}
+spec:
+  image:
+  - labring/kubernetes:v1.25.0
+  - labring/helm:v3.8.2
+`
+	if err := os.WriteFile(clusterfile, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	schemas := map[string][]byte{"labring/kubernetes:v1.25.0": schema}
+	tests := []struct {
+		name    string
+		sets    []string
+		wantErr bool
+	}{
+		{"valid", []string{"clusterName=test"}, false},
+		{"missing", nil, true},
+		{"invalid", []string{"clusterName=Test-1"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cf := NewClusterFile(clusterfile, WithCustomSets(tt.sets))
+			if err := cf.Process(); err != nil {
+				t.Fatal(err)
+			}
+			err := cf.ValidateValues(schemas)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateValues() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cf.GetCluster().Name != "test" {
+				t.Errorf("got cluster name %s", cf.GetCluster().Name)
+			}
+		})
+	}
+}
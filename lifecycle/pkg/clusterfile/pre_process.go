@@ -17,7 +17,10 @@ package clusterfile
 import (
 	"bytes"
 	"errors"
+	"fmt"
+	"sort"
 
+	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli/values"
 	"helm.sh/helm/v3/pkg/getter"
 
@@ -68,17 +71,14 @@ func (c *ClusterFile) loadClusterFile() ([]byte, error) {
 		"Values": mergeValues,
 	}
 	out := bytes.NewBuffer(nil)
-	tpl, isOk, err := template.TryParse(string(body))
-	if isOk {
-		if err != nil {
-			return nil, err
-		}
-		if err := tpl.Execute(out, data); err != nil {
-			return nil, err
-		}
-	} else {
-		out.Write(body)
+	tpl, err := template.New("Clusterfile").Parse(string(body))
+	if err != nil {
+		return nil, err
 	}
+	if err := tpl.Execute(out, data); err != nil {
+		return nil, err
+	}
+	c.values = mergeValues
 
 	for i := range c.customConfigFiles {
 		configData, err := fileutil.ReadAll(c.customConfigFiles[i])
@@ -111,6 +111,28 @@ func (c *ClusterFile) loadRenderValues() (map[string]interface{}, error) {
 	}})
 }
 
+// ValidateValues validates the render values against the JSON schemas shipped
+// in the images, keyed by image name. The schemas are read from the mounted
+// images, which were verified by the policy of the cluster.
+func (c *ClusterFile) ValidateValues(schemas map[string][]byte) error {
+	images := make([]string, 0, len(schemas))
+	for image := range schemas {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	vals := c.values
+	if vals == nil {
+		vals = map[string]interface{}{}
+	}
+	for _, image := range images {
+		logger.Debug("validating values against the schema of image %s", image)
+		if err := chartutil.ValidateAgainstSingleSchema(vals, schemas[image]); err != nil {
+			return fmt.Errorf("values don't meet the specifications of the schema of image %s: %v", image, err)
+		}
+	}
+	return nil
+}
+
 func (c *ClusterFile) decode(data []byte) error {
 	for _, fn := range []func([]byte) error{
 		c.DecodeCluster, c.DecodeConfigs, c.DecodeRuntimeConfig,
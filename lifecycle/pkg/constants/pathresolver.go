@@ -34,6 +34,7 @@ var (
 
 const (
 	DefaultRootfsConfigFileName = "config.yml"
+	ValuesSchemaFileName        = "values.schema.json"
 	rootFsDirName               = "rootfs"
 	EtcDirName                  = "etc"
 	ChartsDirName               = "charts"
@@ -0,0 +1,296 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labring/sealos/pkg/constants"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/progress"
+)
+
+const testToken = "secret"
+
+type fakeRunner struct {
+	mu    sync.Mutex
+	order []string
+	// release blocks the run operations until closed
+	release chan struct{}
+}
+
+func (f *fakeRunner) record(s string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.order = append(f.order, s)
+}
+
+func (f *fakeRunner) status(cluster string) (*v2.Cluster, error) {
+	if cluster != "default" {
+		return nil, errors.New("not found")
+	}
+	c := &v2.Cluster{}
+	c.Name = cluster
+	return c, nil
+}
+
+func (f *fakeRunner) run(cluster string, req *RunRequest) (RunFunc, error) {
+	if len(req.Images) == 0 {
+		return nil, errors.New("images cannot be empty")
+	}
+	return func(ctx context.Context, op *Operation) (interface{}, error) {
+		<-f.release
+		logger.Info("running %s", req.Images[0])
+		f.record(cluster + "/run/" + req.Images[0])
+		return nil, nil
+	}, nil
+}
+
+func (f *fakeRunner) apply(cluster string, _ *ApplyRequest) (RunFunc, error) {
+	return func(ctx context.Context, op *Operation) (interface{}, error) {
+		f.record(cluster + "/apply")
+		return nil, progress.RunStep("192.168.0.2:22", "bootstrap", func() error { return errors.New("apply failed") })
+	}, nil
+}
+
+func (f *fakeRunner) scale(cluster, action string, _ *ScaleRequest) (RunFunc, error) {
+	return func(ctx context.Context, op *Operation) (interface{}, error) {
+		f.record(cluster + "/" + action)
+		return nil, nil
+	}, nil
+}
+
+func (f *fakeRunner) reset(cluster string) RunFunc {
+	return func(ctx context.Context, op *Operation) (interface{}, error) {
+		f.record(cluster + "/reset")
+		return nil, nil
+	}
+}
+
+func (f *fakeRunner) exec(cluster string, req *ExecRequest) (RunFunc, error) {
+	return func(ctx context.Context, op *Operation) (interface{}, error) {
+		return []ExecResult{{Host: "192.168.0.2:22", Output: req.Command}}, nil
+	}, nil
+}
+
+func newTestServer(t *testing.T) (*httptest.Server, *fakeRunner) {
+	constants.DefaultRuntimeRootDir = t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	s, err := NewServer(ctx, Options{Token: testToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRunner{release: make(chan struct{})}
+	s.runner = f
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return ts, f
+}
+
+func do(t *testing.T, ts *httptest.Server, method, path, body string, out interface{}) int {
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func waitDone(t *testing.T, ts *httptest.Server, id string) *Operation {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		op := &Operation{}
+		do(t, ts, http.MethodGet, "/v1/operations/"+id, "", op)
+		if op.State.Done() {
+			return op
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("operation %s is not done in time", id)
+	return nil
+}
+
+func TestNewServer(t *testing.T) {
+	if _, err := NewServer(context.Background(), Options{}); err == nil {
+		t.Error("expected an error without token")
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	ts, _ := newTestServer(t)
+	resp, err := http.Get(ts.URL + "/v1/clusters/default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("got status %d without token, want 401", resp.StatusCode)
+	}
+	resp, err = http.Get(ts.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("got status %d of healthz, want 200", resp.StatusCode)
+	}
+	cluster := &v2.Cluster{}
+	if code := do(t, ts, http.MethodGet, "/v1/clusters/default", "", cluster); code != http.StatusOK || cluster.Name != "default" {
+		t.Errorf("got status %d and cluster %q", code, cluster.Name)
+	}
+	if code := do(t, ts, http.MethodGet, "/v1/clusters/other", "", nil); code != http.StatusNotFound {
+		t.Errorf("got status %d of unknown cluster, want 404", code)
+	}
+}
+
+func TestBadRequest(t *testing.T) {
+	ts, _ := newTestServer(t)
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodPost, "/v1/clusters/default/run", `{"images":[]}`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/clusters/default/run", `{"unknown":1}`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/clusters/default/upgrade", `{}`, http.StatusNotFound},
+		{http.MethodGet, "/v1/clusters/default/run", ``, http.StatusMethodNotAllowed},
+		{http.MethodGet, "/v1/operations/unknown", ``, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if code := do(t, ts, tt.method, tt.path, tt.body, nil); code != tt.want {
+			t.Errorf("%s %s: got status %d, want %d", tt.method, tt.path, code, tt.want)
+		}
+	}
+}
+
+func TestOperationsInOrder(t *testing.T) {
+	ts, f := newTestServer(t)
+	var ids []string
+	for _, image := range []string{"a", "b"} {
+		op := &Operation{}
+		if code := do(t, ts, http.MethodPost, "/v1/clusters/default/run", `{"images":["`+image+`"]}`, op); code != http.StatusAccepted {
+			t.Fatalf("got status %d, want 202", code)
+		}
+		ids = append(ids, op.ID)
+	}
+	op := &Operation{}
+	do(t, ts, http.MethodPost, "/v1/clusters/default/add", `{"nodes":"192.168.0.3"}`, op)
+	ids = append(ids, op.ID)
+	// operations of the other clusters wait for the running one
+	other := &Operation{}
+	do(t, ts, http.MethodDelete, "/v1/clusters/other", "", other)
+	ids = append(ids, other.ID)
+	time.Sleep(100 * time.Millisecond)
+	do(t, ts, http.MethodGet, "/v1/operations/"+other.ID, "", other)
+	if other.State != StateQueued {
+		t.Errorf("operation of the other cluster is %s, want queued", other.State)
+	}
+	close(f.release)
+	for _, id := range ids {
+		if op := waitDone(t, ts, id); op.State != StateSucceeded {
+			t.Errorf("operation %s is %s: %s", id, op.State, op.Error)
+		}
+	}
+	want := []string{"default/run/a", "default/run/b", "default/add", "other/reset"}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if strings.Join(f.order, ",") != strings.Join(want, ",") {
+		t.Errorf("got order %v, want %v", f.order, want)
+	}
+	var list []Operation
+	do(t, ts, http.MethodGet, "/v1/operations?cluster=default", "", &list)
+	if len(list) != 3 || list[0].ID != ids[0] {
+		t.Errorf("got %d operations of the cluster, want 3", len(list))
+	}
+}
+
+func TestEvents(t *testing.T) {
+	ts, f := newTestServer(t)
+	close(f.release)
+	op := &Operation{}
+	do(t, ts, http.MethodPost, "/v1/clusters/default/apply", `{"clusterfile":"x"}`, op)
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/operations/"+op.ID+"/events", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var types []EventType
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		e := Event{}
+		if err = json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		switch e.Type {
+		case EventLog:
+		case EventProgress:
+			if e.Progress.Host != "192.168.0.2:22" {
+				t.Errorf("got progress event of host %q", e.Progress.Host)
+			}
+			types = append(types, EventType(e.Progress.Type))
+		default:
+			types = append(types, e.Type)
+		}
+	}
+	want := []EventType{EventQueued, EventStarted, EventType(progress.StepStarted), EventType(progress.StepFinished), EventType(progress.HostError), EventFailed}
+	if fmt.Sprint(types) != fmt.Sprint(want) {
+		t.Errorf("got events %v, want %v", types, want)
+	}
+	if op = waitDone(t, ts, op.ID); op.Error != "apply failed" {
+		t.Errorf("got error %q", op.Error)
+	}
+}
+
+func TestExec(t *testing.T) {
+	ts, _ := newTestServer(t)
+	op := &Operation{}
+	do(t, ts, http.MethodPost, "/v1/clusters/default/exec", `{"command":"uptime","roles":["master"]}`, op)
+	waitDone(t, ts, op.ID)
+
+	var ret struct {
+		Result []ExecResult `json:"result"`
+	}
+	do(t, ts, http.MethodGet, "/v1/operations/"+op.ID, "", &ret)
+	if len(ret.Result) != 1 || ret.Result[0].Output != "uptime" {
+		t.Errorf("got result %+v", ret.Result)
+	}
+}
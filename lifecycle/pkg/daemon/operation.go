@@ -0,0 +1,173 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/labring/sealos/pkg/utils/progress"
+	"github.com/labring/sealos/pkg/utils/rand"
+)
+
+type OperationState string
+
+const (
+	StateQueued    OperationState = "queued"
+	StateRunning   OperationState = "running"
+	StateSucceeded OperationState = "succeeded"
+	StateFailed    OperationState = "failed"
+)
+
+func (s OperationState) Done() bool {
+	return s == StateSucceeded || s == StateFailed
+}
+
+type EventType string
+
+const (
+	EventQueued    EventType = "queued"
+	EventStarted   EventType = "started"
+	EventLog       EventType = "log"
+	EventProgress  EventType = "progress"
+	EventSucceeded EventType = "succeeded"
+	EventFailed    EventType = "failed"
+)
+
+// Event is a progress event of an operation, streamed as a line of json.
+type Event struct {
+	Seq     int       `json:"seq"`
+	Time    time.Time `json:"time"`
+	Type    EventType `json:"type"`
+	Level   string    `json:"level,omitempty"`
+	Message string    `json:"message,omitempty"`
+	// Progress is set on progress events.
+	Progress *progress.Event `json:"progress,omitempty"`
+}
+
+// RunFunc runs an operation, the returned result is kept in the operation.
+type RunFunc func(ctx context.Context, op *Operation) (interface{}, error)
+
+// Operation is a request queued to run on a cluster.
+type Operation struct {
+	ID      string `json:"id"`
+	Cluster string `json:"cluster"`
+	// Type is the name of the sealos command, e.g. run or apply.
+	Type       string         `json:"type"`
+	State      OperationState `json:"state"`
+	Error      string         `json:"error,omitempty"`
+	Result     interface{}    `json:"result,omitempty"`
+	CreateTime time.Time      `json:"createTime"`
+	StartTime  *time.Time     `json:"startTime,omitempty"`
+	EndTime    *time.Time     `json:"endTime,omitempty"`
+
+	mu      sync.Mutex
+	run     RunFunc
+	args    []string
+	flags   map[string]string
+	events  []Event
+	changed chan struct{}
+}
+
+func newOperation(cluster, typ string, args []string, flags map[string]string, run RunFunc) *Operation {
+	op := &Operation{
+		ID:         fmt.Sprintf("%s-%s", time.Now().Format("20060102150405"), rand.Generator(6)),
+		Cluster:    cluster,
+		Type:       typ,
+		State:      StateQueued,
+		CreateTime: time.Now(),
+		run:        run,
+		args:       args,
+		flags:      flags,
+		changed:    make(chan struct{}),
+	}
+	op.emitLocked(Event{Type: EventQueued})
+	return op
+}
+
+// Emit appends an event and wakes up the watchers.
+func (op *Operation) Emit(typ EventType, level, msg string) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.emitLocked(Event{Type: typ, Level: level, Message: msg})
+}
+
+// EmitProgress appends a progress event.
+func (op *Operation) EmitProgress(pe progress.Event) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.emitLocked(Event{Type: EventProgress, Progress: &pe})
+}
+
+func (op *Operation) emitLocked(e Event) {
+	e.Seq = len(op.events)
+	e.Time = time.Now()
+	op.events = append(op.events, e)
+	close(op.changed)
+	op.changed = make(chan struct{})
+}
+
+// setState updates the state and emits its event at once, so that watchers
+// which see a finished operation have seen all of its events.
+func (op *Operation) setState(state OperationState, result interface{}, err error) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	now := time.Now()
+	op.State = state
+	switch state {
+	case StateRunning:
+		op.StartTime = &now
+		op.emitLocked(Event{Type: EventStarted})
+	case StateSucceeded:
+		op.EndTime = &now
+		op.Result = result
+		op.emitLocked(Event{Type: EventSucceeded})
+	case StateFailed:
+		op.EndTime = &now
+		op.Result = result
+		op.Error = err.Error()
+		op.emitLocked(Event{Type: EventFailed, Message: op.Error})
+	}
+}
+
+// Events returns the events from seq on, the channel is closed when a new event is emitted.
+func (op *Operation) Events(seq int) ([]Event, bool, <-chan struct{}) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	var ret []Event
+	if seq < len(op.events) {
+		ret = append(ret, op.events[seq:]...)
+	}
+	return ret, op.State.Done(), op.changed
+}
+
+// Snapshot returns a copy of the operation which is safe to encode.
+func (op *Operation) Snapshot() *Operation {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	return &Operation{
+		ID:         op.ID,
+		Cluster:    op.Cluster,
+		Type:       op.Type,
+		State:      op.State,
+		Error:      op.Error,
+		Result:     op.Result,
+		CreateTime: op.CreateTime,
+		StartTime:  op.StartTime,
+		EndTime:    op.EndTime,
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/labring/sealos/pkg/audit"
+	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/progress"
+)
+
+// maxFinished is the number of finished operations kept in memory.
+const maxFinished = 200
+
+// Queue runs the operations one at a time, in the order they were submitted,
+// whatever their cluster. The logger, the progress events and the phases are
+// process wide, running a single operation is what keeps the logs and events
+// streamed by an operation its own.
+type Queue struct {
+	ctx context.Context
+	ch  chan *Operation
+
+	mu    sync.Mutex
+	ops   map[string]*Operation
+	order []string
+}
+
+func NewQueue(ctx context.Context) *Queue {
+	q := &Queue{
+		ctx: ctx,
+		// operations are never dropped, the channel only has to be large enough to not block submitters
+		ch:  make(chan *Operation, 1024),
+		ops: map[string]*Operation{},
+	}
+	go q.worker()
+	return q
+}
+
+// Submit queues an operation of typ on cluster, args and flags are recorded in the audit log.
+func (q *Queue) Submit(cluster, typ string, args []string, flags map[string]string, run RunFunc) *Operation {
+	op := newOperation(cluster, typ, args, flags, run)
+	q.mu.Lock()
+	q.ops[op.ID] = op
+	q.order = append(q.order, op.ID)
+	q.prune()
+	q.mu.Unlock()
+	// a full channel must not block the readers of the queue
+	q.ch <- op
+	return op
+}
+
+func (q *Queue) worker() {
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case op := <-q.ch:
+			q.execute(op)
+		}
+	}
+}
+
+func (q *Queue) execute(op *Operation) {
+	if err := q.ctx.Err(); err != nil {
+		op.setState(StateFailed, nil, err)
+		return
+	}
+	removeHook := logger.AddHook(func(l zapcore.Level, msg string) {
+		op.Emit(EventLog, l.String(), msg)
+	})
+	defer removeHook()
+	removeSink := progress.AddSink(op.EmitProgress)
+	defer removeSink()
+
+	op.setState(StateRunning, nil, nil)
+	recorder := audit.Begin(op.Type, op.args, op.flags)
+	recorder.SetCluster(op.Cluster)
+	result, err := q.runSafely(op)
+	audit.End(recorder, err)
+	if err != nil {
+		logger.Error("operation %s %s on cluster %s failed: %v", op.ID, op.Type, op.Cluster, err)
+		op.setState(StateFailed, result, err)
+		return
+	}
+	op.setState(StateSucceeded, result, nil)
+}
+
+func (q *Queue) runSafely(op *Operation) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return op.run(q.ctx, op)
+}
+
+// prune forgets the oldest finished operations, must be called with q.mu held.
+func (q *Queue) prune() {
+	finished := 0
+	for _, id := range q.order {
+		if q.ops[id].Snapshot().State.Done() {
+			finished++
+		}
+	}
+	order := q.order[:0]
+	for _, id := range q.order {
+		if finished > maxFinished && q.ops[id].Snapshot().State.Done() {
+			delete(q.ops, id)
+			finished--
+			continue
+		}
+		order = append(order, id)
+	}
+	q.order = order
+}
+
+func (q *Queue) Get(id string) (*Operation, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	op, ok := q.ops[id]
+	return op, ok
+}
+
+// List returns the operations of cluster, all clusters if empty, in the order they were submitted.
+func (q *Queue) List(cluster string) []*Operation {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var ret []*Operation
+	for _, id := range q.order {
+		if op := q.ops[id]; cluster == "" || op.Cluster == cluster {
+			ret = append(ret, op.Snapshot())
+		}
+	}
+	return ret
+}
@@ -0,0 +1,325 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/labring/sealos/pkg/audit"
+	"github.com/labring/sealos/pkg/exec"
+	"github.com/labring/sealos/pkg/sdk"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+// SSH overrides the ssh config of the Clusterfile, like the ssh flags of the command line.
+type SSH struct {
+	User     string `json:"user,omitempty"`
+	Passwd   string `json:"passwd,omitempty"`
+	Pk       string `json:"pk,omitempty"`
+	PkPasswd string `json:"pkPasswd,omitempty"`
+	Port     uint16 `json:"port,omitempty"`
+}
+
+func (s *SSH) addTo(fv flagValues) {
+	if s == nil {
+		return
+	}
+	fv.add("user", s.User)
+	fv.add("passwd", s.Passwd)
+	fv.add("pk", s.Pk)
+	fv.add("pk-passwd", s.PkPasswd)
+	if s.Port != 0 {
+		fv.add("port", strconv.Itoa(int(s.Port)))
+	}
+}
+
+func (s *SSH) toSDK() *sdk.SSH {
+	if s == nil {
+		return nil
+	}
+	return &sdk.SSH{User: s.User, Passwd: s.Passwd, Pk: s.Pk, PkPasswd: s.PkPasswd, Port: s.Port}
+}
+
+// RunRequest is the request of sealos run.
+type RunRequest struct {
+	Images  []string `json:"images"`
+	Masters string   `json:"masters,omitempty"`
+	Nodes   string   `json:"nodes,omitempty"`
+	SSH     *SSH     `json:"ssh,omitempty"`
+	Env     []string `json:"env,omitempty"`
+	Cmd     []string `json:"cmd,omitempty"`
+	// Force overrides the apps which are already installed.
+	Force bool `json:"force,omitempty"`
+}
+
+func (r *RunRequest) flagValues() flagValues {
+	fv := flagValues{}
+	fv.add("masters", r.Masters)
+	fv.add("nodes", r.Nodes)
+	fv.add("env", r.Env...)
+	fv.add("cmd", r.Cmd...)
+	r.SSH.addTo(fv)
+	return fv
+}
+
+// ApplyRequest is the request of sealos apply.
+type ApplyRequest struct {
+	// Clusterfile is the content of the Clusterfile.
+	Clusterfile string   `json:"clusterfile"`
+	Sets        []string `json:"sets,omitempty"`
+	Env         []string `json:"env,omitempty"`
+	Force       bool     `json:"force,omitempty"`
+}
+
+func (r *ApplyRequest) flagValues() flagValues {
+	fv := flagValues{}
+	fv.add("set", r.Sets...)
+	fv.add("env", r.Env...)
+	return fv
+}
+
+// ScaleRequest is the request of sealos add and delete, delete does not support SSH.
+type ScaleRequest struct {
+	Masters string `json:"masters,omitempty"`
+	Nodes   string `json:"nodes,omitempty"`
+	SSH     *SSH   `json:"ssh,omitempty"`
+	// Force answers yes to the confirmations, such as an even number of masters,
+	// delete requires it to confirm the deletion of the nodes.
+	Force bool `json:"force,omitempty"`
+}
+
+func (r *ScaleRequest) flagValues() flagValues {
+	fv := flagValues{}
+	fv.add("masters", r.Masters)
+	fv.add("nodes", r.Nodes)
+	r.SSH.addTo(fv)
+	return fv
+}
+
+// ExecRequest is the request of sealos exec, the command runs on all hosts if
+// neither roles nor ips are set.
+type ExecRequest struct {
+	Command string   `json:"command"`
+	Roles   []string `json:"roles,omitempty"`
+	IPs     []string `json:"ips,omitempty"`
+}
+
+func (r *ExecRequest) flagValues() flagValues {
+	fv := flagValues{}
+	fv.add("roles", r.Roles...)
+	fv.add("ips", r.IPs...)
+	return fv
+}
+
+// ExecResult is the result of an exec operation on a host.
+type ExecResult struct {
+	Host   string `json:"host"`
+	Output string `json:"output"`
+	Error  string `json:"error,omitempty"`
+}
+
+// flagValues are the command line flags of an operation, as they are recorded in the audit log.
+type flagValues map[string][]string
+
+func (fv flagValues) add(name string, values ...string) {
+	for _, v := range values {
+		if v != "" {
+			fv[name] = append(fv[name], v)
+		}
+	}
+}
+
+// audit returns the flags as they are recorded in the audit log.
+func (fv flagValues) audit() map[string]string {
+	ret := make(map[string]string, len(fv))
+	for name, values := range fv {
+		ret[name] = strings.Join(values, ",")
+	}
+	return ret
+}
+
+// runner builds the operations from the requests.
+type runner interface {
+	status(cluster string) (*v2.Cluster, error)
+	run(cluster string, req *RunRequest) (RunFunc, error)
+	apply(cluster string, req *ApplyRequest) (RunFunc, error)
+	scale(cluster, action string, req *ScaleRequest) (RunFunc, error)
+	reset(cluster string) RunFunc
+	exec(cluster string, req *ExecRequest) (RunFunc, error)
+}
+
+// defaultRunner runs the operations with the sdk client.
+type defaultRunner struct {
+	client *sdk.Client
+}
+
+func (r defaultRunner) status(cluster string) (*v2.Cluster, error) {
+	c, err := r.client.Get(context.Background(), cluster)
+	if err != nil {
+		return nil, err
+	}
+	redactSSH(&c.Spec.SSH)
+	for i := range c.Spec.Hosts {
+		if c.Spec.Hosts[i].SSH != nil {
+			redactSSH(c.Spec.Hosts[i].SSH)
+		}
+	}
+	return c, nil
+}
+
+func redactSSH(s *v2.SSH) {
+	for _, v := range []*string{&s.Passwd, &s.PkData, &s.PkPasswd} {
+		if *v != "" {
+			*v = "******"
+		}
+	}
+}
+
+func (r defaultRunner) run(cluster string, req *RunRequest) (RunFunc, error) {
+	if len(req.Images) == 0 {
+		return nil, errors.New("images cannot be empty")
+	}
+	opts := sdk.RunOptions{
+		Cluster: cluster,
+		Images:  req.Images,
+		Masters: req.Masters,
+		Nodes:   req.Nodes,
+		SSH:     req.SSH.toSDK(),
+		Env:     req.Env,
+		Cmd:     req.Cmd,
+		Force:   req.Force,
+	}
+	return func(ctx context.Context, _ *Operation) (interface{}, error) {
+		return nil, r.client.Run(ctx, opts)
+	}, nil
+}
+
+func (r defaultRunner) apply(cluster string, req *ApplyRequest) (RunFunc, error) {
+	if strings.TrimSpace(req.Clusterfile) == "" {
+		return nil, errors.New("clusterfile cannot be empty")
+	}
+	opts := sdk.ApplyOptions{
+		Cluster: cluster,
+		Sets:    req.Sets,
+		Env:     req.Env,
+		Force:   req.Force,
+	}
+	return func(ctx context.Context, _ *Operation) (interface{}, error) {
+		dir, err := os.MkdirTemp("", "sealos-serve")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+		opts.Clusterfile = filepath.Join(dir, "Clusterfile")
+		if err = os.WriteFile(opts.Clusterfile, []byte(req.Clusterfile), 0600); err != nil {
+			return nil, err
+		}
+		return nil, r.client.Apply(ctx, opts)
+	}, nil
+}
+
+func (r defaultRunner) scale(cluster, action string, req *ScaleRequest) (RunFunc, error) {
+	if req.Masters == "" && req.Nodes == "" {
+		return nil, errors.New("nodes and masters can't both be empty")
+	}
+	if action == "delete" && req.SSH != nil {
+		return nil, errors.New("delete does not support ssh, it reads from the Clusterfile")
+	}
+	if action == "delete" && !req.Force {
+		return nil, errors.New("delete requires force to confirm the deletion of the nodes")
+	}
+	opts := sdk.ScaleOptions{
+		Cluster: cluster,
+		Masters: req.Masters,
+		Nodes:   req.Nodes,
+		SSH:     req.SSH.toSDK(),
+		Force:   req.Force,
+	}
+	return func(ctx context.Context, _ *Operation) (interface{}, error) {
+		if action == "add" {
+			return nil, r.client.Add(ctx, opts)
+		}
+		return nil, r.client.Delete(ctx, opts)
+	}, nil
+}
+
+func (r defaultRunner) reset(cluster string) RunFunc {
+	return func(ctx context.Context, _ *Operation) (interface{}, error) {
+		// the request is the confirmation
+		return nil, r.client.Reset(ctx, sdk.ResetOptions{Cluster: cluster, Force: true})
+	}
+}
+
+func (r defaultRunner) exec(cluster string, req *ExecRequest) (RunFunc, error) {
+	if strings.TrimSpace(req.Command) == "" {
+		return nil, errors.New("command cannot be empty")
+	}
+	return func(ctx context.Context, _ *Operation) (interface{}, error) {
+		c, err := r.client.Get(ctx, cluster)
+		if err != nil {
+			return nil, err
+		}
+		audit.SetCluster(cluster, c.GetAllIPS()...)
+		targets := req.IPs
+		if len(targets) == 0 {
+			if len(req.Roles) == 0 {
+				targets = c.GetAllIPS()
+			}
+			for _, role := range req.Roles {
+				targets = append(targets, c.GetIPSByRole(role)...)
+			}
+		}
+		execer, err := exec.NewFromCluster(c, false)
+		if err != nil {
+			return nil, err
+		}
+		var (
+			mu      sync.Mutex
+			wg      sync.WaitGroup
+			results []ExecResult
+			failed  int
+		)
+		for i := range targets {
+			host := targets[i]
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				out, err := execer.Cmd(host, req.Command)
+				r := ExecResult{Host: host, Output: string(out)}
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					r.Error = err.Error()
+					failed++
+				}
+				results = append(results, r)
+			}()
+		}
+		wg.Wait()
+		sort.Slice(results, func(i, j int) bool { return results[i].Host < results[j].Host })
+		if failed > 0 {
+			return results, fmt.Errorf("command failed on %d of %d hosts", failed, len(targets))
+		}
+		return results, nil
+	}, nil
+}
@@ -0,0 +1,247 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package daemon serves the cluster operations of sealos as a REST API. The
+// operations are run by the sdk client with the same appliers as the sealos
+// command line and run one at a time, their progress is streamed as json lines.
+package daemon
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labring/sealos/pkg/sdk"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+// Options of the server.
+type Options struct {
+	// Token authenticates the requests as a bearer token, it must not be empty.
+	Token string
+}
+
+// Server handles the API requests, operations are run by Queue.
+type Server struct {
+	opts    Options
+	queue   *Queue
+	handler http.Handler
+	// runner builds the operations, replaced in tests.
+	runner runner
+}
+
+func NewServer(ctx context.Context, opts Options) (*Server, error) {
+	if opts.Token == "" {
+		return nil, errors.New("token of the server cannot be empty")
+	}
+	client, err := sdk.New(sdk.Options{})
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{opts: opts, queue: NewQueue(ctx), runner: defaultRunner{client: client}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.Handle("/v1/clusters/", s.authenticate(http.HandlerFunc(s.handleCluster)))
+	mux.Handle("/v1/operations", s.authenticate(http.HandlerFunc(s.handleOperations)))
+	mux.Handle("/v1/operations/", s.authenticate(http.HandlerFunc(s.handleOperation)))
+	s.handler = mux
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("invalid or missing bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleCluster serves /v1/clusters/{name} and /v1/clusters/{name}/{action}.
+func (s *Server) handleCluster(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/clusters/"), "/"), "/")
+	name := parts[0]
+	if name == "" || len(parts) > 2 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown path %s", r.URL.Path))
+		return
+	}
+	if len(parts) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			cluster, err := s.runner.status(name)
+			if err != nil {
+				writeError(w, http.StatusNotFound, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, cluster)
+		case http.MethodDelete:
+			s.submit(w, name, "reset", nil, nil, s.runner.reset(name))
+		default:
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+		}
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+		return
+	}
+	var (
+		run   RunFunc
+		args  []string
+		flags map[string]string
+		err   error
+	)
+	switch action := parts[1]; action {
+	case "run":
+		req := &RunRequest{}
+		if err = decode(r, req); err == nil {
+			args, flags = req.Images, req.flagValues().audit()
+			run, err = s.runner.run(name, req)
+		}
+	case "apply":
+		req := &ApplyRequest{}
+		if err = decode(r, req); err == nil {
+			flags = req.flagValues().audit()
+			run, err = s.runner.apply(name, req)
+		}
+	case "add", "delete":
+		req := &ScaleRequest{}
+		if err = decode(r, req); err == nil {
+			flags = req.flagValues().audit()
+			run, err = s.runner.scale(name, action, req)
+		}
+	case "exec":
+		req := &ExecRequest{}
+		if err = decode(r, req); err == nil {
+			args, flags = []string{req.Command}, req.flagValues().audit()
+			run, err = s.runner.exec(name, req)
+		}
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown action %s", action))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.submit(w, name, parts[1], args, flags, run)
+}
+
+func (s *Server) submit(w http.ResponseWriter, cluster, typ string, args []string, flags map[string]string, run RunFunc) {
+	op := s.queue.Submit(cluster, typ, args, flags, run)
+	writeJSON(w, http.StatusAccepted, op.Snapshot())
+}
+
+func (s *Server) handleOperations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.queue.List(r.URL.Query().Get("cluster")))
+}
+
+// handleOperation serves /v1/operations/{id} and /v1/operations/{id}/events.
+func (s *Server) handleOperation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/operations/"), "/"), "/")
+	op, ok := s.queue.Get(parts[0])
+	if !ok || len(parts) > 2 || (len(parts) == 2 && parts[1] != "events") {
+		writeError(w, http.StatusNotFound, fmt.Errorf("operation %s not found", parts[0]))
+		return
+	}
+	if len(parts) == 1 {
+		writeJSON(w, http.StatusOK, op.Snapshot())
+		return
+	}
+	follow := true
+	if v := r.URL.Query().Get("follow"); v != "" {
+		follow, _ = strconv.ParseBool(v)
+	}
+	streamEvents(r.Context(), w, op, follow)
+}
+
+// streamEvents writes the events of op as json lines, following new events
+// until the operation is done or the client goes away.
+func streamEvents(ctx context.Context, w http.ResponseWriter, op *Operation, follow bool) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	// keep the connection alive through proxies while an operation waits in the queue
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	seq := 0
+	for {
+		events, done, changed := op.Events(seq)
+		for i := range events {
+			if err := enc.Encode(events[i]); err != nil {
+				return
+			}
+		}
+		seq += len(events)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if done || !follow {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		case <-ticker.C:
+			if _, err := w.Write([]byte("\n")); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func decode(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Debug("failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,56 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"sync"
+
+	"github.com/labring/sealos/pkg/ssh"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+// Factory creates the executor used to operate on the hosts of a cluster.
+type Factory func(cluster *v2.Cluster, isStdout bool) (Interface, error)
+
+func defaultFactory(cluster *v2.Cluster, isStdout bool) (Interface, error) {
+	return New(ssh.NewCacheClientFromCluster(cluster, isStdout))
+}
+
+var (
+	factoryMu sync.RWMutex
+	factory   Factory = defaultFactory
+)
+
+// NewFromCluster returns the executor for the hosts of cluster, which is a cached ssh
+// client that runs commands of the local host directly unless SetFactory was called.
+func NewFromCluster(cluster *v2.Cluster, isStdout bool) (Interface, error) {
+	factoryMu.RLock()
+	defer factoryMu.RUnlock()
+	return factory(cluster, isStdout)
+}
+
+// SetFactory replaces the executor of every cluster, e.g. with the in-memory executor
+// of package fake in tests, and returns a func that restores the previous one.
+func SetFactory(f Factory) func() {
+	factoryMu.Lock()
+	defer factoryMu.Unlock()
+	prev := factory
+	factory = f
+	return func() {
+		factoryMu.Lock()
+		defer factoryMu.Unlock()
+		factory = prev
+	}
+}
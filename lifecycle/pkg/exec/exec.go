@@ -22,9 +22,11 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 
+	"github.com/labring/sealos/pkg/audit"
 	"github.com/labring/sealos/pkg/ssh"
 	"github.com/labring/sealos/pkg/unshare"
 	fileutil "github.com/labring/sealos/pkg/utils/file"
@@ -59,21 +61,23 @@ func (w *wrap) Ping(host string) error {
 	return w.inner.Ping(host)
 }
 
-func (w *wrap) Cmd(host string, command string) ([]byte, error) {
+func (w *wrap) Cmd(host string, command string) (b []byte, err error) {
+	defer observe(host, time.Now(), &err)
 	if w.isLocal(host) {
 		// nosemgrep: go.lang.security.audit.dangerous-exec-command.dangerous-exec-command
-		b, err := exec.Command("/bin/bash", "-c", command).CombinedOutput()
+		b, err = exec.Command("/bin/bash", "-c", command).CombinedOutput()
 		return b, err
 	}
 	return w.inner.Cmd(host, command)
 }
 
-func (w *wrap) CmdAsyncWithContext(ctx context.Context, host string, commands ...string) error {
+func (w *wrap) CmdAsyncWithContext(ctx context.Context, host string, commands ...string) (err error) {
+	defer observe(host, time.Now(), &err)
 	if w.isLocal(host) {
 		for i := range commands {
 			// nosemgrep: go.lang.security.audit.dangerous-exec-command.dangerous-exec-command
 			cmd := exec.CommandContext(ctx, "/bin/bash", "-c", commands[i])
-			cmd.Stdout = os.Stdout
+			cmd.Stdout = logger.Console()
 			cmd.Stderr = os.Stderr
 			if err := cmd.Run(); err != nil {
 				return err
@@ -96,7 +100,8 @@ func warnIfNotAbs(path string) {
 	}
 }
 
-func (w *wrap) Copy(host string, src string, dest string) error {
+func (w *wrap) Copy(host string, src string, dest string) (err error) {
+	defer observe(host, time.Now(), &err)
 	if w.isLocal(host) {
 		warnIfNotAbs(src)
 		warnIfNotAbs(dest)
@@ -106,7 +111,8 @@ func (w *wrap) Copy(host string, src string, dest string) error {
 	return w.inner.Copy(host, src, dest)
 }
 
-func (w *wrap) Fetch(host string, src string, dest string) error {
+func (w *wrap) Fetch(host string, src string, dest string) (err error) {
+	defer observe(host, time.Now(), &err)
 	if w.isLocal(host) {
 		warnIfNotAbs(src)
 		warnIfNotAbs(dest)
@@ -116,6 +122,11 @@ func (w *wrap) Fetch(host string, src string, dest string) error {
 	return w.inner.Fetch(host, src, dest)
 }
 
+// observe reports the outcome of a remote operation to the audit log.
+func observe(host string, start time.Time, err *error) {
+	audit.ObserveHost(host, start, *err)
+}
+
 func (w *wrap) CmdToString(host, cmd, sep string) (string, error) {
 	output, err := w.Cmd(host, cmd)
 	if err != nil {
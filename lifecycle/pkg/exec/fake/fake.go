@@ -0,0 +1,363 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fake provides an executor which simulates the hosts of a cluster
+// in memory or in local directories, so that the apply pipelines can be tested
+// without provisioning machines.
+package fake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labring/sealos/pkg/exec"
+	"github.com/labring/sealos/pkg/ssh"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/iputils"
+)
+
+type Op string
+
+const (
+	OpCmd   Op = "cmd"
+	OpCopy  Op = "copy"
+	OpFetch Op = "fetch"
+	OpPing  Op = "ping"
+)
+
+// Call is an operation made on a host.
+type Call struct {
+	Host    string
+	Op      Op
+	Command string
+	Src     string
+	Dst     string
+	Err     error
+	Time    time.Time
+}
+
+// Failure injects err into the operations which match it.
+type Failure struct {
+	// Host to fail, empty for all hosts.
+	Host string
+	// Op to fail, empty for all operations.
+	Op Op
+	// Match is a regular expression the command, or the src and dst of a
+	// copy, must match, empty for all.
+	Match string
+	// Times is the number of operations to fail, 0 for all.
+	Times int
+	Err   error
+
+	re    *regexp.Regexp
+	count int
+}
+
+// ErrUnknownHost is returned when operating on a host that was not added to the executor.
+var ErrUnknownHost = errors.New("no route to host")
+
+type responder struct {
+	re *regexp.Regexp
+	fn func(host, cmd string) ([]byte, error)
+}
+
+// Executor implements exec.Interface without connecting to any host, it records
+// every operation and copies files into the filesystem of the simulated host.
+type Executor struct {
+	mu         sync.Mutex
+	rootDir    string
+	hosts      map[string]hostFS
+	calls      []Call
+	responders []responder
+	failures   []*Failure
+}
+
+var _ ssh.Interface = &Executor{}
+
+type Option func(*Executor)
+
+// WithRootDir keeps the filesystem of each host in the directory rootDir/<host>
+// instead of memory, like a chroot of the host.
+func WithRootDir(rootDir string) Option {
+	return func(e *Executor) {
+		e.rootDir = rootDir
+	}
+}
+
+// New returns an executor which simulates hosts, addresses may contain ports.
+func New(hosts []string, opts ...Option) *Executor {
+	e := &Executor{hosts: map[string]hostFS{}}
+	for i := range opts {
+		opts[i](e)
+	}
+	for _, h := range hosts {
+		e.AddHost(h)
+	}
+	return e
+}
+
+// NewFromCluster returns an executor which simulates all hosts of cluster.
+func NewFromCluster(cluster *v2.Cluster, opts ...Option) *Executor {
+	return New(cluster.GetAllIPS(), opts...)
+}
+
+// Install makes exec.NewFromCluster return e for every cluster, so that the
+// processors, runtimes and guests created afterwards operate on e.
+// The returned func restores the real executor.
+func (e *Executor) Install() func() {
+	return exec.SetFactory(func(*v2.Cluster, bool) (exec.Interface, error) {
+		return e, nil
+	})
+}
+
+func hostKey(host string) string {
+	return iputils.GetHostIP(host)
+}
+
+// AddHost adds a host, e.g. a node joined by a scale up.
+func (e *Executor) AddHost(host string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	key := hostKey(host)
+	if _, ok := e.hosts[key]; ok {
+		return
+	}
+	if e.rootDir != "" {
+		e.hosts[key] = newDirFS(e.rootDir, key)
+	} else {
+		e.hosts[key] = newMemFS()
+	}
+}
+
+// RemoveHost makes host unreachable.
+func (e *Executor) RemoveHost(host string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.hosts, hostKey(host))
+}
+
+// Respond makes the commands matching pattern output out.
+func (e *Executor) Respond(pattern string, out string) {
+	e.RespondFunc(pattern, func(string, string) ([]byte, error) {
+		return []byte(out), nil
+	})
+}
+
+// RespondFunc makes the commands matching pattern call fn, responders
+// are tried in the order they were added.
+func (e *Executor) RespondFunc(pattern string, fn func(host, cmd string) ([]byte, error)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.responders = append(e.responders, responder{re: regexp.MustCompile(pattern), fn: fn})
+}
+
+// Fail injects a failure.
+func (e *Executor) Fail(f Failure) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if f.Match != "" {
+		f.re = regexp.MustCompile(f.Match)
+	}
+	if f.Err == nil {
+		f.Err = fmt.Errorf("injected failure of %s on %s", f.Op, f.Host)
+	}
+	e.failures = append(e.failures, &f)
+}
+
+// Calls returns all operations made so far.
+func (e *Executor) Calls() []Call {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Call(nil), e.calls...)
+}
+
+// Commands returns the commands run on host, or on all hosts if host is empty.
+func (e *Executor) Commands(host string) []string {
+	var ret []string
+	for _, c := range e.Calls() {
+		if c.Op == OpCmd && (host == "" || c.Host == hostKey(host)) {
+			ret = append(ret, c.Command)
+		}
+	}
+	return ret
+}
+
+// Reset forgets the recorded operations.
+func (e *Executor) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls = nil
+}
+
+// ReadFile returns the content of a file on host.
+func (e *Executor) ReadFile(host, path string) ([]byte, error) {
+	fs, err := e.getHost(host)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ReadFile(path)
+}
+
+// WriteFile creates a file on host.
+func (e *Executor) WriteFile(host, path string, data []byte) error {
+	fs, err := e.getHost(host)
+	if err != nil {
+		return err
+	}
+	return fs.WriteFile(path, data)
+}
+
+// Exists reports whether the file or directory exists on host.
+func (e *Executor) Exists(host, path string) bool {
+	fs, err := e.getHost(host)
+	if err != nil {
+		return false
+	}
+	return fs.Exists(path)
+}
+
+func (e *Executor) getHost(host string) (hostFS, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fs, ok := e.hosts[hostKey(host)]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", host, ErrUnknownHost)
+	}
+	return fs, nil
+}
+
+// begin records the call and returns its index, the filesystem of the host and the injected failure.
+func (e *Executor) begin(c Call) (int, hostFS, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c.Host = hostKey(c.Host)
+	c.Time = time.Now()
+	fs, ok := e.hosts[c.Host]
+	if !ok {
+		c.Err = fmt.Errorf("%s: %w", c.Host, ErrUnknownHost)
+	} else if f := e.matchFailure(c); f != nil {
+		c.Err = f.Err
+	}
+	e.calls = append(e.calls, c)
+	return len(e.calls) - 1, fs, c.Err
+}
+
+func (e *Executor) matchFailure(c Call) *Failure {
+	for _, f := range e.failures {
+		if f.Host != "" && hostKey(f.Host) != c.Host {
+			continue
+		}
+		if f.Op != "" && f.Op != c.Op {
+			continue
+		}
+		if f.re != nil && !f.re.MatchString(c.Command) && !f.re.MatchString(c.Src) && !f.re.MatchString(c.Dst) {
+			continue
+		}
+		if f.Times > 0 && f.count >= f.Times {
+			continue
+		}
+		f.count++
+		return f
+	}
+	return nil
+}
+
+// end stores the error of the call at index i which failed after it started,
+// the calls on other hosts may have been recorded in the meantime.
+func (e *Executor) end(i int, err error) error {
+	if err == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// the calls are forgotten by Reset
+	if i < len(e.calls) {
+		e.calls[i].Err = err
+	}
+	return err
+}
+
+func (e *Executor) Ping(host string) error {
+	_, _, err := e.begin(Call{Host: host, Op: OpPing})
+	return err
+}
+
+func (e *Executor) Cmd(host, cmd string) ([]byte, error) {
+	i, fs, err := e.begin(Call{Host: host, Op: OpCmd, Command: cmd})
+	if err != nil {
+		return nil, err
+	}
+	out, err := e.run(fs, host, cmd)
+	return out, e.end(i, err)
+}
+
+func (e *Executor) run(fs hostFS, host, cmd string) ([]byte, error) {
+	e.mu.Lock()
+	responders := append([]responder(nil), e.responders...)
+	e.mu.Unlock()
+	for _, r := range responders {
+		if r.re.MatchString(cmd) {
+			return r.fn(hostKey(host), cmd)
+		}
+	}
+	return builtin(fs, cmd)
+}
+
+func (e *Executor) CmdAsync(host string, cmds ...string) error {
+	return e.CmdAsyncWithContext(context.Background(), host, cmds...)
+}
+
+func (e *Executor) CmdAsyncWithContext(ctx context.Context, host string, cmds ...string) error {
+	for _, cmd := range cmds {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := e.Cmd(host, cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Executor) CmdToString(host, cmd, sep string) (string, error) {
+	out, err := e.Cmd(host, cmd)
+	if err != nil {
+		return "", err
+	}
+	if len(out) == 0 {
+		return "", fmt.Errorf("command %s on %s return nil", cmd, host)
+	}
+	return strings.ReplaceAll(strings.ReplaceAll(string(out), "\r\n", sep), "\n", sep), nil
+}
+
+func (e *Executor) Copy(host, src, dst string) error {
+	i, fs, err := e.begin(Call{Host: host, Op: OpCopy, Src: src, Dst: dst})
+	if err != nil {
+		return err
+	}
+	return e.end(i, copyToHost(fs, src, dst))
+}
+
+func (e *Executor) Fetch(host, src, dst string) error {
+	i, fs, err := e.begin(Call{Host: host, Op: OpFetch, Src: src, Dst: dst})
+	if err != nil {
+		return err
+	}
+	return e.end(i, fetchFromHost(fs, src, dst))
+}
@@ -0,0 +1,154 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fake
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/labring/sealos/pkg/exec"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+func testExecutor(t *testing.T, e *Executor) {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "etc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "etc", "kubeadm.yaml"), []byte("kind: InitConfiguration"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.Copy("192.168.0.2:22", src, "/var/lib/sealos/data/default/rootfs"); err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	out, err := e.Cmd("192.168.0.2", "cat /var/lib/sealos/data/default/rootfs/etc/kubeadm.yaml")
+	if err != nil || string(out) != "kind: InitConfiguration" {
+		t.Errorf("Cmd() = %q, %v", out, err)
+	}
+
+	dst := t.TempDir()
+	if err = e.Fetch("192.168.0.2", "/var/lib/sealos/data/default/rootfs/etc", dst); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(dst, "kubeadm.yaml")); err != nil {
+		t.Errorf("Fetch() did not copy the file: %v", err)
+	}
+
+	if _, err = e.Cmd("192.168.0.2", "rm -rf /var/lib/sealos/data/default/rootfs"); err != nil {
+		t.Fatal(err)
+	}
+	if e.Exists("192.168.0.2", "/var/lib/sealos/data/default/rootfs/etc/kubeadm.yaml") {
+		t.Errorf("rm -rf did not remove the files")
+	}
+
+	e.Respond(`^hostname$`, "master0\n")
+	if s, err := e.CmdToString("192.168.0.2", "hostname", ""); err != nil || s != "master0" {
+		t.Errorf("CmdToString() = %q, %v", s, err)
+	}
+
+	if err = e.Ping("192.168.0.9"); !errors.Is(err, ErrUnknownHost) {
+		t.Errorf("Ping() of unknown host error = %v", err)
+	}
+	if got := len(e.Commands("192.168.0.2")); got != 3 {
+		t.Errorf("Commands() got %d commands, want 3", got)
+	}
+}
+
+func TestExecutor(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		testExecutor(t, New([]string{"192.168.0.2", "192.168.0.3"}))
+	})
+	t.Run("directory", func(t *testing.T) {
+		root := t.TempDir()
+		testExecutor(t, New([]string{"192.168.0.2", "192.168.0.3"}, WithRootDir(root)))
+		if _, err := os.Stat(filepath.Join(root, "192.168.0.2")); err != nil {
+			t.Errorf("host directory was not created: %v", err)
+		}
+	})
+}
+
+func TestExecutor_Fail(t *testing.T) {
+	e := New([]string{"192.168.0.2", "192.168.0.3"})
+	injected := errors.New("connection reset")
+	e.Fail(Failure{Host: "192.168.0.3", Op: OpCmd, Match: "kubeadm join", Times: 1, Err: injected})
+
+	if _, err := e.Cmd("192.168.0.2", "kubeadm join 192.168.0.2:6443"); err != nil {
+		t.Errorf("Cmd() on other host error = %v", err)
+	}
+	if _, err := e.Cmd("192.168.0.3", "kubeadm join 192.168.0.2:6443"); !errors.Is(err, injected) {
+		t.Errorf("Cmd() error = %v, want injected failure", err)
+	}
+	if _, err := e.Cmd("192.168.0.3", "kubeadm join 192.168.0.2:6443"); err != nil {
+		t.Errorf("Cmd() after the failure is consumed error = %v", err)
+	}
+	calls := e.Calls()
+	if len(calls) != 3 || !errors.Is(calls[1].Err, injected) {
+		t.Errorf("Calls() = %+v, want the failure recorded", calls)
+	}
+}
+
+func TestExecutor_Install(t *testing.T) {
+	cluster := &v2.Cluster{}
+	cluster.Spec.Hosts = []v2.Host{{IPS: []string{"192.168.0.2:22"}, Roles: []string{v2.MASTER}}}
+	e := NewFromCluster(cluster)
+	restore := e.Install()
+	execer, err := exec.NewFromCluster(cluster, true)
+	restore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = execer.Ping("192.168.0.2:22"); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+	if len(e.Calls()) != 1 {
+		t.Errorf("exec.NewFromCluster() did not return the fake executor")
+	}
+}
+
+func TestExecutor_ConcurrentErrors(t *testing.T) {
+	e := New([]string{"192.168.0.2", "192.168.0.3"})
+	started, done := make(chan struct{}), make(chan struct{})
+	failed := errors.New("kubeadm failed")
+	e.RespondFunc(`^kubeadm join$`, func(host, _ string) ([]byte, error) {
+		if host == "192.168.0.2" {
+			close(started)
+			// fail after the call on the other host is recorded
+			<-done
+			return nil, failed
+		}
+		return nil, nil
+	})
+	errCh := make(chan error)
+	go func() {
+		_, err := e.Cmd("192.168.0.2", "kubeadm join")
+		errCh <- err
+	}()
+	<-started
+	if _, err := e.Cmd("192.168.0.3", "kubeadm join"); err != nil {
+		t.Fatal(err)
+	}
+	close(done)
+	if err := <-errCh; !errors.Is(err, failed) {
+		t.Fatalf("Cmd() error = %v, want %v", err, failed)
+	}
+	for _, c := range e.Calls() {
+		if wantErr := c.Host == "192.168.0.2"; (c.Err != nil) != wantErr {
+			t.Errorf("call on %s has error %v", c.Host, c.Err)
+		}
+	}
+}
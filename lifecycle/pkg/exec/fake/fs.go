@@ -0,0 +1,303 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fake
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// hostFS is the filesystem of a simulated host, paths are absolute paths on the host.
+type hostFS interface {
+	ReadFile(p string) ([]byte, error)
+	WriteFile(p string, data []byte) error
+	MkdirAll(p string) error
+	RemoveAll(p string) error
+	Exists(p string) bool
+	// List returns the files under p, or p itself if it is a file.
+	List(p string) ([]string, error)
+}
+
+func clean(p string) string {
+	return path.Clean("/" + p)
+}
+
+type memFS struct {
+	mu    sync.RWMutex
+	files map[string][]byte
+	dirs  map[string]struct{}
+}
+
+func newMemFS() *memFS {
+	return &memFS{files: map[string][]byte{}, dirs: map[string]struct{}{"/": {}}}
+}
+
+func (m *memFS) ReadFile(p string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.files[clean(p)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: p, Err: fs.ErrNotExist}
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (m *memFS) WriteFile(p string, data []byte) error {
+	p = clean(p)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mkdirAll(path.Dir(p))
+	m.files[p] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *memFS) MkdirAll(p string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mkdirAll(clean(p))
+	return nil
+}
+
+func (m *memFS) mkdirAll(p string) {
+	for ; p != "/"; p = path.Dir(p) {
+		m.dirs[p] = struct{}{}
+	}
+}
+
+func (m *memFS) RemoveAll(p string) error {
+	p = clean(p)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for f := range m.files {
+		if f == p || strings.HasPrefix(f, p+"/") {
+			delete(m.files, f)
+		}
+	}
+	for d := range m.dirs {
+		if d != "/" && (d == p || strings.HasPrefix(d, p+"/")) {
+			delete(m.dirs, d)
+		}
+	}
+	return nil
+}
+
+func (m *memFS) Exists(p string) bool {
+	p = clean(p)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, isFile := m.files[p]
+	_, isDir := m.dirs[p]
+	return isFile || isDir
+}
+
+func (m *memFS) List(p string) ([]string, error) {
+	p = clean(p)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.files[p]; ok {
+		return []string{p}, nil
+	}
+	if _, ok := m.dirs[p]; !ok {
+		return nil, &fs.PathError{Op: "stat", Path: p, Err: fs.ErrNotExist}
+	}
+	var ret []string
+	for f := range m.files {
+		if strings.HasPrefix(f, strings.TrimSuffix(p, "/")+"/") {
+			ret = append(ret, f)
+		}
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
+
+// dirFS keeps the filesystem of a host in a local directory.
+type dirFS struct {
+	root string
+}
+
+func newDirFS(rootDir, host string) *dirFS {
+	return &dirFS{root: filepath.Join(rootDir, host)}
+}
+
+func (d *dirFS) local(p string) string {
+	return filepath.Join(d.root, filepath.FromSlash(clean(p)))
+}
+
+func (d *dirFS) ReadFile(p string) ([]byte, error) {
+	return os.ReadFile(d.local(p))
+}
+
+func (d *dirFS) WriteFile(p string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(d.local(p)), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(d.local(p), data, 0644)
+}
+
+func (d *dirFS) MkdirAll(p string) error {
+	return os.MkdirAll(d.local(p), 0755)
+}
+
+func (d *dirFS) RemoveAll(p string) error {
+	return os.RemoveAll(d.local(p))
+}
+
+func (d *dirFS) Exists(p string) bool {
+	_, err := os.Stat(d.local(p))
+	return err == nil
+}
+
+func (d *dirFS) List(p string) ([]string, error) {
+	var ret []string
+	err := filepath.WalkDir(d.local(p), func(lp string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if e.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(d.root, lp)
+		if err != nil {
+			return err
+		}
+		ret = append(ret, clean(filepath.ToSlash(rel)))
+		return nil
+	})
+	return ret, err
+}
+
+// copyToHost copies the local file or directory src to dst on the host, like scp -r.
+func copyToHost(h hostFS, src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		return h.WriteFile(dst, data)
+	}
+	if err = h.MkdirAll(dst); err != nil {
+		return err
+	}
+	return filepath.WalkDir(src, func(p string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := path.Join(dst, filepath.ToSlash(rel))
+		if e.IsDir() {
+			return h.MkdirAll(target)
+		}
+		if !e.Type().IsRegular() {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return h.WriteFile(target, data)
+	})
+}
+
+// fetchFromHost copies the file or directory src on the host to the local dst.
+func fetchFromHost(h hostFS, src, dst string) error {
+	files, err := h.List(src)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		target := dst
+		if f != clean(src) {
+			target = filepath.Join(dst, filepath.FromSlash(strings.TrimPrefix(f, clean(src)+"/")))
+		}
+		data, err := h.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err = os.WriteFile(target, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// builtin simulates the few commands which change or read files, so that
+// later commands and fetches observe their effect. Other commands succeed
+// without output.
+func builtin(h hostFS, cmd string) ([]byte, error) {
+	fields := strings.Fields(cmd)
+	if len(fields) == 0 || strings.ContainsAny(cmd, "|&;><$`") {
+		return nil, nil
+	}
+	args := fields[1:]
+	var paths []string
+	for _, a := range args {
+		if !strings.HasPrefix(a, "-") {
+			paths = append(paths, a)
+		}
+	}
+	switch fields[0] {
+	case "cat":
+		var out []byte
+		for _, p := range paths {
+			data, err := h.ReadFile(p)
+			if err != nil {
+				return []byte(fmt.Sprintf("cat: %s: No such file or directory\n", p)), fmt.Errorf("exit status 1")
+			}
+			out = append(out, data...)
+		}
+		return out, nil
+	case "mkdir":
+		for _, p := range paths {
+			if err := h.MkdirAll(p); err != nil {
+				return nil, err
+			}
+		}
+	case "rm":
+		for _, p := range paths {
+			if err := h.RemoveAll(p); err != nil {
+				return nil, err
+			}
+		}
+	case "touch":
+		for _, p := range paths {
+			if !h.Exists(p) {
+				if err := h.WriteFile(p, nil); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case "test", "[":
+		if len(paths) > 0 && !h.Exists(strings.TrimSuffix(paths[0], "]")) {
+			return nil, fmt.Errorf("exit status 1")
+		}
+	}
+	return nil, nil
+}
@@ -19,8 +19,10 @@ package registry
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
+	gosync "sync"
 	"time"
 
 	"github.com/containers/image/v5/copy"
@@ -35,9 +37,12 @@ import (
 	"github.com/labring/sealos/pkg/filesystem"
 	"github.com/labring/sealos/pkg/ssh"
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/archive"
 	"github.com/labring/sealos/pkg/utils/file"
+	"github.com/labring/sealos/pkg/utils/flags"
 	httputils "github.com/labring/sealos/pkg/utils/http"
 	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/progress"
 )
 
 const (
@@ -54,6 +59,16 @@ type impl struct {
 	pathResolver constants.PathResolver
 	execer       exec.Interface
 	mounts       []v2.MountImage
+
+	mu gosync.Mutex
+	// archives of the registry dirs, shared by the hosts synced via ssh
+	archives map[string]*registryArchive
+}
+
+type registryArchive struct {
+	once gosync.Once
+	path string
+	err  error
 }
 
 func shouldSkip(mounts []v2.MountImage) bool {
@@ -69,6 +84,7 @@ func (s *impl) Sync(ctx context.Context, hosts ...string) error {
 	if shouldSkip(s.mounts) {
 		return nil
 	}
+	defer s.removeArchives()
 	logger.Info("trying default http mode to sync images to hosts %v", hosts)
 	// run `sealctl registry serve` to start a temporary registry
 	for i := range hosts {
@@ -87,6 +103,7 @@ func (s *impl) Sync(ctx context.Context, hosts ...string) error {
 	}
 
 	type syncOption struct {
+		host   string
 		target string
 		typ    int
 	}
@@ -100,9 +117,9 @@ func (s *impl) Sync(ctx context.Context, hosts ...string) error {
 				ep := sync.ParseRegistryAddress(trimPortStr(target), defaultTemporaryPort)
 				if err := httputils.WaitUntilEndpointAlive(probeCtx, "http://"+ep); err != nil {
 					logger.Warn("cannot connect to remote temporary registry %s: %v, fallback using ssh mode instead", ep, err)
-					syncOptionChan <- &syncOption{target: target, typ: sshMode}
+					syncOptionChan <- &syncOption{host: target, target: target, typ: sshMode}
 				} else {
-					syncOptionChan <- &syncOption{target: ep, typ: httpMode}
+					syncOptionChan <- &syncOption{host: target, target: ep, typ: httpMode}
 				}
 			}(hosts[i])
 		}
@@ -119,14 +136,17 @@ func (s *impl) Sync(ctx context.Context, hosts ...string) error {
 			if !file.IsDir(registryDir) {
 				continue
 			}
-			eg.Go(func() (err error) {
-				switch opt.typ {
-				case httpMode:
-					err = syncViaHTTP(ctx, opt.target, registryDir)
-				case sshMode:
-					err = syncViaSSH(ctx, s, opt.target, registryDir)
-				}
-				return
+			imageName := s.mounts[j].ImageName
+			eg.Go(func() error {
+				return progress.RunStep(opt.host, fmt.Sprintf("sync registry of %s", imageName), func() (err error) {
+					switch opt.typ {
+					case httpMode:
+						err = syncViaHTTP(ctx, opt.target, registryDir)
+					case sshMode:
+						err = syncViaSSH(ctx, s, opt.target, registryDir)
+					}
+					return
+				})
 			})
 		}
 	}
@@ -146,10 +166,68 @@ func getRegistryServeCommand(pathResolver constants.PathResolver, port string) s
 	)
 }
 
+// syncViaSSH copies the registry dir to target as a zstd archive with digests and
+// unpacks it there, which is much faster than copying the blobs one by one. It falls back
+// to copying the dir if the archive cannot be created or unpacked.
 func syncViaSSH(_ context.Context, s *impl, target string, localDir string) error {
+	localArchive, err := s.archiveOf(localDir)
+	if err == nil {
+		remoteArchive := filepath.Join(s.pathResolver.RootFSPath(), filepath.Base(localArchive))
+		err = s.execer.Copy(target, localArchive+archive.DigestSuffix, remoteArchive+archive.DigestSuffix)
+		if err == nil {
+			err = s.execer.Copy(target, localArchive, remoteArchive)
+		}
+		if err == nil {
+			if err = s.execer.CmdAsync(target, getUntarCommand(s.pathResolver, remoteArchive)); err == nil {
+				return nil
+			}
+		}
+	}
+	logger.Warn("failed to sync archive of %s to %s: %v, fallback to copy the dir", localDir, target, err)
 	return ssh.CopyDir(s.execer, target, localDir, s.pathResolver.RootFSRegistryPath(), nil)
 }
 
+func getUntarCommand(pathResolver constants.PathResolver, archive string) string {
+	return fmt.Sprintf("%s untar --clear -o %s %s", pathResolver.RootFSSealctlPath(), pathResolver.RootFSRegistryPath(), archive)
+}
+
+// archiveOf returns the zstd archive of the registry dir, which is created once.
+func (s *impl) archiveOf(registryDir string) (string, error) {
+	s.mu.Lock()
+	if s.archives == nil {
+		s.archives = make(map[string]*registryArchive)
+	}
+	a, ok := s.archives[registryDir]
+	if !ok {
+		a = &registryArchive{}
+		s.archives[registryDir] = a
+	}
+	s.mu.Unlock()
+	a.once.Do(func() {
+		f, err := os.CreateTemp("", "registry-*.tar.zst")
+		if err != nil {
+			a.err = err
+			return
+		}
+		_ = f.Close()
+		a.path = f.Name()
+		a.err = archive.Tar(registryDir, a.path, flags.Zstd, false, true)
+	})
+	return a.path, a.err
+}
+
+func (s *impl) removeArchives() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range s.archives {
+		if a.path != "" {
+			_ = os.Remove(a.path)
+			_ = os.Remove(a.path + archive.DigestSuffix)
+		}
+	}
+	s.archives = nil
+}
+
 func syncViaHTTP(ctx context.Context, target string, localDir string) error {
 	sys := &types.SystemContext{
 		DockerInsecureSkipTLSVerify: types.OptionalBoolTrue,
@@ -189,5 +267,5 @@ func syncViaHTTP(ctx context.Context, target string, localDir string) error {
 }
 
 func New(pathResolver constants.PathResolver, execer exec.Interface, mounts []v2.MountImage) filesystem.RegistrySyncer {
-	return &impl{pathResolver, execer, mounts}
+	return &impl{pathResolver: pathResolver, execer: execer, mounts: mounts}
 }
@@ -89,8 +89,7 @@ func (f *defaultRootfs) mountRootfs(cluster *v2.Cluster, ipList []string) error
 		return err
 	}
 
-	sshClient := ssh.NewCacheClientFromCluster(cluster, true)
-	execer, err := exec.New(sshClient)
+	execer, err := exec.NewFromCluster(cluster, true)
 	if err != nil {
 		return err
 	}
@@ -177,8 +176,7 @@ func (f *defaultRootfs) unmountRootfs(cluster *v2.Cluster, ipList []string) erro
 	rmRootfs := fmt.Sprintf("rm -rf %s", clusterRootfsDir)
 	deleteHomeDirCmd := fmt.Sprintf("rm -rf %s", constants.ClusterDir(cluster.Name))
 	eg, _ := errgroup.WithContext(context.Background())
-	sshClient := ssh.NewCacheClientFromCluster(cluster, true)
-	execer, err := exec.New(sshClient)
+	execer, err := exec.NewFromCluster(cluster, true)
 	if err != nil {
 		return err
 	}
@@ -23,7 +23,6 @@ import (
 	"github.com/labring/sealos/fork/golang/expansion"
 	"github.com/labring/sealos/pkg/env"
 	"github.com/labring/sealos/pkg/exec"
-	"github.com/labring/sealos/pkg/ssh"
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 	"github.com/labring/sealos/pkg/utils/maps"
 	stringsutil "github.com/labring/sealos/pkg/utils/strings"
@@ -42,8 +41,7 @@ func NewGuestManager() (Interface, error) {
 
 func (d *Default) Apply(cluster *v2.Cluster, mounts []v2.MountImage, targetHosts []string) error {
 	envGetter := env.NewEnvProcessor(cluster)
-	sshClient := ssh.NewCacheClientFromCluster(cluster, true)
-	execer, err := exec.New(sshClient)
+	execer, err := exec.NewFromCluster(cluster, true)
 	if err != nil {
 		return err
 	}
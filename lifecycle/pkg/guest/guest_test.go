@@ -17,11 +17,13 @@ limitations under the License.
 package guest
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
 
 	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/exec/fake"
 
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 )
@@ -355,3 +357,33 @@ func TestDefault_getGuestCmd_dollarVariable(t *testing.T) {
 		})
 	}
 }
+
+func TestDefault_Apply(t *testing.T) {
+	cluster := &v2.Cluster{}
+	cluster.Name = "default"
+	cluster.Spec.Hosts = []v2.Host{
+		{IPS: []string{"192.168.0.2:22"}, Roles: []string{v2.MASTER}},
+		{IPS: []string{"192.168.0.3:22"}, Roles: []string{v2.NODE}},
+	}
+	mounts := []v2.MountImage{
+		{Name: "rootfs", Type: v2.RootfsImage, Cmd: []string{"bash init.sh"}},
+		{Name: "helm", Type: v2.AppImage, Cmd: []string{"cp opt/helm /usr/bin/"}},
+	}
+
+	execer := fake.NewFromCluster(cluster)
+	defer execer.Install()()
+	if err := (&Default{}).Apply(cluster, mounts, cluster.GetAllIPS()); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if got := execer.Commands("192.168.0.2"); len(got) != 2 {
+		t.Errorf("Apply() ran %v on master0, want the rootfs and application commands", got)
+	}
+	if got := execer.Commands("192.168.0.3"); len(got) != 1 {
+		t.Errorf("Apply() ran %v on node, want the rootfs command only", got)
+	}
+
+	execer.Fail(fake.Failure{Host: "192.168.0.3", Match: "init.sh", Err: errors.New("exit status 1")})
+	if err := (&Default{}).Apply(cluster, mounts, cluster.GetAllIPS()); err == nil {
+		t.Errorf("Apply() succeeded while init.sh failed on a node")
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/labring/sealos/pkg/env"
+	"github.com/labring/sealos/pkg/exec"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/iputils"
+	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/maps"
+	stringsutil "github.com/labring/sealos/pkg/utils/strings"
+)
+
+const DefaultTimeout = 5 * time.Minute
+
+const (
+	EnvPhase        = "SEALOS_HOOK_PHASE"
+	EnvStage        = "SEALOS_HOOK_STAGE"
+	EnvName         = "SEALOS_HOOK_NAME"
+	EnvChangedHosts = "SEALOS_HOOK_CHANGED_HOSTS"
+)
+
+type phaseKey struct{}
+
+// WithPhase marks the operation run with ctx, hooks are only run for operations with a phase.
+func WithPhase(ctx context.Context, phase v2.HookPhase) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, phaseKey{}, phase)
+}
+
+func GetPhase(ctx context.Context) v2.HookPhase {
+	if ctx == nil {
+		return ""
+	}
+	if v, ok := ctx.Value(phaseKey{}).(v2.HookPhase); ok {
+		return v
+	}
+	return ""
+}
+
+// Validate checks the hooks of the cluster.
+func Validate(hooks []v2.Hook) error {
+	for i, h := range hooks {
+		name := h.Name
+		if name == "" {
+			name = fmt.Sprintf("hooks[%d]", i)
+		}
+		if !slices.Contains(v2.HookPhases, h.Phase) {
+			return fmt.Errorf("hook %s: unknown phase %q", name, h.Phase)
+		}
+		if h.Stage != v2.HookStagePre && h.Stage != v2.HookStagePost {
+			return fmt.Errorf("hook %s: stage must be %s or %s", name, v2.HookStagePre, v2.HookStagePost)
+		}
+		if len(h.Commands) == 0 {
+			return fmt.Errorf("hook %s: commands cannot be empty", name)
+		}
+		switch h.FailurePolicy {
+		case "", v2.HookFailurePolicyFail, v2.HookFailurePolicyIgnore:
+		default:
+			return fmt.Errorf("hook %s: unknown failure policy %q", name, h.FailurePolicy)
+		}
+	}
+	return nil
+}
+
+// Run runs the hooks of cluster for the stage of phase. Hosts are selected from
+// cluster, changed are the hosts added or deleted by the operation.
+func Run(cluster *v2.Cluster, phase v2.HookPhase, stage v2.HookStage, changed []string) error {
+	if phase == "" || len(cluster.Spec.Hooks) == 0 {
+		return nil
+	}
+	if err := Validate(cluster.Spec.Hooks); err != nil {
+		return err
+	}
+	var selected []v2.Hook
+	for _, h := range cluster.Spec.Hooks {
+		if h.Phase == phase && h.Stage == stage {
+			selected = append(selected, h)
+		}
+	}
+	if len(selected) == 0 {
+		return nil
+	}
+	execer, err := exec.NewFromCluster(cluster, true)
+	if err != nil {
+		return err
+	}
+	envGetter := env.NewEnvProcessor(cluster)
+	for _, h := range selected {
+		hosts := SelectHosts(cluster, h.Target, changed)
+		if len(hosts) == 0 {
+			logger.Debug("no hosts selected by hook %s", h.Name)
+			continue
+		}
+		logger.Info("running %s-%s hook %s on %v", stage, phase, h.Name, hosts)
+		extra := map[string]string{
+			EnvPhase:        string(phase),
+			EnvStage:        string(stage),
+			EnvName:         h.Name,
+			EnvChangedHosts: strings.Join(iputils.GetHostIPs(changed), ","),
+		}
+		err = runHook(execer, h, hosts, func(host string) map[string]string {
+			return maps.Merge(envGetter.Getenv(host), extra)
+		})
+		if err == nil {
+			continue
+		}
+		if h.FailurePolicy == v2.HookFailurePolicyIgnore {
+			logger.Warn("ignore failure of %s-%s hook %s: %v", stage, phase, h.Name, err)
+			continue
+		}
+		return fmt.Errorf("%s-%s hook %s failed: %w", stage, phase, h.Name, err)
+	}
+	return nil
+}
+
+func runHook(execer exec.Interface, h v2.Hook, hosts []string, getenv func(string) map[string]string) error {
+	timeout := DefaultTimeout
+	if h.Timeout != nil && h.Timeout.Duration > 0 {
+		timeout = h.Timeout.Duration
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	eg, ctx := errgroup.WithContext(ctx)
+	for i := range hosts {
+		host := hosts[i]
+		eg.Go(func() error {
+			// stop at the first failed command, so that its error is the error of the hook
+			shell := stringsutil.RenderShellWithEnv(strings.Join(h.Commands, " && "), getenv(host))
+			if err := execer.CmdAsyncWithContext(ctx, host, shell); err != nil {
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					return fmt.Errorf("timed out after %s on %s", timeout, host)
+				}
+				return fmt.Errorf("on %s: %w", host, err)
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+// SelectHosts returns the hosts of cluster, with ssh ports, matched by target.
+func SelectHosts(cluster *v2.Cluster, target v2.HookTarget, changed []string) []string {
+	if len(target.Roles) == 0 && len(target.IPs) == 0 && !target.Master0 {
+		target.Master0 = !target.Changed
+	}
+	changedIPs := iputils.GetHostIPs(changed)
+	var ret []string
+	for _, h := range cluster.Spec.Hosts {
+		for _, addr := range h.IPS {
+			ip := iputils.GetHostIP(addr)
+			matched := (target.Master0 && addr == cluster.GetMaster0IPAndPort()) ||
+				slices.Contains(target.IPs, ip) || slices.Contains(target.IPs, addr)
+			for _, role := range target.Roles {
+				if slices.Contains(h.Roles, role) {
+					matched = true
+				}
+			}
+			if len(target.Roles) == 0 && len(target.IPs) == 0 && !target.Master0 {
+				// only changed is set, select all changed hosts
+				matched = true
+			}
+			if matched && target.Changed && !slices.Contains(changedIPs, ip) {
+				matched = false
+			}
+			if matched && !slices.Contains(ret, addr) {
+				ret = append(ret, addr)
+			}
+		}
+	}
+	return ret
+}
+
+// Hosts returns a copy of desired with the hosts of current which were removed
+// from it, so that hooks can select the hosts deleted by the operation.
+func Hosts(desired, current *v2.Cluster) *v2.Cluster {
+	ret := desired.DeepCopy()
+	if current == nil {
+		return ret
+	}
+	_, sub := iputils.GetDiffHosts(current.GetAllIPS(), desired.GetAllIPS())
+	for _, h := range current.Spec.Hosts {
+		var ips []string
+		for _, addr := range h.IPS {
+			if slices.Contains(sub, addr) {
+				ips = append(ips, addr)
+			}
+		}
+		if len(ips) > 0 {
+			host := *h.DeepCopy()
+			host.IPS = ips
+			ret.Spec.Hosts = append(ret.Spec.Hosts, host)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,125 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hooks
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/labring/sealos/pkg/exec/fake"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+func testCluster(hooks ...v2.Hook) *v2.Cluster {
+	cluster := &v2.Cluster{}
+	cluster.Name = "default"
+	cluster.Spec.Hosts = []v2.Host{
+		{IPS: []string{"192.168.0.2:22"}, Roles: []string{v2.MASTER, "amd64"}},
+		{IPS: []string{"192.168.0.3:22", "192.168.0.4:22"}, Roles: []string{v2.NODE, "amd64"}},
+	}
+	cluster.Spec.Hooks = hooks
+	return cluster
+}
+
+func TestSelectHosts(t *testing.T) {
+	cluster := testCluster()
+	tests := []struct {
+		name    string
+		target  v2.HookTarget
+		changed []string
+		want    []string
+	}{
+		{"empty target is master0", v2.HookTarget{}, nil, []string{"192.168.0.2:22"}},
+		{"roles", v2.HookTarget{Roles: []string{v2.NODE}}, nil, []string{"192.168.0.3:22", "192.168.0.4:22"}},
+		{"ips without port", v2.HookTarget{IPs: []string{"192.168.0.4"}, Master0: true}, nil, []string{"192.168.0.2:22", "192.168.0.4:22"}},
+		{"changed only", v2.HookTarget{Changed: true}, []string{"192.168.0.4:22"}, []string{"192.168.0.4:22"}},
+		{"changed roles", v2.HookTarget{Roles: []string{v2.NODE}, Changed: true}, []string{"192.168.0.3:22"}, []string{"192.168.0.3:22"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SelectHosts(cluster, tt.target, tt.changed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectHosts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	cluster := testCluster(
+		v2.Hook{Name: "drain", Phase: v2.HookPhaseDelete, Stage: v2.HookStagePre, Commands: []string{"echo drain", "echo drained"}},
+		v2.Hook{Name: "cleanup", Phase: v2.HookPhaseDelete, Stage: v2.HookStagePost, Commands: []string{"echo cleanup"},
+			Target: v2.HookTarget{Changed: true}},
+		v2.Hook{Name: "notify", Phase: v2.HookPhaseDelete, Stage: v2.HookStagePost, Commands: []string{"notify"},
+			FailurePolicy: v2.HookFailurePolicyIgnore},
+	)
+	e := fake.NewFromCluster(cluster)
+	defer e.Install()()
+
+	if err := Run(cluster, v2.HookPhaseDelete, v2.HookStagePre, []string{"192.168.0.3:22"}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	cmds := e.Commands("")
+	if len(cmds) != 1 || !strings.Contains(cmds[0], "echo drain && echo drained") || !strings.Contains(cmds[0], EnvName+`="drain"`) {
+		t.Fatalf("pre hooks ran %v", cmds)
+	}
+
+	e.Reset()
+	e.Fail(fake.Failure{Match: "notify"})
+	if err := Run(cluster, v2.HookPhaseDelete, v2.HookStagePost, []string{"192.168.0.3:22"}); err != nil {
+		t.Fatalf("Run() with ignored failure error = %v", err)
+	}
+	if got := e.Commands("192.168.0.3"); len(got) != 1 || !strings.Contains(got[0], "echo cleanup") {
+		t.Errorf("post hooks on changed host ran %v", got)
+	}
+
+	e.Reset()
+	cluster.Spec.Hooks[0].FailurePolicy = v2.HookFailurePolicyFail
+	e.Fail(fake.Failure{Match: "drain"})
+	if err := Run(cluster, v2.HookPhaseDelete, v2.HookStagePre, nil); err == nil {
+		t.Errorf("Run() expected the failure of the hook")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    v2.Hook
+		wantErr bool
+	}{
+		{"valid", v2.Hook{Phase: v2.HookPhaseRun, Stage: v2.HookStagePost, Commands: []string{"true"}}, false},
+		{"unknown phase", v2.Hook{Phase: "install", Stage: v2.HookStagePost, Commands: []string{"true"}}, true},
+		{"unknown stage", v2.Hook{Phase: v2.HookPhaseRun, Stage: "during", Commands: []string{"true"}}, true},
+		{"no commands", v2.Hook{Phase: v2.HookPhaseRun, Stage: v2.HookStagePre}, true},
+		{"unknown policy", v2.Hook{Phase: v2.HookPhaseRun, Stage: v2.HookStagePre, Commands: []string{"true"}, FailurePolicy: "Retry"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate([]v2.Hook{tt.hook}); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPhase(t *testing.T) {
+	if got := GetPhase(context.Background()); got != "" {
+		t.Errorf("GetPhase() = %q, want empty", got)
+	}
+	if got := GetPhase(WithPhase(context.Background(), v2.HookPhaseAdd)); got != v2.HookPhaseAdd {
+		t.Errorf("GetPhase() = %q, want %q", got, v2.HookPhaseAdd)
+	}
+}
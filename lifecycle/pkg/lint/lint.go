@@ -0,0 +1,189 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openshift/imagebuilder/dockerfile/parser"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is a problem found in a Kubefile or its build context.
+type Issue struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line,omitempty"`
+	Severity Severity `json:"severity"`
+	Rule     string   `json:"rule"`
+	Message  string   `json:"message"`
+}
+
+func (i Issue) String() string {
+	pos := i.File
+	if i.Line > 0 {
+		pos = fmt.Sprintf("%s:%d", i.File, i.Line)
+	}
+	return fmt.Sprintf("%s: %s: [%s] %s", pos, i.Severity, i.Rule, i.Message)
+}
+
+// Result is the outcome of linting a build context.
+type Result struct {
+	Issues []Issue `json:"issues"`
+}
+
+func (r *Result) add(file string, line int, severity Severity, rule, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, Issue{File: file, Line: line, Severity: severity, Rule: rule, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *Result) HasErrors() bool {
+	for _, i := range r.Issues {
+		if i.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns a error summarizing the issues with error severity, nil if there is none.
+func (r *Result) Err() error {
+	var errs []string
+	for _, i := range r.Issues {
+		if i.Severity == SeverityError {
+			errs = append(errs, i.String())
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("lint failed with %d error(s):\n%s", len(errs), strings.Join(errs, "\n"))
+}
+
+func (r *Result) Print(w io.Writer) {
+	for _, i := range r.Issues {
+		fmt.Fprintln(w, i.String())
+	}
+}
+
+// Options controls the checks made by Lint.
+type Options struct {
+	// RequireCachedImages reports images referenced by charts and manifests
+	// which are missing from the registry directory as errors even if the
+	// registry directory was not created yet.
+	RequireCachedImages bool
+}
+
+// Lint checks the Kubefiles against the build context directory.
+func Lint(contextDir string, kubefiles []string, opts Options) (*Result, error) {
+	result := &Result{}
+	for _, f := range kubefiles {
+		kf, err := parseKubefile(f)
+		if err != nil {
+			return nil, err
+		}
+		checkLabels(result, kf)
+		checkCopySources(result, kf, contextDir)
+		checkCmds(result, kf, contextDir)
+		if err = checkCachedImages(result, kf, contextDir, opts); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+type instruction struct {
+	cmd   string
+	args  []string
+	flags []string
+	line  int
+}
+
+type kubefile struct {
+	path         string
+	instructions []instruction
+}
+
+func parseKubefile(path string) (*kubefile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	res, err := parser.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	kf := &kubefile{path: filepath.Base(path)}
+	for _, child := range res.AST.Children {
+		ins := instruction{
+			cmd:   strings.ToLower(child.Value),
+			flags: child.Flags,
+			line:  child.StartLine,
+		}
+		for n := child.Next; n != nil; n = n.Next {
+			ins.args = append(ins.args, n.Value)
+		}
+		kf.instructions = append(kf.instructions, ins)
+	}
+	return kf, nil
+}
+
+// stage returns the instructions of the last build stage, which is the one
+// that ends up in the image.
+func (kf *kubefile) stage() []instruction {
+	start := 0
+	for i, ins := range kf.instructions {
+		if ins.cmd == "from" {
+			start = i
+		}
+	}
+	return kf.instructions[start:]
+}
+
+func (kf *kubefile) fromScratch() bool {
+	stage := kf.stage()
+	return len(stage) > 0 && stage[0].cmd == "from" && len(stage[0].args) > 0 &&
+		strings.EqualFold(stage[0].args[0], "scratch")
+}
+
+func (kf *kubefile) labels() (map[string]string, map[string]int) {
+	labels, lines := map[string]string{}, map[string]int{}
+	for _, ins := range kf.stage() {
+		if ins.cmd != "label" {
+			continue
+		}
+		for i := 0; i+1 < len(ins.args); i += 2 {
+			labels[unquote(ins.args[i])] = unquote(ins.args[i+1])
+			lines[unquote(ins.args[i])] = ins.line
+		}
+	}
+	return labels, lines
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
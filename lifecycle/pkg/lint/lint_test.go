@@ -0,0 +1,147 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func writeFile(t *testing.T, root, rel, data string) {
+	t.Helper()
+	p := filepath.Join(root, rel)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name     string
+		kubefile string
+		files    map[string]string
+		opts     Options
+		want     []string
+	}{
+		{
+			name: "valid rootfs image",
+			kubefile: `FROM scratch
+LABEL sealos.io.type="rootfs" sealos.io.version="v1beta1"
+COPY . .
+CMD ["bash init.sh"]`,
+			files: map[string]string{"scripts/init.sh": "", "init.sh": ""},
+		},
+		{
+			name: "valid app image with shell form cmd",
+			kubefile: `FROM scratch
+COPY charts charts
+COPY manifests ./manifests
+COPY install.sh scripts/
+CMD helm upgrade -i nginx charts/nginx && kubectl apply -f manifests/ && bash scripts/install.sh`,
+			files: map[string]string{"charts/nginx/Chart.yaml": "name: nginx\nversion: 0.1.0\n", "manifests/ns.yaml": "", "install.sh": ""},
+			want:  []string{RuleImageType},
+		},
+		{
+			name: "unsupported labels",
+			kubefile: `FROM scratch
+LABEL sealos.io.type=cluster
+LABEL sealos.io.version=v1`,
+			want: []string{RuleImageType, RuleImageVersion},
+		},
+		{
+			name: "rootfs without version",
+			kubefile: `FROM scratch
+LABEL sealos.io.type=rootfs`,
+			want: []string{RuleImageVersion},
+		},
+		{
+			name: "labels inherited from base image",
+			kubefile: `FROM labring/kubernetes:v1.25.0
+CMD ["bash missing.sh"]`,
+		},
+		{
+			name: "missing copy source and cmd path",
+			kubefile: `FROM scratch
+LABEL sealos.io.type=application
+COPY charts charts
+CMD ["helm install nginx charts/nginx", "bash ./deploy.sh"]`,
+			want: []string{RuleCmdPath, RuleCmdPath, RuleCopySource},
+		},
+		{
+			name: "referenced image not cached",
+			kubefile: `FROM scratch
+LABEL sealos.io.type=application
+COPY registry registry
+COPY images images`,
+			files: map[string]string{
+				"images/shim/images": "nginx:1.25\nlabring/lvscare:v4.3.0",
+				"registry/docker/registry/v2/repositories/library/nginx/_manifests/tags/1.25/current/link": "sha256:abc",
+			},
+			want: []string{RuleImageNotSaved},
+		},
+		{
+			name: "images not cached yet",
+			kubefile: `FROM scratch
+LABEL sealos.io.type=application
+COPY images images`,
+			files: map[string]string{"images/shim/images": "nginx:1.25"},
+			want:  []string{RuleImageNotSaved, RuleRegistryCopy},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, dir, "Kubefile", tt.kubefile)
+			for k, v := range tt.files {
+				writeFile(t, dir, k, v)
+			}
+			result, err := Lint(dir, []string{filepath.Join(dir, "Kubefile")}, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, i := range result.Issues {
+				got = append(got, i.Rule)
+			}
+			sort.Strings(got)
+			sort.Strings(tt.want)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Lint() issues = %v, want %v", result.Issues, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Lint() issues = %v, want %v", result.Issues, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestResultErr(t *testing.T) {
+	r := &Result{}
+	r.add("Kubefile", 0, SeverityWarning, RuleImageType, "missing label")
+	if r.Err() != nil || r.HasErrors() {
+		t.Errorf("warnings must not fail the lint")
+	}
+	r.add("Kubefile", 3, SeverityError, RuleCopySource, "no such file")
+	if r.Err() == nil || !r.HasErrors() {
+		t.Errorf("errors must fail the lint")
+	}
+}
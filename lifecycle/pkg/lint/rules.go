@@ -0,0 +1,278 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/containers/image/v5/docker/reference"
+	"github.com/google/shlex"
+	"github.com/labring/sreg/pkg/buildimage"
+	"golang.org/x/exp/slices"
+
+	"github.com/labring/sealos/pkg/constants"
+	v1beta1 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+const (
+	RuleImageType     = "image-type"
+	RuleImageVersion  = "image-version"
+	RuleCopySource    = "copy-source"
+	RuleCmdPath       = "cmd-path"
+	RuleRegistryCopy  = "registry-copy"
+	RuleImageNotSaved = "image-not-cached"
+)
+
+var imageTypes = []string{string(v1beta1.AppImage), string(v1beta1.RootfsImage), string(v1beta1.PatchImage)}
+
+func checkLabels(r *Result, kf *kubefile) {
+	labels, lines := kf.labels()
+	typeKey := firstPresentKey(labels, v1beta1.ImageTypeKeys)
+	imageType := labels[typeKey]
+	switch {
+	case typeKey == "":
+		// labels of a base image are inherited, only images built from scratch are known to miss it
+		if kf.fromScratch() {
+			r.add(kf.path, 0, SeverityWarning, RuleImageType, "missing label %s, the image will be treated as %s image",
+				v1beta1.ImageTypeKeys[0], v1beta1.AppImage)
+		}
+	case isVariable(imageType):
+	case !slices.Contains(imageTypes, imageType):
+		r.add(kf.path, lines[typeKey], SeverityError, RuleImageType, "unsupported %s %q, must be one of %s",
+			typeKey, imageType, strings.Join(imageTypes, ", "))
+	}
+
+	versionKey := firstPresentKey(labels, v1beta1.ImageVersionKeys)
+	version := labels[versionKey]
+	switch {
+	case versionKey == "":
+		if imageType == string(v1beta1.RootfsImage) {
+			r.add(kf.path, lines[typeKey], SeverityError, RuleImageVersion, "%s image requires label %s, must be one of %s",
+				v1beta1.RootfsImage, v1beta1.ImageVersionKeys[0], strings.Join(v1beta1.ImageVersionList, ", "))
+		}
+	case isVariable(version):
+	case !slices.Contains(v1beta1.ImageVersionList, version):
+		r.add(kf.path, lines[versionKey], SeverityError, RuleImageVersion, "unsupported %s %q, must be one of %s",
+			versionKey, version, strings.Join(v1beta1.ImageVersionList, ", "))
+	}
+}
+
+func firstPresentKey(labels map[string]string, keys []string) string {
+	for _, k := range keys {
+		if v, ok := labels[k]; ok && v != "" {
+			return k
+		}
+	}
+	return ""
+}
+
+func isVariable(s string) bool {
+	return strings.Contains(s, "$")
+}
+
+type copyMapping struct {
+	src, dest string
+}
+
+// copies returns the sources copied from the build context into the final stage
+// and their destination, relative to the root of the image.
+func (kf *kubefile) copies() []copyMapping {
+	var ret []copyMapping
+	for _, ins := range kf.stage() {
+		if (ins.cmd != "copy" && ins.cmd != "add") || len(ins.args) < 2 || hasFromFlag(ins.flags) {
+			continue
+		}
+		dest := cleanImagePath(ins.args[len(ins.args)-1])
+		for _, src := range ins.args[:len(ins.args)-1] {
+			ret = append(ret, copyMapping{src: filepath.Clean(src), dest: dest})
+		}
+	}
+	return ret
+}
+
+func hasFromFlag(flags []string) bool {
+	for _, f := range flags {
+		if strings.HasPrefix(f, "--from") {
+			return true
+		}
+	}
+	return false
+}
+
+func cleanImagePath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
+
+func isRemote(src string) bool {
+	return strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") || strings.HasPrefix(src, "git@")
+}
+
+func checkCopySources(r *Result, kf *kubefile, contextDir string) {
+	for _, ins := range kf.stage() {
+		if (ins.cmd != "copy" && ins.cmd != "add") || len(ins.args) < 2 || hasFromFlag(ins.flags) {
+			continue
+		}
+		for _, src := range ins.args[:len(ins.args)-1] {
+			if isRemote(src) || isVariable(src) {
+				continue
+			}
+			matches, _ := filepath.Glob(filepath.Join(contextDir, src))
+			if len(matches) == 0 {
+				r.add(kf.path, ins.line, SeverityError, RuleCopySource, "%s %s: no such file or directory in build context",
+					strings.ToUpper(ins.cmd), src)
+			}
+		}
+	}
+}
+
+// checkCmds verifies that the relative paths used by CMD, which runs in the root
+// directory of the image on the guest, are copied into the image.
+func checkCmds(r *Result, kf *kubefile, contextDir string) {
+	if !kf.fromScratch() {
+		// files may come from the base image
+		return
+	}
+	copies := kf.copies()
+	for _, ins := range kf.stage() {
+		if ins.cmd != "cmd" && ins.cmd != "entrypoint" {
+			continue
+		}
+		var words []string
+		// elements of the exec form could be shell commands as well, e.g. ["sh", "-c", "bash init.sh"]
+		for _, arg := range ins.args {
+			w, err := shlex.Split(arg)
+			if err != nil {
+				w = strings.Fields(arg)
+			}
+			words = append(words, w...)
+		}
+		for _, w := range words {
+			if !looksLikeImagePath(w) {
+				continue
+			}
+			if !existsInImage(contextDir, copies, cleanImagePath(w)) {
+				r.add(kf.path, ins.line, SeverityError, RuleCmdPath, "%s references %s which is not copied into the image",
+					strings.ToUpper(ins.cmd), w)
+			}
+		}
+	}
+}
+
+var scriptSuffixes = []string{".sh", ".yaml", ".yml", ".json", ".tgz"}
+
+func looksLikeImagePath(w string) bool {
+	if w == "" || strings.HasPrefix(w, "-") || strings.HasPrefix(w, "/") || isVariable(w) ||
+		strings.Contains(w, "://") || strings.ContainsAny(w, "=*?&|;<>()`") {
+		return false
+	}
+	if strings.HasPrefix(w, "./") {
+		return true
+	}
+	for _, s := range scriptSuffixes {
+		if strings.HasSuffix(w, s) {
+			return true
+		}
+	}
+	dir := strings.SplitN(w, "/", 2)[0]
+	return strings.Contains(w, "/") && slices.Contains(
+		[]string{constants.ChartsDirName, constants.ManifestsDirName, "scripts", "etc", "opt"}, dir)
+}
+
+func existsInImage(contextDir string, copies []copyMapping, p string) bool {
+	for _, c := range copies {
+		info, err := os.Stat(filepath.Join(contextDir, c.src))
+		if err != nil {
+			continue
+		}
+		// COPY file dest keeps the name of file if dest is a directory
+		if !info.IsDir() {
+			if p == c.dest || p == path.Join(c.dest, filepath.Base(c.src)) {
+				return true
+			}
+			continue
+		}
+		// COPY dir dest copies the content of dir into dest
+		rel := p
+		if c.dest != "" {
+			if !strings.HasPrefix(p+"/", c.dest+"/") {
+				continue
+			}
+			rel = strings.TrimPrefix(strings.TrimPrefix(p, c.dest), "/")
+		}
+		if _, err = os.Stat(filepath.Join(contextDir, c.src, rel)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCachedImages verifies that images referenced by charts, manifests and
+// images/shim were cached into the registry directory of the build context.
+func checkCachedImages(r *Result, kf *kubefile, contextDir string, opts Options) error {
+	images, err := buildimage.List(contextDir)
+	if err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		return nil
+	}
+	copiesRegistry := false
+	for _, c := range kf.copies() {
+		if c.src == constants.RegistryDirName || c.src == "." {
+			copiesRegistry = true
+		}
+	}
+	if !copiesRegistry {
+		r.add(kf.path, 0, SeverityWarning, RuleRegistryCopy, "%d image(s) are referenced but the %s directory is not copied into the image",
+			len(images), constants.RegistryDirName)
+	}
+
+	registryDir := filepath.Join(contextDir, constants.RegistryDirName)
+	_, statErr := os.Stat(registryDir)
+	severity := SeverityError
+	if os.IsNotExist(statErr) && !opts.RequireCachedImages {
+		// sealos build will cache them before building
+		severity = SeverityWarning
+	}
+	for _, img := range images {
+		if ok, msg := isCached(registryDir, img); !ok {
+			r.add(kf.path, 0, severity, RuleImageNotSaved, "image %s is not cached in %s: %s", img, constants.RegistryDirName, msg)
+		}
+	}
+	return nil
+}
+
+// isCached looks up the image in the filesystem storage of the distribution registry.
+func isCached(registryDir, img string) (bool, string) {
+	named, err := reference.ParseNormalizedNamed(img)
+	if err != nil {
+		return false, err.Error()
+	}
+	manifestsDir := filepath.Join(registryDir, "docker/registry/v2/repositories", reference.Path(named), "_manifests")
+	var p string
+	if canonical, ok := named.(reference.Canonical); ok {
+		d := canonical.Digest()
+		p = filepath.Join(manifestsDir, "revisions", d.Algorithm().String(), d.Encoded(), "link")
+	} else {
+		p = filepath.Join(manifestsDir, "tags", reference.TagNameOnly(named).(reference.Tagged).Tag(), "current", "link")
+	}
+	if _, err = os.Stat(p); err != nil {
+		return false, "manifest not found"
+	}
+	return true, ""
+}
@@ -0,0 +1,236 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metering
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Meter describes how the objects of an extension resource are billed.
+type Meter interface {
+	// Prices returns the properties billed for obj, their Enum are ignored.
+	Prices(obj client.Object) ([]Property, error)
+	// Usage returns the resources used by obj now, nil if it uses nothing.
+	Usage(obj client.Object) (*Usage, error)
+}
+
+// ErrPropertiesNotInitialized is returned when there is no property yet, the billing
+// controllers would replace their default properties with the extension ones.
+var ErrPropertiesNotInitialized = errors.New("the properties are not initialized by the billing controllers")
+
+// ResourceController implements ResourceControllerInterface with a Meter.
+type ResourceController struct {
+	store Store
+	meter Meter
+	now   func() time.Time
+
+	mu sync.Mutex
+	// properties caches the properties by name
+	properties map[string]Property
+}
+
+var _ ResourceControllerInterface = &ResourceController{}
+
+func NewResourceController(store Store, meter Meter) *ResourceController {
+	return &ResourceController{store: store, meter: meter, now: time.Now}
+}
+
+func (c *ResourceController) loadProperties(ctx context.Context, reload bool) (map[string]Property, error) {
+	if c.properties != nil && !reload {
+		return c.properties, nil
+	}
+	list, err := c.store.GetProperties(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrPropertiesNotInitialized
+	}
+	c.properties = make(map[string]Property, len(list))
+	for _, p := range list {
+		c.properties[p.Name] = p
+	}
+	return c.properties, nil
+}
+
+// maxCreateAttempts is the number of attempts to create a property whose enum is
+// taken by other replicas at the same time.
+const maxCreateAttempts = 5
+
+// CreateOrUpdateExtensionResourcesPrice saves the prices of obj, the enum of a new
+// property is the next one of the existing properties.
+func (c *ResourceController) CreateOrUpdateExtensionResourcesPrice(ctx context.Context, obj client.Object) error {
+	prices, err := c.meter.Prices(obj)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// the properties may be changed by others, always compare with the latest
+	if _, err = c.loadProperties(ctx, true); err != nil {
+		return err
+	}
+	for _, p := range prices {
+		if p.Name == "" {
+			return errors.New("name of the property cannot be empty")
+		}
+		if _, err = resource.ParseQuantity(p.Unit); err != nil {
+			return fmt.Errorf("invalid unit %q of property %s: %v", p.Unit, p.Name, err)
+		}
+		if p.PriceType == "" {
+			p.PriceType = AVG
+		}
+		if err = c.createOrUpdateProperty(ctx, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createOrUpdateProperty saves p, it is retried with the next enum if the enum is
+// taken by another replica, and updated if the property is created by it.
+func (c *ResourceController) createOrUpdateProperty(ctx context.Context, p Property) error {
+	for attempt := 0; attempt < maxCreateAttempts; attempt++ {
+		if old, ok := c.properties[p.Name]; ok {
+			return c.updateProperty(ctx, old, p)
+		}
+		var err error
+		if p.Enum, err = nextEnum(c.properties); err != nil {
+			return err
+		}
+		if p.EncryptUnitPrice, err = encryptUnitPrice(p.UnitPrice); err != nil {
+			return fmt.Errorf("failed to encrypt the unit price of property %s: %v", p.Name, err)
+		}
+		err = c.store.CreateProperty(ctx, p)
+		if err == nil {
+			c.properties[p.Name] = p
+			return nil
+		}
+		if !errors.Is(err, ErrPropertyExists) {
+			return fmt.Errorf("failed to create property %s: %v", p.Name, err)
+		}
+		if _, err = c.loadProperties(ctx, true); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to create property %s after %d attempts: %w", p.Name, maxCreateAttempts, ErrPropertyExists)
+}
+
+// updateProperty saves p with the enum of old, the unit price is encrypted again when it changes.
+func (c *ResourceController) updateProperty(ctx context.Context, old, p Property) error {
+	p.Enum, p.EncryptUnitPrice = old.Enum, old.EncryptUnitPrice
+	if p.UnitPrice != old.UnitPrice || p.EncryptUnitPrice == "" {
+		var err error
+		if p.EncryptUnitPrice, err = encryptUnitPrice(p.UnitPrice); err != nil {
+			return fmt.Errorf("failed to encrypt the unit price of property %s: %v", p.Name, err)
+		}
+	}
+	if old == p {
+		return nil
+	}
+	if err := c.store.SaveProperty(ctx, p); err != nil {
+		return fmt.Errorf("failed to save property %s: %v", p.Name, err)
+	}
+	c.properties[p.Name] = p
+	return nil
+}
+
+func nextEnum(properties map[string]Property) (uint8, error) {
+	var next int
+	for _, p := range properties {
+		if int(p.Enum) >= next {
+			next = int(p.Enum) + 1
+		}
+	}
+	if next > math.MaxUint8 {
+		return 0, errors.New("no enum is left for a new property")
+	}
+	return uint8(next), nil
+}
+
+// UpdateResourceUsed records the usage of obj at now, the used quantities are
+// rounded up to the units of the properties.
+func (c *ResourceController) UpdateResourceUsed(ctx context.Context, obj client.Object) error {
+	usage, err := c.meter.Usage(obj)
+	if err != nil || usage == nil {
+		return err
+	}
+	used, err := c.toEnumUsed(ctx, usage.Used)
+	if err != nil {
+		return err
+	}
+	if len(used) == 0 {
+		return nil
+	}
+	m := &Monitor{
+		Time:       c.now().UTC(),
+		Category:   usage.Namespace,
+		Type:       usage.AppType,
+		ParentType: usage.ParentType,
+		ParentName: usage.ParentName,
+		Name:       usage.Name,
+		Used:       used,
+	}
+	if m.Category == "" {
+		m.Category = obj.GetNamespace()
+	}
+	if m.Type == 0 {
+		m.Type = AppTypeOther
+	}
+	if m.Name == "" {
+		m.Name = obj.GetName()
+	}
+	return c.store.InsertMonitor(ctx, m)
+}
+
+// toEnumUsed converts the used quantities to the units of the properties by enum.
+func (c *ResourceController) toEnumUsed(ctx context.Context, quantities map[string]resource.Quantity) (map[uint8]int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	properties, err := c.loadProperties(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[uint8]int64, len(quantities))
+	for name, q := range quantities {
+		if q.IsZero() {
+			continue
+		}
+		p, ok := properties[name]
+		if !ok {
+			// the price may be created by another replica
+			if properties, err = c.loadProperties(ctx, true); err != nil {
+				return nil, err
+			}
+			if p, ok = properties[name]; !ok {
+				return nil, fmt.Errorf("property %s has no price", name)
+			}
+		}
+		unit, err := resource.ParseQuantity(p.Unit)
+		if err != nil || unit.IsZero() {
+			return nil, fmt.Errorf("invalid unit %q of property %s", p.Unit, name)
+		}
+		used[p.Enum] = int64(math.Ceil(float64(q.MilliValue()) / float64(unit.MilliValue())))
+	}
+	return used, nil
+}
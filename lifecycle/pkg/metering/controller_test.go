@@ -0,0 +1,251 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metering
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type memStore struct {
+	properties []Property
+	monitors   []*Monitor
+	// beforeCreate is called before a property is created, e.g. to create it by another replica
+	beforeCreate func(s *memStore)
+}
+
+func (s *memStore) GetProperties(context.Context) ([]Property, error) {
+	return append([]Property(nil), s.properties...), nil
+}
+
+func (s *memStore) CreateProperty(_ context.Context, p Property) error {
+	if s.beforeCreate != nil {
+		s.beforeCreate(s)
+	}
+	for i := range s.properties {
+		if s.properties[i].Name == p.Name || s.properties[i].Enum == p.Enum {
+			return ErrPropertyExists
+		}
+	}
+	s.properties = append(s.properties, p)
+	return nil
+}
+
+func (s *memStore) SaveProperty(_ context.Context, p Property) error {
+	for i := range s.properties {
+		if s.properties[i].Name == p.Name {
+			s.properties[i] = p
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func (s *memStore) InsertMonitor(_ context.Context, monitors ...*Monitor) error {
+	s.monitors = append(s.monitors, monitors...)
+	return nil
+}
+
+// configMapMeter bills the number of keys of the config maps.
+type configMapMeter struct {
+	price float64
+}
+
+func (m *configMapMeter) Prices(client.Object) ([]Property, error) {
+	return []Property{{Name: "configmap.keys", UnitPrice: m.price, Unit: "1"}}, nil
+}
+
+func (m *configMapMeter) Usage(obj client.Object) (*Usage, error) {
+	cm := obj.(*corev1.ConfigMap)
+	if len(cm.Data) == 0 {
+		return nil, nil
+	}
+	return &Usage{Used: map[string]resource.Quantity{"configmap.keys": *resource.NewQuantity(int64(len(cm.Data)), resource.DecimalSI)}}, nil
+}
+
+func newTestStore() *memStore {
+	return &memStore{properties: []Property{
+		{Name: "cpu", Enum: 0, PriceType: AVG, UnitPrice: 2.237442922, Unit: "1m"},
+		{Name: "memory", Enum: 1, PriceType: AVG, UnitPrice: 1.092501427, Unit: "1Mi"},
+		{Name: "services.nodeports", Enum: 4, PriceType: AVG, UnitPrice: 2.083, Unit: "1"},
+	}}
+}
+
+func testConfigMap(keys int) *corev1.ConfigMap {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "ns-user"}, Data: map[string]string{}}
+	for i := 0; i < keys; i++ {
+		cm.Data[string(rune('a'+i))] = ""
+	}
+	return cm
+}
+
+func TestCreateOrUpdateExtensionResourcesPrice(t *testing.T) {
+	ctx := context.Background()
+	if err := NewResourceController(&memStore{}, &configMapMeter{}).CreateOrUpdateExtensionResourcesPrice(ctx, testConfigMap(1)); !errors.Is(err, ErrPropertiesNotInitialized) {
+		t.Errorf("expected ErrPropertiesNotInitialized, got %v", err)
+	}
+
+	store, meter := newTestStore(), &configMapMeter{price: 10}
+	c := NewResourceController(store, meter)
+	if err := c.CreateOrUpdateExtensionResourcesPrice(ctx, testConfigMap(1)); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.properties) != 4 || store.properties[3].Enum != 5 || store.properties[3].PriceType != AVG {
+		t.Fatalf("unexpected properties %+v", store.properties)
+	}
+	if price := decryptTestUnitPrice(t, store.properties[3].EncryptUnitPrice); price != "10" {
+		t.Errorf("got encrypted unit price %s, want 10", price)
+	}
+	// the enum is kept when the price changes
+	meter.price = 20
+	if err := c.CreateOrUpdateExtensionResourcesPrice(ctx, testConfigMap(1)); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.properties) != 4 || store.properties[3].Enum != 5 || store.properties[3].UnitPrice != 20 {
+		t.Errorf("unexpected properties %+v", store.properties)
+	}
+	if price := decryptTestUnitPrice(t, store.properties[3].EncryptUnitPrice); price != "20" {
+		t.Errorf("got encrypted unit price %s, want 20", price)
+	}
+}
+
+func TestCreatePropertyConcurrently(t *testing.T) {
+	ctx := context.Background()
+	// another replica takes the next enum
+	store := newTestStore()
+	store.beforeCreate = func(s *memStore) {
+		s.beforeCreate = nil
+		s.properties = append(s.properties, Property{Name: "secret.keys", Enum: 5, Unit: "1"})
+	}
+	if err := NewResourceController(store, &configMapMeter{price: 10}).CreateOrUpdateExtensionResourcesPrice(ctx, testConfigMap(1)); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.properties) != 5 || store.properties[4].Name != "configmap.keys" || store.properties[4].Enum != 6 {
+		t.Errorf("unexpected properties %+v", store.properties)
+	}
+
+	// another replica creates the same property
+	store = newTestStore()
+	store.beforeCreate = func(s *memStore) {
+		s.beforeCreate = nil
+		s.properties = append(s.properties, Property{Name: "configmap.keys", Enum: 7, PriceType: AVG, UnitPrice: 5, Unit: "1"})
+	}
+	if err := NewResourceController(store, &configMapMeter{price: 10}).CreateOrUpdateExtensionResourcesPrice(ctx, testConfigMap(1)); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.properties) != 4 || store.properties[3].Enum != 7 || store.properties[3].UnitPrice != 10 {
+		t.Errorf("unexpected properties %+v", store.properties)
+	}
+}
+
+func decryptTestUnitPrice(t *testing.T, ciphertext string) string {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := aes.NewCipher([]byte(defaultEncryptionKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := aesgcm.Open(nil, data[:12], data[12:], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(plaintext)
+}
+
+func TestUpdateResourceUsed(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore()
+	c := NewResourceController(store, &configMapMeter{price: 10})
+	now := time.Date(2023, 10, 1, 8, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	if err := c.UpdateResourceUsed(ctx, testConfigMap(3)); err == nil {
+		t.Error("expected an error of the property without price")
+	}
+	if err := c.CreateOrUpdateExtensionResourcesPrice(ctx, testConfigMap(3)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UpdateResourceUsed(ctx, testConfigMap(3)); err != nil {
+		t.Fatal(err)
+	}
+	// nothing is recorded without usage
+	if err := c.UpdateResourceUsed(ctx, testConfigMap(0)); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.monitors) != 1 {
+		t.Fatalf("expected 1 monitor, got %d", len(store.monitors))
+	}
+	m := store.monitors[0]
+	if !m.Time.Equal(now) || m.Category != "ns-user" || m.Name != "foo" || m.Type != AppTypeOther || m.Used[5] != 3 {
+		t.Errorf("unexpected monitor %+v", m)
+	}
+}
+
+func TestToEnumUsedRoundUp(t *testing.T) {
+	c := NewResourceController(newTestStore(), nil)
+	used, err := c.toEnumUsed(context.Background(), map[string]resource.Quantity{
+		"cpu":    resource.MustParse("1500u"),
+		"memory": resource.MustParse("1.5Mi"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if used[0] != 2 || used[1] != 2 {
+		t.Errorf("unexpected used %v", used)
+	}
+}
+
+func TestHelpers(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore()
+	rc := NewResourceController(store, &configMapMeter{price: 10})
+	cli := fake.NewClientBuilder().WithObjects(testConfigMap(2)).Build()
+
+	r := NewPriceReconciler(cli, &corev1.ConfigMap{}, rc)
+	if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns-user", Name: "foo"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns-user", Name: "bar"}}); err != nil {
+		t.Errorf("not found objects should be ignored, got %v", err)
+	}
+	if len(store.properties) != 4 {
+		t.Fatalf("unexpected properties %+v", store.properties)
+	}
+
+	if err := NewUsageRecorder(cli, &corev1.ConfigMapList{}, rc).Record(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.monitors) != 1 || store.monitors[0].Used[5] != 2 {
+		t.Errorf("unexpected monitors %+v", store.monitors)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metering
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EnvCryptoKeys is the key ring of the billing controllers, a comma separated list of
+// <version>=<key>, the newest version encrypts. Set it to the same keys as the billing
+// controllers, the built-in key of version 0 is used if it is empty.
+const EnvCryptoKeys = "CRYPTO_KEYS"
+
+// defaultEncryptionKey is the built-in key of version 0 of the billing controllers.
+const defaultEncryptionKey = "Bg1c3Dd5e9e0F84bdF0A5887cF43aB63"
+
+// encryptUnitPrice encrypts the unit price like the billing controllers encrypt the
+// properties: AES-GCM with the current key of the key ring, the ciphertext is prefixed
+// by "v<version>:" unless the version is 0.
+func encryptUnitPrice(price float64) (string, error) {
+	version, key, err := currentKey(os.Getenv(EnvCryptoKeys))
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", fmt.Errorf("invalid key version %d: %v", version, err)
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, 12)
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := aesgcm.Seal(nonce, nonce, []byte(strconv.FormatFloat(price, 'f', -1, 64)), nil)
+	ciphertext := base64.StdEncoding.EncodeToString(sealed)
+	if version == 0 {
+		return ciphertext, nil
+	}
+	return "v" + strconv.Itoa(version) + ":" + ciphertext, nil
+}
+
+// currentKey returns the newest key of the key ring config.
+func currentKey(config string) (int, []byte, error) {
+	version, key := -1, ""
+	for _, entry := range strings.Split(config, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		v, k, found := strings.Cut(entry, "=")
+		if !found {
+			return 0, nil, fmt.Errorf("invalid entry of %s, must be <version>=<key>", EnvCryptoKeys)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 0 {
+			return 0, nil, fmt.Errorf("invalid key version %q of %s", v, EnvCryptoKeys)
+		}
+		if n > version {
+			version, key = n, strings.TrimSpace(k)
+		}
+	}
+	if version < 0 {
+		return 0, []byte(defaultEncryptionKey), nil
+	}
+	return version, []byte(key), nil
+}
@@ -0,0 +1,117 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metering
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// DefaultUsageInterval is the interval of the usage records, the billing controllers
+// expect a record of every minute.
+const DefaultUsageInterval = time.Minute
+
+// PriceReconciler keeps the prices of the reconciled objects up to date, register it
+// for the extension resource with the controller builder of the manager:
+//
+//	ctrl.NewControllerManagedBy(mgr).For(&v1.Foo{}).Complete(metering.NewPriceReconciler(mgr.GetClient(), &v1.Foo{}, rc))
+type PriceReconciler struct {
+	client client.Client
+	obj    client.Object
+	rc     ResourceControllerInterface
+}
+
+var _ reconcile.Reconciler = &PriceReconciler{}
+
+func NewPriceReconciler(c client.Client, obj client.Object, rc ResourceControllerInterface) *PriceReconciler {
+	return &PriceReconciler{client: c, obj: obj, rc: rc}
+}
+
+func (r *PriceReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	obj := r.obj.DeepCopyObject().(client.Object)
+	if err := r.client.Get(ctx, req.NamespacedName, obj); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	if obj.GetDeletionTimestamp() != nil {
+		return reconcile.Result{}, nil
+	}
+	return reconcile.Result{}, r.rc.CreateOrUpdateExtensionResourcesPrice(ctx, obj)
+}
+
+// UsageRecorder records the usage of the objects of a list type every interval, it
+// is a Runnable of the manager which runs only on the leader:
+//
+//	mgr.Add(metering.NewUsageRecorder(mgr.GetClient(), &v1.FooList{}, rc))
+type UsageRecorder struct {
+	reader   client.Reader
+	list     client.ObjectList
+	rc       ResourceControllerInterface
+	Interval time.Duration
+}
+
+func NewUsageRecorder(reader client.Reader, list client.ObjectList, rc ResourceControllerInterface) *UsageRecorder {
+	return &UsageRecorder{reader: reader, list: list, rc: rc, Interval: DefaultUsageInterval}
+}
+
+// NeedLeaderElection implements the LeaderElectionRunnable of the manager.
+func (r *UsageRecorder) NeedLeaderElection() bool {
+	return true
+}
+
+// Start records the usage at the start of every interval until ctx is done.
+func (r *UsageRecorder) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("metering")
+	for {
+		// align the records to the interval, like the records of the billing controllers
+		wait := time.Until(time.Now().Truncate(r.Interval).Add(r.Interval))
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+		if err := r.Record(ctx); err != nil {
+			logger.Error(err, "failed to record the usage")
+		}
+	}
+}
+
+// Record records the usage of all objects once.
+func (r *UsageRecorder) Record(ctx context.Context) error {
+	list := r.list.DeepCopyObject().(client.ObjectList)
+	if err := r.reader.List(ctx, list); err != nil {
+		return err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := range items {
+		obj, ok := items[i].(client.Object)
+		if !ok || obj.GetDeletionTimestamp() != nil {
+			continue
+		}
+		if err = r.rc.UpdateResourceUsed(ctx, obj); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
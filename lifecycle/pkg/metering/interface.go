@@ -12,6 +12,17 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+// Package metering meters the extension resources of third-party controllers, so that
+// they are billed by the account billing controllers. The prices are saved into the
+// properties collection and the usage into the daily monitor collections.
+//
+// A controller implements a Meter for its resource, and registers the helpers with
+// its manager:
+//
+//	store, err := metering.NewMongoStoreFromURI(ctx, os.Getenv("MONGO_URI"))
+//	rc := metering.NewResourceController(store, &fooMeter{})
+//	err = ctrl.NewControllerManagedBy(mgr).For(&v1.Foo{}).Complete(metering.NewPriceReconciler(mgr.GetClient(), &v1.Foo{}, rc))
+//	err = mgr.Add(metering.NewUsageRecorder(mgr.GetClient(), &v1.FooList{}, rc))
 package metering
 
 import (
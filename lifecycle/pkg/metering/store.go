@@ -0,0 +1,187 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metering
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	EnvAccountDBName      = "ACCOUNT_DB_NAME"
+	DefaultAccountDBName  = "sealos-resources"
+	DefaultPropertiesConn = "properties"
+	DefaultMonitorConn    = "monitor"
+)
+
+// ErrPropertyExists is returned by Store.CreateProperty when the name or the enum of
+// the property is taken.
+var ErrPropertyExists = errors.New("the name or the enum of the property exists")
+
+// Store keeps the properties and the monitor records read by the billing controllers.
+type Store interface {
+	// GetProperties returns all the properties.
+	GetProperties(ctx context.Context) ([]Property, error)
+	// CreateProperty creates the property, it returns ErrPropertyExists if the name
+	// or the enum is taken.
+	CreateProperty(ctx context.Context, property Property) error
+	// SaveProperty updates the property by name, its enum is not changed.
+	SaveProperty(ctx context.Context, property Property) error
+	// InsertMonitor inserts the records into the collection of the day of their time.
+	InsertMonitor(ctx context.Context, monitors ...*Monitor) error
+}
+
+type mongoStore struct {
+	db *mongo.Database
+	// created keeps the monitor collections which are known to exist
+	mu      sync.Mutex
+	created map[string]bool
+	// indexed is whether the unique indexes of the properties are created
+	indexed bool
+}
+
+// NewMongoStore returns a Store in the account database of client, the database
+// name is read from ACCOUNT_DB_NAME like the billing controllers.
+func NewMongoStore(client *mongo.Client) Store {
+	name := os.Getenv(EnvAccountDBName)
+	if name == "" {
+		name = DefaultAccountDBName
+	}
+	return &mongoStore{db: client.Database(name), created: map[string]bool{}}
+}
+
+// NewMongoStoreFromURI connects to uri and returns a Store.
+func NewMongoStoreFromURI(ctx context.Context, uri string) (Store, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, err
+	}
+	if err = client.Ping(ctx, nil); err != nil {
+		return nil, err
+	}
+	return NewMongoStore(client), nil
+}
+
+func (s *mongoStore) GetProperties(ctx context.Context) ([]Property, error) {
+	cursor, err := s.db.Collection(DefaultPropertiesConn).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("get properties error: %v", err)
+	}
+	var properties []Property
+	if err = cursor.All(ctx, &properties); err != nil {
+		return nil, fmt.Errorf("get properties error: %v", err)
+	}
+	return properties, nil
+}
+
+func (s *mongoStore) CreateProperty(ctx context.Context, property Property) error {
+	if err := s.ensurePropertyIndexes(ctx); err != nil {
+		return err
+	}
+	_, err := s.db.Collection(DefaultPropertiesConn).InsertOne(ctx, property)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %v", ErrPropertyExists, err)
+	}
+	return err
+}
+
+func (s *mongoStore) SaveProperty(ctx context.Context, property Property) error {
+	_, err := s.db.Collection(DefaultPropertiesConn).ReplaceOne(ctx,
+		bson.M{"name": property.Name, "enum": property.Enum}, property)
+	return err
+}
+
+// ensurePropertyIndexes makes the names and the enums of the properties unique, so
+// that the replicas creating properties at the same time cannot take the same enum.
+func (s *mongoStore) ensurePropertyIndexes(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.indexed {
+		return nil
+	}
+	_, err := s.db.Collection(DefaultPropertiesConn).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "name", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "enum", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create the unique indexes of the properties: %v", err)
+	}
+	s.indexed = true
+	return nil
+}
+
+func (s *mongoStore) InsertMonitor(ctx context.Context, monitors ...*Monitor) error {
+	if len(monitors) == 0 {
+		return nil
+	}
+	// the monitor data is saved daily, e.g. monitor_20201201
+	name := fmt.Sprintf("%s_%s", DefaultMonitorConn, monitors[0].Time.UTC().Format("20060102"))
+	if err := s.createTimeSeriesIfNotExist(ctx, name); err != nil {
+		return err
+	}
+	docs := make([]interface{}, len(monitors))
+	for i := range monitors {
+		docs[i] = monitors[i]
+	}
+	_, err := s.db.Collection(name).InsertMany(ctx, docs)
+	return err
+}
+
+// createTimeSeriesIfNotExist creates the collection as the billing controllers do,
+// an implicitly created collection would not be a time series.
+func (s *mongoStore) createTimeSeriesIfNotExist(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.created[name] {
+		return nil
+	}
+	names, err := s.db.ListCollectionNames(ctx, bson.M{"name": name})
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		cmd := bson.D{
+			{Key: "create", Value: name},
+			{Key: "timeseries", Value: bson.D{{Key: "timeField", Value: "time"}}},
+		}
+		// the collection may be created by others at the same time
+		if err = s.db.RunCommand(ctx, cmd).Err(); err != nil && !isNamespaceExists(err) {
+			return err
+		}
+	}
+	s.created[name] = true
+	// forget the collections of the past days
+	cutoff := fmt.Sprintf("%s_%s", DefaultMonitorConn, time.Now().UTC().AddDate(0, 0, -1).Format("20060102"))
+	for n := range s.created {
+		if n < cutoff {
+			delete(s.created, n)
+		}
+	}
+	return nil
+}
+
+// isNamespaceExists returns whether err is the NamespaceExists error of mongodb.
+func isNamespaceExists(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Code == 48
+}
@@ -0,0 +1,94 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metering
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// The documents below keep the same schema as the PropertyType and Monitor of
+// github.com/labring/sealos/controllers/pkg/resources, which are read by the
+// account billing controllers.
+
+const (
+	// AVG bills the average value of the usage in the billing period.
+	AVG = "AVG"
+	// SUM bills the accumulated value of the usage.
+	SUM = "SUM"
+	// DIF bills the difference between the max and min value of the usage.
+	DIF = "DIF"
+)
+
+// App types of the monitor records, the billings are grouped by them.
+const (
+	AppTypeDB uint8 = iota + 1
+	AppTypeApp
+	AppTypeTerminal
+	AppTypeJob
+	AppTypeOther
+	AppTypeObjectStorage
+	AppTypeCVM
+	AppTypeAppStore
+	AppTypeDBBackup
+	AppTypeDevBox
+	AppTypeLLMToken
+)
+
+// Property is a billed resource and its price.
+type Property struct {
+	Name  string `json:"name" bson:"name"`
+	Alias string `json:"alias" bson:"alias"`
+	// Enum is the key of the property in the monitor records, it is allocated
+	// when the property is created.
+	Enum uint8 `json:"enum" bson:"enum"`
+	// PriceType is one of AVG, SUM and DIF, default is AVG.
+	PriceType string `json:"price_type,omitempty" bson:"price_type,omitempty"`
+	// UnitPrice is the price of a Unit of the used value, 1000000 = 1¥.
+	UnitPrice        float64 `json:"unit_price" bson:"unit_price"`
+	ViewPrice        float64 `json:"view_price" bson:"view_price"`
+	EncryptUnitPrice string  `json:"encrypt_unit_price" bson:"encrypt_unit_price"`
+	// Unit is a quantity such as 1m, 1Mi or 1, the usage is recorded in it.
+	Unit       string `json:"unit" bson:"unit"`
+	UnitPeriod string `json:"unit_period,omitempty" bson:"unit_period,omitempty"`
+}
+
+// Monitor is a usage record of an app, the records are sampled every minute.
+type Monitor struct {
+	Time time.Time `json:"time" bson:"time"`
+	// Category is the namespace of the app.
+	Category   string          `json:"category" bson:"category"`
+	Type       uint8           `json:"type" bson:"type"`
+	ParentType uint8           `json:"parent_type" bson:"parent_type"`
+	ParentName string          `json:"parent_name" bson:"parent_name"`
+	Name       string          `json:"name" bson:"name"`
+	Used       map[uint8]int64 `json:"used" bson:"used"`
+	Property   string          `json:"property,omitempty" bson:"property,omitempty"`
+}
+
+// Usage is the resources used by an object at a time.
+type Usage struct {
+	// Namespace is the namespace which is billed, default is the namespace of the object.
+	Namespace string
+	// AppType groups the billings, default is AppTypeOther.
+	AppType uint8
+	// Name is the app name, default is the name of the object.
+	Name       string
+	ParentType uint8
+	ParentName string
+	// Used is the used quantity of each property by name.
+	Used map[string]resource.Quantity
+}
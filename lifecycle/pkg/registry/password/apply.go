@@ -29,7 +29,6 @@ import (
 	"github.com/labring/sealos/pkg/constants"
 	"github.com/labring/sealos/pkg/exec"
 	"github.com/labring/sealos/pkg/registry/helpers"
-	"github.com/labring/sealos/pkg/ssh"
 	"github.com/labring/sealos/pkg/types/v1beta1"
 	"github.com/labring/sealos/pkg/utils/confirm"
 	fileutil "github.com/labring/sealos/pkg/utils/file"
@@ -133,8 +132,7 @@ func (r *RegistryPasswdResults) Validate() (*v1beta1.Cluster, error) {
 
 func (r *RegistryPasswdResults) Apply(cluster *v1beta1.Cluster) error {
 	if r.execer == nil {
-		sshClient := ssh.NewCacheClientFromCluster(cluster, true)
-		execer, err := exec.New(sshClient)
+		execer, err := exec.NewFromCluster(cluster, true)
 		if err != nil {
 			return err
 		}
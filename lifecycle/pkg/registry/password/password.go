@@ -27,8 +27,10 @@ import (
 	"github.com/labring/sealos/pkg/constants"
 	"github.com/labring/sealos/pkg/exec"
 	"github.com/labring/sealos/pkg/registry/helpers"
+	"github.com/labring/sealos/pkg/ssh"
 	"github.com/labring/sealos/pkg/types/v1beta1"
 	"github.com/labring/sealos/pkg/utils/file"
+	"github.com/labring/sealos/pkg/utils/initsystem"
 	"github.com/labring/sealos/pkg/utils/logger"
 	"github.com/labring/sealos/pkg/utils/passwd"
 )
@@ -63,6 +65,11 @@ type upgrade struct {
 	mk                 *maker
 }
 
+// initSystem restarts the services by the init system detected on host.
+func (m *upgrade) initSystem(host string) initsystem.InitSystem {
+	return ssh.NewRemoteFromSSH(m.Cluster, m.SSHInterface).InitSystem(host)
+}
+
 func (m *upgrade) UpdateRegistryConfig(rc *v1beta1.RegistryConfig, target, host string) error {
 	configPath, err := m.mk.configLocalRegistryConfig(path.Join(constants.ClusterDir(m.Cluster), constants.EtcDirName), rc)
 	if err != nil {
@@ -90,7 +97,7 @@ func (m *upgrade) UpdateImageShimConfig(rc *types.Config, target, host string) e
 		if err = m.SSHInterface.Copy(host, configPath, target); err != nil {
 			return err
 		}
-		if err = m.SSHInterface.CmdAsync(host, "systemctl restart image-cri-shim"); err != nil {
+		if err = m.initSystem(host).ServiceRestart("image-cri-shim"); err != nil {
 			return err
 		}
 	}
@@ -120,7 +127,7 @@ func (m *upgrade) UpdateRegistryPasswd(rc *v1beta1.RegistryConfig, target, host
 				return err
 			}
 		case RegistryTypeRegistry:
-			if err = m.SSHInterface.CmdAsync(host, "systemctl restart registry"); err != nil {
+			if err = m.initSystem(host).ServiceRestart("registry"); err != nil {
 				return err
 			}
 		}
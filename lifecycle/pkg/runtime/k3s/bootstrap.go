@@ -29,6 +29,7 @@ import (
 	"github.com/labring/sealos/pkg/constants"
 	"github.com/labring/sealos/pkg/utils/file"
 	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/progress"
 	"github.com/labring/sealos/pkg/utils/rand"
 	"github.com/labring/sealos/pkg/utils/yaml"
 )
@@ -94,7 +95,8 @@ func (k *K3s) writeJoinConfigWithCallbacks(runMode string, callbacks ...callback
 }
 
 func (k *K3s) joinMaster(master string) error {
-	return k.runPipelines(fmt.Sprintf("join master %s", master),
+	done := progress.Step(master, "join master")
+	err := k.runPipelines(fmt.Sprintf("join master %s", master),
 		func() error {
 			// the rest masters are also running in agent mode, so agent-token file is needed.
 			return k.generateAndSendTokenFiles(master, "token", "agent-token")
@@ -108,6 +110,8 @@ func (k *K3s) joinMaster(master string) error {
 		},
 		func() error { return k.copyKubeConfigFileToNodes(master) },
 	)
+	done(err)
+	return err
 }
 
 func (k *K3s) joinNodes(nodes []string) error {
@@ -147,7 +151,8 @@ func (k *K3s) getVipAndPort() string {
 }
 
 func (k *K3s) joinNode(node string) error {
-	return k.runPipelines(fmt.Sprintf("join node %s", node),
+	done := progress.Step(node, "join node")
+	err := k.runPipelines(fmt.Sprintf("join node %s", node),
 		func() error {
 			return k.remoteUtil.IPVS(node, k.getVipAndPort(), k.getMasterIPListAndHTTPSPort())
 		},
@@ -158,6 +163,8 @@ func (k *K3s) joinNode(node string) error {
 		func() error { return k.enableK3sService(node) },
 		func() error { return k.copyKubeConfigFileToNodes(node) },
 	)
+	done(err)
+	return err
 }
 
 func (k *K3s) generateAndSendCerts() error {
@@ -29,6 +29,7 @@ import (
 	"github.com/labring/sealos/pkg/ssh"
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/progress"
 	"github.com/labring/sealos/pkg/utils/yaml"
 )
 
@@ -43,8 +44,7 @@ type K3s struct {
 }
 
 func New(cluster *v2.Cluster, config any) (*K3s, error) {
-	sshClient := ssh.NewCacheClientFromCluster(cluster, true)
-	execer, err := exec.New(sshClient)
+	execer, err := exec.NewFromCluster(cluster, true)
 	if err != nil {
 		return nil, err
 	}
@@ -146,10 +146,12 @@ func (k *K3s) SyncNodeIPVS(mastersIPList, nodeIPList []string) error {
 
 func (k *K3s) runPipelines(phase string, pipelines ...func() error) error {
 	logger.Info("starting %s", phase)
-	for i := range pipelines {
-		if err := pipelines[i](); err != nil {
-			return fmt.Errorf("failed to %s: %v", phase, err)
+	return progress.RunPhase(phase, func() error {
+		for i := range pipelines {
+			if err := pipelines[i](); err != nil {
+				return fmt.Errorf("failed to %s: %v", phase, err)
+			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
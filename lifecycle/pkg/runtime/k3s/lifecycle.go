@@ -25,6 +25,7 @@ import (
 	"golang.org/x/exp/slices"
 
 	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/progress"
 
 	"golang.org/x/sync/errgroup"
 )
@@ -56,15 +57,18 @@ func (k *K3s) removeNodes(nodes []string) error {
 
 func (k *K3s) resetNode(host string) error {
 	logger.Info("start to reset node: %s", host)
+	defer progress.Step(host, "reset node")(nil)
 	removeKubeConfig := "rm -rf $HOME/.kube"
 	removeKubeConfigErr := k.execer.CmdAsync(host, removeKubeConfig)
 	if removeKubeConfigErr != nil {
 		logger.Error("failed to clean node, exec command %s failed, %v", removeKubeConfig, removeKubeConfigErr)
+		progress.Error(host, removeKubeConfigErr)
 	}
 	if slices.Contains(k.cluster.GetNodeIPAndPortList(), host) {
 		ipvsclearErr := k.remoteUtil.IPVSClean(host, k.getVipAndPort())
 		if ipvsclearErr != nil {
 			logger.Error("failed to clear ipvs rules for node %s: %v", host, ipvsclearErr)
+			progress.Error(host, ipvsclearErr)
 		}
 	}
 	return nil
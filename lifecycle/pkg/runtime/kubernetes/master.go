@@ -22,6 +22,7 @@ import (
 	"github.com/labring/sealos/pkg/ssh"
 	"github.com/labring/sealos/pkg/utils/file"
 	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/progress"
 	"github.com/labring/sealos/pkg/utils/strings"
 
 	"golang.org/x/sync/errgroup"
@@ -125,32 +126,40 @@ func (k *KubeadmRuntime) joinMasters(masters []string) error {
 		return fmt.Errorf("get join master command failed, kubernetes version is %s", k.getKubeVersion())
 	}
 	for _, master := range masters {
-		logger.Info("start to join %s as master", master)
-		if err = k.imagePull(master, ""); err != nil {
+		master := master
+		if err = progress.RunStep(master, "join master", func() error { return k.joinMaster(master, joinCmd) }); err != nil {
 			return err
 		}
-		logger.Debug("start to generate cert for master %s", master)
-		err = k.execCert(master)
-		if err != nil {
-			return fmt.Errorf("failed to create cert for master %s: %v", master, err)
-		}
+	}
+	return nil
+}
 
-		err = k.sshCmdAsync(master, joinCmd)
-		if err != nil {
-			return fmt.Errorf("exec kubeadm join in %s failed %v", master, err)
-		}
+func (k *KubeadmRuntime) joinMaster(master, joinCmd string) error {
+	logger.Info("start to join %s as master", master)
+	if err := k.imagePull(master, ""); err != nil {
+		return err
+	}
+	logger.Debug("start to generate cert for master %s", master)
+	err := k.execCert(master)
+	if err != nil {
+		return fmt.Errorf("failed to create cert for master %s: %v", master, err)
+	}
 
-		err = k.execHostsAppend(master, master, k.getAPIServerDomain())
-		if err != nil {
-			return fmt.Errorf("add master0 apiserver domain hosts in %s failed %v", master, err)
-		}
+	err = k.sshCmdAsync(master, joinCmd)
+	if err != nil {
+		return fmt.Errorf("exec kubeadm join in %s failed %v", master, err)
+	}
 
-		err = k.copyMasterKubeConfig(master)
-		if err != nil {
-			return err
-		}
-		logger.Info("succeeded in joining %s as master", master)
+	err = k.execHostsAppend(master, master, k.getAPIServerDomain())
+	if err != nil {
+		return fmt.Errorf("add master0 apiserver domain hosts in %s failed %v", master, err)
+	}
+
+	err = k.copyMasterKubeConfig(master)
+	if err != nil {
+		return err
 	}
+	logger.Info("succeeded in joining %s as master", master)
 	return nil
 }
 
@@ -167,7 +176,7 @@ func (k *KubeadmRuntime) deleteMasters(masters []string) error {
 		master := master
 		eg.Go(func() error {
 			logger.Info("start to delete master %s", master)
-			if err := k.deleteMaster(master); err != nil {
+			if err := progress.RunStep(master, "delete master", func() error { return k.deleteMaster(master) }); err != nil {
 				logger.Error("delete master %s failed %v", master, err)
 			} else {
 				logger.Info("succeeded in deleting master %s", master)
@@ -22,6 +22,7 @@ import (
 	"github.com/labring/sealos/pkg/ssh"
 	"github.com/labring/sealos/pkg/utils/file"
 	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/progress"
 
 	"golang.org/x/sync/errgroup"
 )
@@ -42,7 +43,9 @@ func (k *KubeadmRuntime) joinNodes(newNodesIPList []string) error {
 	eg, _ := errgroup.WithContext(context.Background())
 	for _, node := range newNodesIPList {
 		node := node
-		eg.Go(func() error {
+		eg.Go(func() (err error) {
+			done := progress.Step(node, "join node")
+			defer func() { done(err) }()
 			logger.Info("start to join %s as worker", node)
 			k.mu.Lock()
 			err = k.copyKubeadmConfigToNode(node)
@@ -98,7 +101,7 @@ func (k *KubeadmRuntime) deleteNodes(nodes []string) error {
 		node := node
 		eg.Go(func() error {
 			logger.Info("start to delete worker %s", node)
-			if err := k.deleteNode(node); err != nil {
+			if err := progress.RunStep(node, "delete node", func() error { return k.deleteNode(node) }); err != nil {
 				return fmt.Errorf("delete node %s failed %v", node, err)
 			}
 			logger.Info("succeeded in deleting worker %s", node)
@@ -22,6 +22,7 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/progress"
 )
 
 const (
@@ -67,6 +68,7 @@ func (k *KubeadmRuntime) resetMasters(nodes []string) {
 
 func (k *KubeadmRuntime) resetNode(node string, cleanHook func()) error {
 	logger.Info("start to reset node: %s", node)
+	defer progress.Step(node, "reset node")(nil)
 	resetCmd := fmt.Sprintf(remoteCleanMasterOrNode, vlogToStr(k.klogLevel), k.getEtcdDataDir())
 
 	resetCmdErr := k.sshCmdAsync(node, resetCmd)
@@ -76,15 +78,18 @@ func (k *KubeadmRuntime) resetNode(node string, cleanHook func()) error {
 
 	if resetCmdErr != nil {
 		logger.Error("failed to clean node, exec command %s failed, %v", resetCmd, resetCmdErr)
+		progress.Error(node, resetCmdErr)
 	}
 	removeKubeConfigErr := k.sshCmdAsync(node, removeKubeConfig)
 	if removeKubeConfigErr != nil {
 		logger.Error("failed to clean node, exec command %s failed, %v", removeKubeConfig, removeKubeConfigErr)
+		progress.Error(node, removeKubeConfigErr)
 	}
 	if slices.Contains(k.cluster.GetNodeIPAndPortList(), node) {
 		ipvscleanErr := k.execIPVSClean(node)
 		if ipvscleanErr != nil {
 			logger.Error("failed to clean node route and ipvs failed, %v", ipvscleanErr)
+			progress.Error(node, ipvscleanErr)
 		}
 	}
 	return nil
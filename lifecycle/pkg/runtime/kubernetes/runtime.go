@@ -123,8 +123,7 @@ func (k *KubeadmRuntime) ScaleDown(deleteMastersIPList []string, deleteNodesIPLi
 }
 
 func newKubeadmRuntime(cluster *v2.Cluster, kubeadm *types.KubeadmConfig) (*KubeadmRuntime, error) {
-	sshClient := ssh.NewCacheClientFromCluster(cluster, true)
-	execer, err := exec.New(sshClient)
+	execer, err := exec.NewFromCluster(cluster, true)
 	if err != nil {
 		return nil, err
 	}
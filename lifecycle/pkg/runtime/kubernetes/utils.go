@@ -27,15 +27,18 @@ import (
 
 	"github.com/labring/sealos/pkg/client-go/kubernetes"
 	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/progress"
 )
 
 func (k *KubeadmRuntime) runPipelines(phase string, pipelines ...func() error) error {
-	for i := range pipelines {
-		if err := pipelines[i](); err != nil {
-			return fmt.Errorf("failed to %s: %v", phase, err)
+	return progress.RunPhase(phase, func() error {
+		for i := range pipelines {
+			if err := pipelines[i](); err != nil {
+				return fmt.Errorf("failed to %s: %v", phase, err)
+			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 func (k *KubeadmRuntime) SendJoinMasterKubeConfigs(masters []string, files ...string) error {
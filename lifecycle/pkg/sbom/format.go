@@ -0,0 +1,279 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+
+	"github.com/labring/sealos/pkg/version"
+)
+
+const (
+	FormatSPDX      = "spdx"
+	FormatCycloneDX = "cyclonedx"
+)
+
+var Formats = []string{FormatSPDX, FormatCycloneDX}
+
+// Encode writes the inventory as a SPDX 2.3 or CycloneDX 1.5 JSON document.
+func Encode(w io.Writer, format string, inv *Inventory) error {
+	var doc interface{}
+	switch format {
+	case FormatSPDX:
+		doc = toSPDX(inv, time.Now())
+	case FormatCycloneDX:
+		doc = toCycloneDX(inv, time.Now())
+	default:
+		return fmt.Errorf("unsupported sbom format %s, available formats are %s", format, strings.Join(Formats, ", "))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func toolName() string {
+	return "sealos-" + version.Get().GitVersion
+}
+
+// purl returns the package url of an embedded container image, see
+// https://github.com/package-url/purl-spec/blob/master/PURL-TYPES.rst#oci
+func purl(img ContainerImage) string {
+	q := url.Values{}
+	q.Set("repository_url", img.Repository)
+	q.Set("tag", img.Tag)
+	return fmt.Sprintf("pkg:oci/%s@%s?%s", strings.ToLower(path.Base(img.Repository)), url.PathEscape(img.Digest), q.Encode())
+}
+
+func digestHex(d string) (string, string) {
+	if algo, hex, ok := strings.Cut(d, ":"); ok {
+		return strings.ToUpper(algo), hex
+	}
+	return "SHA256", d
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	PrimaryPurpose   string            `json:"primaryPackagePurpose,omitempty"`
+	Checksums        []spdxChecksum    `json:"checksums,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+	Comment          string            `json:"comment,omitempty"`
+}
+
+type spdxFile struct {
+	SPDXID    string         `json:"SPDXID"`
+	FileName  string         `json:"fileName"`
+	Checksums []spdxChecksum `json:"checksums"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+type spdxDocument struct {
+	SPDXVersion       string `json:"spdxVersion"`
+	DataLicense       string `json:"dataLicense"`
+	SPDXID            string `json:"SPDXID"`
+	Name              string `json:"name"`
+	DocumentNamespace string `json:"documentNamespace"`
+	CreationInfo      struct {
+		Created  string   `json:"created"`
+		Creators []string `json:"creators"`
+	} `json:"creationInfo"`
+	Packages      []spdxPackage      `json:"packages"`
+	Files         []spdxFile         `json:"files"`
+	Relationships []spdxRelationship `json:"relationships"`
+}
+
+func toSPDX(inv *Inventory, now time.Time) *spdxDocument {
+	doc := &spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              inv.Name,
+		DocumentNamespace: fmt.Sprintf("https://sealos.io/spdxdocs/%s-%s", url.PathEscape(inv.Name), uuid.NewUUID()),
+	}
+	doc.CreationInfo.Created = now.UTC().Format(time.RFC3339)
+	doc.CreationInfo.Creators = []string{"Tool: " + toolName()}
+
+	const root = "SPDXRef-Package-0"
+	rootPkg := spdxPackage{
+		SPDXID:           root,
+		Name:             inv.Name,
+		DownloadLocation: "NOASSERTION",
+		FilesAnalyzed:    true,
+		PrimaryPurpose:   "CONTAINER",
+	}
+	if inv.Digest != "" {
+		algo, hex := digestHex(inv.Digest)
+		rootPkg.Checksums = []spdxChecksum{{Algorithm: algo, ChecksumValue: hex}}
+	}
+	doc.Packages = append(doc.Packages, rootPkg)
+	doc.Relationships = append(doc.Relationships, spdxRelationship{doc.SPDXID, "DESCRIBES", root})
+
+	for i, img := range inv.Images {
+		id := fmt.Sprintf("SPDXRef-Image-%d", i)
+		algo, hex := digestHex(img.Digest)
+		doc.Packages = append(doc.Packages, spdxPackage{
+			SPDXID:           id,
+			Name:             img.Repository,
+			VersionInfo:      img.Tag,
+			DownloadLocation: "NOASSERTION",
+			PrimaryPurpose:   "CONTAINER",
+			Checksums:        []spdxChecksum{{Algorithm: algo, ChecksumValue: hex}},
+			ExternalRefs:     []spdxExternalRef{{"PACKAGE-MANAGER", "purl", purl(img)}},
+		})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{root, "CONTAINS", id})
+	}
+	for i, chart := range inv.Charts {
+		id := fmt.Sprintf("SPDXRef-Chart-%d", i)
+		doc.Packages = append(doc.Packages, spdxPackage{
+			SPDXID:           id,
+			Name:             chart.Name,
+			VersionInfo:      chart.Version,
+			DownloadLocation: "NOASSERTION",
+			PrimaryPurpose:   "APPLICATION",
+			Comment:          fmt.Sprintf("helm chart at %s, app version %s", chart.Path, chart.AppVersion),
+		})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{root, "CONTAINS", id})
+	}
+	for i, f := range inv.Files {
+		id := fmt.Sprintf("SPDXRef-File-%d", i)
+		doc.Files = append(doc.Files, spdxFile{
+			SPDXID:    id,
+			FileName:  "./" + f.Path,
+			Checksums: []spdxChecksum{{Algorithm: "SHA256", ChecksumValue: f.SHA256}},
+		})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{root, "CONTAINS", id})
+	}
+	return doc
+}
+
+type cdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxComponent struct {
+	BOMRef     string        `json:"bom-ref,omitempty"`
+	Type       string        `json:"type"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	Hashes     []cdxHash     `json:"hashes,omitempty"`
+	Purl       string        `json:"purl,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxDocument struct {
+	BOMFormat    string `json:"bomFormat"`
+	SpecVersion  string `json:"specVersion"`
+	SerialNumber string `json:"serialNumber"`
+	Version      int    `json:"version"`
+	Metadata     struct {
+		Timestamp string `json:"timestamp"`
+		Tools     []struct {
+			Name string `json:"name"`
+		} `json:"tools"`
+		Component cdxComponent `json:"component"`
+	} `json:"metadata"`
+	Components []cdxComponent `json:"components"`
+}
+
+func cdxDigest(d string) []cdxHash {
+	algo, hex := digestHex(d)
+	if algo == "SHA256" {
+		algo = "SHA-256"
+	}
+	return []cdxHash{{Alg: algo, Content: hex}}
+}
+
+func toCycloneDX(inv *Inventory, now time.Time) *cdxDocument {
+	doc := &cdxDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: fmt.Sprintf("urn:uuid:%s", uuid.NewUUID()),
+		Version:      1,
+	}
+	doc.Metadata.Timestamp = now.UTC().Format(time.RFC3339)
+	doc.Metadata.Tools = []struct {
+		Name string `json:"name"`
+	}{{Name: toolName()}}
+	doc.Metadata.Component = cdxComponent{BOMRef: inv.Name, Type: "container", Name: inv.Name}
+	if inv.Digest != "" {
+		doc.Metadata.Component.Hashes = cdxDigest(inv.Digest)
+	}
+
+	for _, img := range inv.Images {
+		p := purl(img)
+		doc.Components = append(doc.Components, cdxComponent{
+			BOMRef:  p,
+			Type:    "container",
+			Name:    img.Repository,
+			Version: img.Tag,
+			Hashes:  cdxDigest(img.Digest),
+			Purl:    p,
+		})
+	}
+	for _, chart := range inv.Charts {
+		doc.Components = append(doc.Components, cdxComponent{
+			BOMRef:  "chart:" + chart.Path,
+			Type:    "application",
+			Name:    chart.Name,
+			Version: chart.Version,
+			Properties: []cdxProperty{
+				{Name: "sealos:helm:path", Value: chart.Path},
+				{Name: "sealos:helm:appVersion", Value: chart.AppVersion},
+			},
+		})
+	}
+	for _, f := range inv.Files {
+		doc.Components = append(doc.Components, cdxComponent{
+			BOMRef: "file:" + f.Path,
+			Type:   "file",
+			Name:   f.Path,
+			Hashes: []cdxHash{{Alg: "SHA-256", Content: f.SHA256}},
+		})
+	}
+	return doc
+}
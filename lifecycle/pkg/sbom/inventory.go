@@ -0,0 +1,283 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/labring/sealos/pkg/constants"
+)
+
+const (
+	chartFileName = "Chart.yaml"
+	// layout of the distribution filesystem storage which `sealos build` caches images into
+	registryRepositoriesDir = "docker/registry/v2/repositories"
+	registryBlobsDir        = "docker/registry/v2/blobs"
+	manifestsDirName        = "_manifests"
+)
+
+// File is a regular file inside the image rootfs.
+type File struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ContainerImage is an image cached in the registry directory of the image.
+type ContainerImage struct {
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+	Digest     string `json:"digest"`
+}
+
+func (c ContainerImage) String() string {
+	return fmt.Sprintf("%s:%s@%s", c.Repository, c.Tag, c.Digest)
+}
+
+// Chart is a helm chart shipped in the charts directory of the image.
+type Chart struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	AppVersion string `json:"appVersion,omitempty"`
+	Path       string `json:"path"`
+}
+
+// Inventory is everything found inside a cluster image.
+type Inventory struct {
+	Name   string           `json:"name"`
+	Digest string           `json:"digest,omitempty"`
+	Files  []File           `json:"files"`
+	Images []ContainerImage `json:"images"`
+	Charts []Chart          `json:"charts"`
+}
+
+// Collect walks the rootfs of a mounted or unpacked cluster image.
+func Collect(name, digest, root string) (*Inventory, error) {
+	inv := &Inventory{Name: name, Digest: digest}
+	var err error
+	if inv.Files, err = collectFiles(root); err != nil {
+		return nil, fmt.Errorf("failed to collect files: %w", err)
+	}
+	if inv.Images, err = collectImages(filepath.Join(root, constants.RegistryDirName)); err != nil {
+		return nil, fmt.Errorf("failed to collect container images: %w", err)
+	}
+	if inv.Charts, err = collectCharts(root, constants.ChartsDirName); err != nil {
+		return nil, fmt.Errorf("failed to collect charts: %w", err)
+	}
+	return inv, nil
+}
+
+func collectFiles(root string) ([]File, error) {
+	var files []File
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		sum, err := fileDigest(root, filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		files = append(files, File{Path: filepath.ToSlash(rel), Size: info.Size(), SHA256: sum})
+		return nil
+	})
+	return files, err
+}
+
+// fileDigest returns the sha256 of a file, registry blobs are content addressable
+// so the digest is taken from the path instead of hashing gigabytes of layers again.
+func fileDigest(root, rel string) (string, error) {
+	blobsPrefix := path.Join(constants.RegistryDirName, registryBlobsDir, "sha256") + "/"
+	if strings.HasPrefix(rel, blobsPrefix) && path.Base(rel) == "data" {
+		return path.Base(path.Dir(rel)), nil
+	}
+	f, err := os.Open(filepath.Join(root, rel))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func collectImages(registryDir string) ([]ContainerImage, error) {
+	reposDir := filepath.Join(registryDir, registryRepositoriesDir)
+	if _, err := os.Stat(reposDir); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	var images []ContainerImage
+	err := filepath.WalkDir(reposDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || d.Name() != manifestsDirName {
+			return nil
+		}
+		repo, err := filepath.Rel(reposDir, filepath.Dir(p))
+		if err != nil {
+			return err
+		}
+		tags, err := readTags(filepath.Join(p, "tags"))
+		if err != nil {
+			return err
+		}
+		for tag, dgst := range tags {
+			images = append(images, ContainerImage{Repository: filepath.ToSlash(repo), Tag: tag, Digest: dgst})
+		}
+		return filepath.SkipDir
+	})
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].String() < images[j].String()
+	})
+	return images, err
+}
+
+func readTags(tagsDir string) (map[string]string, error) {
+	entries, err := os.ReadDir(tagsDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	tags := make(map[string]string, len(entries))
+	for _, e := range entries {
+		link, err := os.ReadFile(filepath.Join(tagsDir, e.Name(), "current", "link"))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		tags[e.Name()] = strings.TrimSpace(string(link))
+	}
+	return tags, nil
+}
+
+type chartMetadata struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	AppVersion string `json:"appVersion,omitempty"`
+}
+
+func collectCharts(root, chartsDirName string) ([]Chart, error) {
+	chartsDir := filepath.Join(root, chartsDirName)
+	if _, err := os.Stat(chartsDir); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	var charts []Chart
+	err := filepath.WalkDir(chartsDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p)
+		rel = filepath.ToSlash(rel)
+		switch {
+		case d.IsDir():
+			data, err := os.ReadFile(filepath.Join(p, chartFileName))
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			chart, err := parseChart(data, rel)
+			if err != nil {
+				return err
+			}
+			charts = append(charts, chart)
+			// dependencies under charts/ of a chart belong to the parent chart
+			return filepath.SkipDir
+		case strings.HasSuffix(d.Name(), ".tgz"):
+			data, err := readChartFromArchive(p)
+			if err != nil {
+				return err
+			}
+			if data == nil {
+				return nil
+			}
+			chart, err := parseChart(data, rel)
+			if err != nil {
+				return err
+			}
+			charts = append(charts, chart)
+		}
+		return nil
+	})
+	return charts, err
+}
+
+func parseChart(data []byte, rel string) (Chart, error) {
+	var meta chartMetadata
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return Chart{}, fmt.Errorf("failed to parse chart %s: %w", rel, err)
+	}
+	return Chart{Name: meta.Name, Version: meta.Version, AppVersion: meta.AppVersion, Path: rel}, nil
+}
+
+// readChartFromArchive returns the content of <chart>/Chart.yaml in a packaged chart.
+func readChartFromArchive(p string) ([]byte, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chart archive %s: %w", p, err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read chart archive %s: %w", p, err)
+		}
+		parts := strings.Split(strings.TrimPrefix(hdr.Name, "./"), "/")
+		if len(parts) == 2 && parts[1] == chartFileName {
+			return io.ReadAll(tr)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const blobDigest = "2d8d7ef3a2e7a7c2f1d5e3bd6cb0d1f1b1e9b9d2d0a7f9b0c7c0a1d1e2f3a4b5"
+
+func writeFile(t *testing.T, root, rel string, data []byte) {
+	t.Helper()
+	p := filepath.Join(root, rel)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func packChart(t *testing.T, name, chartYaml string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: name + "/Chart.yaml", Mode: 0644, Size: int64(len(chartYaml))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte(chartYaml)); err != nil {
+		t.Fatal(err)
+	}
+	_ = tw.Close()
+	_ = gz.Close()
+	return buf.Bytes()
+}
+
+func newRootfs(t *testing.T) string {
+	root := t.TempDir()
+	writeFile(t, root, "Kubefile", []byte("FROM scratch\n"))
+	writeFile(t, root, "registry/docker/registry/v2/repositories/library/nginx/_manifests/tags/1.25/current/link", []byte("sha256:"+blobDigest))
+	writeFile(t, root, "registry/docker/registry/v2/blobs/sha256/2d/"+blobDigest+"/data", []byte("{}"))
+	writeFile(t, root, "charts/cilium/Chart.yaml", []byte("name: cilium\nversion: 1.12.0\nappVersion: 1.12.0\n"))
+	writeFile(t, root, "charts/cilium/charts/sub/Chart.yaml", []byte("name: sub\nversion: 0.1.0\n"))
+	writeFile(t, root, "charts/ingress-nginx-4.7.0.tgz", packChart(t, "ingress-nginx", "name: ingress-nginx\nversion: 4.7.0\n"))
+	return root
+}
+
+func TestCollect(t *testing.T) {
+	inv, err := Collect("labring/test:v1", "sha256:abc", newRootfs(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inv.Files) != 6 {
+		t.Errorf("Collect() got %d files, want 6", len(inv.Files))
+	}
+	for _, f := range inv.Files {
+		if filepath.Base(f.Path) == "data" && f.SHA256 != blobDigest {
+			t.Errorf("Collect() blob digest = %s, want %s", f.SHA256, blobDigest)
+		}
+	}
+	wantImage := ContainerImage{Repository: "library/nginx", Tag: "1.25", Digest: "sha256:" + blobDigest}
+	if len(inv.Images) != 1 || inv.Images[0] != wantImage {
+		t.Errorf("Collect() images = %v, want %v", inv.Images, wantImage)
+	}
+	if len(inv.Charts) != 2 {
+		t.Fatalf("Collect() charts = %v, want cilium and ingress-nginx", inv.Charts)
+	}
+	for _, c := range inv.Charts {
+		if c.Name != "cilium" && c.Name != "ingress-nginx" {
+			t.Errorf("Collect() got unexpected chart %v", c)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	inv, err := Collect("labring/test:v1", "sha256:abc", newRootfs(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		format  string
+		key     string
+		wantErr bool
+	}{
+		{format: FormatSPDX, key: "spdxVersion"},
+		{format: FormatCycloneDX, key: "bomFormat"},
+		{format: "syft", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Encode(&buf, tt.format, inv)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Encode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var doc map[string]interface{}
+			if err = json.Unmarshal(buf.Bytes(), &doc); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := doc[tt.key]; !ok {
+				t.Errorf("Encode() output has no %s field", tt.key)
+			}
+			if !bytes.Contains(buf.Bytes(), []byte("pkg:oci/nginx@sha256")) {
+				t.Errorf("Encode() output has no purl of embedded image")
+			}
+		})
+	}
+}
@@ -0,0 +1,344 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sdk drives sealos from Go programs, the same way as the sealos command line
+// does, but without interactive prompts.
+//
+// The appliers of sealos keep their state at package level: the data and runtime
+// roots, the logger and its hooks, the progress sinks and phases, the audit recorders,
+// the executor factory of pkg/exec and the force flags of pkg/apply/processor. So a
+// process serves only one pair of roots, and the operations of all Clients of the
+// process run one at a time, whatever their cluster; the other state must not be
+// changed while an operation is running. The Client must run as root, like the
+// sealos command line.
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/labring/sealos/pkg/apply"
+	"github.com/labring/sealos/pkg/apply/applydrivers"
+	"github.com/labring/sealos/pkg/apply/processor"
+	"github.com/labring/sealos/pkg/buildah"
+	"github.com/labring/sealos/pkg/client-go/kubernetes"
+	"github.com/labring/sealos/pkg/clusterfile"
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/system"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/confirm"
+	"github.com/labring/sealos/pkg/utils/file"
+)
+
+// ErrConfirmationRequired is returned when an operation needs a confirmation, Force
+// is not set and the Client has no Confirm function.
+var ErrConfirmationRequired = errors.New("confirmation required, set Force to proceed")
+
+var (
+	rootsMu sync.Mutex
+
+	buildahOnce sync.Once
+	buildahErr  error
+
+	// operation is held by the running operation of the process, see the package doc.
+	operation = make(chan struct{}, 1)
+	runningMu sync.Mutex
+	// running is the cluster of the running operation.
+	running string
+)
+
+// Client runs the cluster operations, it is safe for concurrent use.
+type Client struct {
+	confirm ConfirmFunc
+}
+
+// New returns a Client, the roots of the process are set from opts the first time,
+// it fails if they were already set to different directories.
+func New(opts Options) (*Client, error) {
+	if err := setupRoots(opts); err != nil {
+		return nil, err
+	}
+	return &Client{confirm: opts.Confirm}, nil
+}
+
+func setupRoots(opts Options) error {
+	rootsMu.Lock()
+	defer rootsMu.Unlock()
+	if err := setupRoot(&constants.DefaultClusterRootFsDir, opts.DataRoot, system.DataRootConfigKey); err != nil {
+		return err
+	}
+	if err := setupRoot(&constants.DefaultRuntimeRootDir, opts.RuntimeRoot, system.RuntimeRootConfigKey); err != nil {
+		return err
+	}
+	return file.MkDirs(constants.LogPath(), constants.WorkDir())
+}
+
+func setupRoot(root *string, want, key string) error {
+	if want == "" {
+		if *root != "" {
+			return nil
+		}
+		val, err := system.Get(key)
+		if err != nil {
+			return err
+		}
+		want = val
+	}
+	if *root != "" && *root != want {
+		return fmt.Errorf("%s is already %s, it cannot be changed to %s in the same process", key, *root, want)
+	}
+	*root = want
+	return nil
+}
+
+// setupBuildah sets up the image storage once, as the sealos command line does before
+// the commands which require buildah.
+func setupBuildah() error {
+	buildahOnce.Do(func() {
+		buildah.EnsureRootCommand(constants.AppName)
+		buildahErr = buildah.TrySetupWithDefaults()
+	})
+	return buildahErr
+}
+
+func clusterName(name string) string {
+	if name == "" {
+		return "default"
+	}
+	return name
+}
+
+// lock waits for the running operation of the process, it returns the unlock function.
+func lock(ctx context.Context, cluster string) (func(), error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case operation <- struct{}{}:
+	}
+	runningMu.Lock()
+	running = cluster
+	runningMu.Unlock()
+	return func() {
+		runningMu.Lock()
+		running = ""
+		runningMu.Unlock()
+		<-operation
+	}, nil
+}
+
+// operationContext answers the confirmations of the operation by force, or by the
+// Confirm function of the Client, instead of prompting.
+func (c *Client) operationContext(ctx context.Context, force bool) context.Context {
+	ctx = processor.WithForceOverride(ctx, force)
+	return confirm.WithConfirmer(ctx, func(prompt, _ string) (bool, error) {
+		if force {
+			return true, nil
+		}
+		if c.confirm != nil {
+			return c.confirm(prompt)
+		}
+		return false, fmt.Errorf("%w: %s", ErrConfirmationRequired, prompt)
+	})
+}
+
+func (c *Client) do(ctx context.Context, cluster string, force bool, fn func(ctx context.Context) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := setupBuildah(); err != nil {
+		return err
+	}
+	unlock, err := lock(ctx, clusterName(cluster))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return fn(c.operationContext(ctx, force))
+}
+
+// Run runs the images on the cluster like sealos run, the cluster is created if it does not exist.
+func (c *Client) Run(ctx context.Context, opts RunOptions) error {
+	if len(opts.Images) == 0 {
+		return errors.New("images cannot be empty")
+	}
+	return c.do(ctx, opts.Cluster, opts.Force, func(ctx context.Context) error {
+		args := &apply.RunArgs{Cluster: &apply.Cluster{}, SSH: &apply.SSH{}}
+		cmd, err := newCommand(ctx, "run", args.RegisterFlags, opts.flagValues())
+		if err != nil {
+			return err
+		}
+		applier, err := apply.NewApplierFromArgs(cmd, args, opts.Images)
+		if err != nil {
+			return err
+		}
+		return applier.Apply()
+	})
+}
+
+// Apply applies the Clusterfile like sealos apply.
+func (c *Client) Apply(ctx context.Context, opts ApplyOptions) error {
+	if opts.Clusterfile == "" {
+		return errors.New("clusterfile cannot be empty")
+	}
+	return c.do(ctx, opts.Cluster, opts.Force, func(ctx context.Context) error {
+		args := &apply.Args{}
+		cmd, err := newCommand(ctx, "apply", args.RegisterFlags, opts.flagValues())
+		if err != nil {
+			return err
+		}
+		applier, err := apply.NewApplierFromFile(cmd, opts.Clusterfile, args)
+		if err != nil {
+			return err
+		}
+		if a, ok := applier.(*applydrivers.Applier); ok && opts.Cluster != "" && a.ClusterDesired.Name != opts.Cluster {
+			return fmt.Errorf("name of the Clusterfile %s does not match cluster %s", a.ClusterDesired.Name, opts.Cluster)
+		}
+		return applier.Apply()
+	})
+}
+
+// Add adds the masters and nodes to the cluster like sealos add.
+func (c *Client) Add(ctx context.Context, opts ScaleOptions) error {
+	return c.scale(ctx, "add", opts)
+}
+
+// Delete deletes the masters and nodes from the cluster like sealos delete, it is
+// confirmed by opts.Force or the Confirm function of the Client.
+func (c *Client) Delete(ctx context.Context, opts ScaleOptions) error {
+	if opts.SSH != nil {
+		return errors.New("delete does not support ssh, it reads from the Clusterfile")
+	}
+	if err := processor.ConfirmDeleteNodesContext(c.operationContext(ctx, opts.Force), opts.Force); err != nil {
+		return err
+	}
+	return c.scale(ctx, "delete", opts)
+}
+
+func (c *Client) scale(ctx context.Context, action string, opts ScaleOptions) error {
+	if opts.Masters == "" && opts.Nodes == "" {
+		return errors.New("nodes and masters can't both be empty")
+	}
+	return c.do(ctx, opts.Cluster, opts.Force, func(ctx context.Context) error {
+		args := &apply.ScaleArgs{Cluster: &apply.Cluster{}}
+		if action == "add" {
+			args.SSH = &apply.SSH{}
+		}
+		cmd, err := newCommand(ctx, action, func(fs *pflag.FlagSet) {
+			args.RegisterFlags(fs, action, action)
+		}, opts.flagValues())
+		if err != nil {
+			return err
+		}
+		applier, err := apply.NewScaleApplierFromArgs(cmd, args)
+		if err != nil {
+			return err
+		}
+		return applier.Apply()
+	})
+}
+
+// Reset deletes the cluster like sealos reset, it is confirmed by opts.Force or the
+// Confirm function of the Client.
+func (c *Client) Reset(ctx context.Context, opts ResetOptions) error {
+	if err := processor.ConfirmDeleteNodesContext(c.operationContext(ctx, opts.Force), opts.Force); err != nil {
+		return err
+	}
+	return c.do(ctx, opts.Cluster, opts.Force, func(ctx context.Context) error {
+		args := &apply.ResetArgs{ClusterName: &apply.ClusterName{}, SSH: &apply.SSH{}}
+		cmd, err := newCommand(ctx, "reset", args.RegisterFlags, opts.flagValues())
+		if err != nil {
+			return err
+		}
+		applier, err := apply.NewApplierFromResetArgs(cmd, args)
+		if err != nil {
+			return err
+		}
+		return applier.Delete()
+	})
+}
+
+// Get returns the cluster from the Clusterfile saved by its last operation, it does
+// not wait for the running operation.
+func (c *Client) Get(ctx context.Context, cluster string) (*v2.Cluster, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return clusterfile.GetClusterFromName(clusterName(cluster))
+}
+
+// ClusterStatus is the live state of a cluster.
+type ClusterStatus struct {
+	// Cluster is the Clusterfile saved by the last operation of the cluster.
+	Cluster *v2.Cluster `json:"cluster"`
+	// Busy is true while an operation of the process is running on the cluster.
+	Busy bool `json:"busy"`
+	// Nodes are the nodes registered in the API server of the cluster.
+	Nodes []NodeStatus `json:"nodes"`
+}
+
+// NodeStatus is the state of a node registered in the API server.
+type NodeStatus struct {
+	Name  string `json:"name"`
+	IP    string `json:"ip"`
+	Ready bool   `json:"ready"`
+}
+
+// newKubernetesClient connects to the API server of a cluster, replaced in tests.
+var newKubernetesClient = kubernetes.NewKubernetesClient
+
+// Status returns the live state of the cluster, the nodes are listed from its API server.
+func (c *Client) Status(ctx context.Context, cluster string) (*ClusterStatus, error) {
+	name := clusterName(cluster)
+	saved, err := c.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	status := &ClusterStatus{Cluster: saved, Busy: busy(name)}
+	client, err := newKubernetesClient(constants.NewPathResolver(name).AdminFile(), "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the API server of cluster %s: %w", name, err)
+	}
+	nodes, err := client.Kubernetes().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the nodes of cluster %s: %w", name, err)
+	}
+	for _, node := range nodes.Items {
+		ns := NodeStatus{Name: node.Name}
+		for _, addr := range node.Status.Addresses {
+			if addr.Type == corev1.NodeInternalIP {
+				ns.IP = addr.Address
+			}
+		}
+		for _, cond := range node.Status.Conditions {
+			if cond.Type == corev1.NodeReady {
+				ns.Ready = cond.Status == corev1.ConditionTrue
+			}
+		}
+		status.Nodes = append(status.Nodes, ns)
+	}
+	return status, nil
+}
+
+// busy returns whether the running operation of the process is on the cluster.
+func busy(cluster string) bool {
+	runningMu.Lock()
+	defer runningMu.Unlock()
+	return running == cluster
+}
@@ -0,0 +1,248 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/labring/sealos/pkg/apply/processor"
+	"github.com/labring/sealos/pkg/client-go/kubernetes"
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/utils/confirm"
+)
+
+func withRoots(t *testing.T, data, runtime string) {
+	oldData, oldRuntime := constants.DefaultClusterRootFsDir, constants.DefaultRuntimeRootDir
+	constants.DefaultClusterRootFsDir, constants.DefaultRuntimeRootDir = data, runtime
+	t.Cleanup(func() {
+		constants.DefaultClusterRootFsDir, constants.DefaultRuntimeRootDir = oldData, oldRuntime
+	})
+}
+
+func TestNew(t *testing.T) {
+	runtimeRoot := t.TempDir()
+	withRoots(t, "", "")
+	if _, err := New(Options{DataRoot: "/var/lib/test", RuntimeRoot: runtimeRoot}); err != nil {
+		t.Fatal(err)
+	}
+	if constants.DefaultClusterRootFsDir != "/var/lib/test" || constants.DefaultRuntimeRootDir != runtimeRoot {
+		t.Errorf("unexpected roots %s and %s", constants.DefaultClusterRootFsDir, constants.DefaultRuntimeRootDir)
+	}
+	if _, err := os.Stat(filepath.Join(runtimeRoot, "logs")); err != nil {
+		t.Error(err)
+	}
+	// empty options keep the roots
+	if _, err := New(Options{}); err != nil {
+		t.Error(err)
+	}
+	if _, err := New(Options{RuntimeRoot: t.TempDir()}); err == nil {
+		t.Error("expected an error of changing the runtime root")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	withRoots(t, "/var/lib/test", t.TempDir())
+	c, err := New(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err = c.Run(ctx, RunOptions{}); err == nil {
+		t.Error("expected an error of empty images")
+	}
+	if err = c.Apply(ctx, ApplyOptions{}); err == nil {
+		t.Error("expected an error of empty clusterfile")
+	}
+	if err = c.Add(ctx, ScaleOptions{}); err == nil {
+		t.Error("expected an error of empty nodes")
+	}
+	if err = c.Delete(ctx, ScaleOptions{Nodes: "192.168.0.2", SSH: &SSH{}}); err == nil {
+		t.Error("expected an error of ssh in delete")
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err = c.Reset(ctx, ResetOptions{}); !errors.Is(err, ErrConfirmationRequired) {
+		t.Errorf("expected ErrConfirmationRequired without force, got %v", err)
+	}
+	if err = c.Reset(cancelled, ResetOptions{Force: true}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancelled error, got %v", err)
+	}
+}
+
+func TestLock(t *testing.T) {
+	ctx := context.Background()
+	unlock, err := lock(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !busy("a") || busy("b") {
+		t.Error("only cluster a should be busy")
+	}
+	// operations of all clusters run one at a time
+	locked := make(chan struct{})
+	go func() {
+		u, _ := lock(ctx, "b")
+		close(locked)
+		u()
+	}()
+	select {
+	case <-locked:
+		t.Fatal("operations of the process must be serialized")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	<-locked
+
+	unlock, err = lock(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err = lock(cancelled, "b"); err == nil {
+		t.Error("expected an error of cancelled context")
+	}
+}
+
+type fakeKubernetesClient struct {
+	kubernetes.Client
+	clientset *fake.Clientset
+}
+
+func (f fakeKubernetesClient) Kubernetes() k8s.Interface {
+	return f.clientset
+}
+
+func TestStatus(t *testing.T) {
+	withRoots(t, "/var/lib/test", t.TempDir())
+	cfPath := constants.Clusterfile("default")
+	if err := os.MkdirAll(filepath.Dir(cfPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := "apiVersion: apps.sealos.io/v1beta1\nkind: Cluster\nmetadata:\n  name: default\nspec:\n  hosts:\n  - ips: [192.168.0.2:22]\n    roles: [master]\n"
+	if err := os.WriteFile(cfPath, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "master0"},
+		Status: corev1.NodeStatus{
+			Addresses:  []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "192.168.0.2"}},
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionFalse}},
+		},
+	}
+	old := newKubernetesClient
+	newKubernetesClient = func(string, string) (kubernetes.Client, error) {
+		return fakeKubernetesClient{clientset: fake.NewSimpleClientset(node)}, nil
+	}
+	t.Cleanup(func() { newKubernetesClient = old })
+
+	c := &Client{}
+	unlock, err := lock(context.Background(), "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+	status, err := c.Status(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []NodeStatus{{Name: "master0", IP: "192.168.0.2", Ready: false}}
+	if status.Cluster.Name != "default" || !status.Busy || !reflect.DeepEqual(status.Nodes, want) {
+		t.Errorf("unexpected status %+v", status)
+	}
+}
+
+func TestOperationContext(t *testing.T) {
+	c := &Client{}
+	ctx := c.operationContext(context.Background(), false)
+	if processor.GetForceOverride(ctx) {
+		t.Error("force override should be false")
+	}
+	if _, err := confirm.ConfirmContext(ctx, "continue?", ""); !errors.Is(err, ErrConfirmationRequired) {
+		t.Errorf("expected ErrConfirmationRequired, got %v", err)
+	}
+	ctx = c.operationContext(context.Background(), true)
+	if !processor.GetForceOverride(ctx) {
+		t.Error("force override should be true")
+	}
+	if yes, err := confirm.ConfirmContext(ctx, "continue?", ""); !yes || err != nil {
+		t.Errorf("expected confirmed, got %v, %v", yes, err)
+	}
+	var prompts []string
+	c = &Client{confirm: func(prompt string) (bool, error) {
+		prompts = append(prompts, prompt)
+		return false, nil
+	}}
+	if yes, err := confirm.ConfirmContext(c.operationContext(context.Background(), false), "continue?", ""); yes || err != nil {
+		t.Errorf("expected denied by the confirm function, got %v, %v", yes, err)
+	}
+	if len(prompts) != 1 {
+		t.Errorf("got prompts %v, want one", prompts)
+	}
+}
+
+func TestDeleteConfirmation(t *testing.T) {
+	ctx := context.Background()
+	c := &Client{}
+	if err := c.Delete(ctx, ScaleOptions{Nodes: "192.168.0.3"}); !errors.Is(err, ErrConfirmationRequired) {
+		t.Errorf("expected ErrConfirmationRequired of delete, got %v", err)
+	}
+	if err := c.Reset(ctx, ResetOptions{}); !errors.Is(err, ErrConfirmationRequired) {
+		t.Errorf("expected ErrConfirmationRequired of reset, got %v", err)
+	}
+	c = &Client{confirm: func(string) (bool, error) { return false, nil }}
+	if err := c.Delete(ctx, ScaleOptions{Nodes: "192.168.0.3"}); !errors.Is(err, processor.ErrCancelled) {
+		t.Errorf("expected ErrCancelled of delete, got %v", err)
+	}
+}
+
+func TestFlagValues(t *testing.T) {
+	var (
+		env     []string
+		masters string
+		port    uint16
+	)
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.StringSliceVar(&env, "env", nil, "")
+	fs.StringVar(&masters, "masters", "", "")
+	fs.Uint16Var(&port, "port", 22, "")
+
+	opts := RunOptions{Masters: "192.168.0.2", Env: []string{"a=1,2", "b=3"}, SSH: &SSH{Port: 2222}}
+	if err := opts.flagValues().set(fs); err != nil {
+		t.Fatal(err)
+	}
+	if masters != "192.168.0.2" || port != 2222 || len(env) != 2 || env[0] != "a=1,2" {
+		t.Errorf("unexpected flags %s %d %v", masters, port, env)
+	}
+	if err := (flagValues{"unknown": {"x"}}).set(fs); err == nil {
+		t.Error("expected an error of unknown flag")
+	}
+	if err := (flagValues{"masters": {"a", "b"}}).set(fs); err == nil {
+		t.Error("expected an error of multiple values")
+	}
+}
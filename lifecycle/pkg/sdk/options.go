@@ -0,0 +1,208 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Options are the options of a Client.
+type Options struct {
+	// DataRoot is the root directory of the cluster data on the hosts, it is the
+	// DATA_ROOT of `sealos env` by default.
+	DataRoot string
+	// RuntimeRoot is the local directory of the Clusterfiles and logs, it is the
+	// RUNTIME_ROOT of `sealos env` by default.
+	RuntimeRoot string
+	// Confirm answers the confirmations of the operations without Force, such as the
+	// deletion of the nodes, they fail with ErrConfirmationRequired if it is nil.
+	Confirm ConfirmFunc
+}
+
+// ConfirmFunc answers a confirmation of an operation, the operation is cancelled if
+// it returns false.
+type ConfirmFunc func(prompt string) (bool, error)
+
+// SSH overrides the ssh config of the Clusterfile, like the ssh flags of the command line.
+type SSH struct {
+	User     string
+	Passwd   string
+	Pk       string
+	PkPasswd string
+	Port     uint16
+}
+
+func (s *SSH) addTo(fv flagValues) {
+	if s == nil {
+		return
+	}
+	fv.add("user", s.User)
+	fv.add("passwd", s.Passwd)
+	fv.add("pk", s.Pk)
+	fv.add("pk-passwd", s.PkPasswd)
+	if s.Port != 0 {
+		fv.add("port", strconv.Itoa(int(s.Port)))
+	}
+}
+
+// RunOptions are the options of Client.Run, like the flags of sealos run.
+type RunOptions struct {
+	// Cluster is the name of the cluster, it is "default" if empty.
+	Cluster string
+	Images  []string
+	// Masters and Nodes are the hosts in the format of the --masters and --nodes flags.
+	Masters     string
+	Nodes       string
+	SSH         *SSH
+	Env         []string
+	Cmd         []string
+	ConfigFiles []string
+	// Force overrides the apps which are already installed and answers yes to all
+	// confirmations, the operation fails with ErrConfirmationRequired otherwise.
+	Force bool
+}
+
+func (o *RunOptions) flagValues() flagValues {
+	fv := flagValues{}
+	fv.add("cluster", o.Cluster)
+	fv.add("masters", o.Masters)
+	fv.add("nodes", o.Nodes)
+	fv.add("env", o.Env...)
+	fv.add("cmd", o.Cmd...)
+	fv.add("config-file", o.ConfigFiles...)
+	o.SSH.addTo(fv)
+	return fv
+}
+
+// ApplyOptions are the options of Client.Apply, like the flags of sealos apply.
+type ApplyOptions struct {
+	// Clusterfile is the path of the Clusterfile.
+	Clusterfile string
+	// Cluster is the expected name of the cluster in the Clusterfile, it is not checked if empty.
+	Cluster     string
+	Values      []string
+	Sets        []string
+	Env         []string
+	ConfigFiles []string
+	// Force is the same as RunOptions.Force.
+	Force bool
+}
+
+func (o *ApplyOptions) flagValues() flagValues {
+	fv := flagValues{}
+	fv.add("values", o.Values...)
+	fv.add("set", o.Sets...)
+	fv.add("env", o.Env...)
+	fv.add("config-file", o.ConfigFiles...)
+	return fv
+}
+
+// ScaleOptions are the options of Client.Add and Client.Delete, Delete does not support SSH.
+type ScaleOptions struct {
+	// Cluster is the name of the cluster, it is "default" if empty.
+	Cluster string
+	Masters string
+	Nodes   string
+	SSH     *SSH
+	// Force answers yes to all confirmations, Delete is confirmed by the Confirm
+	// function of the Client or fails with ErrConfirmationRequired otherwise.
+	Force bool
+}
+
+func (o *ScaleOptions) flagValues() flagValues {
+	fv := flagValues{}
+	fv.add("cluster", o.Cluster)
+	fv.add("masters", o.Masters)
+	fv.add("nodes", o.Nodes)
+	o.SSH.addTo(fv)
+	return fv
+}
+
+// ResetOptions are the options of Client.Reset.
+type ResetOptions struct {
+	// Cluster is the name of the cluster, it is "default" if empty.
+	Cluster string
+	SSH     *SSH
+	// Force confirms the deletion of the nodes, like the --force flag, it is confirmed
+	// by the Confirm function of the Client otherwise.
+	Force bool
+}
+
+func (o *ResetOptions) flagValues() flagValues {
+	fv := flagValues{}
+	fv.add("cluster", o.Cluster)
+	o.SSH.addTo(fv)
+	return fv
+}
+
+// flagValues are the command line flags of an operation.
+type flagValues map[string][]string
+
+func (fv flagValues) add(name string, values ...string) {
+	for _, v := range values {
+		if v != "" {
+			fv[name] = append(fv[name], v)
+		}
+	}
+}
+
+// set sets the flags in fs, the elements of slice flags are quoted so that
+// they may contain commas.
+func (fv flagValues) set(fs *pflag.FlagSet) error {
+	for name, values := range fv {
+		f := fs.Lookup(name)
+		if f == nil {
+			return fmt.Errorf("unknown flag %s", name)
+		}
+		isSlice := strings.HasSuffix(f.Value.Type(), "Slice")
+		if !isSlice && len(values) > 1 {
+			return fmt.Errorf("flag %s only accepts one value", name)
+		}
+		for _, v := range values {
+			if isSlice {
+				v = csvQuote(v)
+			}
+			if err := fs.Set(name, v); err != nil {
+				return fmt.Errorf("invalid value of %s: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func csvQuote(s string) string {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	_ = w.Write([]string{s})
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// newCommand returns a command with the flags of the sealos command name, so that the
+// appliers are built from the same flags as when they are run from the command line.
+func newCommand(ctx context.Context, name string, register func(*pflag.FlagSet), fv flagValues) (*cobra.Command, error) {
+	cmd := &cobra.Command{Use: name}
+	cmd.SetContext(ctx)
+	register(cmd.Flags())
+	return cmd, fv.set(cmd.Flags())
+}
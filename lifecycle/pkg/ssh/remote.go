@@ -20,6 +20,7 @@ package ssh
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/labring/sealos/pkg/utils/initsystem"
 
@@ -140,6 +141,12 @@ type initSystem struct {
 	remoter *Remote
 }
 
+// Name returns the init system detected on the target, empty if it is unknown.
+func (s *initSystem) Name() string {
+	out, _ := s.remoter.outputRemoteUtilSubcommand(s.target, "initsystem name")
+	return strings.TrimSpace(out)
+}
+
 func (s *initSystem) ServiceEnable(service string) error {
 	return s.remoter.executeRemoteUtilSubcommand(s.target, fmt.Sprintf(initSystemCommandFmt, "enable", service))
 }
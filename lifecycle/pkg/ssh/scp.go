@@ -211,9 +211,11 @@ func (c *Client) doCopy(client *sftp.Client, host, src, dest string, epu *progre
 			if err = dstfp.Chmod(lfp.Mode()); err != nil {
 				return fmt.Errorf("failed to Chmod dst: %v", err)
 			}
-			if _, err = io.Copy(dstfp, lf); err != nil {
+			n, err := io.Copy(dstfp, lf)
+			if err != nil {
 				return fmt.Errorf("failed to Copy: %v", err)
 			}
+			progress.Transferred(host, dest, n)
 			return nil
 		}(destTmp); err != nil {
 			return err
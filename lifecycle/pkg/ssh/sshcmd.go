@@ -20,7 +20,6 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"os"
 	"strings"
 	"sync"
 
@@ -184,7 +183,7 @@ func (c *Client) handlePipe(host string, pipe io.Reader, out io.Writer, isStdout
 	r := bufio.NewReader(pipe)
 	writers := []io.Writer{out}
 	if isStdout {
-		writers = append(writers, &withPrefixWriter{prefix: host + "\t", newline: true, w: os.Stdout})
+		writers = append(writers, &withPrefixWriter{prefix: host + "\t", newline: true, w: logger.Console()})
 	}
 	w := io.MultiWriter(writers...)
 	var line []byte
@@ -26,8 +26,11 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"sync"
 	"text/template"
 
+	"github.com/pkg/errors"
+
 	"github.com/BurntSushi/toml"
 	"github.com/Masterminds/sprig/v3"
 	"sigs.k8s.io/yaml"
@@ -59,6 +62,89 @@ func funcMap() template.FuncMap {
 	return f
 }
 
+// recursionMaxNums limits the maximum number of nesting of include and tpl.
+const recursionMaxNums = 1000
+
+// helmFuncMap returns the functions of Helm which depend on the template t,
+// include and tpl execute the named templates defined in t.
+func helmFuncMap(t *template.Template) template.FuncMap {
+	var (
+		mu            sync.Mutex
+		includedNames = make(map[string]int)
+	)
+	enter := func(name string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if includedNames[name] > recursionMaxNums {
+			return errors.Errorf("rendering template has a nested reference name: %s", name)
+		}
+		includedNames[name]++
+		return nil
+	}
+	leave := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		includedNames[name]--
+	}
+
+	return template.FuncMap{
+		"include": func(name string, data interface{}) (string, error) {
+			if err := enter(name); err != nil {
+				return "", err
+			}
+			defer leave(name)
+			var buf strings.Builder
+			if err := t.ExecuteTemplate(&buf, name, data); err != nil {
+				return "", err
+			}
+			return buf.String(), nil
+		},
+		"tpl": func(tpl string, data interface{}) (string, error) {
+			if err := enter(tpl); err != nil {
+				return "", err
+			}
+			defer leave(tpl)
+			nt := newTemplate("tpl")
+			// make the named templates available to the text
+			for _, named := range t.Templates() {
+				if named.Tree != nil && named.Name() != t.Name() {
+					if _, err := nt.AddParseTree(named.Name(), named.Tree); err != nil {
+						return "", err
+					}
+				}
+			}
+			if _, err := nt.Parse(tpl); err != nil {
+				return "", errors.Wrapf(err, "cannot parse template %q", tpl)
+			}
+			var buf strings.Builder
+			if err := nt.Execute(&buf, data); err != nil {
+				return "", errors.Wrapf(err, "error during tpl function execution for %q", tpl)
+			}
+			return strings.ReplaceAll(buf.String(), "<no value>", ""), nil
+		},
+		"required": required,
+		"lookup":   lookup,
+	}
+}
+
+// required returns val, or an error with warn if val is nil or an empty string.
+func required(warn string, val interface{}) (interface{}, error) {
+	if val == nil {
+		return val, errors.New(warn)
+	} else if _, ok := val.(string); ok {
+		if val == "" {
+			return val, errors.New(warn)
+		}
+	}
+	return val, nil
+}
+
+// lookup always returns an empty map like `helm template`, since the
+// Clusterfile is rendered before the cluster is available.
+func lookup(_, _, _, _ string) (map[string]interface{}, error) {
+	return map[string]interface{}{}, nil
+}
+
 // toYAML takes an interface, marshals it to yaml, and returns a string. It will
 // always return a string, even on marshal error (empty string).
 //
@@ -23,10 +23,21 @@ import (
 var defaultTpl *template.Template
 
 func init() {
-	// todo: include helm builtin func map
-	defaultTpl = template.New("goTpl").
+	defaultTpl = newTemplate("goTpl")
+}
+
+// newTemplate returns a template with the Sprig and Helm functions.
+func newTemplate(name string) *template.Template {
+	t := template.New(name).
 		Option("missingkey=default").
 		Funcs(funcMap())
+	return t.Funcs(helmFuncMap(t))
+}
+
+// New returns an empty template with the Sprig and Helm functions, which can
+// be parsed and executed independently of the default one.
+func New(name string) *template.Template {
+	return newTemplate(name)
 }
 
 func Parse(text string) (*template.Template, error) {
@@ -15,6 +15,7 @@
 package template
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -34,3 +35,54 @@ func TestTemplateSemverCompare(t *testing.T) {
 	}
 	t.Log(out)
 }
+
+func TestHelmFuncs(t *testing.T) {
+	data := map[string]interface{}{
+		"Values": map[string]interface{}{
+			"name":    "sealos",
+			"version": "v1.25.0",
+			"empty":   "",
+			"tpl":     "{{ .Values.name | upper }}",
+		},
+	}
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr string
+	}{
+		{"default", `{{ .Values.missing | default "x" }}`, "x", ""},
+		{"b64enc", `{{ .Values.name | b64enc }}`, "c2VhbG9z", ""},
+		{"semverCompare", `{{ semverCompare ">=1.24.0" .Values.version }}`, "true", ""},
+		{"required", `{{ required "name is required" .Values.name }}`, "sealos", ""},
+		{"required empty", `{{ required "empty is required" .Values.empty }}`, "", "empty is required"},
+		{"required missing", `{{ required "missing is required" .Values.missing }}`, "", "missing is required"},
+		{"tpl", `{{ tpl .Values.tpl . }}`, "SEALOS", ""},
+		{"include", `{{ define "n" }}{{ .Values.name }}{{ end }}{{ include "n" . | quote }}`, `"sealos"`, ""},
+		{"tpl include", `{{ define "n" }}{{ .Values.name }}{{ end }}{{ tpl "{{ include \"n\" . }}" . }}`, "sealos", ""},
+		{"lookup", `{{ lookup "v1" "Secret" "default" "x" | len }}`, "0", ""},
+		{"recursion", `{{ define "r" }}{{ include "r" . }}{{ end }}{{ include "r" . }}`, "", "nested reference"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl, err := New(tt.name).Parse(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out strings.Builder
+			err = tpl.Execute(&out, data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("got %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -263,4 +263,146 @@ type ClusterSpec struct {
 	// More info: https://kubernetes.io/docs/tasks/inject-data-application/define-command-argument-container/#running-a-command-in-a-shell
 	// +optional
 	Command []string `json:"command,omitempty"`
+	// ImageVerification is the signature verification policy of cluster images,
+	// it's enforced before the images are mounted.
+	// +optional
+	ImageVerification *ImageVerification `json:"imageVerification,omitempty"`
+	// Hooks are user defined commands which run on the hosts before or after
+	// the run, apply, add, delete, reset and upgrade operations.
+	// +optional
+	Hooks []Hook `json:"hooks,omitempty"`
+	// HostConfig is the OS configuration sealos applies to every host before the
+	// init scripts of the rootfs image, and undoes on reset.
+	// +optional
+	HostConfig *HostConfig `json:"hostConfig,omitempty"`
+}
+
+type ImageVerificationMode string
+
+const (
+	// ImageVerificationDisabled skips signature verification, the default.
+	ImageVerificationDisabled ImageVerificationMode = "Disabled"
+	// ImageVerificationWarn logs unverified images but continues.
+	ImageVerificationWarn ImageVerificationMode = "Warn"
+	// ImageVerificationEnforce refuses to mount unverified images.
+	ImageVerificationEnforce ImageVerificationMode = "Enforce"
+)
+
+// ImageVerification defines the trusted sigstore public keys of cluster images,
+// an image is trusted if it's signed by any one of them.
+type ImageVerification struct {
+	Mode ImageVerificationMode `json:"mode,omitempty"`
+	// PublicKeys are paths of PEM encoded public keys on the host which runs sealos.
+	PublicKeys []string `json:"publicKeys,omitempty"`
+	// PublicKeyData are inline PEM encoded public keys.
+	PublicKeyData []string `json:"publicKeyData,omitempty"`
+	// Exclude lists the images which skip verification.
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+type HookPhase string
+
+const (
+	HookPhaseRun     HookPhase = "run"
+	HookPhaseApply   HookPhase = "apply"
+	HookPhaseAdd     HookPhase = "add"
+	HookPhaseDelete  HookPhase = "delete"
+	HookPhaseReset   HookPhase = "reset"
+	HookPhaseUpgrade HookPhase = "upgrade"
+)
+
+var HookPhases = []HookPhase{HookPhaseRun, HookPhaseApply, HookPhaseAdd, HookPhaseDelete, HookPhaseReset, HookPhaseUpgrade}
+
+type HookStage string
+
+const (
+	HookStagePre  HookStage = "pre"
+	HookStagePost HookStage = "post"
+)
+
+type HookFailurePolicy string
+
+const (
+	// HookFailurePolicyFail aborts the operation if the hook fails, the default.
+	HookFailurePolicyFail HookFailurePolicy = "Fail"
+	// HookFailurePolicyIgnore logs the failure and continues.
+	HookFailurePolicyIgnore HookFailurePolicy = "Ignore"
+)
+
+// Hook is a list of shell commands run on the target hosts before or after an operation,
+// with the env of the host, the cluster and SEALOS_HOOK_* variables.
+type Hook struct {
+	Name  string    `json:"name"`
+	Phase HookPhase `json:"phase"`
+	Stage HookStage `json:"stage"`
+	// Commands run in order in a single shell on every target host, the
+	// first failed command fails the hook.
+	Commands []string   `json:"commands"`
+	Target   HookTarget `json:"target,omitempty"`
+	// Timeout of the hook on each host, defaults to 5m.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// +optional
+	FailurePolicy HookFailurePolicy `json:"failurePolicy,omitempty"`
+}
+
+// HookTarget selects the hosts a hook runs on, a host is selected if it
+// matches any of the fields. An empty target selects master0.
+type HookTarget struct {
+	Roles   []string `json:"roles,omitempty"`
+	IPs     []string `json:"ips,omitempty"`
+	Master0 bool     `json:"master0,omitempty"`
+	// Changed restricts the selected hosts to the ones added or deleted by the operation.
+	Changed bool `json:"changed,omitempty"`
+}
+
+type FirewallMode string
+
+const (
+	FirewallModeDisabled FirewallMode = "disabled"
+	FirewallModeEnabled  FirewallMode = "enabled"
+)
+
+type SELinuxMode string
+
+const (
+	SELinuxModeDisabled   SELinuxMode = "disabled"
+	SELinuxModePermissive SELinuxMode = "permissive"
+	SELinuxModeEnforcing  SELinuxMode = "enforcing"
+)
+
+// HostConfig declares the OS settings of the hosts, empty fields leave the
+// setting untouched.
+type HostConfig struct {
+	// Sysctls are set at runtime and persisted in /etc/sysctl.d.
+	Sysctls map[string]string `json:"sysctls,omitempty"`
+	// KernelModules are loaded and persisted in /etc/modules-load.d.
+	KernelModules []string `json:"kernelModules,omitempty"`
+	// DisableSwap turns swap off and comments out the swap entries of /etc/fstab.
+	DisableSwap bool `json:"disableSwap,omitempty"`
+	// Ulimits are written to /etc/security/limits.d.
+	Ulimits []Ulimit `json:"ulimits,omitempty"`
+	// +optional
+	TimeSync *TimeSync `json:"timeSync,omitempty"`
+	// Firewall is the mode of firewalld and ufw.
+	// +optional
+	Firewall FirewallMode `json:"firewall,omitempty"`
+	// +optional
+	SELinux SELinuxMode `json:"selinux,omitempty"`
+}
+
+// Ulimit is an entry of limits.conf.
+type Ulimit struct {
+	// Domain defaults to "*".
+	Domain string `json:"domain,omitempty"`
+	// Type is soft, hard or "-" for both, defaults to "-".
+	Type  string `json:"type,omitempty"`
+	Item  string `json:"item"`
+	Value string `json:"value"`
+}
+
+// TimeSync enables NTP time synchronization on the hosts.
+type TimeSync struct {
+	// Servers override the NTP servers of systemd-timesyncd and chrony.
+	Servers []string `json:"servers,omitempty"`
 }
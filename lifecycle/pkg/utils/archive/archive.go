@@ -58,7 +58,10 @@ func NewArchive(compressible, keepRoot bool) Archive {
 	}
 }
 
-func Tar(src, dst string, compression flags.Compression, cleanup bool) error {
+// Tar creates an archive of src at dst, gzip and zstd are compressed in
+// parallel. If digests is true, the sha256 digests of the files in the archive
+// are written next to it, and checked by Untar.
+func Tar(src, dst string, compression flags.Compression, cleanup, digests bool) error {
 	if compression == flags.Disable {
 		return nil
 	}
@@ -73,17 +76,63 @@ func Tar(src, dst string, compression flags.Compression, cleanup bool) error {
 		return err
 	}
 	defer out.Close()
-	rc, err := archive.Tar(src, compression.Compression())
+	parallel := compression == flags.Gzip || compression == flags.Zstd
+	c := compression.Compression()
+	if parallel {
+		c = archive.Uncompressed
+	}
+	rc, err := archive.Tar(src, c)
 	if err != nil {
 		return err
 	}
 	defer rc.Close()
-	if _, err = io.Copy(out, rc); err != nil {
+
+	var (
+		w  io.Writer = out
+		pw io.WriteCloser
+		dw *digestWriter
+	)
+	if parallel {
+		if pw, err = NewParallelWriter(out, compression); err != nil {
+			return err
+		}
+		w = pw
+	}
+	if digests {
+		if !parallel && compression != flags.Uncompressed {
+			return fmt.Errorf("digests are not supported with compression %s", compression.String())
+		}
+		dw = newDigestWriter()
+		w = io.MultiWriter(w, dw)
+	}
+	if _, err = io.Copy(w, rc); err != nil {
+		if dw != nil {
+			_, _ = dw.Close()
+		}
+		if pw != nil {
+			_ = pw.Close()
+		}
 		return err
 	}
+	if pw != nil {
+		if err = pw.Close(); err != nil {
+			return err
+		}
+	}
+	if dw != nil {
+		ret, err := dw.Close()
+		if err != nil {
+			return fmt.Errorf("failed to compute digests of %s: %v", dst, err)
+		}
+		if err = writeDigests(dst+DigestSuffix, ret); err != nil {
+			return err
+		}
+	}
 	return clean(src, !cleanup)
 }
 
+// Untar unpacks the archives matching paths into dst, the digests of an
+// archive are verified if its digest file exists.
 func Untar(paths []string, dst string, cleanup bool) error {
 	if err := file.MkDirs(dst); err != nil {
 		return err
@@ -105,20 +154,162 @@ func Untar(paths []string, dst string, cleanup bool) error {
 		if err != nil {
 			return err
 		}
-		sources = append(sources, matches...)
+		for i := range matches {
+			if !strings.HasSuffix(matches[i], DigestSuffix) {
+				sources = append(sources, matches[i])
+			}
+		}
 	}
 	logger.Debug("glob matches are: %v", sources)
 	for i := range sources {
-		if err := archive.UntarPath(sources[i], dst); err != nil {
+		if err := untarPath(sources[i], dst); err != nil {
 			return err
 		}
 		if err := clean(sources[i], !cleanup); err != nil {
 			return err
 		}
+		if err := clean(sources[i]+DigestSuffix, !cleanup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// untarPath unpacks src into dst. If src has a digest file, it is unpacked into
+// a temporary directory next to dst first, and moved into dst only when the
+// digests match, so dst never holds the files of a corrupted archive.
+func untarPath(src, dst string) error {
+	if !file.IsFile(src + DigestSuffix) {
+		return untarVerified(src, dst, nil)
+	}
+	expected, err := readDigests(src + DigestSuffix)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".untar-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	if err = untarVerified(src, tmp, expected); err != nil {
+		return err
+	}
+	return mergeDir(tmp, dst)
+}
+
+// untarVerified unpacks src into dst, the digests of the files are verified if expected is not nil.
+func untarVerified(src, dst string, expected map[string]string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	rc, err := NewParallelReader(f)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if expected == nil {
+		return archive.UntarUncompressed(rc, dst, &archive.TarOptions{})
+	}
+
+	dw := newDigestWriter()
+	r := io.TeeReader(rc, dw)
+	err = archive.UntarUncompressed(r, dst, &archive.TarOptions{})
+	if err == nil {
+		// the tar reader may stop before the padding at the end of the stream
+		_, err = io.Copy(io.Discard, r)
+	}
+	actual, digestErr := dw.Close()
+	if err != nil {
+		return err
+	}
+	if digestErr != nil {
+		return fmt.Errorf("failed to compute digests of %s: %v", src, digestErr)
+	}
+	return verifyDigests(src, expected, actual)
+}
+
+// mergeDir moves the entries of src into dst like an extraction into dst would
+// leave them: directories are merged, the other existing entries are replaced.
+func mergeDir(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		from, to := filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())
+		fi, err := os.Lstat(to)
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			return err
+		case entry.IsDir() && fi.IsDir():
+			if err = mergeDir(from, to); err != nil {
+				return err
+			}
+			continue
+		default:
+			if err = os.RemoveAll(to); err != nil {
+				return err
+			}
+		}
+		if err = os.Rename(from, to); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// CompressFile compresses src to dst in parallel with gzip or zstd.
+func CompressFile(src, dst string, compression flags.Compression) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	w, err := NewParallelWriter(out, compression)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(w, in); err != nil {
+		_ = w.Close()
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// DecompressFile decompresses src to dst in parallel, src is copied as is if it is not compressed.
+func DecompressFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	rc, err := NewParallelReader(in)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err = io.Copy(out, rc); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
 func clean(path string, skip bool) (err error) {
 	if !skip {
 		err = os.RemoveAll(path)
@@ -0,0 +1,139 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DigestSuffix is the suffix of the file next to an archive which keeps the
+// sha256 digests of the regular files in it, in the format of sha256sum.
+const DigestSuffix = ".sha256"
+
+type fileDigest struct {
+	name string
+	hex  string
+}
+
+// digestWriter computes the digests of the files in the tar stream written to it.
+type digestWriter struct {
+	pw   *io.PipeWriter
+	done chan struct{}
+	ret  []fileDigest
+	err  error
+}
+
+func newDigestWriter() *digestWriter {
+	pr, pw := io.Pipe()
+	dw := &digestWriter{pw: pw, done: make(chan struct{})}
+	go func() {
+		defer close(dw.done)
+		dw.ret, dw.err = digestTar(pr)
+		// drain the stream, so that the writer is never blocked
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+	return dw
+}
+
+func (dw *digestWriter) Write(p []byte) (int, error) {
+	return dw.pw.Write(p)
+}
+
+// Close waits for the digests to be computed.
+func (dw *digestWriter) Close() ([]fileDigest, error) {
+	_ = dw.pw.Close()
+	<-dw.done
+	return dw.ret, dw.err
+}
+
+func digestTar(r io.Reader) ([]fileDigest, error) {
+	var ret []fileDigest
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return ret, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		h := sha256.New()
+		if _, err = io.Copy(h, tr); err != nil {
+			return nil, err
+		}
+		ret = append(ret, fileDigest{name: hdr.Name, hex: hex.EncodeToString(h.Sum(nil))})
+	}
+}
+
+func writeDigests(path string, digests []fileDigest) error {
+	var sb strings.Builder
+	for _, d := range digests {
+		fmt.Fprintf(&sb, "%s  %s\n", d.hex, d.name)
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0644)
+}
+
+func readDigests(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ret := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "  ", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid digest line %q in %s", line, path)
+		}
+		ret[parts[1]] = parts[0]
+	}
+	return ret, scanner.Err()
+}
+
+// verifyDigests checks the computed digests of an archive against the expected ones.
+func verifyDigests(archive string, expected map[string]string, actual []fileDigest) error {
+	seen := make(map[string]bool, len(actual))
+	for _, d := range actual {
+		want, ok := expected[d.name]
+		if !ok {
+			return fmt.Errorf("file %s in %s has no digest", d.name, archive)
+		}
+		if want != d.hex {
+			return fmt.Errorf("digest of file %s in %s mismatch, expected %s, got %s", d.name, archive, want, d.hex)
+		}
+		seen[d.name] = true
+	}
+	for name := range expected {
+		if !seen[name] {
+			return fmt.Errorf("file %s is missing in %s", name, archive)
+		}
+	}
+	return nil
+}
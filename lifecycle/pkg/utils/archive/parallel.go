@@ -0,0 +1,225 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/containers/storage/pkg/archive"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+
+	"github.com/labring/sealos/pkg/utils/flags"
+)
+
+// DefaultChunkSize is the size of the uncompressed data of each frame written by the parallel writer.
+const DefaultChunkSize = 4 << 20
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+type chunkResult struct {
+	data []byte
+	err  error
+}
+
+// parallelWriter splits the stream into chunks and compresses them concurrently,
+// each chunk is an independently decompressible gzip member or zstd frame, so
+// that the output is still a valid stream for any standard decoder.
+type parallelWriter struct {
+	compress  func(dst *bytes.Buffer, src []byte) error
+	chunkSize int
+	buf       []byte
+	// chunks keeps the pending chunks in order, its capacity bounds the concurrency
+	chunks  chan chan chunkResult
+	done    chan struct{}
+	mu      sync.Mutex
+	err     error
+	written bool
+	closed  bool
+	release func()
+}
+
+// NewParallelWriter returns a writer which compresses the data written to w with
+// gzip or zstd in chunks of DefaultChunkSize using all CPUs.
+func NewParallelWriter(w io.Writer, c flags.Compression) (io.WriteCloser, error) {
+	return newParallelWriter(w, c, DefaultChunkSize, runtime.NumCPU())
+}
+
+func newParallelWriter(w io.Writer, c flags.Compression, chunkSize, concurrency int) (io.WriteCloser, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	pw := &parallelWriter{
+		chunkSize: chunkSize,
+		chunks:    make(chan chan chunkResult, concurrency),
+		done:      make(chan struct{}),
+		release:   func() {},
+	}
+	switch c {
+	case flags.Gzip:
+		gzPool := sync.Pool{New: func() interface{} {
+			return gzip.NewWriter(nil)
+		}}
+		pw.compress = func(dst *bytes.Buffer, src []byte) error {
+			gw := gzPool.Get().(*gzip.Writer)
+			defer gzPool.Put(gw)
+			gw.Reset(dst)
+			if _, err := gw.Write(src); err != nil {
+				return err
+			}
+			return gw.Close()
+		}
+	case flags.Zstd:
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(concurrency))
+		if err != nil {
+			return nil, err
+		}
+		pw.release = func() { _ = enc.Close() }
+		pw.compress = func(dst *bytes.Buffer, src []byte) error {
+			dst.Write(enc.EncodeAll(src, make([]byte, 0, len(src)/2)))
+			return nil
+		}
+	default:
+		return nil, fmt.Errorf("parallel compression of %s is not supported", c.String())
+	}
+	pw.buf = make([]byte, 0, chunkSize)
+	go pw.writeChunks(w)
+	return pw, nil
+}
+
+// writeChunks writes the compressed chunks to w in the order they are written.
+func (pw *parallelWriter) writeChunks(w io.Writer) {
+	defer close(pw.done)
+	for ch := range pw.chunks {
+		ret := <-ch
+		if pw.failed() != nil {
+			continue
+		}
+		if ret.err == nil {
+			_, ret.err = w.Write(ret.data)
+		}
+		if ret.err != nil {
+			pw.mu.Lock()
+			pw.err = ret.err
+			pw.mu.Unlock()
+		}
+	}
+}
+
+func (pw *parallelWriter) failed() error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	return pw.err
+}
+
+func (pw *parallelWriter) flush() {
+	ch := make(chan chunkResult, 1)
+	pw.chunks <- ch
+	go func(src []byte) {
+		var dst bytes.Buffer
+		err := pw.compress(&dst, src)
+		ch <- chunkResult{data: dst.Bytes(), err: err}
+	}(pw.buf)
+	pw.buf = make([]byte, 0, pw.chunkSize)
+	pw.written = true
+}
+
+func (pw *parallelWriter) Write(p []byte) (int, error) {
+	if pw.closed {
+		return 0, errors.New("write to closed writer")
+	}
+	if err := pw.failed(); err != nil {
+		return 0, err
+	}
+	n := len(p)
+	for len(p) > 0 {
+		m := pw.chunkSize - len(pw.buf)
+		if m > len(p) {
+			m = len(p)
+		}
+		pw.buf = append(pw.buf, p[:m]...)
+		p = p[m:]
+		if len(pw.buf) == pw.chunkSize {
+			pw.flush()
+		}
+	}
+	return n, nil
+}
+
+// Close compresses the buffered data and waits for all chunks to be written,
+// it does not close the underlying writer.
+func (pw *parallelWriter) Close() error {
+	if pw.closed {
+		return pw.failed()
+	}
+	pw.closed = true
+	// an empty stream is still written as a frame, so that it can be decompressed
+	if len(pw.buf) > 0 || !pw.written {
+		pw.flush()
+	}
+	close(pw.chunks)
+	<-pw.done
+	pw.release()
+	return pw.failed()
+}
+
+// NewParallelReader returns a reader which decompresses r in parallel if it is
+// a gzip or zstd stream, other compressions and plain streams are read as
+// before, so that existing archives can still be unpacked.
+func NewParallelReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return pgzip.NewReader(br)
+	case bytes.HasPrefix(magic, zstdMagic):
+		dec, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(runtime.NumCPU()))
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	}
+	return archive.DecompressStream(br)
+}
+
+// DetectCompression returns the compression of the stream r, only gzip and
+// zstd are detected, others are treated as uncompressed.
+func DetectCompression(r io.Reader) (flags.Compression, error) {
+	magic := make([]byte, len(zstdMagic))
+	n, err := io.ReadFull(r, magic)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return flags.Uncompressed, err
+	}
+	switch {
+	case bytes.HasPrefix(magic[:n], gzipMagic):
+		return flags.Gzip, nil
+	case bytes.HasPrefix(magic[:n], zstdMagic):
+		return flags.Zstd, nil
+	}
+	return flags.Uncompressed, nil
+}
@@ -0,0 +1,206 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/containers/storage/pkg/archive"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/labring/sealos/pkg/utils/flags"
+)
+
+func testData(n int) []byte {
+	r := rand.New(rand.NewSource(1))
+	data := make([]byte, n)
+	for i := range data {
+		// compressible but not trivial
+		data[i] = byte('a' + r.Intn(8))
+	}
+	return data
+}
+
+func TestParallelWriter(t *testing.T) {
+	for _, c := range []flags.Compression{flags.Gzip, flags.Zstd} {
+		for _, size := range []int{0, 100, 1000, 2500} {
+			data := testData(size)
+			var buf bytes.Buffer
+			w, err := newParallelWriter(&buf, c, 1000, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// write in pieces not aligned to the chunks
+			for p := data; len(p) > 0; {
+				n := 333
+				if n > len(p) {
+					n = len(p)
+				}
+				if _, err = w.Write(p[:n]); err != nil {
+					t.Fatal(err)
+				}
+				p = p[n:]
+			}
+			if err = w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			compressed := buf.Bytes()
+
+			// the output must be readable by the standard decoders
+			var std io.Reader
+			if c == flags.Gzip {
+				std, err = gzip.NewReader(bytes.NewReader(compressed))
+			} else {
+				std, err = zstd.NewReader(bytes.NewReader(compressed))
+			}
+			if err != nil {
+				t.Fatalf("%s/%d: %v", c.String(), size, err)
+			}
+			got, err := io.ReadAll(std)
+			if err != nil || !bytes.Equal(got, data) {
+				t.Errorf("%s/%d: standard decoder got %d bytes, err %v", c.String(), size, len(got), err)
+			}
+
+			r, err := NewParallelReader(bytes.NewReader(compressed))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err = io.ReadAll(r)
+			_ = r.Close()
+			if err != nil || !bytes.Equal(got, data) {
+				t.Errorf("%s/%d: parallel reader got %d bytes, err %v", c.String(), size, len(got), err)
+			}
+		}
+	}
+}
+
+func TestNewParallelWriterUnsupported(t *testing.T) {
+	if _, err := NewParallelWriter(io.Discard, flags.Xz); err == nil {
+		t.Error("expected an error of xz")
+	}
+}
+
+func TestNewParallelReaderPlain(t *testing.T) {
+	r, err := NewParallelReader(strings.NewReader("plain"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(r)
+	if string(got) != "plain" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestDetectCompression(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want flags.Compression
+	}{
+		{[]byte{0x1f, 0x8b, 0x08}, flags.Gzip},
+		{[]byte{0x28, 0xb5, 0x2f, 0xfd, 0x00}, flags.Zstd},
+		{[]byte("ustar"), flags.Uncompressed},
+		{nil, flags.Uncompressed},
+	}
+	for _, tt := range tests {
+		if got, err := DetectCompression(bytes.NewReader(tt.data)); err != nil || got != tt.want {
+			t.Errorf("DetectCompression(%v) = %s, %v, want %s", tt.data, got.String(), err, tt.want.String())
+		}
+	}
+}
+
+func writeTestTree(t *testing.T) string {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{"a.txt": []byte("a"), "sub/b.bin": testData(3 << 20)} {
+		if err := os.WriteFile(filepath.Join(src, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return src
+}
+
+func TestTarUntarDigests(t *testing.T) {
+	for _, c := range []flags.Compression{flags.Uncompressed, flags.Gzip, flags.Zstd} {
+		src := writeTestTree(t)
+		dst := filepath.Join(t.TempDir(), "out.tar")
+		if err := Tar(src, dst, c, false, true); err != nil {
+			t.Fatalf("%s: %v", c.String(), err)
+		}
+		digests, err := readDigests(dst + DigestSuffix)
+		if err != nil || len(digests) != 2 || digests["a.txt"] != "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb" {
+			t.Fatalf("%s: got digests %v, err %v", c.String(), digests, err)
+		}
+		out := t.TempDir()
+		if err = Untar([]string{filepath.Dir(dst)}, out, false); err != nil {
+			t.Fatalf("%s: %v", c.String(), err)
+		}
+		if data, _ := os.ReadFile(filepath.Join(out, "sub/b.bin")); !bytes.Equal(data, testData(3<<20)) {
+			t.Errorf("%s: unexpected content of sub/b.bin", c.String())
+		}
+
+		// tamper the digest of a file
+		if err = os.WriteFile(dst+DigestSuffix, []byte(strings.Repeat("0", 64)+"  a.txt\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		out = t.TempDir()
+		if err = os.WriteFile(filepath.Join(out, "keep.txt"), []byte("keep"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err = Untar([]string{dst}, out, false); err == nil || !strings.Contains(err.Error(), "mismatch") {
+			t.Errorf("%s: expected a digest mismatch, got %v", c.String(), err)
+		}
+		// nothing of the corrupted archive is left in the destination
+		entries, _ := os.ReadDir(out)
+		if len(entries) != 1 || entries[0].Name() != "keep.txt" {
+			t.Errorf("%s: got entries %v in the destination, want only keep.txt", c.String(), entries)
+		}
+	}
+}
+
+func TestUntarCompatible(t *testing.T) {
+	// archives created before the parallel engine are single stream
+	src := writeTestTree(t)
+	for _, c := range []archive.Compression{archive.Gzip, archive.Zstd, archive.Uncompressed} {
+		rc, err := archive.Tar(src, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst := filepath.Join(t.TempDir(), "old.tar")
+		data, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		if err = os.WriteFile(dst, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		out := t.TempDir()
+		if err = Untar([]string{dst}, out, true); err != nil {
+			t.Fatalf("%s: %v", c.Extension(), err)
+		}
+		if data, _ := os.ReadFile(filepath.Join(out, "a.txt")); string(data) != "a" {
+			t.Errorf("%s: got content %q of a.txt", c.Extension(), data)
+		}
+		if _, err = os.Stat(dst); !os.IsNotExist(err) {
+			t.Errorf("%s: archive is not cleaned", c.Extension())
+		}
+	}
+}
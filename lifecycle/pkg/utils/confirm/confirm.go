@@ -16,6 +16,7 @@ package confirm
 
 import (
 	"errors"
+	"io"
 	"os"
 
 	"github.com/manifoldco/promptui"
@@ -23,6 +24,15 @@ import (
 	"github.com/labring/sealos/pkg/utils/logger"
 )
 
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// promptOutput follows the console logs, so the prompts never mix with machine readable stdout.
+func promptOutput() io.WriteCloser {
+	return nopCloser{logger.Console()}
+}
+
 // Confirm is send the prompt and get result
 func Confirm(prompt, cancel string) (bool, error) {
 	var hostname, _ = os.Hostname()
@@ -39,6 +49,7 @@ func Confirm(prompt, cancel string) (bool, error) {
 	promptObj := promptui.Prompt{
 		Label:    promptLabel,
 		Validate: validate,
+		Stdout:   promptOutput(),
 	}
 
 	result, err := promptObj.Run()
@@ -67,6 +78,7 @@ func PasswordInput(promptInput string) string {
 		Label:    promptInput,
 		Validate: validate,
 		Mask:     '*',
+		Stdout:   promptOutput(),
 	}
 
 	result, err := prompt.Run()
@@ -88,8 +100,9 @@ func SelectInput(promptInput string, items []string) string {
 		return ""
 	}
 	prompt := promptui.Select{
-		Label: promptInput,
-		Items: items,
+		Label:  promptInput,
+		Items:  items,
+		Stdout: promptOutput(),
 	}
 
 	_, result, err := prompt.Run()
@@ -107,6 +120,7 @@ func Input(promptInput, defaultValue string, validate func(input string) error)
 		Label:    promptInput,
 		Validate: validate,
 		Default:  defaultValue,
+		Stdout:   promptOutput(),
 	}
 
 	result, err := prompt.Run()
@@ -0,0 +1,40 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confirm
+
+import "context"
+
+// Confirmer answers a confirmation instead of prompting the terminal.
+type Confirmer func(prompt, cancel string) (bool, error)
+
+type confirmerKey struct{}
+
+// WithConfirmer returns a context whose confirmations are answered by c.
+//
+//nolint:staticcheck
+func WithConfirmer(ctx context.Context, c Confirmer) context.Context {
+	return context.WithValue(ctx, confirmerKey{}, c)
+}
+
+// ConfirmContext answers the confirmation with the Confirmer of ctx, it prompts
+// the terminal like Confirm if there is none.
+func ConfirmContext(ctx context.Context, prompt, cancel string) (bool, error) {
+	if ctx != nil {
+		if c, ok := ctx.Value(confirmerKey{}).(Confirmer); ok && c != nil {
+			return c(prompt, cancel)
+		}
+	}
+	return Confirm(prompt, cancel)
+}
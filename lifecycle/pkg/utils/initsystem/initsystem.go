@@ -17,23 +17,39 @@ limitations under the License.
 package initsystem
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"k8s.io/kubernetes/cmd/kubeadm/app/util/initsystem"
 )
 
+const (
+	Systemd = "systemd"
+	OpenRC  = "openrc"
+	SysV    = "sysvinit"
+)
+
 type InitSystem interface {
+	// Name returns the name of the init system, one of systemd, openrc or sysvinit
+	Name() string
 	// ServiceEnable tries to enable a specific service
 	ServiceEnable(service string) error
 	initsystem.InitSystem
 }
 
 type initSystem struct {
+	name string
 	initsystem.InitSystem
 }
 
+func (s *initSystem) Name() string {
+	return s.name
+}
+
 func (s *initSystem) ServiceEnable(service string) error {
 	cmd := s.InitSystem.EnableCommand(service)
 	parts := strings.Split(cmd, " ")
@@ -47,10 +63,55 @@ func (s *initSystem) ServiceEnable(service string) error {
 	return exec.Command(parts[0], args...).Run()
 }
 
+var (
+	// rootDir and lookPath are replaced in tests
+	rootDir  = "/"
+	lookPath = exec.LookPath
+)
+
+func isDir(path string) bool {
+	fi, err := os.Stat(filepath.Join(rootDir, path))
+	return err == nil && fi.IsDir()
+}
+
+func isFile(path string) bool {
+	fi, err := os.Stat(filepath.Join(rootDir, path))
+	return err == nil && !fi.IsDir()
+}
+
+func hasCommand(names ...string) bool {
+	for _, name := range names {
+		if _, err := lookPath(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Detect returns the name of the running init system, or empty if it is not supported.
+func Detect() string {
+	switch {
+	// the same check as sd_booted(3), systemctl may exist where systemd is not running
+	case isDir("run/systemd/system"):
+		return Systemd
+	case hasCommand("openrc", "rc-service") && isDir("etc/init.d"):
+		return OpenRC
+	case hasCommand("systemctl"):
+		return Systemd
+	case hasCommand("update-rc.d", "chkconfig") && isDir("etc/init.d"):
+		return SysV
+	}
+	return ""
+}
+
 func GetInitSystem() (InitSystem, error) {
-	is, err := initsystem.GetInitSystem()
-	if err != nil {
-		return nil, err
+	switch name := Detect(); name {
+	case Systemd:
+		return &initSystem{name, &initsystem.SystemdInitSystem{}}, nil
+	case OpenRC:
+		return &initSystem{name, &initsystem.OpenRCInitSystem{}}, nil
+	case SysV:
+		return &initSystem{name, &sysVInitSystem{}}, nil
 	}
-	return &initSystem{is}, nil
+	return nil, errors.New("no supported init system detected, skipping checking for services")
 }
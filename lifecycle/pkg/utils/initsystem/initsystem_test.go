@@ -0,0 +1,99 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package initsystem
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func fakeHost(t *testing.T, dirs []string, commands ...string) {
+	root := t.TempDir()
+	for _, dir := range dirs {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldRoot, oldLookPath := rootDir, lookPath
+	t.Cleanup(func() { rootDir, lookPath = oldRoot, oldLookPath })
+	rootDir = root
+	lookPath = func(file string) (string, error) {
+		for _, c := range commands {
+			if c == file {
+				return "/usr/bin/" + file, nil
+			}
+		}
+		return "", errors.New("not found")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		dirs     []string
+		commands []string
+		want     string
+	}{
+		{"systemd booted", []string{"run/systemd/system", "etc/init.d"}, []string{"systemctl", "update-rc.d"}, Systemd},
+		{"alpine", []string{"etc/init.d"}, []string{"openrc", "rc-service"}, OpenRC},
+		{"systemctl only", nil, []string{"systemctl"}, Systemd},
+		{"debian sysvinit", []string{"etc/init.d"}, []string{"update-rc.d", "service"}, SysV},
+		{"centos 6", []string{"etc/init.d"}, []string{"chkconfig"}, SysV},
+		{"unknown", nil, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeHost(t, tt.dirs, tt.commands...)
+			if got := Detect(); got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+			is, err := GetInitSystem()
+			if tt.want == "" {
+				if err == nil {
+					t.Error("expected an error of unknown init system")
+				}
+				return
+			}
+			if err != nil || is.Name() != tt.want {
+				t.Errorf("GetInitSystem() = %v, %v", is, err)
+			}
+		})
+	}
+}
+
+func TestSysVInitSystem(t *testing.T) {
+	fakeHost(t, []string{"etc/init.d", "etc/rc3.d"}, "chkconfig")
+	if err := os.WriteFile(filepath.Join(rootDir, "etc/init.d/containerd"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	s := sysVInitSystem{}
+	if got := s.EnableCommand("containerd"); got != "chkconfig containerd on" {
+		t.Errorf("EnableCommand() = %q", got)
+	}
+	if !s.ServiceExists("containerd") || s.ServiceExists("registry") {
+		t.Error("ServiceExists() should only find containerd")
+	}
+	if s.ServiceIsEnabled("containerd") {
+		t.Error("containerd is not enabled yet")
+	}
+	if err := os.Symlink("../init.d/containerd", filepath.Join(rootDir, "etc/rc3.d/S20containerd")); err != nil {
+		t.Fatal(err)
+	}
+	if !s.ServiceIsEnabled("containerd") {
+		t.Error("containerd is enabled in runlevel 3")
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package initsystem
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+)
+
+// sysVInitSystem manages the services by the scripts in /etc/init.d.
+type sysVInitSystem struct{}
+
+func (sysVInitSystem) run(service, verb string) *exec.Cmd {
+	if hasCommand("service") {
+		return exec.Command("service", service, verb)
+	}
+	// nosemgrep: go.lang.security.audit.dangerous-exec-command.dangerous-exec-command
+	return exec.Command(filepath.Join(rootDir, "etc/init.d", service), verb)
+}
+
+// EnableCommand uses update-rc.d on debian based systems, and chkconfig on redhat based systems.
+func (sysVInitSystem) EnableCommand(service string) string {
+	if hasCommand("update-rc.d") {
+		return fmt.Sprintf("update-rc.d %s defaults", service)
+	}
+	return fmt.Sprintf("chkconfig %s on", service)
+}
+
+func (s sysVInitSystem) ServiceStart(service string) error {
+	return s.run(service, "start").Run()
+}
+
+func (s sysVInitSystem) ServiceStop(service string) error {
+	return s.run(service, "stop").Run()
+}
+
+func (s sysVInitSystem) ServiceRestart(service string) error {
+	return s.run(service, "restart").Run()
+}
+
+func (sysVInitSystem) ServiceExists(service string) bool {
+	return isFile(filepath.Join("etc/init.d", service))
+}
+
+// ServiceIsEnabled checks the start links of the multi-user runlevels.
+func (sysVInitSystem) ServiceIsEnabled(service string) bool {
+	for _, dir := range []string{"etc/rc2.d", "etc/rc3.d", "etc/rc5.d", "etc/rc.d/rc3.d"} {
+		matches, _ := filepath.Glob(filepath.Join(rootDir, dir, "S??"+service))
+		if len(matches) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ServiceIsActive relies on the LSB exit code of status, 0 means running.
+func (s sysVInitSystem) ServiceIsActive(service string) bool {
+	return s.ServiceExists(service) && s.run(service, "status").Run() == nil
+}
@@ -16,8 +16,10 @@ package logger
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -27,8 +29,34 @@ import (
 
 var (
 	defaultLogger *zap.Logger
+	// console is the writer of the console logs, see SetConsoleOutput.
+	console = &consoleWriter{w: os.Stdout}
 )
 
+type consoleWriter struct {
+	mu sync.RWMutex
+	w  io.Writer
+}
+
+func (c *consoleWriter) Write(p []byte) (int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.w.Write(p)
+}
+
+// SetConsoleOutput redirects the console logs to w, e.g. to keep stdout for machine readable output.
+func SetConsoleOutput(w io.Writer) {
+	console.mu.Lock()
+	defer console.mu.Unlock()
+	console.w = w
+}
+
+// Console returns the writer of the console logs, output shown to the user
+// alongside the logs is written to it to follow SetConsoleOutput.
+func Console() io.Writer {
+	return console
+}
+
 // init default logger with only console output info above
 func init() {
 	zc := zapcore.NewTee(newConsoleCore(zap.InfoLevel))
@@ -76,7 +104,7 @@ func genConfigs(debugMode bool, showPath bool) (zapcore.LevelEnabler, []zap.Opti
 }
 
 func newConsoleCore(le zapcore.LevelEnabler) zapcore.Core {
-	consoleLogger := zapcore.Lock(os.Stdout)
+	consoleLogger := zapcore.Lock(zapcore.AddSync(console))
 
 	zec := zap.NewProductionEncoderConfig()
 	zec.EncodeLevel = zapcore.LowercaseColorLevelEncoder
@@ -127,31 +155,39 @@ func Panic(f interface{}, v ...interface{}) {
 
 // Error logs a message at error level.
 func Error(f interface{}, v ...interface{}) {
+	notifyHooks(zapcore.ErrorLevel, f, v...)
 	defaultLogger.Sugar().Errorf(formatLog(zapcore.ErrorLevel, f, v...))
 }
 
 // Warn logs a message at warning level.
 func Warn(f interface{}, v ...interface{}) {
+	notifyHooks(zapcore.WarnLevel, f, v...)
 	defaultLogger.Sugar().Warnf(formatLog(zapcore.WarnLevel, f, v...))
 }
 
 // Info logs a message at info level.
 func Info(f interface{}, v ...interface{}) {
+	notifyHooks(zapcore.InfoLevel, f, v...)
 	defaultLogger.Sugar().Infof(formatLog(zapcore.InfoLevel, f, v...))
 }
 
 // Debug logs a message at debug level.
 func Debug(f interface{}, v ...interface{}) {
+	notifyHooks(zapcore.DebugLevel, f, v...)
 	defaultLogger.Sugar().Debugf(formatLog(zapcore.DebugLevel, f, v...))
 }
 
 func formatLog(l zapcore.Level, f interface{}, v ...interface{}) string {
+	return appendColor(l, sprint(f, v...))
+}
+
+func sprint(f interface{}, v ...interface{}) string {
 	var msg string
 	switch f := f.(type) {
 	case string:
 		msg = f
 		if len(v) == 0 {
-			return appendColor(l, msg)
+			return msg
 		}
 		if !strings.Contains(msg, "%") || strings.Contains(msg, "%%") {
 			// do not contain format char
@@ -160,11 +196,47 @@ func formatLog(l zapcore.Level, f interface{}, v ...interface{}) string {
 	default:
 		msg = fmt.Sprint(f)
 		if len(v) == 0 {
-			return appendColor(l, msg)
+			return msg
 		}
 		msg += strings.Repeat(" %v", len(v))
 	}
-	return appendColor(l, fmt.Sprintf(msg, v...))
+	return fmt.Sprintf(msg, v...)
+}
+
+// Hook receives the messages logged at the enabled levels, without colors.
+type Hook func(level zapcore.Level, msg string)
+
+var (
+	hooksMu sync.RWMutex
+	hooks   = map[int]Hook{}
+	hookID  int
+)
+
+// AddHook registers h and returns the func to remove it, e.g. to stream the
+// logs of an operation run by the daemon.
+func AddHook(h Hook) func() {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hookID++
+	id := hookID
+	hooks[id] = h
+	return func() {
+		hooksMu.Lock()
+		defer hooksMu.Unlock()
+		delete(hooks, id)
+	}
+}
+
+func notifyHooks(l zapcore.Level, f interface{}, v ...interface{}) {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	if len(hooks) == 0 || !defaultLogger.Core().Enabled(l) {
+		return
+	}
+	msg := sprint(f, v...)
+	for _, h := range hooks {
+		h(l, msg)
+	}
 }
 
 func appendColor(l zapcore.Level, s string) string {
@@ -15,10 +15,14 @@
 package logger
 
 import (
+	"bytes"
 	"errors"
 	"os"
 	"os/exec"
+	"strings"
 	"testing"
+
+	"go.uber.org/zap/zapcore"
 )
 
 func TestInfoLog(t *testing.T) {
@@ -65,3 +69,29 @@ func TestPanicLog(t *testing.T) {
 	}()
 	Panic("this panics")
 }
+
+func TestAddHook(t *testing.T) {
+	CfgConsoleLogger(false, false)
+	var msgs []string
+	remove := AddHook(func(_ zapcore.Level, msg string) {
+		msgs = append(msgs, msg)
+	})
+	Info("apply %s on host %s", "initializer", "192.168.0.2:22")
+	Debug("cannot see me")
+	remove()
+	Info("removed")
+	if len(msgs) != 1 || msgs[0] != "apply initializer on host 192.168.0.2:22" {
+		t.Errorf("hook got %q", msgs)
+	}
+}
+
+func TestSetConsoleOutput(t *testing.T) {
+	CfgConsoleLogger(false, false)
+	buf := &bytes.Buffer{}
+	SetConsoleOutput(buf)
+	defer SetConsoleOutput(os.Stdout)
+	Info("redirected")
+	if !strings.Contains(buf.String(), "redirected") {
+		t.Errorf("console output got %q", buf.String())
+	}
+}
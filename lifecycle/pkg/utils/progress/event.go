@@ -0,0 +1,183 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	PhaseStarted     EventType = "phaseStarted"
+	PhaseFinished    EventType = "phaseFinished"
+	StepStarted      EventType = "stepStarted"
+	StepFinished     EventType = "stepFinished"
+	BytesTransferred EventType = "bytesTransferred"
+	HostError        EventType = "error"
+)
+
+// Event is a typed progress event of a long operation.
+type Event struct {
+	Time time.Time `json:"time"`
+	Type EventType `json:"type"`
+	// Phase is the innermost running phase, or the phase started or finished.
+	Phase string `json:"phase,omitempty"`
+	Host  string `json:"host,omitempty"`
+	Step  string `json:"step,omitempty"`
+	// Path is the file transferred to the host.
+	Path  string `json:"path,omitempty"`
+	Bytes int64  `json:"bytes,omitempty"`
+	// Duration is the milliseconds a phase or step took.
+	Duration int64  `json:"durationMs,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Sink receives the events, it must be safe for concurrent use.
+type Sink func(Event)
+
+var (
+	mu     sync.RWMutex
+	sinks  = map[int]Sink{}
+	nextID int
+	// phases is the stack of running phases
+	phases []string
+)
+
+// AddSink registers a sink of the events, the returned func removes it.
+func AddSink(s Sink) func() {
+	mu.Lock()
+	defer mu.Unlock()
+	id := nextID
+	nextID++
+	sinks[id] = s
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(sinks, id)
+		if len(sinks) == 0 {
+			phases = nil
+		}
+	}
+}
+
+// NewJSONLSink returns a sink which writes the events to w as json lines.
+func NewJSONLSink(w io.Writer) Sink {
+	var wmu sync.Mutex
+	enc := json.NewEncoder(w)
+	return func(e Event) {
+		wmu.Lock()
+		defer wmu.Unlock()
+		_ = enc.Encode(e)
+	}
+}
+
+func enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(sinks) > 0
+}
+
+func emit(e Event) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if len(sinks) == 0 {
+		return
+	}
+	e.Time = time.Now()
+	if e.Phase == "" && len(phases) > 0 {
+		e.Phase = phases[len(phases)-1]
+	}
+	for _, s := range sinks {
+		s(e)
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// Phase emits the started event of phase, the returned func emits its finished event.
+func Phase(name string) func(err error) {
+	if !enabled() {
+		return func(error) {}
+	}
+	start := time.Now()
+	mu.Lock()
+	phases = append(phases, name)
+	mu.Unlock()
+	emit(Event{Type: PhaseStarted, Phase: name})
+	return func(err error) {
+		mu.Lock()
+		for i := len(phases) - 1; i >= 0; i-- {
+			if phases[i] == name {
+				phases = append(phases[:i], phases[i+1:]...)
+				break
+			}
+		}
+		mu.Unlock()
+		emit(Event{Type: PhaseFinished, Phase: name, Duration: time.Since(start).Milliseconds(), Error: errString(err)})
+	}
+}
+
+// Step emits the started event of step on host, the returned func emits its
+// finished event, and an error event of the host if err is not nil.
+func Step(host, name string) func(err error) {
+	if !enabled() {
+		return func(error) {}
+	}
+	start := time.Now()
+	emit(Event{Type: StepStarted, Host: host, Step: name})
+	return func(err error) {
+		emit(Event{Type: StepFinished, Host: host, Step: name, Duration: time.Since(start).Milliseconds(), Error: errString(err)})
+		if err != nil {
+			emit(Event{Type: HostError, Host: host, Step: name, Error: err.Error()})
+		}
+	}
+}
+
+// RunPhase runs fn as phase.
+func RunPhase(name string, fn func() error) error {
+	done := Phase(name)
+	err := fn()
+	done(err)
+	return err
+}
+
+// RunStep runs fn as step on host.
+func RunStep(host, name string, fn func() error) error {
+	done := Step(host, name)
+	err := fn()
+	done(err)
+	return err
+}
+
+// Transferred emits the bytes of path transferred to host.
+func Transferred(host, path string, bytes int64) {
+	emit(Event{Type: BytesTransferred, Host: host, Path: path, Bytes: bytes})
+}
+
+// Error emits an error of host which is not the result of a step.
+func Error(host string, err error) {
+	if err != nil {
+		emit(Event{Type: HostError, Host: host, Error: err.Error()})
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package progress
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestJSONLSink(t *testing.T) {
+	// no sinks, nothing is emitted
+	_ = RunPhase("ignored", func() error { return nil })
+
+	buf := &bytes.Buffer{}
+	remove := AddSink(NewJSONLSink(buf))
+	_ = RunPhase("Bootstrap", func() error {
+		return RunStep("192.168.0.2:22", "bootstrap initializer", func() error {
+			Transferred("192.168.0.2:22", "/var/lib/sealos/a", 1024)
+			return errors.New("timeout")
+		})
+	})
+	remove()
+	Error("192.168.0.3:22", errors.New("removed"))
+
+	var events []Event
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		e := Event{}
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+	want := []Event{
+		{Type: PhaseStarted, Phase: "Bootstrap"},
+		{Type: StepStarted, Phase: "Bootstrap", Host: "192.168.0.2:22", Step: "bootstrap initializer"},
+		{Type: BytesTransferred, Phase: "Bootstrap", Host: "192.168.0.2:22", Path: "/var/lib/sealos/a", Bytes: 1024},
+		{Type: StepFinished, Phase: "Bootstrap", Host: "192.168.0.2:22", Step: "bootstrap initializer", Error: "timeout"},
+		{Type: HostError, Phase: "Bootstrap", Host: "192.168.0.2:22", Step: "bootstrap initializer", Error: "timeout"},
+		{Type: PhaseFinished, Phase: "Bootstrap", Error: "timeout"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i := range want {
+		got := events[i]
+		if got.Time.IsZero() {
+			t.Errorf("event %d has no time", i)
+		}
+		got.Time, got.Duration = want[i].Time, 0
+		if got != want[i] {
+			t.Errorf("event %d: got %+v, want %+v", i, got, want[i])
+		}
+	}
+}
@@ -77,6 +77,226 @@ func AdminChargeBilling(c *gin.Context) {
 	})
 }
 
+// AdminRefundPayment
+// @Summary Refund payment
+// @Description Refund a payment fully or partially with its payment provider and deduct the balance
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param request body helper.AdminRefundPaymentReq true "Refund payment request"
+// @Success 200 {object} map[string]interface{} "successfully refunded payment"
+// @Failure 400 {object} map[string]interface{} "failed to parse request"
+// @Failure 401 {object} map[string]interface{} "authenticate error"
+// @Failure 500 {object} map[string]interface{} "failed to refund payment"
+// @Router /admin/v1alpha1/refund-payment [post]
+func AdminRefundPayment(c *gin.Context) {
+	admin, err := authenticateAdmin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, helper.ErrorMessage{Error: fmt.Sprintf("authenticate error : %v", err)})
+		return
+	}
+	req, err := helper.ParseAdminRefundPaymentReq(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.ErrorMessage{Error: fmt.Sprintf("failed to parse request : %v", err)})
+		return
+	}
+	req.Operator = adminOperator(admin)
+	refund, err := dao.DBClient.RefundPayment(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.ErrorMessage{Error: fmt.Sprintf("failed to refund payment : %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"refund": refund,
+	})
+}
+
+// AdminReconcileRefunds
+// @Summary Reconcile refunds
+// @Description Refund the pending refunds again with their refund numbers to finish them, the payment providers do not refund them twice
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "successfully reconciled refunds"
+// @Failure 401 {object} map[string]interface{} "authenticate error"
+// @Failure 500 {object} map[string]interface{} "failed to reconcile refunds"
+// @Router /admin/v1alpha1/reconcile-refunds [post]
+func AdminReconcileRefunds(c *gin.Context) {
+	err := authenticateAdminRequest(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, helper.ErrorMessage{Error: fmt.Sprintf("authenticate error : %v", err)})
+		return
+	}
+	refunds, err := dao.DBClient.ReconcileRefunds()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"refunds": refunds, "error": fmt.Sprintf("failed to reconcile refunds : %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"refunds": refunds,
+	})
+}
+
+// AdminCancelRefund
+// @Summary Cancel refund
+// @Description Fail a pending refund which the payment provider confirms is not refunded and restore the balance
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param request body helper.AdminCancelRefundReq true "Cancel refund request"
+// @Success 200 {object} map[string]interface{} "successfully canceled refund"
+// @Failure 400 {object} map[string]interface{} "failed to parse request"
+// @Failure 401 {object} map[string]interface{} "authenticate error"
+// @Failure 500 {object} map[string]interface{} "failed to cancel refund"
+// @Router /admin/v1alpha1/cancel-refund [post]
+func AdminCancelRefund(c *gin.Context) {
+	admin, err := authenticateAdmin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, helper.ErrorMessage{Error: fmt.Sprintf("authenticate error : %v", err)})
+		return
+	}
+	req, err := helper.ParseAdminCancelRefundReq(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.ErrorMessage{Error: fmt.Sprintf("failed to parse request : %v", err)})
+		return
+	}
+	req.Operator = adminOperator(admin)
+	refund, err := dao.DBClient.CancelRefund(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.ErrorMessage{Error: fmt.Sprintf("failed to cancel refund : %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"refund": refund,
+	})
+}
+
+// AdminGetLedgerDiscrepancies
+// @Summary Get ledger discrepancies
+// @Description Get the accounts whose stored balances did not match their ledger in the daily reconciliations
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param userUID query string false "user uid"
+// @Param startTime query string false "start time in RFC3339, default is 24 hours before endTime"
+// @Param endTime query string false "end time in RFC3339, default is now"
+// @Success 200 {object} map[string]interface{} "successfully retrieved ledger discrepancies"
+// @Failure 400 {object} map[string]interface{} "failed to parse request"
+// @Failure 401 {object} map[string]interface{} "authenticate error"
+// @Failure 500 {object} map[string]interface{} "failed to get ledger discrepancies"
+// @Router /admin/v1alpha1/ledger-discrepancies [get]
+func AdminGetLedgerDiscrepancies(c *gin.Context) {
+	err := authenticateAdminRequest(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, helper.ErrorMessage{Error: fmt.Sprintf("authenticate error : %v", err)})
+		return
+	}
+	req, err := helper.ParseAdminLedgerDiscrepanciesReq(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.ErrorMessage{Error: fmt.Sprintf("failed to parse request : %v", err)})
+		return
+	}
+	discrepancies, err := dao.DBClient.GetLedgerDiscrepancies(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.ErrorMessage{Error: fmt.Sprintf("failed to get ledger discrepancies : %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"discrepancies": discrepancies,
+	})
+}
+
+// AdminGetDebtPolicies
+// @Summary Get debt policies
+// @Description Get the debt lifecycle policies of the user tiers
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "successfully retrieved debt policies"
+// @Failure 401 {object} map[string]interface{} "authenticate error"
+// @Failure 500 {object} map[string]interface{} "failed to get debt policies"
+// @Router /admin/v1alpha1/debt-policies [get]
+func AdminGetDebtPolicies(c *gin.Context) {
+	err := authenticateAdminRequest(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, helper.ErrorMessage{Error: fmt.Sprintf("authenticate error : %v", err)})
+		return
+	}
+	policies, err := dao.DBClient.GetDebtPolicies()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.ErrorMessage{Error: fmt.Sprintf("failed to get debt policies : %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"policies": policies,
+	})
+}
+
+// AdminSaveDebtPolicy
+// @Summary Save debt policy
+// @Description Create or update a debt lifecycle policy with the durations, debt thresholds, actions and notification channels of its stages
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param request body helper.AdminSaveDebtPolicyReq true "Save debt policy request"
+// @Success 200 {object} map[string]interface{} "successfully saved debt policy"
+// @Failure 400 {object} map[string]interface{} "failed to parse request"
+// @Failure 401 {object} map[string]interface{} "authenticate error"
+// @Failure 500 {object} map[string]interface{} "failed to save debt policy"
+// @Router /admin/v1alpha1/debt-policies [post]
+func AdminSaveDebtPolicy(c *gin.Context) {
+	err := authenticateAdminRequest(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, helper.ErrorMessage{Error: fmt.Sprintf("authenticate error : %v", err)})
+		return
+	}
+	req, err := helper.ParseAdminSaveDebtPolicyReq(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.ErrorMessage{Error: fmt.Sprintf("failed to parse request : %v", err)})
+		return
+	}
+	policy, err := dao.DBClient.SaveDebtPolicy(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.ErrorMessage{Error: fmt.Sprintf("failed to save debt policy : %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"policy": policy,
+	})
+}
+
+// AdminSetUserDebtPolicy
+// @Summary Set user debt policy
+// @Description Assign a user to a debt lifecycle policy, or back to the default policy if the policy is empty
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param request body helper.AdminSetUserDebtPolicyReq true "Set user debt policy request"
+// @Success 200 {object} map[string]interface{} "successfully set user debt policy"
+// @Failure 400 {object} map[string]interface{} "failed to parse request"
+// @Failure 401 {object} map[string]interface{} "authenticate error"
+// @Failure 500 {object} map[string]interface{} "failed to set user debt policy"
+// @Router /admin/v1alpha1/user-debt-policy [post]
+func AdminSetUserDebtPolicy(c *gin.Context) {
+	err := authenticateAdminRequest(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, helper.ErrorMessage{Error: fmt.Sprintf("authenticate error : %v", err)})
+		return
+	}
+	req, err := helper.ParseAdminSetUserDebtPolicyReq(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.ErrorMessage{Error: fmt.Sprintf("failed to parse request : %v", err)})
+		return
+	}
+	if err = dao.DBClient.SetUserDebtPolicy(req); err != nil {
+		c.JSON(http.StatusInternalServerError, helper.ErrorMessage{Error: fmt.Sprintf("failed to set user debt policy : %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "successfully set user debt policy",
+	})
+}
+
 // AdminGetUserRealNameInfo
 // @Summary Get user real name info
 // @Description Get user real name info
@@ -158,15 +378,30 @@ func AdminGetUserRealNameInfo(c *gin.Context) {
 const AdminUserName = "sealos-admin"
 
 func authenticateAdminRequest(c *gin.Context) error {
+	_, err := authenticateAdmin(c)
+	return err
+}
+
+// authenticateAdmin returns the admin of the request.
+func authenticateAdmin(c *gin.Context) (*helper.JwtUser, error) {
 	user, err := dao.JwtMgr.ParseUser(c)
 	if err != nil {
-		return fmt.Errorf("failed to parse user: %v", err)
+		return nil, fmt.Errorf("failed to parse user: %v", err)
 	}
 	if user == nil {
-		return fmt.Errorf("user not found")
+		return nil, fmt.Errorf("user not found")
 	}
 	if user.Requester != AdminUserName {
-		return fmt.Errorf("user is not admin")
+		return nil, fmt.Errorf("user is not admin")
+	}
+	return user, nil
+}
+
+// adminOperator returns the name of the admin recorded for audit, the user id of the token
+// if it is issued to a user, otherwise the admin requester.
+func adminOperator(admin *helper.JwtUser) string {
+	if admin.UserID != "" {
+		return admin.UserID
 	}
-	return nil
+	return admin.Requester
 }
@@ -1006,6 +1006,162 @@ func GetRechargeDiscount(c *gin.Context) {
 	})
 }
 
+// GetBudget
+// @Summary Get budgets
+// @Description Get the budgets of the user and its workspaces in the region
+// @Tags Budget
+// @Accept json
+// @Produce json
+// @Param request body object true "Get budget request"
+// @Success 200 {object} map[string]interface{} "successfully get budgets"
+// @Failure 401 {object} map[string]interface{} "authenticate error"
+// @Failure 500 {object} map[string]interface{} "failed to get budgets"
+// @Router /account/v1alpha1/budget/get [post]
+func GetBudget(c *gin.Context) {
+	req := &helper.AuthBase{}
+	if err := authenticateRequest(c, req); err != nil {
+		c.JSON(http.StatusUnauthorized, helper.ErrorMessage{Error: fmt.Sprintf("authenticate error : %v", err)})
+		return
+	}
+	budgets, err := dao.DBClient.GetBudgets(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.ErrorMessage{Error: fmt.Sprintf("failed to get budgets : %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"budgets": budgets,
+	})
+}
+
+// SetBudget
+// @Summary Set budget
+// @Description Create or update the monthly budget of the user or one of its workspaces
+// @Tags Budget
+// @Accept json
+// @Produce json
+// @Param request body helper.SetBudgetReq true "Set budget request"
+// @Success 200 {object} map[string]interface{} "successfully set budget"
+// @Failure 400 {object} map[string]interface{} "failed to parse set budget request"
+// @Failure 401 {object} map[string]interface{} "authenticate error"
+// @Failure 500 {object} map[string]interface{} "failed to set budget"
+// @Router /account/v1alpha1/budget/set [post]
+func SetBudget(c *gin.Context) {
+	req, err := helper.ParseSetBudgetReq(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.ErrorMessage{Error: fmt.Sprintf("failed to parse set budget request: %v", err)})
+		return
+	}
+	if err := authenticateRequest(c, req); err != nil {
+		c.JSON(http.StatusUnauthorized, helper.ErrorMessage{Error: fmt.Sprintf("authenticate error : %v", err)})
+		return
+	}
+	budget, err := dao.DBClient.SetBudget(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.ErrorMessage{Error: fmt.Sprintf("failed to set budget : %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"budget": budget,
+	})
+}
+
+// CreateBillingExport
+// @Summary Create billing export
+// @Description Export the billing line items of the user or one of its workspaces in a time range to a CSV or Parquet file in the object storage, the user is notified with the download link
+// @Tags BillingExport
+// @Accept json
+// @Produce json
+// @Param request body helper.CreateBillingExportReq true "Create billing export request"
+// @Success 200 {object} map[string]interface{} "successfully create billing export"
+// @Failure 400 {object} map[string]interface{} "failed to parse create billing export request"
+// @Failure 401 {object} map[string]interface{} "authenticate error"
+// @Failure 409 {object} map[string]interface{} "another billing export of the user is pending"
+// @Failure 500 {object} map[string]interface{} "failed to create billing export"
+// @Router /account/v1alpha1/billing-export/create [post]
+func CreateBillingExport(c *gin.Context) {
+	req, err := helper.ParseCreateBillingExportReq(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.ErrorMessage{Error: fmt.Sprintf("failed to parse create billing export request: %v", err)})
+		return
+	}
+	if err := authenticateRequest(c, req); err != nil {
+		c.JSON(http.StatusUnauthorized, helper.ErrorMessage{Error: fmt.Sprintf("authenticate error : %v", err)})
+		return
+	}
+	export, err := dao.DBClient.CreateBillingExport(req)
+	if errors.Is(err, types.ErrBillingExportPending) {
+		c.JSON(http.StatusConflict, helper.ErrorMessage{Error: fmt.Sprintf("failed to create billing export : %v", err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.ErrorMessage{Error: fmt.Sprintf("failed to create billing export : %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"export": export,
+	})
+}
+
+// GetBillingExports
+// @Summary Get billing exports
+// @Description Get the latest billing exports and the monthly billing export schedules of the user in the region
+// @Tags BillingExport
+// @Accept json
+// @Produce json
+// @Param request body object true "Get billing exports request"
+// @Success 200 {object} map[string]interface{} "successfully get billing exports"
+// @Failure 401 {object} map[string]interface{} "authenticate error"
+// @Failure 500 {object} map[string]interface{} "failed to get billing exports"
+// @Router /account/v1alpha1/billing-export/get [post]
+func GetBillingExports(c *gin.Context) {
+	req := &helper.AuthBase{}
+	if err := authenticateRequest(c, req); err != nil {
+		c.JSON(http.StatusUnauthorized, helper.ErrorMessage{Error: fmt.Sprintf("authenticate error : %v", err)})
+		return
+	}
+	exports, schedules, err := dao.DBClient.GetBillingExports(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.ErrorMessage{Error: fmt.Sprintf("failed to get billing exports : %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"exports":   exports,
+		"schedules": schedules,
+	})
+}
+
+// SetBillingExportSchedule
+// @Summary Set billing export schedule
+// @Description Export the billing line items of the previous month on the first day of every month, or stop the monthly exports
+// @Tags BillingExport
+// @Accept json
+// @Produce json
+// @Param request body helper.SetBillingExportScheduleReq true "Set billing export schedule request"
+// @Success 200 {object} map[string]interface{} "successfully set billing export schedule"
+// @Failure 400 {object} map[string]interface{} "failed to parse set billing export schedule request"
+// @Failure 401 {object} map[string]interface{} "authenticate error"
+// @Failure 500 {object} map[string]interface{} "failed to set billing export schedule"
+// @Router /account/v1alpha1/billing-export/schedule [post]
+func SetBillingExportSchedule(c *gin.Context) {
+	req, err := helper.ParseSetBillingExportScheduleReq(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.ErrorMessage{Error: fmt.Sprintf("failed to parse set billing export schedule request: %v", err)})
+		return
+	}
+	if err := authenticateRequest(c, req); err != nil {
+		c.JSON(http.StatusUnauthorized, helper.ErrorMessage{Error: fmt.Sprintf("authenticate error : %v", err)})
+		return
+	}
+	schedule, err := dao.DBClient.SetBillingExportSchedule(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.ErrorMessage{Error: fmt.Sprintf("failed to set billing export schedule : %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"schedule": schedule,
+	})
+}
+
 // GetUserRealNameInfo
 // @Summary Get user real name information
 // @Description Retrieve the real name information for a user
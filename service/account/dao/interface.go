@@ -2,6 +2,7 @@ package dao
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -19,6 +20,7 @@ import (
 
 	"github.com/labring/sealos/controllers/pkg/database/cockroach"
 
+	"github.com/labring/sealos/controllers/pkg/pay"
 	"github.com/labring/sealos/controllers/pkg/types"
 
 	"github.com/labring/sealos/service/account/common"
@@ -40,6 +42,13 @@ type Interface interface {
 	GetCosts(req helper.ConsumptionRecordReq) (common.TimeCostsMap, error)
 	GetAppCosts(req *helper.AppCostsReq) (*common.AppCosts, error)
 	ChargeBilling(req *helper.AdminChargeBillingReq) error
+	RefundPayment(req *helper.AdminRefundPaymentReq) (*types.Refund, error)
+	ReconcileRefunds() ([]types.Refund, error)
+	CancelRefund(req *helper.AdminCancelRefundReq) (*types.Refund, error)
+	GetLedgerDiscrepancies(req *helper.AdminLedgerDiscrepanciesReq) ([]types.LedgerDiscrepancy, error)
+	GetDebtPolicies() ([]types.DebtPolicy, error)
+	SaveDebtPolicy(req *helper.AdminSaveDebtPolicyReq) (*types.DebtPolicy, error)
+	SetUserDebtPolicy(req *helper.AdminSetUserDebtPolicyReq) error
 	GetAppCostTimeRange(req helper.GetCostAppListReq) (helper.TimeRange, error)
 	GetCostOverview(req helper.GetCostAppListReq) (helper.CostOverviewResp, error)
 	GetBasicCostDistribution(req helper.GetCostAppListReq) (map[string]int64, error)
@@ -65,6 +74,11 @@ type Interface interface {
 	GetLocalRegion() types.Region
 	UseGiftCode(req *helper.UseGiftCodeReq) (*types.GiftCode, error)
 	GetRechargeDiscount(req helper.AuthReq) (helper.RechargeDiscountResp, error)
+	GetBudgets(req helper.AuthReq) ([]types.Budget, error)
+	SetBudget(req *helper.SetBudgetReq) (*types.Budget, error)
+	CreateBillingExport(req *helper.CreateBillingExportReq) (*types.BillingExport, error)
+	GetBillingExports(req helper.AuthReq) ([]types.BillingExport, []types.BillingExportSchedule, error)
+	SetBillingExportSchedule(req *helper.SetBillingExportScheduleReq) (*types.BillingExportSchedule, error)
 	ProcessPendingTaskRewards() error
 	GetUserRealNameInfo(req *helper.GetRealNameInfoReq) (*types.UserRealNameInfo, error)
 	GetEnterpriseRealNameInfo(req *helper.GetRealNameInfoReq) (*types.EnterpriseRealNameInfo, error)
@@ -1331,6 +1345,9 @@ func NewAccountInterface(mongoURI, globalCockRoachURI, localCockRoachURI string)
 		ActiveBillingConn: "active-billing",
 		PropertiesConn:    "properties",
 	}
+	pay.SetExchangeRateFunc(func(method, currency string) (float64, error) {
+		return pay.GetMongoExchangeRate(client, method, currency)
+	})
 	ck, err := cockroach.NewCockRoach(globalCockRoachURI, localCockRoachURI)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect cockroach: %v", err)
@@ -1571,6 +1588,123 @@ func (m *Account) GetRechargeDiscount(req helper.AuthReq) (helper.RechargeDiscou
 	}, nil
 }
 
+func (m *Account) GetBudgets(req helper.AuthReq) ([]types.Budget, error) {
+	if req.GetAuth() == nil || req.GetAuth().UserUID == uuid.Nil {
+		return nil, fmt.Errorf("user uid is empty")
+	}
+	budgets, err := m.ck.GetBudgets(&types.UserQueryOpts{UID: req.GetAuth().UserUID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budgets: %v", err)
+	}
+	return budgets, nil
+}
+
+func (m *Account) SetBudget(req *helper.SetBudgetReq) (*types.Budget, error) {
+	if req.Auth == nil || req.Auth.UserUID == uuid.Nil {
+		return nil, fmt.Errorf("user uid is empty")
+	}
+	if req.Workspace != "" {
+		// only the owner of the workspace pays for it
+		account, err := m.ck.GetAccountWithWorkspace(req.Workspace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get account of workspace: %v", err)
+		}
+		if account.UserUID != req.Auth.UserUID {
+			return nil, fmt.Errorf("workspace %s is not owned by the user", req.Workspace)
+		}
+	}
+	budget := &types.Budget{
+		Workspace:     req.Workspace,
+		MonthlyAmount: req.MonthlyAmount,
+		Thresholds:    req.Thresholds,
+		HardLimit:     req.HardLimit,
+	}
+	if err := m.ck.SetBudget(&types.UserQueryOpts{UID: req.Auth.UserUID}, budget); err != nil {
+		return nil, fmt.Errorf("failed to set budget: %v", err)
+	}
+	return budget, nil
+}
+
+// billingExportLimit is the number of the latest billing exports returned to the user
+const billingExportLimit = 50
+
+func (m *Account) CreateBillingExport(req *helper.CreateBillingExportReq) (*types.BillingExport, error) {
+	if req.Auth == nil || req.Auth.UserUID == uuid.Nil {
+		return nil, fmt.Errorf("user uid is empty")
+	}
+	if err := m.checkWorkspaceOwner(req.Auth.UserUID, req.Workspace); err != nil {
+		return nil, err
+	}
+	export := &types.BillingExport{
+		UserUID:   req.Auth.UserUID,
+		Workspace: req.Workspace,
+		Format:    req.Format,
+		StartTime: req.StartTime.UTC(),
+		EndTime:   req.EndTime.UTC(),
+	}
+	if err := m.ck.CreateBillingExport(export); err != nil {
+		return nil, fmt.Errorf("failed to create billing export: %w", err)
+	}
+	return export, nil
+}
+
+func (m *Account) GetBillingExports(req helper.AuthReq) ([]types.BillingExport, []types.BillingExportSchedule, error) {
+	if req.GetAuth() == nil || req.GetAuth().UserUID == uuid.Nil {
+		return nil, nil, fmt.Errorf("user uid is empty")
+	}
+	exports, err := m.ck.GetBillingExports(req.GetAuth().UserUID, billingExportLimit)
+	if err != nil {
+		return nil, nil, err
+	}
+	schedules, err := m.ck.GetBillingExportSchedules(req.GetAuth().UserUID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return exports, schedules, nil
+}
+
+// SetBillingExportSchedule creates or updates the monthly billing export of the user or one of its
+// workspaces, the schedule is deleted and nil is returned if it is disabled.
+func (m *Account) SetBillingExportSchedule(req *helper.SetBillingExportScheduleReq) (*types.BillingExportSchedule, error) {
+	if req.Auth == nil || req.Auth.UserUID == uuid.Nil {
+		return nil, fmt.Errorf("user uid is empty")
+	}
+	if req.Disable {
+		if err := m.ck.DeleteBillingExportSchedule(req.Auth.UserUID, req.Workspace); err != nil {
+			return nil, fmt.Errorf("failed to delete billing export schedule: %v", err)
+		}
+		return nil, nil
+	}
+	if err := m.checkWorkspaceOwner(req.Auth.UserUID, req.Workspace); err != nil {
+		return nil, err
+	}
+	schedule := &types.BillingExportSchedule{
+		UserUID:   req.Auth.UserUID,
+		Workspace: req.Workspace,
+		Format:    req.Format,
+	}
+	if err := m.ck.SetBillingExportSchedule(schedule); err != nil {
+		return nil, fmt.Errorf("failed to set billing export schedule: %v", err)
+	}
+	return schedule, nil
+}
+
+// checkWorkspaceOwner checks the workspace is owned by the user, the billings of a workspace are
+// owned by the owner of the workspace.
+func (m *Account) checkWorkspaceOwner(userUID uuid.UUID, workspace string) error {
+	if workspace == "" {
+		return nil
+	}
+	account, err := m.ck.GetAccountWithWorkspace(workspace)
+	if err != nil {
+		return fmt.Errorf("failed to get account of workspace: %v", err)
+	}
+	if account.UserUID != userUID {
+		return fmt.Errorf("workspace %s is not owned by the user", workspace)
+	}
+	return nil
+}
+
 func (m *Account) ProcessPendingTaskRewards() error {
 	return m.ck.ProcessPendingTaskRewards()
 }
@@ -1710,6 +1844,91 @@ func (m *Account) ChargeBilling(req *helper.AdminChargeBillingReq) error {
 	return nil
 }
 
+// RefundPayment refunds the payment with its payment provider and deducts the refunded
+// amount and the gift in proportion from the balance of the user. The retry of a refund whose
+// result is unknown finishes it with the same refund number.
+func (m *Account) RefundPayment(req *helper.AdminRefundPaymentReq) (*types.Refund, error) {
+	refund := &types.Refund{
+		PaymentID: req.PaymentID,
+		Amount:    req.Amount,
+		Operator:  req.Operator,
+		Reason:    req.Reason,
+	}
+	if err := m.ck.RefundPayment(refund, refundWithProvider); err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// pendingRefundGrace is the time for the refunds to be finished by their requests before they
+// are reconciled.
+const pendingRefundGrace = time.Minute
+
+// ReconcileRefunds refunds the pending refunds again with the same refund numbers to finish
+// them, it returns the refunds with their results.
+func (m *Account) ReconcileRefunds() ([]types.Refund, error) {
+	return m.ck.ReconcileRefunds(time.Now().Add(-pendingRefundGrace), refundWithProvider)
+}
+
+// CancelRefund fails the pending refund and restores the balance of the user, it is for the
+// refunds which the payment provider confirms are not refunded.
+func (m *Account) CancelRefund(req *helper.AdminCancelRefundReq) (*types.Refund, error) {
+	return m.ck.CancelRefund(req.RefundID, req.Operator, req.Reason)
+}
+
+// refundWithProvider refunds the refund of the payment with its payment provider in the charged
+// currency of the payment. The payments settled before their charged amounts were saved are
+// refunded in CNY converted at the current exchange rate.
+func refundWithProvider(payment *types.Payment, refund *types.Refund) (string, error) {
+	var (
+		payHandler pay.Interface
+		amount     int64
+		err        error
+	)
+	if refund.ChargedAmount > 0 {
+		payHandler, _, err = pay.NewChargeHandler(payment.Method)
+		amount = refund.ChargedAmount
+	} else {
+		payHandler, err = pay.NewPayHandler(payment.Method)
+		// the amount of the payment provider is in the unit of 0.01¥
+		amount = refund.Amount / 10000
+	}
+	if err != nil {
+		return "", fmt.Errorf("get pay handler failed: %v", err)
+	}
+	refundNO, err := payHandler.Refund(payment.TradeNO, refund.OutRefundNO(), amount)
+	if errors.Is(err, pay.ErrRefundRejected) {
+		return "", fmt.Errorf("%w: %v", types.ErrRefundRejected, err)
+	}
+	return refundNO, err
+}
+
+// GetLedgerDiscrepancies returns the accounts whose stored balances did not match their ledger
+// in the reconciliations of the time range.
+func (m *Account) GetLedgerDiscrepancies(req *helper.AdminLedgerDiscrepanciesReq) ([]types.LedgerDiscrepancy, error) {
+	return m.ck.GetLedgerDiscrepancies(req.UserUID, req.StartTime, req.EndTime)
+}
+
+// GetDebtPolicies returns the debt policies of the user tiers.
+func (m *Account) GetDebtPolicies() ([]types.DebtPolicy, error) {
+	return m.ck.GetDebtPolicies()
+}
+
+// SaveDebtPolicy creates or updates the debt policy, it is applied to the users of the policy
+// from their next debt status change.
+func (m *Account) SaveDebtPolicy(req *helper.AdminSaveDebtPolicyReq) (*types.DebtPolicy, error) {
+	policy := &types.DebtPolicy{Name: req.Name, Description: req.Description, Stages: req.Stages}
+	if err := m.ck.SaveDebtPolicy(policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// SetUserDebtPolicy assigns the user to the debt policy.
+func (m *Account) SetUserDebtPolicy(req *helper.AdminSetUserDebtPolicyReq) error {
+	return m.ck.SetUserDebtPolicy(req.UserUID, req.Policy)
+}
+
 func (m *Account) ActiveBilling(req resources.ActiveBilling) error {
 	return m.ck.DB.Transaction(func(tx *gorm.DB) error {
 		if err := m.ck.AddDeductionBalanceWithDB(&types.UserQueryOpts{UID: req.UserUID}, req.Amount, tx); err != nil {
@@ -30,6 +30,11 @@ const (
 	UserUsage                     = "/user-usage"
 	GetRechargeDiscount           = "/recharge-discount"
 	GetUserRealNameInfo           = "/real-name-info"
+	GetBudget                     = "/budget/get"
+	SetBudget                     = "/budget/set"
+	CreateBillingExport           = "/billing-export/create"
+	GetBillingExports             = "/billing-export/get"
+	SetBillingExportSchedule      = "/billing-export/schedule"
 )
 
 const (
@@ -38,6 +43,12 @@ const (
 	AdminChargeBilling           = "/charge-billing"
 	AdminActiveBilling           = "/active-billing"
 	AdminGetUserRealNameInfo     = "/real-name-info"
+	AdminRefundPayment           = "/refund-payment"
+	AdminReconcileRefunds        = "/reconcile-refunds"
+	AdminCancelRefund            = "/cancel-refund"
+	AdminLedgerDiscrepancies     = "/ledger-discrepancies"
+	AdminDebtPolicies            = "/debt-policies"
+	AdminUserDebtPolicy          = "/user-debt-policy"
 )
 
 // env
@@ -6,6 +6,8 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/labring/sealos/controllers/pkg/types"
+
 	"github.com/labring/sealos/service/account/common"
 
 	"github.com/dustin/go-humanize"
@@ -219,6 +221,102 @@ type UseGiftCodeReq struct {
 	AuthBase `json:",inline" bson:",inline"`
 }
 
+type SetBudgetReq struct {
+	// @Summary Workspace of the budget
+	// @Description The namespace of the workspace, empty for the budget of the user
+	Workspace string `json:"workspace,omitempty" bson:"workspace" example:"ns-admin"`
+
+	// @Summary Monthly amount of the budget
+	// @Description The budget of a calendar month, 1000000 = 1¥
+	// @JSONSchema required
+	MonthlyAmount int64 `json:"monthlyAmount" bson:"monthlyAmount" binding:"required" example:"100000000"`
+
+	// @Summary Alert thresholds
+	// @Description The percentages of the budget to alert, default is [50, 80, 100]
+	Thresholds []int `json:"thresholds,omitempty" bson:"thresholds" example:"50,80,100"`
+
+	// @Summary Hard limit
+	// @Description Suspend the resources when the spending reaches the budget
+	HardLimit bool `json:"hardLimit,omitempty" bson:"hardLimit" example:"false"`
+
+	// @Summary Authentication information
+	// @Description Authentication information
+	// @JSONSchema required
+	AuthBase `json:",inline" bson:",inline"`
+}
+
+func ParseSetBudgetReq(c *gin.Context) (*SetBudgetReq, error) {
+	budget := &SetBudgetReq{}
+	if err := c.ShouldBindJSON(budget); err != nil {
+		return nil, fmt.Errorf("bind json error: %v", err)
+	}
+	if budget.MonthlyAmount <= 0 {
+		return nil, fmt.Errorf("monthly amount must be greater than 0")
+	}
+	return budget, nil
+}
+
+type CreateBillingExportReq struct {
+	// @Summary Workspace of the export
+	// @Description The namespace of the workspace, empty for all workspaces of the user
+	Workspace string `json:"workspace,omitempty" bson:"workspace" example:"ns-admin"`
+
+	// @Summary Format of the export
+	// @Description The file format of the export, csv or parquet
+	// @JSONSchema required
+	Format types.BillingExportFormat `json:"format" bson:"format" binding:"required" example:"csv"`
+
+	// @Summary Time range
+	// @Description The time range of the billings, at most 366 days
+	// @JSONSchema required
+	TimeRange `json:",inline" bson:",inline"`
+
+	// @Summary Authentication information
+	// @Description Authentication information
+	// @JSONSchema required
+	AuthBase `json:",inline" bson:",inline"`
+}
+
+func ParseCreateBillingExportReq(c *gin.Context) (*CreateBillingExportReq, error) {
+	export := &CreateBillingExportReq{}
+	if err := c.ShouldBindJSON(export); err != nil {
+		return nil, fmt.Errorf("bind json error: %v", err)
+	}
+	return export, nil
+}
+
+type SetBillingExportScheduleReq struct {
+	// @Summary Workspace of the schedule
+	// @Description The namespace of the workspace, empty for all workspaces of the user
+	Workspace string `json:"workspace,omitempty" bson:"workspace" example:"ns-admin"`
+
+	// @Summary Format of the exports
+	// @Description The file format of the monthly exports, csv or parquet
+	Format types.BillingExportFormat `json:"format,omitempty" bson:"format" example:"parquet"`
+
+	// @Summary Disable the schedule
+	// @Description Stop the monthly exports of the workspace
+	Disable bool `json:"disable,omitempty" bson:"disable" example:"false"`
+
+	// @Summary Authentication information
+	// @Description Authentication information
+	// @JSONSchema required
+	AuthBase `json:",inline" bson:",inline"`
+}
+
+func ParseSetBillingExportScheduleReq(c *gin.Context) (*SetBillingExportScheduleReq, error) {
+	schedule := &SetBillingExportScheduleReq{}
+	if err := c.ShouldBindJSON(schedule); err != nil {
+		return nil, fmt.Errorf("bind json error: %v", err)
+	}
+	if !schedule.Disable {
+		if err := schedule.Format.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return schedule, nil
+}
+
 type GetRealNameInfoReq struct {
 	// @Summary Authentication information
 	// @Description Authentication information
@@ -604,6 +702,121 @@ type AdminChargeBillingReq struct {
 	UserUID   uuid.UUID `json:"userUID" bson:"userUID"`
 }
 
+type AdminRefundPaymentReq struct {
+	// PaymentID is the id of the payment to refund
+	PaymentID string `json:"paymentID" bson:"paymentID" binding:"required" example:"payment-id"`
+	// Amount is the amount to refund, 1000000 = 1¥, it must be a multiple of 10000 (0.01¥)
+	Amount int64 `json:"amount" bson:"amount" binding:"required" example:"100000000"`
+	// Operator is the authenticated admin who issued the refund, it is recorded for audit
+	Operator string `json:"-" bson:"operator"`
+	// Reason is the reason of the refund, it is recorded for audit
+	Reason string `json:"reason" bson:"reason" binding:"required" example:"duplicate payment"`
+}
+
+func ParseAdminRefundPaymentReq(c *gin.Context) (*AdminRefundPaymentReq, error) {
+	refund := &AdminRefundPaymentReq{}
+	if err := c.ShouldBindJSON(refund); err != nil {
+		return nil, fmt.Errorf("bind json error: %v", err)
+	}
+	if refund.Amount <= 0 || refund.Amount%10000 != 0 {
+		return nil, fmt.Errorf("amount must be a positive multiple of 10000")
+	}
+	return refund, nil
+}
+
+type AdminCancelRefundReq struct {
+	// RefundID is the id of the pending refund to cancel
+	RefundID uuid.UUID `json:"refundID" bson:"refundID" binding:"required"`
+	// Operator is the authenticated admin who canceled the refund, it is recorded for audit
+	Operator string `json:"-" bson:"operator"`
+	// Reason is the reason of the cancellation, e.g. the refund is not found by the payment provider
+	Reason string `json:"reason" bson:"reason" binding:"required" example:"refund not found by the provider"`
+}
+
+func ParseAdminCancelRefundReq(c *gin.Context) (*AdminCancelRefundReq, error) {
+	req := &AdminCancelRefundReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		return nil, fmt.Errorf("bind json error: %v", err)
+	}
+	if req.RefundID == uuid.Nil {
+		return nil, fmt.Errorf("refundID must not be empty")
+	}
+	return req, nil
+}
+
+type AdminLedgerDiscrepanciesReq struct {
+	// UserUID is the user of the discrepancies, all users if it is nil
+	UserUID uuid.UUID `json:"userUID"`
+	// StartTime and EndTime are the range of the reconciliation time, default is the last 24 hours
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+}
+
+// ParseAdminLedgerDiscrepanciesReq parses the optional query parameters userUID, startTime and
+// endTime, the times are in RFC3339.
+func ParseAdminLedgerDiscrepanciesReq(c *gin.Context) (*AdminLedgerDiscrepanciesReq, error) {
+	req := &AdminLedgerDiscrepanciesReq{EndTime: time.Now().UTC()}
+	if userUID := c.Query("userUID"); userUID != "" {
+		uid, err := uuid.Parse(userUID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid userUID: %v", err)
+		}
+		req.UserUID = uid
+	}
+	if endTime := c.Query("endTime"); endTime != "" {
+		t, err := time.Parse(time.RFC3339, endTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endTime: %v", err)
+		}
+		req.EndTime = t
+	}
+	req.StartTime = req.EndTime.Add(-24 * time.Hour)
+	if startTime := c.Query("startTime"); startTime != "" {
+		t, err := time.Parse(time.RFC3339, startTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid startTime: %v", err)
+		}
+		req.StartTime = t
+	}
+	if !req.StartTime.Before(req.EndTime) {
+		return nil, fmt.Errorf("startTime must be before endTime")
+	}
+	return req, nil
+}
+
+type AdminSaveDebtPolicyReq struct {
+	// Name is the name of the debt policy, the policy named default is used for the users which are not assigned
+	Name        string `json:"name" bson:"name" binding:"required" example:"enterprise"`
+	Description string `json:"description" bson:"description" example:"longer grace period without deletion"`
+	// Stages are the stages of the debt lifecycle in order
+	Stages []types.DebtStage `json:"stages" bson:"stages" binding:"required"`
+}
+
+func ParseAdminSaveDebtPolicyReq(c *gin.Context) (*AdminSaveDebtPolicyReq, error) {
+	policy := &AdminSaveDebtPolicyReq{}
+	if err := c.ShouldBindJSON(policy); err != nil {
+		return nil, fmt.Errorf("bind json error: %v", err)
+	}
+	return policy, nil
+}
+
+type AdminSetUserDebtPolicyReq struct {
+	UserUID uuid.UUID `json:"userUID" bson:"userUID" binding:"required" example:"user-uid"`
+	// Policy is the name of the debt policy, empty to assign the user back to the default policy
+	Policy string `json:"policy" bson:"policy" example:"enterprise"`
+}
+
+func ParseAdminSetUserDebtPolicyReq(c *gin.Context) (*AdminSetUserDebtPolicyReq, error) {
+	req := &AdminSetUserDebtPolicyReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		return nil, fmt.Errorf("bind json error: %v", err)
+	}
+	if req.UserUID == uuid.Nil {
+		return nil, fmt.Errorf("userUID is empty")
+	}
+	return req, nil
+}
+
 func ParseAdminChargeBillingReq(c *gin.Context) (*AdminChargeBillingReq, error) {
 	rechargeBilling := &AdminChargeBillingReq{}
 	if err := c.ShouldBindJSON(rechargeBilling); err != nil {
@@ -72,11 +72,23 @@ func RegisterPayRouter() {
 		POST(helper.UseGiftCode, api.UseGiftCode).
 		POST(helper.UserUsage, api.UserUsage).
 		POST(helper.GetRechargeDiscount, api.GetRechargeDiscount).
+		POST(helper.GetBudget, api.GetBudget).
+		POST(helper.SetBudget, api.SetBudget).
+		POST(helper.CreateBillingExport, api.CreateBillingExport).
+		POST(helper.GetBillingExports, api.GetBillingExports).
+		POST(helper.SetBillingExportSchedule, api.SetBillingExportSchedule).
 		POST(helper.GetUserRealNameInfo, api.GetUserRealNameInfo)
 	router.Group(helper.AdminGroup).
 		GET(helper.AdminGetAccountWithWorkspace, api.AdminGetAccountWithWorkspaceID).
 		GET(helper.AdminGetUserRealNameInfo, api.AdminGetUserRealNameInfo).
-		POST(helper.AdminChargeBilling, api.AdminChargeBilling)
+		POST(helper.AdminChargeBilling, api.AdminChargeBilling).
+		POST(helper.AdminRefundPayment, api.AdminRefundPayment).
+		POST(helper.AdminReconcileRefunds, api.AdminReconcileRefunds).
+		POST(helper.AdminCancelRefund, api.AdminCancelRefund).
+		GET(helper.AdminLedgerDiscrepancies, api.AdminGetLedgerDiscrepancies).
+		GET(helper.AdminDebtPolicies, api.AdminGetDebtPolicies).
+		POST(helper.AdminDebtPolicies, api.AdminSaveDebtPolicy).
+		POST(helper.AdminUserDebtPolicy, api.AdminSetUserDebtPolicy)
 	//POST(helper.AdminActiveBilling, api.AdminActiveBilling)
 	docs.SwaggerInfo.Host = env.GetEnvWithDefault("SWAGGER_HOST", "localhost:2333")
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))